@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+// HandleAcceptInvitation requires a verified email since invitations are
+// addressed to emails rather than accounts.
+func (s *Server) HandleAcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	user := getAuthenticatedUser(r)
+	invitation, ok := s.getPathInvitation(w, r)
+	if !ok {
+		return
+	}
+	if !user.Verified {
+		http.Error(
+			w,
+			"Verify your email before accepting invitations",
+			http.StatusForbidden,
+		)
+		return
+	}
+
+	member, err := s.store.AcceptProjectInvitation(r.Context(), invitation.Id, user.Id)
+	if errors.Is(err, data.ErrResourceNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	if err := json.NewEncoder(w).Encode(member); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
+
+// getPathInvitation loads the invitation referenced by the {id} path value
+// when it is addressed to the caller, writing the error response itself when
+// it returns false.
+func (s *Server) getPathInvitation(
+	w http.ResponseWriter,
+	r *http.Request,
+) (*models.ProjectInvitation, bool) {
+	id, ok := getPathId(w, r, "id")
+	if !ok {
+		return nil, false
+	}
+	invitation, err := s.store.GetProjectInvitationById(r.Context(), id)
+	if err == nil && invitation.Email != data.NormalizeEmail(getAuthenticatedUser(r).Email) {
+		err = data.ErrResourceNotFound
+	}
+	if errors.Is(err, data.ErrResourceNotFound) {
+		http.Error(w, data.ErrResourceNotFound.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return invitation, true
+}
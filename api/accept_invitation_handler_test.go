@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleAcceptInvitation(t *testing.T) {
+	invitee := *models.NewUser(2, "John Doe", "john.doe@email.com", "password")
+	invitee.Verified = true
+	invitation := models.ProjectInvitation{
+		Id:        1,
+		ProjectId: 1,
+		Email:     invitee.Email,
+		Role:      models.ProjectRoleEditor,
+		InvitedBy: 1,
+	}
+
+	t.Run("makes the user a member with the invited role", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{invitee}
+		store.ProjectInvitations = []models.ProjectInvitation{invitation}
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPost,
+			"/me/invitations/1/accept",
+			invitee.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.Equals(t, store.ProjectMembers, []models.ProjectMember{
+			{ProjectId: 1, UserId: invitee.Id, Role: models.ProjectRoleEditor},
+		})
+		assert.HasLength(t, store.ProjectInvitations, 0)
+
+		response = sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodGet,
+			"/tasks",
+			invitee.Id,
+			"",
+		)
+		assert.Equals(
+			t,
+			testutils.GetTasksFromResponse(t, response.Body),
+			store.Tasks,
+		)
+	})
+
+	t.Run("responds with a 404 Not Found for invitations sent to others", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		other := *models.NewUser(3, "Jane Doe", "jane.doe@email.com", "password")
+		other.Verified = true
+		store.Users = []models.User{invitee, other}
+		store.ProjectInvitations = []models.ProjectInvitation{invitation}
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPost,
+			"/me/invitations/1/accept",
+			other.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusNotFound)
+		assert.HasLength(t, store.ProjectMembers, 0)
+	})
+
+	t.Run("responds with a 403 Forbidden to users with an unverified email", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		unverified := invitee
+		unverified.Verified = false
+		store.Users = []models.User{unverified}
+		store.ProjectInvitations = []models.ProjectInvitation{invitation}
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPost,
+			"/me/invitations/1/accept",
+			invitee.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusForbidden)
+		assert.HasLength(t, store.ProjectMembers, 0)
+	})
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+const (
+	userDisabledAuditAction = "admin.user.disabled"
+	userEnabledAuditAction  = "admin.user.enabled"
+)
+
+func (s *Server) HandleAdminDisableUser(w http.ResponseWriter, r *http.Request) {
+	s.setUserDisabled(w, r, true)
+}
+
+func (s *Server) HandleAdminEnableUser(w http.ResponseWriter, r *http.Request) {
+	s.setUserDisabled(w, r, false)
+}
+
+func (s *Server) setUserDisabled(
+	w http.ResponseWriter,
+	r *http.Request,
+	disabled bool,
+) {
+	user, ok := s.getPathUser(w, r)
+	if !ok {
+		return
+	}
+	if user.Id == getAuthenticatedUser(r).Id {
+		http.Error(w, "You cannot disable or enable your own account", http.StatusBadRequest)
+		return
+	}
+
+	user.Disabled = disabled
+	updatedUser, err := s.store.UpdateUser(r.Context(), user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	action := userEnabledAuditAction
+	if disabled {
+		action = userDisabledAuditAction
+	}
+	if err := s.recordAdminAction(r, action, updatedUser, ""); err != nil {
+		log.Println("error recording the audit event:", err)
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	err = json.NewEncoder(w).Encode(newUserResponse(updatedUser))
+	if err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
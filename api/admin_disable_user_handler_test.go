@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleAdminDisableUser(t *testing.T) {
+	t.Run("disables the account and records it", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		server := NewServer(store)
+
+		response := sendAdminRequest(t, server, http.MethodPost, "/admin/users/2/disable", admin.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.Equals(t, getUserResponse(t, response.Body).Disabled, true)
+		assert.Equals(t, store.Users[1].Disabled, true)
+		assert.HasLength(t, store.AuditEvents, 1)
+		assert.Equals(t, store.AuditEvents[0].Action, userDisabledAuditAction)
+		assert.Equals(t, store.AuditEvents[0].ActorId, admin.Id)
+		assert.Equals(t, store.AuditEvents[0].Subject, "user:2")
+	})
+
+	t.Run("the disabled user can no longer log in or use their token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		server := NewServer(store)
+
+		sendAdminRequest(t, server, http.MethodPost, "/admin/users/2/disable", admin.Id, nil)
+
+		response := sendLogin(t, server, user.Email, user.Password)
+		assert.Status(t, response.Code, http.StatusForbidden)
+		response = sendAdminRequest(t, server, http.MethodGet, "/me", user.Id, nil)
+		assert.Status(t, response.Code, http.StatusForbidden)
+	})
+
+	t.Run("responds with a 400 Bad Request for the admin's own account", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		server := NewServer(store)
+
+		response := sendAdminRequest(t, server, http.MethodPost, "/admin/users/1/disable", admin.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.Calls(t, store.UpdateUserCalls, 0)
+	})
+
+	t.Run("responds with a 404 Not Found for an unknown user", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		server := NewServer(store)
+
+		response := sendAdminRequest(t, server, http.MethodPost, "/admin/users/3/disable", admin.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusNotFound)
+		assert.HasLength(t, store.AuditEvents, 0)
+	})
+
+	t.Run("responds with a 400 Bad Request for an invalid ID", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		server := NewServer(store)
+
+		response := sendAdminRequest(t, server, http.MethodPost, "/admin/users/abc/disable", admin.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+	})
+}
+
+func TestHandleAdminEnableUser(t *testing.T) {
+	t.Run("re-enables the account and records it", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		user.Disabled = true
+		store.Users = []models.User{admin, user}
+		server := NewServer(store)
+
+		response := sendAdminRequest(t, server, http.MethodPost, "/admin/users/2/enable", admin.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.Equals(t, store.Users[1].Disabled, false)
+		assert.HasLength(t, store.AuditEvents, 1)
+		assert.Equals(t, store.AuditEvents[0].Action, userEnabledAuditAction)
+
+		response = sendLogin(t, server, user.Email, user.Password)
+		assert.Status(t, response.Code, http.StatusOK)
+	})
+}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+func (s *Server) HandleAdminGetAuditEvents(w http.ResponseWriter, r *http.Request) {
+	events, err := s.store.GetAuditEvents(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	err = json.NewEncoder(w).Encode(events)
+	if err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
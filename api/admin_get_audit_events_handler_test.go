@@ -0,0 +1,30 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleAdminGetAuditEvents(t *testing.T) {
+	t.Run("returns the audit trail", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		server := NewServer(store)
+
+		sendAdminRequest(t, server, http.MethodPost, "/admin/users/2/disable", admin.Id, nil)
+		response := sendAdminRequest(t, server, http.MethodGet, "/admin/audit-events", admin.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		var events []models.AuditEvent
+		err := json.NewDecoder(response.Body).Decode(&events)
+		assert.HasNoError(t, err)
+		assert.Equals(t, events, store.AuditEvents)
+		assert.HasLength(t, events, 1)
+	})
+}
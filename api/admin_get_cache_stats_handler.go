@@ -0,0 +1,24 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+)
+
+// cacheStatser is a store that caches reads, such as data.CachingStore.
+type cacheStatser interface {
+	Stats() data.CacheStats
+}
+
+// HandleAdminGetCacheStats reports how well the cache is doing since the
+// server started.
+func (s *Server) HandleAdminGetCacheStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", jsonContentType)
+	err := json.NewEncoder(w).Encode(s.store.(cacheStatser).Stats())
+	if err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleAdminGetCacheStats(t *testing.T) {
+	t.Run("reports the cache hits and misses so far", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		store.ProjectMembers = []models.ProjectMember{
+			{ProjectId: 1, UserId: user.Id, Role: models.ProjectRoleViewer},
+		}
+		server := NewServer(data.NewCachingStore(store, 10, time.Minute))
+		for range 2 {
+			response := sendAuthenticatedRequest(t, server, http.MethodGet, "/tasks", user.Id, "")
+			assert.Status(t, response.Code, http.StatusOK)
+		}
+
+		response := sendAdminRequest(t, server, http.MethodGet, "/admin/cache", admin.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		var stats data.CacheStats
+		assert.HasNoError(t, json.NewDecoder(response.Body).Decode(&stats))
+		assert.Equals(t, stats, data.CacheStats{Hits: 1, Misses: 1})
+	})
+
+	t.Run("responds with a 403 Forbidden to users", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		server := NewServer(data.NewCachingStore(store, 10, time.Minute))
+
+		response := sendAdminRequest(t, server, http.MethodGet, "/admin/cache", user.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusForbidden)
+	})
+
+	t.Run("responds with a 404 Not Found without a cache", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		server := NewServer(store)
+
+		response := sendAdminRequest(t, server, http.MethodGet, "/admin/cache", admin.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusNotFound)
+	})
+}
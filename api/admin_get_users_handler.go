@@ -0,0 +1,26 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+func (s *Server) HandleAdminGetUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := s.store.GetUsers(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := []UserResponse{}
+	for _, user := range users {
+		response = append(response, newUserResponse(&user))
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleAdminGetUsers(t *testing.T) {
+	t.Run("returns every user", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		server := NewServer(store)
+
+		response := sendAdminRequest(t, server, http.MethodGet, "/admin/users", admin.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.ContentType(
+			t,
+			testutils.GetContentTypeFromResponse(response),
+			jsonContentType,
+		)
+		decoder := json.NewDecoder(response.Body)
+		decoder.DisallowUnknownFields()
+		var users []UserResponse
+		err := decoder.Decode(&users)
+		assert.HasNoError(t, err)
+		assert.Equals(t, users, []UserResponse{
+			newUserResponse(&admin),
+			newUserResponse(&user),
+		})
+		assert.Calls(t, store.GetUsersCalls, 1)
+	})
+}
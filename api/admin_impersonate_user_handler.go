@@ -0,0 +1,49 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+const userImpersonatedAuditAction = "admin.user.impersonated"
+
+func (s *Server) HandleAdminImpersonateUser(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	user, ok := s.getPathUser(w, r)
+	if !ok {
+		return
+	}
+	admin := getAuthenticatedUser(r)
+	if user.Id == admin.Id {
+		http.Error(w, "You cannot impersonate yourself", http.StatusBadRequest)
+		return
+	}
+	if user.Disabled {
+		http.Error(w, "Disabled accounts cannot be impersonated", http.StatusBadRequest)
+		return
+	}
+
+	tokenString, _, err := s.createImpersonationToken(user.Id, admin.Id)
+	if err != nil {
+		log.Println("error signing the JWT:", err)
+		http.Error(w, "Error creating the JWT", http.StatusInternalServerError)
+		return
+	}
+
+	// Impersonation is only allowed when it can be traced back to the admin.
+	err = s.recordAdminAction(r, userImpersonatedAuditAction, user, "")
+	if err != nil {
+		log.Println("error recording the audit event:", err)
+		http.Error(w, "Error recording the audit event", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	err = json.NewEncoder(w).Encode(LoginResponse{AccessToken: tokenString})
+	if err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleAdminImpersonateUser(t *testing.T) {
+	t.Run("returns a token acting as the user and records it", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		server := NewServer(store)
+
+		response := sendAdminRequest(t, server, http.MethodPost, "/admin/users/2/impersonate", admin.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		var body LoginResponse
+		err := json.NewDecoder(response.Body).Decode(&body)
+		assert.HasNoError(t, err)
+		claims, err := server.parseAccessTokenClaims(body.AccessToken)
+		assert.HasNoError(t, err)
+		assert.Equals(t, claims.Subject, strconv.Itoa(user.Id))
+		assert.Equals(t, claims.ImpersonatorId, admin.Id)
+		assert.Equals(
+			t,
+			claims.ExpiresAt.Sub(claims.IssuedAt.Time),
+			impersonationTokenTtl,
+		)
+		assert.HasLength(t, store.AuditEvents, 1)
+		assert.Equals(t, store.AuditEvents[0].Action, userImpersonatedAuditAction)
+		assert.Equals(t, store.AuditEvents[0].ActorId, admin.Id)
+		assert.Equals(t, store.AuditEvents[0].Subject, "user:2")
+
+		request := httptest.NewRequest(http.MethodGet, "/me", nil)
+		request.Header.Set("Authorization", "Bearer "+body.AccessToken)
+		response = httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.Equals(t, getUserResponse(t, response.Body), newUserResponse(&user))
+	})
+
+	t.Run("responds with a 400 Bad Request for the admin's own account", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		server := NewServer(store)
+
+		response := sendAdminRequest(t, server, http.MethodPost, "/admin/users/1/impersonate", admin.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.HasLength(t, store.AuditEvents, 0)
+	})
+
+	t.Run("responds with a 400 Bad Request for a disabled account", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		user.Disabled = true
+		store.Users = []models.User{admin, user}
+		server := NewServer(store)
+
+		response := sendAdminRequest(t, server, http.MethodPost, "/admin/users/2/impersonate", admin.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.HasLength(t, store.AuditEvents, 0)
+	})
+}
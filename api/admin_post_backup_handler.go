@@ -0,0 +1,37 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+const backupCreatedAuditAction = "admin.backup.created"
+
+// HandleAdminPostBackup takes a snapshot of the database right away, for
+// instance before an upgrade.
+func (s *Server) HandleAdminPostBackup(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := s.backups.Backup(r.Context())
+	if err != nil {
+		log.Println("error backing up the database:", err)
+		http.Error(w, "Error backing up the database", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = s.store.CreateAuditEvent(r.Context(), newRequestAuditEvent(
+		r,
+		backupCreatedAuditAction,
+		"backup:"+snapshot.Name,
+		"",
+	))
+	if err != nil {
+		log.Println("error recording the audit event:", err)
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(snapshot)
+	if err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
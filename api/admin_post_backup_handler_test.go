@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/backup"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+type stubBackuper struct {
+	snapshot *backup.Snapshot
+	err      error
+	calls    int
+}
+
+func (b *stubBackuper) Backup(ctx context.Context) (*backup.Snapshot, error) {
+	b.calls++
+	return b.snapshot, b.err
+}
+
+func TestHandleAdminPostBackup(t *testing.T) {
+	snapshot := &backup.Snapshot{
+		Name:      "snapshot-20261019T120000.000Z.db",
+		Size:      4096,
+		CreatedAt: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("takes a snapshot and records it", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		backups := &stubBackuper{snapshot: snapshot}
+		server := NewServer(store, WithBackups(backups))
+
+		response := sendAdminRequest(t, server, http.MethodPost, "/admin/backups", admin.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusCreated)
+		var body backup.Snapshot
+		assert.HasNoError(t, json.NewDecoder(response.Body).Decode(&body))
+		assert.Equals(t, body, *snapshot)
+		assert.Equals(t, backups.calls, 1)
+		assert.HasLength(t, store.AuditEvents, 1)
+		assert.Equals(t, store.AuditEvents[0].Action, backupCreatedAuditAction)
+		assert.Equals(t, store.AuditEvents[0].Subject, "backup:"+snapshot.Name)
+	})
+
+	t.Run("responds with a 500 Internal Server Error when the backup fails", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		server := NewServer(store, WithBackups(&stubBackuper{err: errors.New("disk full")}))
+
+		response := sendAdminRequest(t, server, http.MethodPost, "/admin/backups", admin.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusInternalServerError)
+		assert.HasLength(t, store.AuditEvents, 0)
+	})
+
+	t.Run("responds with a 403 Forbidden to regular users", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		backups := &stubBackuper{snapshot: snapshot}
+		server := NewServer(store, WithBackups(backups))
+
+		response := sendAdminRequest(t, server, http.MethodPost, "/admin/backups", user.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusForbidden)
+		assert.Equals(t, backups.calls, 0)
+	})
+
+	t.Run("responds with a 404 Not Found without backups", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		server := NewServer(store)
+
+		response := sendAdminRequest(t, server, http.MethodPost, "/admin/backups", admin.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/utils"
+)
+
+const userPasswordResetForcedAuditAction = "admin.user.password_reset_forced"
+
+// HandleAdminResetUserPassword replaces the user's password with a random one
+// nobody knows, which also signs them out everywhere and deletes their API
+// tokens, and mails them a reset token, so the only way back in is through
+// the reset flow.
+func (s *Server) HandleAdminResetUserPassword(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	user, ok := s.getPathUser(w, r)
+	if !ok {
+		return
+	}
+
+	password, err := utils.GenerateToken()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := s.store.UpdateUserPassword(r.Context(), user.Id, password); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = s.recordAdminAction(r, userPasswordResetForcedAuditAction, user, "")
+	if err != nil {
+		log.Println("error recording the audit event:", err)
+	}
+
+	if err := s.sendPasswordResetEmail(r.Context(), user); err != nil {
+		log.Println("error sending the password reset email:", err)
+		http.Error(w, "Error sending the password reset email", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
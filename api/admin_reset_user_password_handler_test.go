@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/mail"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleAdminResetUserPassword(t *testing.T) {
+	t.Run("invalidates the password and mails a reset token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		mailer := mail.NewMemoryMailer()
+		server := NewServer(store, WithMailer(mailer))
+
+		response := sendAdminRequest(t, server, http.MethodPost, "/admin/users/2/password-reset", admin.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusNoContent)
+		assert.HasLength(t, store.AuditEvents, 1)
+		assert.Equals(t, store.AuditEvents[0].Action, userPasswordResetForcedAuditAction)
+		assert.Equals(t, store.AuditEvents[0].ActorId, admin.Id)
+
+		response = sendLogin(t, server, user.Email, user.Password)
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+
+		token := getMailedToken(t, mailer)
+		response = sendResetPassword(t, server, token, "new-password")
+		assert.Status(t, response.Code, http.StatusNoContent)
+		response = sendLogin(t, server, user.Email, "new-password")
+		assert.Status(t, response.Code, http.StatusOK)
+	})
+
+	t.Run("responds with a 404 Not Found for an unknown user", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		mailer := mail.NewMemoryMailer()
+		server := NewServer(store, WithMailer(mailer))
+
+		response := sendAdminRequest(t, server, http.MethodPost, "/admin/users/3/password-reset", admin.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusNotFound)
+		assert.HasLength(t, mailer.Messages(), 0)
+	})
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+const userRoleChangedAuditAction = "admin.user.role_changed"
+
+type SetUserRoleRequest struct {
+	Role string `json:"role"`
+}
+
+func (s *Server) HandleAdminSetUserRole(w http.ResponseWriter, r *http.Request) {
+	var body SetUserRoleRequest
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		http.Error(w, "Error parsing the JSON body", http.StatusBadRequest)
+		return
+	}
+	if !isValidRole(body.Role) {
+		http.Error(w, fmt.Sprintf("Role %q is invalid", body.Role), http.StatusBadRequest)
+		return
+	}
+
+	user, ok := s.getPathUser(w, r)
+	if !ok {
+		return
+	}
+	if user.Id == getAuthenticatedUser(r).Id {
+		http.Error(w, "You cannot change your own role", http.StatusBadRequest)
+		return
+	}
+
+	previousRole := user.Role
+	user.Role = body.Role
+	updatedUser, err := s.store.UpdateUser(r.Context(), user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = s.recordAdminAction(
+		r,
+		userRoleChangedAuditAction,
+		updatedUser,
+		fmt.Sprintf("from %s to %s", previousRole, updatedUser.Role),
+	)
+	if err != nil {
+		log.Println("error recording the audit event:", err)
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	err = json.NewEncoder(w).Encode(newUserResponse(updatedUser))
+	if err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleAdminSetUserRole(t *testing.T) {
+	t.Run("changes the role and records it", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		server := NewServer(store)
+
+		response := sendAdminRequest(
+			t,
+			server,
+			http.MethodPut,
+			"/admin/users/2/role",
+			admin.Id,
+			strings.NewReader(`{"role":"admin"}`),
+		)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.Equals(t, getUserResponse(t, response.Body).Role, models.RoleAdmin)
+		assert.Equals(t, store.Users[1].Role, models.RoleAdmin)
+		assert.HasLength(t, store.AuditEvents, 1)
+		assert.Equals(t, store.AuditEvents[0].Action, userRoleChangedAuditAction)
+		assert.Equals(t, store.AuditEvents[0].Details, "from user to admin")
+
+		response = sendAdminRequest(t, server, http.MethodGet, "/admin/users", user.Id, nil)
+		assert.Status(t, response.Code, http.StatusOK)
+	})
+
+	t.Run("responds with a 400 Bad Request given an unknown role", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		server := NewServer(store)
+
+		response := sendAdminRequest(
+			t,
+			server,
+			http.MethodPut,
+			"/admin/users/2/role",
+			admin.Id,
+			strings.NewReader(`{"role":"superuser"}`),
+		)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.Calls(t, store.UpdateUserCalls, 0)
+	})
+
+	t.Run("responds with a 400 Bad Request for the admin's own account", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		server := NewServer(store)
+
+		response := sendAdminRequest(
+			t,
+			server,
+			http.MethodPut,
+			"/admin/users/1/role",
+			admin.Id,
+			strings.NewReader(`{"role":"user"}`),
+		)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.Equals(t, store.Users[0].Role, models.RoleAdmin)
+	})
+}
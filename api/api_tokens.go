@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/utils"
+)
+
+// apiTokenPrefix tells API tokens apart from JWTs and makes them easy to spot
+// in leaked logs or repositories.
+const apiTokenPrefix = "tdl_"
+
+func isApiToken(tokenString string) bool {
+	return strings.HasPrefix(tokenString, apiTokenPrefix)
+}
+
+func generateApiToken() (string, error) {
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	return apiTokenPrefix + token, nil
+}
+
+func (s *Server) authenticateApiToken(
+	ctx context.Context,
+	tokenString string,
+) (*models.ApiToken, error) {
+	apiToken, err := s.store.GetApiTokenByHash(ctx, utils.HashToken(tokenString))
+	if errors.Is(err, data.ErrResourceNotFound) {
+		return nil, errInvalidAccessToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if apiToken.ExpiresAt != nil && !now.Before(*apiToken.ExpiresAt) {
+		return nil, errInvalidAccessToken
+	}
+	if err := s.store.UpdateApiTokenLastUsedAt(ctx, apiToken.Id, now); err != nil {
+		log.Println("error updating the API token's last use:", err)
+	}
+	apiToken.LastUsedAt = &now
+	return apiToken, nil
+}
+
+func getAuthenticatedApiToken(r *http.Request) *models.ApiToken {
+	apiToken, _ := r.Context().Value(apiTokenContextKey).(*models.ApiToken)
+	return apiToken
+}
+
+// hasScope reports whether the request may act within scope; logged-in
+// sessions have every scope, API tokens only the ones they were created with.
+func hasScope(r *http.Request, scope string) bool {
+	apiToken := getAuthenticatedApiToken(r)
+	return apiToken == nil || slices.Contains(apiToken.Scopes, scope)
+}
+
+func (s *Server) requireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return s.requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		if !hasScope(r, scope) {
+			http.Error(
+				w,
+				"The API token is missing the "+scope+" scope",
+				http.StatusForbidden,
+			)
+			return
+		}
+		next(w, r)
+	})
+}
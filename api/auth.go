@@ -0,0 +1,322 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const defaultAccessTokenTtl = 24 * time.Hour
+const impersonationTokenTtl = time.Hour
+const accessTokenCookieName = "token"
+
+const accountDisabledMessage = "This account is disabled"
+
+type contextKey int
+
+const (
+	userContextKey contextKey = iota
+	apiTokenContextKey
+	impersonatorContextKey
+)
+
+const impersonatedRequestAuditAction = "admin.user.impersonated_request"
+
+var errInvalidAccessToken = errors.New("invalid access token")
+
+type accessTokenClaims struct {
+	jwt.RegisteredClaims
+	ImpersonatorId int `json:"impersonatorId,omitempty"`
+}
+
+func (s *Server) createAccessToken(userId int) (string, time.Time, error) {
+	return s.signAccessToken(userId, 0, s.accessTokenTtl)
+}
+
+// createImpersonationToken lets impersonatorId act as userId for a shorter
+// time than a regular login; the token records who is impersonating.
+func (s *Server) createImpersonationToken(
+	userId, impersonatorId int,
+) (string, time.Time, error) {
+	return s.signAccessToken(userId, impersonatorId, impersonationTokenTtl)
+}
+
+func (s *Server) signAccessToken(
+	userId, impersonatorId int,
+	ttl time.Duration,
+) (string, time.Time, error) {
+	expirationTime := s.now().Add(ttl)
+	token := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		accessTokenClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   strconv.Itoa(userId),
+				ExpiresAt: jwt.NewNumericDate(expirationTime),
+				IssuedAt:  jwt.NewNumericDate(s.now()),
+			},
+			ImpersonatorId: impersonatorId,
+		},
+	)
+	tokenString, err := token.SignedString(s.jwtKey)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return tokenString, expirationTime, nil
+}
+
+// startSession sets the access token cookie for the user and returns the
+// token so it can also be handed out as a bearer token.
+func (s *Server) startSession(w http.ResponseWriter, userId int) (string, error) {
+	tokenString, expirationTime, err := s.createAccessToken(userId)
+	if err != nil {
+		return "", err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     accessTokenCookieName,
+		Value:    tokenString,
+		Expires:  expirationTime,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return tokenString, nil
+}
+
+func (s *Server) parseAccessToken(tokenString string) (int, error) {
+	claims, err := s.parseAccessTokenClaims(tokenString)
+	if err != nil {
+		return 0, err
+	}
+	return claims.userId()
+}
+
+// userId returns the ID of the user the token acts as.
+func (c *accessTokenClaims) userId() (int, error) {
+	userId, err := strconv.Atoi(c.Subject)
+	if err != nil {
+		return 0, fmt.Errorf("invalid subject %q: %w", c.Subject, err)
+	}
+	return userId, nil
+}
+
+func (s *Server) parseAccessTokenClaims(
+	tokenString string,
+) (*accessTokenClaims, error) {
+	var claims accessTokenClaims
+	_, err := jwt.ParseWithClaims(
+		tokenString,
+		&claims,
+		func(token *jwt.Token) (any, error) {
+			return s.jwtKey, nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(s.now),
+	)
+	if err != nil {
+		return nil, err
+	}
+	// Only MFA challenge tokens have an audience, and they must not be
+	// usable as access tokens.
+	if len(claims.Audience) > 0 {
+		return nil, errors.New("token is not an access token")
+	}
+	return &claims, nil
+}
+
+// requireAuth only calls next for requests carrying a valid access token,
+// either as a bearer token or as the cookie set by HandleLogin, or a valid API
+// token, and makes the authenticated user available through
+// getAuthenticatedUser. Impersonation tokens stop working once the admin who
+// made them can no longer impersonate, and every change made with them is
+// recorded in the audit trail.
+func (s *Server) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokenString := getRequestAccessToken(r)
+		if tokenString == "" {
+			http.Error(w, "Missing access token", http.StatusUnauthorized)
+			return
+		}
+		auth, err := s.authenticateAccessToken(r.Context(), tokenString)
+		if errors.Is(err, errInvalidAccessToken) {
+			http.Error(w, "Invalid access token", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			log.Println("error authenticating the access token:", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		user, ok := s.getAuthenticatingUser(w, r, auth.userId)
+		if !ok {
+			return
+		}
+		if user.Disabled {
+			http.Error(w, accountDisabledMessage, http.StatusForbidden)
+			return
+		}
+		if auth.predatesPasswordChange(user) {
+			http.Error(w, "Invalid access token", http.StatusUnauthorized)
+			return
+		}
+		ctx := context.WithValue(r.Context(), userContextKey, user)
+		ctx = data.WithReader(ctx, user.Id)
+		if auth.apiToken != nil {
+			ctx = context.WithValue(ctx, apiTokenContextKey, auth.apiToken)
+		}
+		if auth.impersonatorId != 0 {
+			impersonator, ok := s.getAuthenticatingUser(w, r, auth.impersonatorId)
+			if !ok {
+				return
+			}
+			if impersonator.Disabled || !hasPermission(impersonator, permissionImpersonateUsers) {
+				http.Error(w, "Invalid access token", http.StatusUnauthorized)
+				return
+			}
+			ctx = context.WithValue(ctx, impersonatorContextKey, impersonator)
+		}
+		r = r.WithContext(ctx)
+		if getImpersonator(r) != nil && !s.recordImpersonatedRequest(w, r) {
+			return
+		}
+		next(w, r)
+	}
+}
+
+// getAuthenticatingUser loads a user named by the access token, writing the
+// error response itself when it returns false.
+func (s *Server) getAuthenticatingUser(
+	w http.ResponseWriter,
+	r *http.Request,
+	id int,
+) (*models.User, bool) {
+	user, err := s.store.GetUserById(r.Context(), id)
+	if errors.Is(err, data.ErrResourceNotFound) {
+		http.Error(w, "Invalid access token", http.StatusUnauthorized)
+		return nil, false
+	}
+	if err != nil {
+		log.Println("error retrieving the authenticated user:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return user, true
+}
+
+// recordImpersonatedRequest records the changes an admin makes as another
+// user, refusing them when they could not be traced back to the admin.
+func (s *Server) recordImpersonatedRequest(w http.ResponseWriter, r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	user := getAuthenticatedUser(r)
+	_, err := s.store.CreateAuditEvent(r.Context(), newRequestAuditEvent(
+		r,
+		impersonatedRequestAuditAction,
+		fmt.Sprintf("user:%d", user.Id),
+		r.Method+" "+r.URL.Path,
+	))
+	if err != nil {
+		log.Println("error recording the audit event:", err)
+		http.Error(w, "Error recording the audit event", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+type authentication struct {
+	userId         int
+	impersonatorId int
+	apiToken       *models.ApiToken
+	// issuedAt is when the JWT was signed, to the second.
+	issuedAt time.Time
+}
+
+// predatesPasswordChange reports whether the JWT was signed before the user
+// last changed their password, which signs them out everywhere. Tokens only
+// record the second they were signed in, so the one signed in the same second
+// as the change is let through.
+func (a *authentication) predatesPasswordChange(user *models.User) bool {
+	return a.apiToken == nil &&
+		user.PasswordChangedAt != nil &&
+		a.issuedAt.Before(user.PasswordChangedAt.Truncate(time.Second))
+}
+
+// authenticateAccessToken returns the ID of the user the token belongs to,
+// along with the API token when it is one rather than a JWT, or the ID of the
+// admin impersonating the user.
+func (s *Server) authenticateAccessToken(
+	ctx context.Context,
+	tokenString string,
+) (*authentication, error) {
+	if isApiToken(tokenString) {
+		apiToken, err := s.authenticateApiToken(ctx, tokenString)
+		if err != nil {
+			return nil, err
+		}
+		return &authentication{userId: apiToken.UserId, apiToken: apiToken}, nil
+	}
+	claims, err := s.parseAccessTokenClaims(tokenString)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidAccessToken, err)
+	}
+	userId, err := claims.userId()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidAccessToken, err)
+	}
+	auth := authentication{userId: userId, impersonatorId: claims.ImpersonatorId}
+	if claims.IssuedAt != nil {
+		auth.issuedAt = claims.IssuedAt.Time
+	}
+	return &auth, nil
+}
+
+// requireSession is requireAuth for endpoints that API tokens must not reach,
+// such as the ones managing the account or the tokens themselves. Admins
+// impersonating the user cannot reach them either: an API token they minted
+// would outlive the impersonation and act as the user alone.
+func (s *Server) requireSession(next http.HandlerFunc) http.HandlerFunc {
+	return s.requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		if getAuthenticatedApiToken(r) != nil {
+			http.Error(w, "API tokens cannot be used here", http.StatusForbidden)
+			return
+		}
+		if getImpersonator(r) != nil {
+			http.Error(w, "Impersonating admins cannot do this", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+}
+
+func getAuthenticatedUser(r *http.Request) *models.User {
+	user, _ := r.Context().Value(userContextKey).(*models.User)
+	return user
+}
+
+// getImpersonator returns the admin acting as the authenticated user, or nil.
+func getImpersonator(r *http.Request) *models.User {
+	user, _ := r.Context().Value(impersonatorContextKey).(*models.User)
+	return user
+}
+
+func getRequestAccessToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	cookie, err := r.Cookie(accessTokenCookieName)
+	if err == nil {
+		return cookie.Value
+	}
+	return ""
+}
@@ -0,0 +1,391 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestRequireAuth(t *testing.T) {
+	user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+
+	t.Run("accepts a bearer token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+
+		request := httptest.NewRequest(http.MethodGet, "/me", nil)
+		authenticateRequest(t, server, request, user.Id)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusOK)
+	})
+
+	t.Run("accepts the login cookie", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+
+		token, _, err := server.createAccessToken(user.Id)
+		assert.HasNoError(t, err)
+		request := httptest.NewRequest(http.MethodGet, "/me", nil)
+		request.AddCookie(&http.Cookie{Name: accessTokenCookieName, Value: token})
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusOK)
+	})
+
+	t.Run("responds with a 401 Unauthorized without a token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		server := NewServer(store)
+
+		request := httptest.NewRequest(http.MethodGet, "/me", nil)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+		assert.Calls(t, store.GetUserByIdCalls, 0)
+	})
+
+	t.Run("responds with a 401 Unauthorized given an invalid token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		server := NewServer(store)
+
+		request := httptest.NewRequest(http.MethodGet, "/me", nil)
+		request.Header.Set("Authorization", "Bearer not-a-jwt")
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+		assert.Calls(t, store.GetUserByIdCalls, 0)
+	})
+
+	t.Run("responds with a 401 Unauthorized given an expired token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+		now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		server.now = func() time.Time { return now }
+
+		request := httptest.NewRequest(http.MethodGet, "/me", nil)
+		authenticateRequest(t, server, request, user.Id)
+		now = now.Add(defaultAccessTokenTtl)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("responds with a 401 Unauthorized given a token signed with another key", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store, WithJwtKey([]byte("a key of this server's own")))
+
+		request := httptest.NewRequest(http.MethodGet, "/me", nil)
+		authenticateRequest(t, NewServer(store), request, user.Id)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("responds with a 401 Unauthorized after the configured token lifetime", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store, WithAccessTokenTtl(time.Hour))
+		now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		server.now = func() time.Time { return now }
+
+		request := httptest.NewRequest(http.MethodGet, "/me", nil)
+		authenticateRequest(t, server, request, user.Id)
+		now = now.Add(time.Hour)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("responds with a 401 Unauthorized when the user no longer exists", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		server := NewServer(store)
+
+		request := httptest.NewRequest(http.MethodGet, "/me", nil)
+		authenticateRequest(t, server, request, user.Id)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+		assert.Calls(t, store.GetUserByIdCalls, 1)
+	})
+
+	t.Run("responds with a 401 Unauthorized given a token from before the password changed", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		passwordChangedAt := time.Date(2024, time.January, 1, 12, 0, 0, 500, time.UTC)
+		changedUser := user
+		changedUser.PasswordChangedAt = &passwordChangedAt
+		store.Users = []models.User{changedUser}
+		server := NewServer(store)
+		tests := []struct {
+			issuedAt time.Time
+			want     int
+		}{
+			{passwordChangedAt.Add(-time.Second), http.StatusUnauthorized},
+			{passwordChangedAt, http.StatusOK},
+			{passwordChangedAt.Add(time.Hour), http.StatusOK},
+		}
+
+		for _, test := range tests {
+			server.now = func() time.Time { return test.issuedAt }
+			request := httptest.NewRequest(http.MethodGet, "/me", nil)
+			authenticateRequest(t, server, request, user.Id)
+			response := httptest.NewRecorder()
+			server.Handler.ServeHTTP(response, request)
+
+			assert.Status(t, response.Code, test.want)
+		}
+	})
+}
+
+func TestRequireAuthWithApiTokens(t *testing.T) {
+	user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+
+	t.Run("accepts an API token and records its use", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+		now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		server.now = func() time.Time { return now }
+		token := createTestApiToken(t, server, user.Id, models.ApiTokenScopeReadProfile)
+
+		now = now.Add(time.Hour)
+		response := sendWithApiToken(server, http.MethodGet, "/me", token)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.Equals(t, getUserResponse(t, response.Body), newUserResponse(&user))
+		assert.Equals(t, *store.ApiTokens[0].LastUsedAt, now)
+	})
+
+	t.Run("responds with a 403 Forbidden when the scope is missing", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+		token := createTestApiToken(t, server, user.Id, models.ApiTokenScopeReadProfile)
+
+		response := sendWithApiToken(server, http.MethodGet, "/tasks", token)
+
+		assert.Status(t, response.Code, http.StatusForbidden)
+	})
+
+	t.Run("responds with a 403 Forbidden on session-only endpoints", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+		token := createTestApiToken(t, server, user.Id, models.ApiTokenScopes...)
+
+		response := sendWithApiToken(server, http.MethodDelete, "/me", token)
+
+		assert.Status(t, response.Code, http.StatusForbidden)
+		assert.Calls(t, store.DeleteUserByIdCalls, 0)
+	})
+
+	t.Run("responds with a 401 Unauthorized given an expired API token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+		now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		server.now = func() time.Time { return now }
+		expiresAt := now.Add(time.Hour)
+		response := sendPostApiToken(t, server, user.Id, models.CreateApiTokenDTO{
+			Name:      "CI",
+			Scopes:    []string{models.ApiTokenScopeReadProfile},
+			ExpiresAt: &expiresAt,
+		})
+		var body ApiTokenResponse
+		err := json.NewDecoder(response.Body).Decode(&body)
+		assert.HasNoError(t, err)
+
+		now = expiresAt
+		response = sendWithApiToken(server, http.MethodGet, "/me", body.Token)
+
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+		assert.Equals(t, store.ApiTokens[0].LastUsedAt, nil)
+	})
+
+	t.Run("responds with a 401 Unauthorized given an unknown API token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+
+		response := sendWithApiToken(server, http.MethodGet, "/me", apiTokenPrefix+"unknown")
+
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+		assert.Calls(t, store.GetUserByIdCalls, 0)
+	})
+}
+
+func TestRequireAuthWithImpersonationTokens(t *testing.T) {
+	admin, user := newAdminTestUsers()
+	other := *models.NewUser(3, "Jane Doe", "jane.doe@email.com", "password")
+	newImpersonationToken := func(t *testing.T, server *Server) string {
+		t.Helper()
+		token, _, err := server.createImpersonationToken(user.Id, admin.Id)
+		assert.HasNoError(t, err)
+		return token
+	}
+
+	t.Run("records the changes made as the user with the admin", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{admin, user}
+		server := NewServer(store)
+		token := newImpersonationToken(t, server)
+
+		response := sendWithApiToken(server, http.MethodGet, "/me", token)
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.HasLength(t, store.AuditEvents, 0)
+
+		request := httptest.NewRequest(
+			http.MethodPost,
+			"/projects",
+			strings.NewReader(`{"name": "Groceries"}`),
+		)
+		request.Header.Set("Authorization", "Bearer "+token)
+		response = httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusCreated)
+		assert.HasLength(t, store.AuditEvents, 1)
+		event := store.AuditEvents[0]
+		assert.Equals(t, event.Action, impersonatedRequestAuditAction)
+		assert.Equals(t, event.ActorId, user.Id)
+		assert.Equals(t, event.ImpersonatorId, admin.Id)
+		assert.Equals(t, event.Details, "POST /projects")
+	})
+
+	t.Run("responds with a 403 Forbidden on session-only endpoints", func(t *testing.T) {
+		endpoints := []struct{ method, path string }{
+			{http.MethodPatch, "/me"},
+			{http.MethodDelete, "/me"},
+			{http.MethodPost, "/me/password"},
+			{http.MethodPost, "/me/tokens"},
+			{http.MethodDelete, "/me/mfa"},
+		}
+		for _, endpoint := range endpoints {
+			store := testutils.NewMockStore(false)
+			store.Users = []models.User{admin, user}
+			server := NewServer(store)
+
+			response := sendWithApiToken(
+				server,
+				endpoint.method,
+				endpoint.path,
+				newImpersonationToken(t, server),
+			)
+
+			assert.Status(t, response.Code, http.StatusForbidden)
+			assert.HasLength(t, store.ApiTokens, 0)
+			assert.HasLength(t, store.Users, 2)
+		}
+	})
+
+	t.Run("responds with a 403 Forbidden on admin endpoints", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		adminUser := user
+		adminUser.Role = models.RoleAdmin
+		store.Users = []models.User{admin, adminUser, other}
+		server := NewServer(store)
+		token := newImpersonationToken(t, server)
+
+		for _, path := range []string{
+			"/admin/users/3/disable",
+			"/admin/users/3/impersonate",
+		} {
+			response := sendWithApiToken(server, http.MethodPost, path, token)
+
+			assert.Status(t, response.Code, http.StatusForbidden)
+		}
+		assert.Equals(t, store.Users[2].Disabled, false)
+	})
+
+	for name, revoke := range map[string]func(admin *models.User){
+		"is disabled":     func(admin *models.User) { admin.Disabled = true },
+		"is not an admin": func(admin *models.User) { admin.Role = models.RoleUser },
+	} {
+		t.Run("responds with a 401 Unauthorized once the admin "+name, func(t *testing.T) {
+			store := testutils.NewMockStore(false)
+			revokedAdmin := admin
+			revoke(&revokedAdmin)
+			store.Users = []models.User{revokedAdmin, user}
+			server := NewServer(store)
+
+			response := sendWithApiToken(server, http.MethodGet, "/me", newImpersonationToken(t, server))
+
+			assert.Status(t, response.Code, http.StatusUnauthorized)
+		})
+	}
+
+	t.Run("responds with a 401 Unauthorized once the admin no longer exists", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+
+		response := sendWithApiToken(server, http.MethodGet, "/me", newImpersonationToken(t, server))
+
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+	})
+}
+
+func sendWithApiToken(
+	server *Server,
+	method, path, token string,
+) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	response := httptest.NewRecorder()
+	server.Handler.ServeHTTP(response, request)
+	return response
+}
+
+func sendAuthenticatedRequest(
+	t *testing.T,
+	server *Server,
+	method, path string,
+	userId int,
+	body string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	authenticateRequest(t, server, request, userId)
+	response := httptest.NewRecorder()
+	server.Handler.ServeHTTP(response, request)
+	return response
+}
+
+func authenticateRequest(
+	t *testing.T,
+	server *Server,
+	request *http.Request,
+	userId int,
+) {
+	t.Helper()
+	token, _, err := server.createAccessToken(userId)
+	assert.HasNoError(t, err)
+	request.Header.Set("Authorization", "Bearer "+token)
+}
+
+// withAuthenticatedUser sets the user the way requireAuth does, for calling
+// handlers directly with stores that fail every call.
+func withAuthenticatedUser(request *http.Request, user *models.User) *http.Request {
+	return request.WithContext(
+		context.WithValue(request.Context(), userContextKey, user),
+	)
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+type permission string
+
+const (
+	permissionImpersonateUsers permission = "users:impersonate"
+	permissionManageBackups    permission = "backups:manage"
+	permissionManageUsers      permission = "users:manage"
+	permissionReadAuditEvents  permission = "audit_events:read"
+	permissionReadCacheStats   permission = "cache_stats:read"
+	permissionReadUsers        permission = "users:read"
+)
+
+var rolePermissions = map[string][]permission{
+	models.RoleAdmin: {
+		permissionImpersonateUsers,
+		permissionManageBackups,
+		permissionManageUsers,
+		permissionReadAuditEvents,
+		permissionReadCacheStats,
+		permissionReadUsers,
+	},
+	models.RoleUser: {},
+}
+
+func isValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func hasPermission(user *models.User, p permission) bool {
+	return slices.Contains(rolePermissions[user.Role], p)
+}
+
+// authorize wraps requireSession so that next is only called for users whose
+// role grants the permission.
+func (s *Server) authorize(p permission, next http.HandlerFunc) http.HandlerFunc {
+	return s.requireSession(func(w http.ResponseWriter, r *http.Request) {
+		if !hasPermission(getAuthenticatedUser(r), p) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// getPathUser loads the user referenced by the {id} path value, writing the
+// error response itself when it returns false.
+func (s *Server) getPathUser(
+	w http.ResponseWriter,
+	r *http.Request,
+) (*models.User, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(
+			w,
+			fmt.Sprintf("ID: %q is invalid", r.PathValue("id")),
+			http.StatusBadRequest,
+		)
+		return nil, false
+	}
+	user, err := s.store.GetUserById(r.Context(), id)
+	if errors.Is(err, data.ErrResourceNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return user, true
+}
+
+func (s *Server) recordAdminAction(
+	r *http.Request,
+	action string,
+	user *models.User,
+	details string,
+) error {
+	_, err := s.store.CreateAuditEvent(r.Context(), newRequestAuditEvent(
+		r,
+		action,
+		fmt.Sprintf("user:%d", user.Id),
+		details,
+	))
+	return err
+}
+
+// newRequestAuditEvent credits the event to the authenticated user, and to
+// the admin impersonating them if any.
+func newRequestAuditEvent(
+	r *http.Request,
+	action, subject, details string,
+) *models.AuditEvent {
+	event := models.NewAuditEvent(action, getAuthenticatedUser(r).Id, subject, details)
+	if impersonator := getImpersonator(r); impersonator != nil {
+		event.ImpersonatorId = impersonator.Id
+	}
+	return event
+}
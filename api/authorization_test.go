@@ -0,0 +1,105 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestAuthorize(t *testing.T) {
+	t.Run("lets admins through", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		server := NewServer(store)
+
+		response := sendAdminRequest(t, server, http.MethodGet, "/admin/users", admin.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusOK)
+	})
+
+	t.Run("responds with a 403 Forbidden to regular users", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		server := NewServer(store)
+
+		response := sendAdminRequest(t, server, http.MethodGet, "/admin/users", user.Id, nil)
+
+		assert.Status(t, response.Code, http.StatusForbidden)
+		assert.Calls(t, store.GetUsersCalls, 0)
+	})
+
+	t.Run("responds with a 401 Unauthorized without a token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		admin, user := newAdminTestUsers()
+		store.Users = []models.User{admin, user}
+		server := NewServer(store)
+
+		request := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("every admin route requires its permission", func(t *testing.T) {
+		routes := []struct{ method, path string }{
+			{http.MethodGet, "/admin/users"},
+			{http.MethodPost, "/admin/users/1/disable"},
+			{http.MethodPost, "/admin/users/1/enable"},
+			{http.MethodPut, "/admin/users/1/role"},
+			{http.MethodPost, "/admin/users/1/password-reset"},
+			{http.MethodPost, "/admin/users/1/impersonate"},
+			{http.MethodGet, "/admin/audit-events"},
+		}
+		for _, route := range routes {
+			store := testutils.NewMockStore(false)
+			admin, user := newAdminTestUsers()
+			store.Users = []models.User{admin, user}
+			server := NewServer(store)
+
+			response := sendAdminRequest(t, server, route.method, route.path, user.Id, nil)
+
+			assert.Status(t, response.Code, http.StatusForbidden)
+			assert.HasLength(t, store.AuditEvents, 0)
+		}
+	})
+}
+
+func TestHasPermission(t *testing.T) {
+	admin := models.User{Role: models.RoleAdmin}
+	user := models.User{Role: models.RoleUser}
+	unknown := models.User{Role: "superuser"}
+
+	assert.Equals(t, hasPermission(&admin, permissionManageUsers), true)
+	assert.Equals(t, hasPermission(&user, permissionManageUsers), false)
+	assert.Equals(t, hasPermission(&unknown, permissionReadUsers), false)
+}
+
+func newAdminTestUsers() (admin, user models.User) {
+	admin = *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+	admin.Role = models.RoleAdmin
+	user = *models.NewUser(2, "John Doe", "john.doe@email.com", "password")
+	return admin, user
+}
+
+func sendAdminRequest(
+	t *testing.T,
+	server *Server,
+	method, path string,
+	userId int,
+	body io.Reader,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	request := httptest.NewRequest(method, path, body)
+	authenticateRequest(t, server, request, userId)
+	response := httptest.NewRecorder()
+	server.Handler.ServeHTTP(response, request)
+	return response
+}
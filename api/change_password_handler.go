@@ -0,0 +1,46 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+func (s *Server) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
+	var dto models.ChangePasswordDTO
+	err := json.NewDecoder(r.Body).Decode(&dto)
+	if err != nil {
+		http.Error(w, "Error parsing the JSON body", http.StatusBadRequest)
+		return
+	}
+	if dto.NewPassword == "" {
+		http.Error(w, "The new password cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	user := getAuthenticatedUser(r)
+	if !s.store.ValidateUserCredentials(r.Context(), user.Email, dto.CurrentPassword) {
+		http.Error(w, "The current password is incorrect", http.StatusForbidden)
+		return
+	}
+
+	err = s.store.UpdateUserPassword(r.Context(), user.Id, dto.NewPassword)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = s.store.DeleteUserTokens(r.Context(), user.Id, models.UserTokenPurposePasswordReset)
+	if err != nil {
+		log.Println("error deleting the password reset tokens:", err)
+	}
+	// Changing the password signs the user out everywhere, so the session
+	// cookie is replaced to keep them signed in here. Bearer clients sign in
+	// again.
+	if _, err := s.startSession(w, user.Id); err != nil {
+		log.Println("error signing the JWT:", err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHandleChangePassword(t *testing.T) {
+	user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+
+	t.Run("updates the password and responds with 204 No Content", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		store.UserTokens = []models.UserToken{*models.NewUserToken(
+			"hash",
+			user.Id,
+			models.UserTokenPurposePasswordReset,
+			time.Now().Add(time.Hour),
+		)}
+		server := NewServer(store)
+
+		response := sendChangePassword(t, server, user.Id, models.ChangePasswordDTO{
+			CurrentPassword: "password",
+			NewPassword:     "new password",
+		})
+
+		assert.Status(t, response.Code, http.StatusNoContent)
+		assert.HasNoError(t, bcrypt.CompareHashAndPassword(
+			[]byte(store.Users[0].Password),
+			[]byte("new password"),
+		))
+		assert.HasLength(t, store.UserTokens, 0)
+	})
+
+	t.Run("signs out the other sessions and renews this one", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		store.ApiTokens = []models.ApiToken{{Id: 1, UserId: user.Id, Hash: "hash"}}
+		server := NewServer(store)
+		server.now = func() time.Time { return time.Now().Add(-time.Minute) }
+		otherSession, _, err := server.createAccessToken(user.Id)
+		assert.HasNoError(t, err)
+		server.now = time.Now
+
+		response := sendChangePassword(t, server, user.Id, models.ChangePasswordDTO{
+			CurrentPassword: "password",
+			NewPassword:     "new password",
+		})
+
+		assert.Status(t, response.Code, http.StatusNoContent)
+		assert.HasLength(t, store.ApiTokens, 0)
+		request := httptest.NewRequest(http.MethodGet, "/me", nil)
+		for _, cookie := range response.Result().Cookies() {
+			request.AddCookie(cookie)
+		}
+		response = httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+		assert.Status(t, response.Code, http.StatusOK)
+		response = sendWithApiToken(server, http.MethodGet, "/me", otherSession)
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("responds with a 403 Forbidden given the wrong current password", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+
+		response := sendChangePassword(t, server, user.Id, models.ChangePasswordDTO{
+			CurrentPassword: "wrong password",
+			NewPassword:     "new password",
+		})
+
+		assert.Status(t, response.Code, http.StatusForbidden)
+		assert.Equals(t, store.Users[0].Password, user.Password)
+	})
+
+	t.Run("responds with a 400 Bad Request given an empty new password", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+
+		response := sendChangePassword(t, server, user.Id, models.ChangePasswordDTO{
+			CurrentPassword: "password",
+		})
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.Calls(t, store.ValidateUserCredentialsCalls, 0)
+	})
+
+	t.Run("responds with a 401 Unauthorized without an access token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+
+		request := httptest.NewRequest(http.MethodPost, "/me/password", nil)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+	})
+}
+
+func sendChangePassword(
+	t *testing.T,
+	server *Server,
+	userId int,
+	dto models.ChangePasswordDTO,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	jsonData, err := json.Marshal(dto)
+	assert.HasNoError(t, err)
+	request := httptest.NewRequest(
+		http.MethodPost,
+		"/me/password",
+		bytes.NewBuffer(jsonData),
+	)
+	authenticateRequest(t, server, request, userId)
+	response := httptest.NewRecorder()
+	server.Handler.ServeHTTP(response, request)
+	return response
+}
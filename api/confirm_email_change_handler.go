@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token"`
+}
+
+// HandleConfirmEmailChange moves the user to the address the token was
+// mailed to. Receiving the token proves the user owns the address, so the
+// user is verified as well.
+func (s *Server) HandleConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", jsonContentType)
+	var body ConfirmEmailChangeRequest
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		http.Error(w, "Error parsing the JSON body", http.StatusBadRequest)
+		return
+	}
+
+	userToken, err := s.consumeUserToken(
+		r.Context(),
+		body.Token,
+		models.UserTokenPurposeEmailChange,
+	)
+	if errors.Is(err, errInvalidUserToken) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	user, err := s.store.GetUserById(r.Context(), userToken.UserId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	user.Email = userToken.Email
+	user.Verified = true
+	user, err = s.store.UpdateUser(r.Context(), user)
+	if errors.Is(err, data.ErrConflict) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(newUserResponse(user))
+	if err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
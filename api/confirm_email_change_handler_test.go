@@ -0,0 +1,117 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/mail"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleConfirmEmailChange(t *testing.T) {
+	user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+	newEmail := "claude@email.com"
+
+	requestChange := func(t *testing.T, server *Server) {
+		t.Helper()
+		response := sendPatchMe(t, server, user.Id, models.UpdateUserDTO{
+			Email:           &newEmail,
+			CurrentPassword: "password",
+		})
+		assert.Status(t, response.Code, http.StatusOK)
+	}
+
+	t.Run("only the latest token changes the email", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		mailer := mail.NewMemoryMailer()
+		server := NewServer(store, WithMailer(mailer))
+		requestChange(t, server)
+		firstToken := getMailedToken(t, mailer)
+		requestChange(t, server)
+
+		response := sendConfirmEmailChange(t, server, firstToken)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.Equals(t, store.Users[0], user)
+
+		response = sendConfirmEmailChange(t, server, getMailedToken(t, mailer))
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.Equals(t, getUserResponse(t, response.Body).Email, newEmail)
+		assert.HasLength(t, store.UserTokens, 0)
+	})
+
+	t.Run("rejects an expired token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		mailer := mail.NewMemoryMailer()
+		server := NewServer(store, WithMailer(mailer))
+		now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		server.now = func() time.Time { return now }
+		requestChange(t, server)
+
+		now = now.Add(emailChangeTokenTtl)
+		response := sendConfirmEmailChange(t, server, getMailedToken(t, mailer))
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.Equals(t, store.Users[0], user)
+	})
+
+	t.Run("responds with a 409 Conflict when the address was taken meanwhile", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		mailer := mail.NewMemoryMailer()
+		server := NewServer(store, WithMailer(mailer))
+		requestChange(t, server)
+		store.Users = append(
+			store.Users,
+			*models.NewUser(2, "Claude", newEmail, "password"),
+		)
+
+		response := sendConfirmEmailChange(t, server, getMailedToken(t, mailer))
+
+		assert.Status(t, response.Code, http.StatusConflict)
+		assert.Equals(t, store.Users[0], user)
+	})
+
+	t.Run("does not accept a verification token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		mailer := mail.NewMemoryMailer()
+		server := NewServer(store, WithMailer(mailer))
+		sendPostUserRequest(t, server, models.NewCreateUserDTO(
+			"Claude Aldric",
+			"claude.aldric@email.com",
+			"password",
+		))
+
+		response := sendConfirmEmailChange(t, server, getMailedToken(t, mailer))
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.Equals(t, store.Users[0].Verified, false)
+	})
+}
+
+func sendConfirmEmailChange(
+	t *testing.T,
+	server *Server,
+	token string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	jsonData, err := json.Marshal(ConfirmEmailChangeRequest{Token: token})
+	assert.HasNoError(t, err)
+	request := httptest.NewRequest(
+		http.MethodPost,
+		"/users/email/confirm",
+		bytes.NewBuffer(jsonData),
+	)
+	response := httptest.NewRecorder()
+	server.Handler.ServeHTTP(response, request)
+	return response
+}
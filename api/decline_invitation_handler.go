@@ -0,0 +1,25 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+)
+
+func (s *Server) HandleDeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	invitation, ok := s.getPathInvitation(w, r)
+	if !ok {
+		return
+	}
+	err := s.store.DeleteProjectInvitation(r.Context(), invitation.Id)
+	if errors.Is(err, data.ErrResourceNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleDeclineInvitation(t *testing.T) {
+	t.Run("deletes the invitation without adding the user", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		invitee := *models.NewUser(2, "John Doe", "john.doe@email.com", "password")
+		store.Users = []models.User{invitee}
+		store.ProjectInvitations = []models.ProjectInvitation{{
+			Id:        1,
+			ProjectId: 1,
+			Email:     invitee.Email,
+			Role:      models.ProjectRoleViewer,
+			InvitedBy: 1,
+		}}
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPost,
+			"/me/invitations/1/decline",
+			invitee.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusNoContent)
+		assert.HasLength(t, store.ProjectInvitations, 0)
+		assert.HasLength(t, store.ProjectMembers, 0)
+	})
+}
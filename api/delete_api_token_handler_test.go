@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleDeleteApiToken(t *testing.T) {
+	user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+	otherUser := *models.NewUser(2, "John Doe", "john.doe@email.com", "password")
+
+	t.Run("revokes the token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+		token := createTestApiToken(t, server, user.Id, models.ApiTokenScopeReadProfile)
+
+		response := sendDeleteApiToken(t, server, user.Id, "1")
+
+		assert.Status(t, response.Code, http.StatusNoContent)
+		assert.HasLength(t, store.ApiTokens, 0)
+
+		request := httptest.NewRequest(http.MethodGet, "/me", nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		response = httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("responds with a 404 Not Found for another user's token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user, otherUser}
+		server := NewServer(store)
+		createTestApiToken(t, server, otherUser.Id, models.ApiTokenScopeReadProfile)
+
+		response := sendDeleteApiToken(t, server, user.Id, "1")
+
+		assert.Status(t, response.Code, http.StatusNotFound)
+		assert.HasLength(t, store.ApiTokens, 1)
+	})
+
+	t.Run("responds with a 400 Bad Request for an invalid ID", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+
+		response := sendDeleteApiToken(t, server, user.Id, "abc")
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+	})
+}
+
+func sendDeleteApiToken(
+	t *testing.T,
+	server *Server,
+	userId int,
+	id string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodDelete, "/me/tokens/"+id, nil)
+	authenticateRequest(t, server, request, userId)
+	response := httptest.NewRecorder()
+	server.Handler.ServeHTTP(response, request)
+	return response
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+)
+
+func (s *Server) HandleDeleteMe(w http.ResponseWriter, r *http.Request) {
+	user := getAuthenticatedUser(r)
+	ownsSharedProject, err := s.isLastOwnerOfSharedProject(r.Context(), user.Id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if ownsSharedProject {
+		http.Error(
+			w,
+			"Make another member an owner of your shared projects first",
+			http.StatusConflict,
+		)
+		return
+	}
+
+	err = s.store.DeleteUserById(r.Context(), user.Id)
+	if errors.Is(err, data.ErrResourceNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = s.resetFailedLogins(
+		r.Context(),
+		[]loginThrottleKey{getEmailLoginThrottleKey(user.Email)},
+	)
+	if err != nil {
+		log.Println("error deleting the failed logins:", err)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     accessTokenCookieName,
+		Value:    "",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// isLastOwnerOfSharedProject reports whether deleting the user would leave a
+// project with members but no owner. Projects nobody else is in are deleted
+// with the user.
+func (s *Server) isLastOwnerOfSharedProject(ctx context.Context, userId int) (bool, error) {
+	memberships, err := s.store.GetProjectMembersByUserId(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+	for _, membership := range memberships {
+		isLastOwner, err := s.isLastProjectOwner(ctx, &membership)
+		if err != nil {
+			return false, err
+		}
+		if !isLastOwner {
+			continue
+		}
+		members, err := s.store.GetProjectMembers(ctx, membership.ProjectId)
+		if err != nil {
+			return false, err
+		}
+		if len(members) > 1 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
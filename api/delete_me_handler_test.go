@@ -0,0 +1,100 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleDeleteMe(t *testing.T) {
+	t.Run("deletes the account with its data and responds with 204 No Content", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+		otherUser := *models.NewUser(2, "John Doe", "john.doe@email.com", "password")
+		store.Users = []models.User{user, otherUser}
+		expiresAt := time.Now().Add(time.Hour)
+		store.UserTokens = []models.UserToken{
+			*models.NewUserToken("a", user.Id, models.UserTokenPurposePasswordReset, expiresAt),
+			*models.NewUserToken("b", otherUser.Id, models.UserTokenPurposePasswordReset, expiresAt),
+		}
+		emailKey := "email:" + user.Email
+		store.LoginAttempts[emailKey] = models.LoginAttempt{Key: emailKey, Failures: 1}
+		server := NewServer(store)
+
+		request := httptest.NewRequest(http.MethodDelete, "/me", nil)
+		authenticateRequest(t, server, request, user.Id)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusNoContent)
+		assert.Calls(t, store.DeleteUserByIdCalls, 1)
+		assert.Equals(t, store.Users, []models.User{otherUser})
+		assert.HasLength(t, store.UserTokens, 1)
+		assert.Equals(t, store.UserTokens[0].UserId, otherUser.Id)
+		_, ok := store.LoginAttempts[emailKey]
+		assert.Equals(t, ok, false)
+	})
+
+	t.Run("the access token no longer works afterwards", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+		store.Users = []models.User{user}
+		server := NewServer(store)
+
+		request := httptest.NewRequest(http.MethodDelete, "/me", nil)
+		authenticateRequest(t, server, request, user.Id)
+		server.Handler.ServeHTTP(httptest.NewRecorder(), request)
+
+		request = httptest.NewRequest(http.MethodGet, "/me", nil)
+		authenticateRequest(t, server, request, user.Id)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+	})
+	t.Run("responds with 409 Conflict for the last owner of a shared project", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+		otherUser := *models.NewUser(2, "John Doe", "john.doe@email.com", "password")
+		store.Users = []models.User{user, otherUser}
+		store.ProjectMembers = []models.ProjectMember{
+			{ProjectId: 1, UserId: user.Id, Role: models.ProjectRoleOwner},
+			{ProjectId: 1, UserId: otherUser.Id, Role: models.ProjectRoleEditor},
+		}
+		server := NewServer(store)
+
+		request := httptest.NewRequest(http.MethodDelete, "/me", nil)
+		authenticateRequest(t, server, request, user.Id)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusConflict)
+		assert.Calls(t, store.DeleteUserByIdCalls, 0)
+	})
+
+	t.Run("deletes the account of an owner whose projects have other owners or no other members", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+		otherUser := *models.NewUser(2, "John Doe", "john.doe@email.com", "password")
+		store.Users = []models.User{user, otherUser}
+		store.ProjectMembers = []models.ProjectMember{
+			{ProjectId: 1, UserId: user.Id, Role: models.ProjectRoleOwner},
+			{ProjectId: 2, UserId: user.Id, Role: models.ProjectRoleOwner},
+			{ProjectId: 2, UserId: otherUser.Id, Role: models.ProjectRoleOwner},
+		}
+		server := NewServer(store)
+
+		request := httptest.NewRequest(http.MethodDelete, "/me", nil)
+		authenticateRequest(t, server, request, user.Id)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusNoContent)
+		assert.Calls(t, store.DeleteUserByIdCalls, 1)
+	})
+}
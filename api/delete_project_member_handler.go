@@ -0,0 +1,54 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+// HandleDeleteProjectMember lets owners remove anyone from the project and
+// any member leave it.
+func (s *Server) HandleDeleteProjectMember(w http.ResponseWriter, r *http.Request) {
+	projectId, ok := getPathId(w, r, "id")
+	if !ok {
+		return
+	}
+	userId, ok := getPathId(w, r, "userId")
+	if !ok {
+		return
+	}
+	role := models.ProjectRoleOwner
+	if userId == getAuthenticatedUser(r).Id {
+		role = models.ProjectRoleViewer
+	}
+	if _, ok := s.checkProjectAccess(w, r, projectId, role); !ok {
+		return
+	}
+
+	member, err := s.store.GetProjectMember(r.Context(), projectId, userId)
+	if errors.Is(err, data.ErrResourceNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	isLastOwner, err := s.isLastProjectOwner(r.Context(), member)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if isLastOwner {
+		http.Error(w, "A project needs at least one owner", http.StatusConflict)
+		return
+	}
+
+	if err := s.store.DeleteProjectMember(r.Context(), projectId, userId); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
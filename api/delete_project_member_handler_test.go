@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleDeleteProjectMember(t *testing.T) {
+	ctx := context.Background()
+	owner := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+	editor := *models.NewUser(2, "John Doe", "john.doe@email.com", "password")
+	viewer := *models.NewUser(3, "Jane Doe", "jane.doe@email.com", "password")
+	members := []models.ProjectMember{
+		{ProjectId: 1, UserId: owner.Id, Role: models.ProjectRoleOwner},
+		{ProjectId: 1, UserId: editor.Id, Role: models.ProjectRoleEditor},
+		{ProjectId: 1, UserId: viewer.Id, Role: models.ProjectRoleViewer},
+	}
+	setUp := func() *Server {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{owner, editor, viewer}
+		store.ProjectMembers = append([]models.ProjectMember{}, members...)
+		return NewServer(store)
+	}
+
+	t.Run("lets owners remove members", func(t *testing.T) {
+		server := setUp()
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodDelete,
+			"/projects/1/members/2",
+			owner.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusNoContent)
+		_, err := server.store.GetProjectMember(ctx, 1, editor.Id)
+		assert.HasError(t, err)
+	})
+
+	t.Run("lets members leave", func(t *testing.T) {
+		server := setUp()
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodDelete,
+			"/projects/1/members/3",
+			viewer.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusNoContent)
+		_, err := server.store.GetProjectMember(ctx, 1, viewer.Id)
+		assert.HasError(t, err)
+	})
+
+	t.Run("unassigns the tasks of the removed member", func(t *testing.T) {
+		server := setUp()
+		task, err := server.store.GetTaskById(ctx, 1)
+		assert.HasNoError(t, err)
+		task.AssigneeId = editor.Id
+		_, err = server.store.UpdateTask(ctx, task)
+		assert.HasNoError(t, err)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodDelete,
+			"/projects/1/members/2",
+			owner.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusNoContent)
+		task, err = server.store.GetTaskById(ctx, 1)
+		assert.HasNoError(t, err)
+		assert.Equals(t, task.AssigneeId, 0)
+	})
+
+	t.Run("responds with a 403 Forbidden when an editor removes someone else", func(t *testing.T) {
+		server := setUp()
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodDelete,
+			"/projects/1/members/3",
+			editor.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusForbidden)
+	})
+
+	t.Run("responds with a 409 Conflict when the last owner leaves", func(t *testing.T) {
+		server := setUp()
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodDelete,
+			"/projects/1/members/1",
+			owner.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusConflict)
+	})
+}
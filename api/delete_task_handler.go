@@ -2,28 +2,24 @@ package api
 
 import (
 	"errors"
-	"fmt"
 	"net/http"
-	"strconv"
 
 	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
 )
 
 func (s *Server) HandleDeleteTask(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.Atoi(r.PathValue("id"))
-	if err != nil {
-		http.Error(
-			w,
-			fmt.Sprintf("ID: %q is invalid", r.PathValue("id")),
-			http.StatusBadRequest,
-		)
+	task, ok := s.getPathTask(w, r, models.ProjectRoleEditor)
+	if !ok {
+		return
 	}
-	if err := s.store.DeleteTaskById(id); err != nil {
+	if err := s.store.DeleteTaskById(r.Context(), task.Id); err != nil {
 		if errors.Is(err, data.ErrResourceNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 		} else {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
+		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -12,8 +12,17 @@ import (
 )
 
 func TestHandleDeleteTask(t *testing.T) {
+	user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+	editor := models.ProjectMember{
+		ProjectId: 1,
+		UserId:    user.Id,
+		Role:      models.ProjectRoleEditor,
+	}
+
 	t.Run("deletes the task and responds with 204 No Content", func(t *testing.T) {
 		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		data.ProjectMembers = []models.ProjectMember{editor}
 		server := NewServer(data)
 
 		initialTasksCount := len(data.Tasks)
@@ -22,13 +31,14 @@ func TestHandleDeleteTask(t *testing.T) {
 		}
 
 		taskToDelete := data.Tasks[0]
-		request := httptest.NewRequest(
+		response := sendAuthenticatedRequest(
+			t,
+			server,
 			http.MethodDelete,
 			fmt.Sprintf("/tasks/%d", taskToDelete.Id),
-			nil,
+			user.Id,
+			"",
 		)
-		response := httptest.NewRecorder()
-		server.Handler.ServeHTTP(response, request)
 
 		assert.Status(t, response.Code, http.StatusNoContent)
 		assert.HasLength(t, data.Tasks, initialTasksCount-1)
@@ -36,37 +46,65 @@ func TestHandleDeleteTask(t *testing.T) {
 
 	t.Run("responds with a 400 Bad Request when sending a non-integer ID", func(t *testing.T) {
 		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
 		server := NewServer(data)
 
-		request := httptest.NewRequest(
+		response := sendAuthenticatedRequest(
+			t,
+			server,
 			http.MethodDelete,
 			"/tasks/not-an-integer",
-			nil,
+			user.Id,
+			"",
 		)
-		response := httptest.NewRecorder()
-		server.Handler.ServeHTTP(response, request)
 
 		assert.Status(t, response.Code, http.StatusBadRequest)
 	})
 
 	t.Run("responds with 404 Not Found when the task does not exist", func(t *testing.T) {
 		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		data.ProjectMembers = []models.ProjectMember{editor}
 		server := NewServer(data)
 
-		taskToDelete := models.NewTask(-1, "Does not exist")
-		request := httptest.NewRequest(
+		taskToDelete := models.NewTask(-1, "Does not exist", 1)
+		response := sendAuthenticatedRequest(
+			t,
+			server,
 			http.MethodDelete,
 			fmt.Sprintf("/tasks/%d", taskToDelete.Id),
-			nil,
+			user.Id,
+			"",
 		)
-		response := httptest.NewRecorder()
-		server.Handler.ServeHTTP(response, request)
 
 		assert.Status(t, response.Code, http.StatusNotFound)
 	})
 
+	t.Run("responds with 403 Forbidden to viewers of the project", func(t *testing.T) {
+		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		viewer := editor
+		viewer.Role = models.ProjectRoleViewer
+		data.ProjectMembers = []models.ProjectMember{viewer}
+		server := NewServer(data)
+
+		initialTasksCount := len(data.Tasks)
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodDelete,
+			fmt.Sprintf("/tasks/%d", data.Tasks[0].Id),
+			user.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusForbidden)
+		assert.HasLength(t, data.Tasks, initialTasksCount)
+	})
+
 	t.Run("responds with 500 error when the store task deletion fails for an unknown reason", func(t *testing.T) {
 		data := testutils.NewMockStore(true)
+		data.ProjectMembers = []models.ProjectMember{editor}
 		server := NewServer(data)
 
 		taskToDelete := data.Tasks[0]
@@ -75,8 +113,9 @@ func TestHandleDeleteTask(t *testing.T) {
 			fmt.Sprintf("/tasks/%d", taskToDelete.Id),
 			nil,
 		)
+		request.SetPathValue("id", fmt.Sprint(taskToDelete.Id))
 		response := httptest.NewRecorder()
-		server.Handler.ServeHTTP(response, request)
+		server.HandleDeleteTask(response, withAuthenticatedUser(request, &user))
 
 		assert.Status(t, response.Code, http.StatusInternalServerError)
 	})
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+type DisableMfaRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+func (s *Server) HandleDisableMfa(w http.ResponseWriter, r *http.Request) {
+	var body DisableMfaRequest
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		http.Error(w, "Error parsing the JSON body", http.StatusBadRequest)
+		return
+	}
+
+	// The codes are guessed against the same limit as at login, so that a
+	// stolen session cannot be used to brute-force the second factor.
+	user := getAuthenticatedUser(r)
+	throttleKeys := getLoginThrottleKeys(r, user.Email)
+	lockout, err := s.getLoginLockout(r.Context(), throttleKeys)
+	if err != nil {
+		log.Println("error checking the login lockout:", err)
+		http.Error(w, "Error checking the login lockout", http.StatusInternalServerError)
+		return
+	}
+	if lockout > 0 {
+		setRetryAfter(w, lockout)
+		http.Error(w, "Too many failed login attempts", http.StatusTooManyRequests)
+		return
+	}
+
+	mfa, err := s.getEnabledUserMfa(r.Context(), user.Id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if mfa == nil {
+		http.Error(w, "Two-factor authentication is not enabled", http.StatusNotFound)
+		return
+	}
+	ok, err := s.checkSecondFactor(r.Context(), mfa, body.Code, body.RecoveryCode)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		if err := s.recordFailedLogin(r.Context(), throttleKeys); err != nil {
+			log.Println("error recording the failed login:", err)
+		}
+		http.Error(w, "Invalid code", http.StatusForbidden)
+		return
+	}
+	err = s.resetFailedLogins(
+		r.Context(),
+		[]loginThrottleKey{getEmailLoginThrottleKey(user.Email)},
+	)
+	if err != nil {
+		log.Println("error resetting the failed logins:", err)
+	}
+
+	if err := s.store.DeleteUserMfa(r.Context(), user.Id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = s.store.DeleteUserTokens(r.Context(), user.Id, models.UserTokenPurposeMfaRecovery)
+	if err != nil {
+		log.Println("error deleting the recovery codes:", err)
+	}
+	s.recordMfaAction(r.Context(), user.Id, mfaDisabledAuditAction)
+	w.WriteHeader(http.StatusNoContent)
+}
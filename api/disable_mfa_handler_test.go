@@ -0,0 +1,105 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleDisableMfa(t *testing.T) {
+	user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+
+	t.Run("turns MFA off given a valid code", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+		now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		server.now = func() time.Time { return now }
+		secret, _ := enableTotp(t, server, user.Id)
+		now = now.Add(time.Minute)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodDelete,
+			"/me/mfa",
+			user.Id,
+			`{"code":"`+getTotpCode(t, secret, now)+`"}`,
+		)
+
+		assert.Status(t, response.Code, http.StatusNoContent)
+		assert.HasLength(t, store.UserTokens, 0)
+		_, ok := store.UserMfa[user.Id]
+		assert.Equals(t, ok, false)
+		assert.Equals(t, store.AuditEvents[len(store.AuditEvents)-1].Action, mfaDisabledAuditAction)
+
+		response = sendLogin(t, server, user.Email, user.Password)
+		assert.Status(t, response.Code, http.StatusOK)
+	})
+
+	t.Run("responds with a 403 Forbidden given a wrong code", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+		enableTotp(t, server, user.Id)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodDelete,
+			"/me/mfa",
+			user.Id,
+			`{"recoveryCode":"wrong-code"}`,
+		)
+
+		assert.Status(t, response.Code, http.StatusForbidden)
+		assert.Equals(t, store.UserMfa[user.Id].Enabled, true)
+	})
+
+	t.Run("locks out after too many wrong codes", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+		now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		server.now = func() time.Time { return now }
+		secret, _ := enableTotp(t, server, user.Id)
+		now = now.Add(time.Minute)
+
+		for range maxFailedLoginsPerEmail {
+			response := sendAuthenticatedRequest(
+				t,
+				server,
+				http.MethodDelete,
+				"/me/mfa",
+				user.Id,
+				`{"code":"000000"}`,
+			)
+			assert.Status(t, response.Code, http.StatusForbidden)
+		}
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodDelete,
+			"/me/mfa",
+			user.Id,
+			`{"code":"`+getTotpCode(t, secret, now)+`"}`,
+		)
+
+		assert.Status(t, response.Code, http.StatusTooManyRequests)
+		assert.Equals(t, store.UserMfa[user.Id].Enabled, true)
+	})
+
+	t.Run("responds with a 404 Not Found when MFA is not enabled", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(t, server, http.MethodDelete, "/me/mfa", user.Id, `{}`)
+
+		assert.Status(t, response.Code, http.StatusNotFound)
+	})
+}
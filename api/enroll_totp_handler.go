@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/totp"
+)
+
+type EnrollTotpResponse struct {
+	Secret string `json:"secret"`
+	Uri    string `json:"uri"`
+}
+
+// HandleEnrollTotp starts a new enrollment; MFA is only turned on once
+// HandleVerifyTotp has seen a code from the authenticator app.
+func (s *Server) HandleEnrollTotp(w http.ResponseWriter, r *http.Request) {
+	user := getAuthenticatedUser(r)
+	mfa, err := s.getEnabledUserMfa(r.Context(), user.Id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if mfa != nil {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = s.store.SaveUserMfa(r.Context(), &models.UserMfa{UserId: user.Id, TotpSecret: secret})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(EnrollTotpResponse{
+		Secret: secret,
+		Uri:    totp.KeyUri(totpIssuer, user.Email, secret),
+	})
+	if err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
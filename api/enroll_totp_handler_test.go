@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+	"github.com/claudealdric/go-todolist-restful-api-server/totp"
+)
+
+func TestHandleEnrollTotp(t *testing.T) {
+	user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+
+	t.Run("returns a secret and its otpauth URI without enabling MFA", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(t, server, http.MethodPost, "/me/mfa/totp", user.Id, "")
+
+		assert.Status(t, response.Code, http.StatusCreated)
+		var body EnrollTotpResponse
+		err := json.NewDecoder(response.Body).Decode(&body)
+		assert.HasNoError(t, err)
+		uri, err := url.Parse(body.Uri)
+		assert.HasNoError(t, err)
+		assert.Equals(t, uri.Scheme, "otpauth")
+		assert.Equals(t, uri.Query().Get("secret"), body.Secret)
+		assert.Equals(t, strings.HasSuffix(uri.Path, user.Email), true)
+		assert.Equals(t, store.UserMfa[user.Id].TotpSecret, body.Secret)
+		assert.Equals(t, store.UserMfa[user.Id].Enabled, false)
+	})
+
+	t.Run("responds with a 409 Conflict when MFA is already enabled", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+		enableTotp(t, server, user.Id)
+		secret := store.UserMfa[user.Id].TotpSecret
+
+		response := sendAuthenticatedRequest(t, server, http.MethodPost, "/me/mfa/totp", user.Id, "")
+
+		assert.Status(t, response.Code, http.StatusConflict)
+		assert.Equals(t, store.UserMfa[user.Id].TotpSecret, secret)
+	})
+}
+
+// enableTotp enrolls the user and verifies the enrollment with a code for the
+// server's current time, returning the secret and the recovery codes.
+func enableTotp(t *testing.T, server *Server, userId int) (string, []string) {
+	t.Helper()
+	response := sendAuthenticatedRequest(t, server, http.MethodPost, "/me/mfa/totp", userId, "")
+	assert.Status(t, response.Code, http.StatusCreated)
+	var enrollment EnrollTotpResponse
+	err := json.NewDecoder(response.Body).Decode(&enrollment)
+	assert.HasNoError(t, err)
+
+	code := getTotpCode(t, enrollment.Secret, server.now())
+	response = sendAuthenticatedRequest(
+		t,
+		server,
+		http.MethodPost,
+		"/me/mfa/totp/verify",
+		userId,
+		`{"code":"`+code+`"}`,
+	)
+	assert.Status(t, response.Code, http.StatusOK)
+	var verification VerifyTotpResponse
+	err = json.NewDecoder(response.Body).Decode(&verification)
+	assert.HasNoError(t, err)
+	return enrollment.Secret, verification.RecoveryCodes
+}
+
+func getTotpCode(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+	code, err := totp.Code(secret, at)
+	assert.HasNoError(t, err)
+	return code
+}
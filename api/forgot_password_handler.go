@@ -0,0 +1,39 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+)
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// HandleForgotPassword always responds with 202 Accepted so that the response
+// does not reveal whether an account exists for the email.
+func (s *Server) HandleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var body ForgotPasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		http.Error(w, "Error parsing the JSON body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := s.store.GetUserByEmail(r.Context(), body.Email)
+	if err != nil {
+		if !errors.Is(err, data.ErrResourceNotFound) {
+			log.Println("error retrieving the user for a password reset:", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if err := s.sendPasswordResetEmail(r.Context(), user); err != nil {
+		log.Println("error sending the password reset email:", err)
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/mail"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+	"github.com/claudealdric/go-todolist-restful-api-server/utils"
+)
+
+func TestHandleForgotPassword(t *testing.T) {
+	t.Run("emails a password reset token and responds with 202 Accepted", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+		store.Users = []models.User{user}
+		mailer := mail.NewMemoryMailer()
+		server := NewServer(store, WithMailer(mailer))
+
+		response := sendForgotPassword(t, server, "Claude.Aldric@email.com")
+
+		assert.Status(t, response.Code, http.StatusAccepted)
+		messages := mailer.Messages()
+		assert.HasLength(t, messages, 1)
+		assert.Equals(t, messages[0].To, user.Email)
+		assert.HasLength(t, store.UserTokens, 1)
+		token := store.UserTokens[0]
+		assert.Equals(t, token.UserId, user.Id)
+		assert.Equals(t, token.Purpose, models.UserTokenPurposePasswordReset)
+		assert.Equals(t, utils.HashToken(getMailedToken(t, mailer)), token.Hash)
+	})
+
+	t.Run("replaces an outstanding password reset token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{
+			*models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password"),
+		}
+		server := NewServer(store)
+
+		sendForgotPassword(t, server, "claude.aldric@email.com")
+		firstToken := store.UserTokens[0]
+		sendForgotPassword(t, server, "claude.aldric@email.com")
+
+		assert.HasLength(t, store.UserTokens, 1)
+		assert.DoesNotEqual(t, store.UserTokens[0].Hash, firstToken.Hash)
+	})
+
+	t.Run("responds with 202 Accepted without sending mail for an unknown email", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		mailer := mail.NewMemoryMailer()
+		server := NewServer(store, WithMailer(mailer))
+
+		response := sendForgotPassword(t, server, "does-not-exist@email.com")
+
+		assert.Status(t, response.Code, http.StatusAccepted)
+		assert.HasLength(t, mailer.Messages(), 0)
+		assert.HasLength(t, store.UserTokens, 0)
+	})
+
+	t.Run("responds with a 400 Bad Request given an invalid body", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		server := NewServer(store)
+
+		request := httptest.NewRequest(
+			http.MethodPost,
+			"/password/forgot",
+			bytes.NewBuffer([]byte(`{`)),
+		)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.Calls(t, store.GetUserByEmailCalls, 0)
+	})
+}
+
+func sendForgotPassword(
+	t *testing.T,
+	server *Server,
+	email string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	jsonData, err := json.Marshal(ForgotPasswordRequest{Email: email})
+	assert.HasNoError(t, err)
+	request := httptest.NewRequest(
+		http.MethodPost,
+		"/password/forgot",
+		bytes.NewBuffer(jsonData),
+	)
+	response := httptest.NewRecorder()
+	server.Handler.ServeHTTP(response, request)
+	return response
+}
+
+// getMailedToken returns the token that was sent in the last message, given
+// that tokens are the only unbroken run of URL-safe characters that long.
+func getMailedToken(t *testing.T, mailer *mail.MemoryMailer) string {
+	t.Helper()
+	messages := mailer.Messages()
+	if len(messages) == 0 {
+		t.Fatal("expected a message to have been sent")
+	}
+	for _, word := range strings.Fields(messages[len(messages)-1].Body) {
+		word = strings.TrimRight(word, ".")
+		if len(word) == 43 {
+			return word
+		}
+	}
+	t.Fatal("expected the message to contain a token")
+	return ""
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+func (s *Server) HandleGetApiTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := s.store.GetApiTokensByUserId(r.Context(), getAuthenticatedUser(r).Id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]ApiTokenResponse, len(tokens))
+	for i, token := range tokens {
+		response[i] = newApiTokenResponse(&token, "")
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleGetApiTokens(t *testing.T) {
+	t.Run("returns the user's tokens without their secrets", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+		otherUser := *models.NewUser(2, "John Doe", "john.doe@email.com", "password")
+		store.Users = []models.User{user, otherUser}
+		server := NewServer(store)
+		createTestApiToken(t, server, user.Id, models.ApiTokenScopeReadTasks)
+		createTestApiToken(t, server, otherUser.Id, models.ApiTokenScopeReadTasks)
+
+		request := httptest.NewRequest(http.MethodGet, "/me/tokens", nil)
+		authenticateRequest(t, server, request, user.Id)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.ContentType(
+			t,
+			testutils.GetContentTypeFromResponse(response),
+			jsonContentType,
+		)
+		var tokens []map[string]any
+		err := json.NewDecoder(response.Body).Decode(&tokens)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, tokens, 1)
+		assert.Equals(t, tokens[0]["id"], any(float64(1)))
+		_, hasToken := tokens[0]["token"]
+		_, hasHash := tokens[0]["hash"]
+		assert.Equals(t, hasToken, false)
+		assert.Equals(t, hasHash, false)
+	})
+}
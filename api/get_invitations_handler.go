@@ -0,0 +1,41 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+type ProjectInvitationResponse struct {
+	models.ProjectInvitation
+	ProjectName string `json:"projectName"`
+}
+
+// HandleGetInvitations lists the pending invitations sent to the caller's
+// email.
+func (s *Server) HandleGetInvitations(w http.ResponseWriter, r *http.Request) {
+	invitations, err := s.store.GetProjectInvitationsByEmail(
+		r.Context(),
+		getAuthenticatedUser(r).Email,
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	response := []ProjectInvitationResponse{}
+	for _, invitation := range invitations {
+		project, err := s.store.GetProjectById(r.Context(), invitation.ProjectId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response = append(response, ProjectInvitationResponse{invitation, project.Name})
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleGetInvitations(t *testing.T) {
+	t.Run("lists the invitations sent to the user's email", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		user := *models.NewUser(2, "John Doe", "john.doe@email.com", "password")
+		store.Users = []models.User{user}
+		invitation := models.ProjectInvitation{
+			Id:        1,
+			ProjectId: 1,
+			Email:     user.Email,
+			Role:      models.ProjectRoleEditor,
+			InvitedBy: 1,
+		}
+		store.ProjectInvitations = []models.ProjectInvitation{
+			invitation,
+			{Id: 2, ProjectId: 1, Email: "jane.doe@email.com", Role: models.ProjectRoleViewer},
+		}
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodGet,
+			"/me/invitations",
+			user.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		var got []ProjectInvitationResponse
+		err := json.NewDecoder(response.Body).Decode(&got)
+		assert.HasNoError(t, err)
+		assert.Equals(t, got, []ProjectInvitationResponse{
+			{ProjectInvitation: invitation, ProjectName: store.Projects[0].Name},
+		})
+	})
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+// UserResponse leaves out the user's password hash.
+type UserResponse struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	Verified    bool   `json:"verified"`
+	Timezone    string `json:"timezone"`
+	Locale      string `json:"locale"`
+	StartOfWeek string `json:"startOfWeek"`
+	Role        string `json:"role"`
+	Disabled    bool   `json:"disabled"`
+}
+
+func newUserResponse(user *models.User) UserResponse {
+	return UserResponse{
+		Id:          user.Id,
+		Name:        user.Name,
+		Email:       user.Email,
+		Verified:    user.Verified,
+		Timezone:    user.Timezone,
+		Locale:      user.Locale,
+		StartOfWeek: user.StartOfWeek,
+		Role:        user.Role,
+		Disabled:    user.Disabled,
+	}
+}
+
+func (s *Server) HandleGetMe(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", jsonContentType)
+	err := json.NewEncoder(w).Encode(newUserResponse(getAuthenticatedUser(r)))
+	if err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleGetMe(t *testing.T) {
+	t.Run("returns the authenticated user", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+		otherUser := *models.NewUser(2, "John Doe", "john.doe@email.com", "password")
+		store.Users = []models.User{user, otherUser}
+		server := NewServer(store)
+
+		request := httptest.NewRequest(http.MethodGet, "/me", nil)
+		authenticateRequest(t, server, request, user.Id)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.ContentType(
+			t,
+			testutils.GetContentTypeFromResponse(response),
+			jsonContentType,
+		)
+		assert.Equals(t, getUserResponse(t, response.Body), newUserResponse(&user))
+	})
+}
+
+// getUserResponse fails the test when the body has any field UserResponse
+// leaves out, such as the password hash.
+func getUserResponse(t *testing.T, body io.Reader) UserResponse {
+	t.Helper()
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	var user UserResponse
+	if err := decoder.Decode(&user); err != nil {
+		t.Fatalf("unable to parse the response into a UserResponse: %v", err)
+	}
+	return user
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+type ProjectMemberResponse struct {
+	UserId int    `json:"userId"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+}
+
+func (s *Server) HandleGetProjectMembers(w http.ResponseWriter, r *http.Request) {
+	projectId, ok := getPathId(w, r, "id")
+	if !ok {
+		return
+	}
+	if _, ok := s.checkProjectAccess(w, r, projectId, models.ProjectRoleViewer); !ok {
+		return
+	}
+
+	members, err := s.store.GetProjectMembers(r.Context(), projectId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	response := []ProjectMemberResponse{}
+	for _, member := range members {
+		user, err := s.store.GetUserById(r.Context(), member.UserId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		response = append(response, ProjectMemberResponse{
+			UserId: user.Id,
+			Name:   user.Name,
+			Email:  user.Email,
+			Role:   member.Role,
+		})
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleGetProjectMembers(t *testing.T) {
+	owner := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+	viewer := *models.NewUser(2, "John Doe", "john.doe@email.com", "password")
+	outsider := *models.NewUser(3, "Jane Doe", "jane.doe@email.com", "password")
+	members := []models.ProjectMember{
+		{ProjectId: 1, UserId: owner.Id, Role: models.ProjectRoleOwner},
+		{ProjectId: 1, UserId: viewer.Id, Role: models.ProjectRoleViewer},
+	}
+
+	t.Run("lists the members to any member of the project", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{owner, viewer, outsider}
+		store.ProjectMembers = members
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodGet,
+			"/projects/1/members",
+			viewer.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		var got []ProjectMemberResponse
+		err := json.NewDecoder(response.Body).Decode(&got)
+		assert.HasNoError(t, err)
+		assert.Equals(t, got, []ProjectMemberResponse{
+			{owner.Id, owner.Name, owner.Email, models.ProjectRoleOwner},
+			{viewer.Id, viewer.Name, viewer.Email, models.ProjectRoleViewer},
+		})
+	})
+
+	t.Run("responds with a 404 Not Found to users outside the project", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{owner, viewer, outsider}
+		store.ProjectMembers = members
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodGet,
+			"/projects/1/members",
+			outsider.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+// ProjectResponse adds the caller's role to the project.
+type ProjectResponse struct {
+	models.Project
+	Role string `json:"role"`
+}
+
+func (s *Server) HandleGetProjects(w http.ResponseWriter, r *http.Request) {
+	members, err := s.store.GetProjectMembersByUserId(r.Context(), getAuthenticatedUser(r).Id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	projects := []ProjectResponse{}
+	for _, member := range members {
+		project, err := s.store.GetProjectById(r.Context(), member.ProjectId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		projects = append(projects, ProjectResponse{*project, member.Role})
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	if err := json.NewEncoder(w).Encode(projects); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
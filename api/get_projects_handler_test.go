@@ -0,0 +1,35 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleGetProjects(t *testing.T) {
+	t.Run("returns the projects of the user with their role", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+		store.Users = []models.User{user}
+		store.Projects = append(store.Projects, models.Project{Id: 2, Name: "Work"})
+		store.ProjectMembers = []models.ProjectMember{
+			{ProjectId: 1, UserId: user.Id, Role: models.ProjectRoleEditor},
+			{ProjectId: 2, UserId: 2, Role: models.ProjectRoleOwner},
+		}
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(t, server, http.MethodGet, "/projects", user.Id, "")
+
+		assert.Status(t, response.Code, http.StatusOK)
+		var projects []ProjectResponse
+		err := json.NewDecoder(response.Body).Decode(&projects)
+		assert.HasNoError(t, err)
+		assert.Equals(t, projects, []ProjectResponse{
+			{Project: store.Projects[0], Role: models.ProjectRoleEditor},
+		})
+	})
+}
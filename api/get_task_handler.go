@@ -5,15 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
 
 	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
 )
 
 func (s *Server) HandleGetTaskById(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("content-type", jsonContentType)
-	id, err := strconv.Atoi(r.PathValue("id"))
-	if err != nil {
+	task, ok := s.getPathTask(w, r, models.ProjectRoleViewer)
+	if !ok {
+		return
+	}
+	if err := json.NewEncoder(w).Encode(task); err != nil {
 		http.Error(
 			w,
 			fmt.Sprintf("ID: %q is invalid", r.PathValue("id")),
@@ -21,7 +24,21 @@ func (s *Server) HandleGetTaskById(w http.ResponseWriter, r *http.Request) {
 		)
 		return
 	}
-	task, err := s.store.GetTaskById(id)
+}
+
+// getPathTask loads the task referenced by the {id} path value when the
+// caller has at least the role in its project, writing the error response
+// itself when it returns false.
+func (s *Server) getPathTask(
+	w http.ResponseWriter,
+	r *http.Request,
+	role string,
+) (*models.Task, bool) {
+	id, ok := getPathId(w, r, "id")
+	if !ok {
+		return nil, false
+	}
+	task, err := s.store.GetTaskById(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, data.ErrResourceNotFound) {
 			http.Error(
@@ -33,14 +50,10 @@ func (s *Server) HandleGetTaskById(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
 
-		return
+		return nil, false
 	}
-	if err := json.NewEncoder(w).Encode(task); err != nil {
-		http.Error(
-			w,
-			fmt.Sprintf("ID: %q is invalid", r.PathValue("id")),
-			http.StatusBadRequest,
-		)
-		return
+	if _, ok := s.checkProjectAccess(w, r, task.ProjectId, role); !ok {
+		return nil, false
 	}
+	return task, true
 }
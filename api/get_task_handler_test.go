@@ -6,23 +6,34 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
 	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
 	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
 )
 
 func TestHandleGetTaskById(t *testing.T) {
+	user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+	member := models.ProjectMember{
+		ProjectId: 1,
+		UserId:    user.Id,
+		Role:      models.ProjectRoleViewer,
+	}
+
 	t.Run("returns the wanted task if it exists", func(t *testing.T) {
 		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		data.ProjectMembers = []models.ProjectMember{member}
 		server := NewServer(data)
 
 		wantedTask := data.Tasks[0]
-		request := httptest.NewRequest(
+		response := sendAuthenticatedRequest(
+			t,
+			server,
 			http.MethodGet,
 			fmt.Sprintf("/tasks/%d", wantedTask.Id),
-			nil,
+			user.Id,
+			"",
 		)
-		response := httptest.NewRecorder()
-		server.Handler.ServeHTTP(response, request)
 
 		assert.ContentType(
 			t,
@@ -40,21 +51,41 @@ func TestHandleGetTaskById(t *testing.T) {
 
 	t.Run("responds with a 400 Bad Request when given non-integer ID", func(t *testing.T) {
 		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
 		server := NewServer(data)
 
 		invalidId := "not-an-integer"
-		request := httptest.NewRequest(
+		response := sendAuthenticatedRequest(
+			t,
+			server,
 			http.MethodGet,
 			fmt.Sprintf("/tasks/%s", invalidId),
-			nil,
+			user.Id,
+			"",
 		)
-		response := httptest.NewRecorder()
-		server.Handler.ServeHTTP(response, request)
 
 		assert.Status(t, response.Code, http.StatusBadRequest)
 		assert.Calls(t, data.GetTaskByIdCalls, 0)
 	})
 
+	t.Run("responds with a 404 Not Found when the task is in another project", func(t *testing.T) {
+		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		server := NewServer(data)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodGet,
+			fmt.Sprintf("/tasks/%d", data.Tasks[0].Id),
+			user.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusNotFound)
+		assert.Calls(t, data.GetTaskByIdCalls, 1)
+	})
+
 	t.Run("responds with a 404 Not Found when the task cannot be found", func(t *testing.T) {
 		data := testutils.NewMockStore(true)
 		server := NewServer(data)
@@ -65,8 +96,9 @@ func TestHandleGetTaskById(t *testing.T) {
 			fmt.Sprintf("/tasks/%d", doesNotExistId),
 			nil,
 		)
+		request.SetPathValue("id", fmt.Sprint(doesNotExistId))
 		response := httptest.NewRecorder()
-		server.Handler.ServeHTTP(response, request)
+		server.HandleGetTaskById(response, withAuthenticatedUser(request, &user))
 
 		assert.Status(t, response.Code, http.StatusNotFound)
 		assert.Calls(t, data.GetTaskByIdCalls, 1)
@@ -82,8 +114,9 @@ func TestHandleGetTaskById(t *testing.T) {
 			fmt.Sprintf("/tasks/%d", doesNotExistId),
 			nil,
 		)
+		request.SetPathValue("id", fmt.Sprint(doesNotExistId))
 		response := httptest.NewRecorder()
-		server.Handler.ServeHTTP(response, request)
+		server.HandleGetTaskById(response, withAuthenticatedUser(request, &user))
 
 		assert.Status(t, response.Code, http.StatusInternalServerError)
 		assert.Calls(t, data.GetTaskByIdCalls, 1)
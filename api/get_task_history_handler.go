@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+// HandleGetTaskHistory lists the changes made to a task, including a deleted
+// one, when the store records them.
+func (s *Server) HandleGetTaskHistory(w http.ResponseWriter, r *http.Request) {
+	historian, ok := s.store.(data.TaskHistorian)
+	if !ok {
+		http.Error(w, data.ErrTaskHistoryNotRecorded.Error(), http.StatusNotImplemented)
+		return
+	}
+	id, ok := getPathId(w, r, "id")
+	if !ok {
+		return
+	}
+	events, err := historian.GetTaskHistory(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrTaskHistoryNotRecorded):
+			http.Error(w, err.Error(), http.StatusNotImplemented)
+		case errors.Is(err, data.ErrResourceNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	// The task is checked against the project it was last in, which is the
+	// one it is still in unless it was deleted.
+	projectId := events[len(events)-1].Task.ProjectId
+	if _, ok := s.checkProjectAccess(w, r, projectId, models.ProjectRoleViewer); !ok {
+		return
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	if err := json.NewEncoder(w).Encode(events); err != nil {
+		log.Println("error encoding the task history:", err)
+	}
+}
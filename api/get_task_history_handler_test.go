@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleGetTaskHistory(t *testing.T) {
+	user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+	member := models.ProjectMember{
+		ProjectId: 1,
+		UserId:    user.Id,
+		Role:      models.ProjectRoleViewer,
+	}
+	createdAt := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	events := []models.TaskEvent{
+		{
+			Id:        1,
+			Type:      models.TaskCreated,
+			Task:      *models.NewTask(2, "Pack clothes", 1),
+			CreatedAt: createdAt,
+		},
+		{
+			Id:        2,
+			Type:      models.TaskRenamed,
+			Task:      *models.NewTask(2, "Pack the suitcase", 1),
+			CreatedAt: createdAt.Add(time.Minute),
+		},
+		{
+			Id:        3,
+			Type:      models.TaskDeleted,
+			Task:      *models.NewTask(2, "Pack the suitcase", 1),
+			CreatedAt: createdAt.Add(time.Hour),
+		},
+	}
+
+	t.Run("returns every change made to the task", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		store.ProjectMembers = []models.ProjectMember{member}
+		store.TaskEvents = events
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodGet,
+			"/tasks/2/history",
+			user.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.ContentType(
+			t,
+			testutils.GetContentTypeFromResponse(response),
+			jsonContentType,
+		)
+		var got []models.TaskEvent
+		assert.HasNoError(t, json.NewDecoder(response.Body).Decode(&got))
+		assert.Equals(t, got, events)
+	})
+
+	t.Run("responds with a 404 Not Found when the task is in another project", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		store.TaskEvents = events
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodGet,
+			"/tasks/2/history",
+			user.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusNotFound)
+	})
+
+	t.Run("responds with a 404 Not Found when the task has no history", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		store.ProjectMembers = []models.ProjectMember{member}
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodGet,
+			fmt.Sprintf("/tasks/%d/history", store.Tasks[0].Id),
+			user.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusNotFound)
+	})
+
+	t.Run("responds with a 501 Not Implemented when the store keeps no history", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		store.ProjectMembers = []models.ProjectMember{member}
+		server := NewServer(struct{ data.Store }{store})
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodGet,
+			"/tasks/2/history",
+			user.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusNotImplemented)
+	})
+}
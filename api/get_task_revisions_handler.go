@@ -0,0 +1,42 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+// HandleGetTaskRevisions lists the versions of a task that updates replaced,
+// from the oldest, when the store records them.
+func (s *Server) HandleGetTaskRevisions(w http.ResponseWriter, r *http.Request) {
+	reviser, ok := s.store.(data.TaskReviser)
+	if !ok {
+		http.Error(w, data.ErrTaskRevisionsNotRecorded.Error(), http.StatusNotImplemented)
+		return
+	}
+	task, ok := s.getPathTask(w, r, models.ProjectRoleViewer)
+	if !ok {
+		return
+	}
+	revisions, err := reviser.GetTaskRevisions(r.Context(), task.Id)
+	if errors.Is(err, data.ErrTaskRevisionsNotRecorded) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if revisions == nil {
+		revisions = []models.TaskRevision{}
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	if err := json.NewEncoder(w).Encode(revisions); err != nil {
+		log.Println("error encoding the task revisions:", err)
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleGetTaskRevisions(t *testing.T) {
+	user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+	member := models.ProjectMember{
+		ProjectId: 1,
+		UserId:    user.Id,
+		Role:      models.ProjectRoleViewer,
+	}
+	revisions := []models.TaskRevision{
+		{
+			TaskId:        1,
+			Revision:      1,
+			Title:         "Pack shoes",
+			ChangedFields: []string{models.TaskFieldTitle},
+			EditorId:      user.Id,
+			CreatedAt:     time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+		},
+	}
+
+	t.Run("returns the revisions of the task", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		store.ProjectMembers = []models.ProjectMember{member}
+		store.TaskRevisions = revisions
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodGet,
+			fmt.Sprintf("/tasks/%d/revisions", store.Tasks[0].Id),
+			user.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.ContentType(
+			t,
+			testutils.GetContentTypeFromResponse(response),
+			jsonContentType,
+		)
+		var got []models.TaskRevision
+		assert.HasNoError(t, json.NewDecoder(response.Body).Decode(&got))
+		assert.Equals(t, got, revisions)
+	})
+
+	t.Run("returns an empty list for a task that was never updated", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		store.ProjectMembers = []models.ProjectMember{member}
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodGet,
+			fmt.Sprintf("/tasks/%d/revisions", store.Tasks[0].Id),
+			user.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.Equals(t, response.Body.String(), "[]\n")
+	})
+
+	t.Run("responds with a 404 Not Found when the task is in another project", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		store.TaskRevisions = revisions
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodGet,
+			fmt.Sprintf("/tasks/%d/revisions", store.Tasks[0].Id),
+			user.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusNotFound)
+	})
+
+	t.Run("responds with a 501 Not Implemented when the store keeps no revisions", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		store.ProjectMembers = []models.ProjectMember{member}
+		server := NewServer(struct{ data.Store }{store})
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodGet,
+			fmt.Sprintf("/tasks/%d/revisions", store.Tasks[0].Id),
+			user.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusNotImplemented)
+	})
+}
@@ -3,15 +3,61 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
 )
 
+// HandleGetTasks returns the tasks of every project the caller is a member
+// of, or only those of the projectId query parameter. With assignee=me it
+// only returns the tasks assigned to the caller.
 func (s *Server) HandleGetTasks(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("content-type", jsonContentType)
-	tasks, err := s.store.GetTasks()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	assigneeId := 0
+	switch r.URL.Query().Get("assignee") {
+	case "":
+	case "me":
+		assigneeId = getAuthenticatedUser(r).Id
+	default:
+		http.Error(w, `assignee must be "me"`, http.StatusBadRequest)
 		return
 	}
+
+	var projectIds []int
+	if r.URL.Query().Has("projectId") {
+		projectId, err := strconv.Atoi(r.URL.Query().Get("projectId"))
+		if err != nil {
+			http.Error(w, "projectId is invalid", http.StatusBadRequest)
+			return
+		}
+		if _, ok := s.checkProjectAccess(w, r, projectId, models.ProjectRoleViewer); !ok {
+			return
+		}
+		projectIds = []int{projectId}
+	} else {
+		members, err := s.store.GetProjectMembersByUserId(r.Context(), getAuthenticatedUser(r).Id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, member := range members {
+			projectIds = append(projectIds, member.ProjectId)
+		}
+	}
+
+	tasks := []models.Task{}
+	for _, projectId := range projectIds {
+		projectTasks, err := s.store.GetTasksByProjectId(r.Context(), projectId)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, task := range projectTasks {
+			if assigneeId == 0 || task.AssigneeId == assigneeId {
+				tasks = append(tasks, task)
+			}
+		}
+	}
 	if err := json.NewEncoder(w).Encode(tasks); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -4,19 +4,32 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
 	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
 	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
 )
 
 func TestHandleGetTasks(t *testing.T) {
-	t.Run("returns the stored tasks", func(t *testing.T) {
+	user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+	member := models.ProjectMember{
+		ProjectId: 1,
+		UserId:    user.Id,
+		Role:      models.ProjectRoleViewer,
+	}
+	otherProjectTask := *models.NewTask(2, "Water the plants", 2)
+
+	t.Run("returns the tasks of the projects the user is a member of", func(t *testing.T) {
 		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		data.ProjectMembers = []models.ProjectMember{member}
+		wantedTasks := data.Tasks
+		data.Tasks = append(data.Tasks, otherProjectTask)
 		server := NewServer(data)
 
-		request := httptest.NewRequest(http.MethodGet, "/tasks", nil)
-		response := httptest.NewRecorder()
-		server.Handler.ServeHTTP(response, request)
+		response := sendAuthenticatedRequest(t, server, http.MethodGet, "/tasks", user.Id, "")
 
 		assert.ContentType(
 			t,
@@ -24,23 +37,173 @@ func TestHandleGetTasks(t *testing.T) {
 			jsonContentType,
 		)
 		assert.Status(t, response.Code, http.StatusOK)
-		assert.Calls(t, data.GetTasksCalls, 1)
+		assert.Calls(t, data.GetTasksByProjectIdCalls, 1)
 		assert.Equals(
 			t,
 			testutils.GetTasksFromResponse(t, response.Body),
-			data.Tasks,
+			wantedTasks,
 		)
 	})
 
-	t.Run("responds with a 500 error when getting tasks from the store errors", func(t *testing.T) {
-		data := testutils.NewMockStore(true)
+	t.Run("does not serve one user the tasks cached for another", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		other := *models.NewUser(2, "Harry Potter", "harry@hogwarts.edu", "password")
+		store.Users = []models.User{user, other}
+		otherMember := member
+		otherMember.UserId = other.Id
+		store.ProjectMembers = []models.ProjectMember{member, otherMember}
+		server := NewServer(data.NewCachingStore(store, 10, time.Minute))
+
+		for _, userId := range []int{user.Id, other.Id, user.Id} {
+			response := sendAuthenticatedRequest(t, server, http.MethodGet, "/tasks", userId, "")
+			assert.Status(t, response.Code, http.StatusOK)
+		}
+
+		assert.Calls(t, store.GetTasksByProjectIdCalls, 2)
+	})
+
+	t.Run("returns an empty list to users without projects", func(t *testing.T) {
+		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		server := NewServer(data)
+
+		response := sendAuthenticatedRequest(t, server, http.MethodGet, "/tasks", user.Id, "")
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.Equals(t, response.Body.String(), "[]\n")
+	})
+
+	t.Run("only returns the tasks of the projectId query parameter", func(t *testing.T) {
+		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		data.ProjectMembers = []models.ProjectMember{
+			member,
+			{ProjectId: 2, UserId: user.Id, Role: models.ProjectRoleViewer},
+		}
+		data.Tasks = append(data.Tasks, otherProjectTask)
+		server := NewServer(data)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodGet,
+			"/tasks?projectId=2",
+			user.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.Equals(
+			t,
+			testutils.GetTasksFromResponse(t, response.Body),
+			[]models.Task{otherProjectTask},
+		)
+	})
+
+	t.Run("only returns the tasks assigned to the user with assignee=me", func(t *testing.T) {
+		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		data.ProjectMembers = []models.ProjectMember{member}
+		assignedTask := *models.NewTask(3, "Walk the dog", 1)
+		assignedTask.AssigneeId = user.Id
+		otherAssigneeTask := *models.NewTask(4, "Cook dinner", 1)
+		otherAssigneeTask.AssigneeId = 2
+		data.Tasks = append(data.Tasks, assignedTask, otherAssigneeTask)
+		server := NewServer(data)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodGet,
+			"/tasks?assignee=me",
+			user.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.Equals(
+			t,
+			testutils.GetTasksFromResponse(t, response.Body),
+			[]models.Task{assignedTask},
+		)
+	})
+
+	t.Run("responds with a 400 Bad Request given an unknown assignee", func(t *testing.T) {
+		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		server := NewServer(data)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodGet,
+			"/tasks?assignee=2",
+			user.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+	})
+
+	t.Run("responds with a 404 Not Found for a project the user is not a member of", func(t *testing.T) {
+		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		data.ProjectMembers = []models.ProjectMember{member}
+		server := NewServer(data)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodGet,
+			"/tasks?projectId=2",
+			user.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusNotFound)
+		assert.Calls(t, data.GetTasksByProjectIdCalls, 0)
+	})
+
+	t.Run("responds with a 401 Unauthorized without authentication", func(t *testing.T) {
+		data := testutils.NewMockStore(false)
 		server := NewServer(data)
 
 		request := httptest.NewRequest(http.MethodGet, "/tasks", nil)
 		response := httptest.NewRecorder()
 		server.Handler.ServeHTTP(response, request)
 
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+		assert.Calls(t, data.GetTasksByProjectIdCalls, 0)
+	})
+
+	t.Run("requires the read:tasks scope from API tokens", func(t *testing.T) {
+		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		data.ProjectMembers = []models.ProjectMember{member}
+		server := NewServer(data)
+		profileToken := createTestApiToken(t, server, user.Id, models.ApiTokenScopeReadProfile)
+		tasksToken := createTestApiToken(t, server, user.Id, models.ApiTokenScopeReadTasks)
+
+		response := sendWithApiToken(server, http.MethodGet, "/tasks", profileToken)
+		assert.Status(t, response.Code, http.StatusForbidden)
+
+		response = sendWithApiToken(server, http.MethodGet, "/tasks", tasksToken)
+		assert.Status(t, response.Code, http.StatusOK)
+	})
+
+	t.Run("responds with a 500 error when getting tasks from the store errors", func(t *testing.T) {
+		data := testutils.NewMockStore(true)
+		data.ProjectMembers = []models.ProjectMember{member}
+		server := NewServer(data)
+
+		request := withAuthenticatedUser(
+			httptest.NewRequest(http.MethodGet, "/tasks", nil),
+			&user,
+		)
+		response := httptest.NewRecorder()
+		server.HandleGetTasks(response, request)
+
 		assert.Status(t, response.Code, http.StatusInternalServerError)
-		assert.Calls(t, data.GetTasksCalls, 1)
+		assert.Calls(t, data.GetTasksByProjectIdCalls, 1)
 	})
 }
@@ -4,12 +4,10 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-	"time"
-
-	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtKey = []byte("secret_key") // TODO: change
+// defaultJwtKey signs tokens for servers that are not given a key of their own.
+var defaultJwtKey = []byte("secret_key")
 
 type LoginCredentials struct {
 	Email    string `json:"email"`
@@ -17,7 +15,9 @@ type LoginCredentials struct {
 }
 
 type LoginResponse struct {
-	AccessToken string `json:"accessToken"`
+	AccessToken string `json:"accessToken,omitempty"`
+	MfaRequired bool   `json:"mfaRequired,omitempty"`
+	MfaToken    string `json:"mfaToken,omitempty"`
 }
 
 func (s *Server) HandleLogin(w http.ResponseWriter, r *http.Request) {
@@ -28,36 +28,77 @@ func (s *Server) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	throttleKeys := getLoginThrottleKeys(r, credentials.Email)
+	lockout, err := s.getLoginLockout(r.Context(), throttleKeys)
+	if err != nil {
+		log.Println("error checking the login lockout:", err)
+		http.Error(w, "Error checking the login lockout", http.StatusInternalServerError)
+		return
+	}
+	if lockout > 0 {
+		setRetryAfter(w, lockout)
+		http.Error(w, "Too many failed login attempts", http.StatusTooManyRequests)
+		return
+	}
+
 	if !s.store.ValidateUserCredentials(
+		r.Context(),
 		credentials.Email,
 		credentials.Password,
 	) {
+		if err := s.recordFailedLogin(r.Context(), throttleKeys); err != nil {
+			log.Println("error recording the failed login:", err)
+		}
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	expirationTime := time.Now().Add(24 * time.Hour)
-	token := jwt.NewWithClaims(
-		jwt.SigningMethodHS256,
-		jwt.MapClaims{"exp": expirationTime},
+	// Only the account's counter is reset so that one valid login does not
+	// clear the failures of every other account tried from the same IP.
+	err = s.resetFailedLogins(
+		r.Context(),
+		[]loginThrottleKey{getEmailLoginThrottleKey(credentials.Email)},
 	)
-	tokenString, err := token.SignedString(jwtKey)
+	if err != nil {
+		log.Println("error resetting the failed logins:", err)
+	}
+
+	user, err := s.store.GetUserByEmail(r.Context(), credentials.Email)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if user.Disabled {
+		http.Error(w, accountDisabledMessage, http.StatusForbidden)
+		return
+	}
+
+	s.completeLogin(w, r, user.Id)
+}
+
+// completeLogin challenges users who enabled MFA for their second factor and
+// starts a session for everyone else, whichever way they proved who they are.
+func (s *Server) completeLogin(w http.ResponseWriter, r *http.Request, userId int) {
+	mfa, err := s.getEnabledUserMfa(r.Context(), userId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if mfa != nil {
+		s.sendMfaChallenge(w, userId)
+		return
+	}
+
+	tokenString, err := s.startSession(w, userId)
 	if err != nil {
 		log.Println("error signing the JWT:", err)
 		http.Error(w, "Error creating the JWT", http.StatusInternalServerError)
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:    "token",
-		Value:   tokenString,
-		Expires: expirationTime,
-	})
-
-	response := LoginResponse{AccessToken: tokenString}
-	err = json.NewEncoder(w).Encode(response)
+	w.Header().Set("content-type", jsonContentType)
+	err = json.NewEncoder(w).Encode(LoginResponse{AccessToken: tokenString})
 	if err != nil {
 		log.Printf("error encoding response: %v", err)
 	}
-
 }
@@ -3,10 +3,13 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
 	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
 	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
 )
@@ -66,6 +69,9 @@ func TestHandleLogin(t *testing.T) {
 
 	t.Run("returns a 200 OK status when given the correct credentials", func(t *testing.T) {
 		store := testutils.NewMockStore(false)
+		store.Users = []models.User{
+			*models.NewUser(7, "The One", "the1@email.com", "password"),
+		}
 		server := NewServer(store)
 
 		credentials := LoginCredentials{
@@ -85,5 +91,101 @@ func TestHandleLogin(t *testing.T) {
 
 		assert.Status(t, response.Code, http.StatusOK)
 		assert.Calls(t, store.ValidateUserCredentialsCalls, 1)
+
+		var body LoginResponse
+		assert.HasNoError(t, json.NewDecoder(response.Body).Decode(&body))
+		userId, err := server.parseAccessToken(body.AccessToken)
+		assert.HasNoError(t, err)
+		assert.Equals(t, userId, 7)
+	})
+}
+
+func TestHandleLoginLockout(t *testing.T) {
+	t.Run("locks the account after too many failed attempts", func(t *testing.T) {
+		store := testutils.NewMockStore(true)
+		server := NewServer(store)
+
+		for range maxFailedLoginsPerEmail {
+			response := sendLogin(t, server, "the1@email.com", "wrong")
+			assert.Status(t, response.Code, http.StatusUnauthorized)
+		}
+		response := sendLogin(t, server, "The1@Email.com", "wrong")
+
+		assert.Status(t, response.Code, http.StatusTooManyRequests)
+		assert.Equals(t, response.Header().Get("Retry-After"), "60")
+		assert.Calls(t, store.ValidateUserCredentialsCalls, maxFailedLoginsPerEmail)
+		assert.HasLength(t, store.AuditEvents, 1)
+		assert.Equals(t, store.AuditEvents[0].Action, loginLockedAuditAction)
+		assert.Equals(t, store.AuditEvents[0].Subject, "email:the1@email.com")
+	})
+
+	t.Run("doubles the lockout for every failure after it expires", func(t *testing.T) {
+		store := testutils.NewMockStore(true)
+		server := NewServer(store)
+		now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		server.now = func() time.Time { return now }
+
+		for range maxFailedLoginsPerEmail {
+			sendLogin(t, server, "the1@email.com", "wrong")
+		}
+		now = now.Add(baseLoginLockout)
+		response := sendLogin(t, server, "the1@email.com", "wrong")
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+
+		response = sendLogin(t, server, "the1@email.com", "wrong")
+		assert.Status(t, response.Code, http.StatusTooManyRequests)
+		assert.Equals(t, response.Header().Get("Retry-After"), "120")
+		assert.Calls(t, store.ValidateUserCredentialsCalls, maxFailedLoginsPerEmail+1)
 	})
+
+	t.Run("locks the client IP across different emails", func(t *testing.T) {
+		store := testutils.NewMockStore(true)
+		server := NewServer(store)
+
+		for i := range maxFailedLoginsPerIp {
+			sendLogin(t, server, fmt.Sprintf("user%d@email.com", i), "wrong")
+		}
+		response := sendLogin(t, server, "someone.else@email.com", "wrong")
+
+		assert.Status(t, response.Code, http.StatusTooManyRequests)
+		assert.Calls(t, store.ValidateUserCredentialsCalls, maxFailedLoginsPerIp)
+	})
+
+	t.Run("a successful login resets the failed attempts of the account", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{
+			*models.NewUser(1, "The One", "the1@email.com", "password"),
+		}
+		server := NewServer(store)
+		emailKey := "email:the1@email.com"
+		ipKey := "ip:192.0.2.1"
+		store.LoginAttempts[emailKey] = models.LoginAttempt{Key: emailKey, Failures: 3}
+		store.LoginAttempts[ipKey] = models.LoginAttempt{Key: ipKey, Failures: 3}
+
+		response := sendLogin(t, server, "the1@email.com", "password")
+
+		assert.Status(t, response.Code, http.StatusOK)
+		_, ok := store.LoginAttempts[emailKey]
+		assert.Equals(t, ok, false)
+		_, ok = store.LoginAttempts[ipKey]
+		assert.Equals(t, ok, true)
+	})
+}
+
+func sendLogin(
+	t *testing.T,
+	server *Server,
+	email, password string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	jsonData, err := json.Marshal(LoginCredentials{Email: email, Password: password})
+	assert.HasNoError(t, err)
+	request := httptest.NewRequest(
+		http.MethodPost,
+		"/login",
+		bytes.NewBuffer(jsonData),
+	)
+	response := httptest.NewRecorder()
+	server.Handler.ServeHTTP(response, request)
+	return response
 }
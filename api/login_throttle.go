@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+const (
+	maxFailedLoginsPerEmail = 5
+	maxFailedLoginsPerIp    = 20
+	baseLoginLockout        = time.Minute
+	maxLoginLockout         = time.Hour
+	failedLoginsExpireAfter = time.Hour
+)
+
+const loginLockedAuditAction = "login.locked"
+
+type loginThrottleKey struct {
+	key         string
+	maxFailures int
+}
+
+func getLoginThrottleKeys(r *http.Request, email string) []loginThrottleKey {
+	return []loginThrottleKey{
+		getEmailLoginThrottleKey(email),
+		{"ip:" + getClientIp(r), maxFailedLoginsPerIp},
+	}
+}
+
+func getEmailLoginThrottleKey(email string) loginThrottleKey {
+	return loginThrottleKey{
+		"email:" + data.NormalizeEmail(email),
+		maxFailedLoginsPerEmail,
+	}
+}
+
+func getClientIp(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// getLoginLockout returns how long the caller has to wait before trying to
+// log in again, or zero if none of the keys are locked.
+func (s *Server) getLoginLockout(
+	ctx context.Context,
+	keys []loginThrottleKey,
+) (time.Duration, error) {
+	var lockout time.Duration
+	now := s.now()
+	for _, k := range keys {
+		attempt, err := s.store.GetLoginAttempt(ctx, k.key)
+		if errors.Is(err, data.ErrResourceNotFound) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		lockout = max(lockout, attempt.LockedUntil.Sub(now))
+	}
+	return lockout, nil
+}
+
+func (s *Server) recordFailedLogin(ctx context.Context, keys []loginThrottleKey) error {
+	now := s.now()
+	for _, k := range keys {
+		attempt, err := s.store.IncrementLoginFailures(
+			ctx,
+			k.key,
+			now,
+			failedLoginsExpireAfter,
+		)
+		if err != nil {
+			return err
+		}
+
+		if attempt.Failures >= k.maxFailures {
+			lockout := getLoginLockoutDuration(attempt.Failures - k.maxFailures)
+			err := s.store.LockLoginAttempt(
+				ctx,
+				k.key,
+				attempt.Failures,
+				now.Add(lockout),
+			)
+			if err != nil {
+				return err
+			}
+			_, err = s.store.CreateAuditEvent(ctx, models.NewAuditEvent(
+				loginLockedAuditAction,
+				0,
+				k.key,
+				fmt.Sprintf(
+					"locked for %s after %d failed attempts",
+					lockout,
+					attempt.Failures,
+				),
+			))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) resetFailedLogins(ctx context.Context, keys []loginThrottleKey) error {
+	for _, k := range keys {
+		if err := s.store.DeleteLoginAttempt(ctx, k.key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getLoginLockoutDuration doubles the lockout for every failure past the
+// allowed maximum, up to maxLoginLockout.
+func getLoginLockoutDuration(failuresOverMax int) time.Duration {
+	lockout := baseLoginLockout
+	for range failuresOverMax {
+		lockout *= 2
+		if lockout >= maxLoginLockout {
+			return maxLoginLockout
+		}
+	}
+	return lockout
+}
+
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", fmt.Sprint(seconds))
+}
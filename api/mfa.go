@@ -0,0 +1,196 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/totp"
+	"github.com/claudealdric/go-todolist-restful-api-server/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	mfaChallengeAudience = "mfa-challenge"
+	mfaChallengeTtl      = 5 * time.Minute
+	mfaRecoveryCodeCount = 10
+	totpIssuer           = "To-do list"
+)
+
+const (
+	mfaDisabledAuditAction         = "mfa.disabled"
+	mfaEnabledAuditAction          = "mfa.enabled"
+	mfaRecoveryCodeUsedAuditAction = "mfa.recovery_code_used"
+)
+
+// createMfaChallengeToken proves that the user got their password right or
+// signed in with the identity provider, so that HandleMfaLogin only has to
+// check the second factor.
+func (s *Server) createMfaChallengeToken(userId int) (string, error) {
+	token := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		jwt.RegisteredClaims{
+			Subject:   strconv.Itoa(userId),
+			Audience:  jwt.ClaimStrings{mfaChallengeAudience},
+			ExpiresAt: jwt.NewNumericDate(s.now().Add(mfaChallengeTtl)),
+			IssuedAt:  jwt.NewNumericDate(s.now()),
+		},
+	)
+	return token.SignedString(s.jwtKey)
+}
+
+func (s *Server) parseMfaChallengeToken(tokenString string) (int, error) {
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(
+		tokenString,
+		&claims,
+		func(token *jwt.Token) (any, error) {
+			return s.jwtKey, nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(mfaChallengeAudience),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(s.now),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(claims.Subject)
+}
+
+func (s *Server) sendMfaChallenge(w http.ResponseWriter, userId int) {
+	mfaToken, err := s.createMfaChallengeToken(userId)
+	if err != nil {
+		log.Println("error signing the MFA challenge:", err)
+		http.Error(w, "Error creating the MFA challenge", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("content-type", jsonContentType)
+	err = json.NewEncoder(w).Encode(LoginResponse{
+		MfaRequired: true,
+		MfaToken:    mfaToken,
+	})
+	if err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
+
+// getEnabledUserMfa returns nil when the user has not turned on MFA, including
+// when an enrollment was started but never verified.
+func (s *Server) getEnabledUserMfa(
+	ctx context.Context,
+	userId int,
+) (*models.UserMfa, error) {
+	mfa, err := s.store.GetUserMfa(ctx, userId)
+	if errors.Is(err, data.ErrResourceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !mfa.Enabled {
+		return nil, nil
+	}
+	return mfa, nil
+}
+
+// checkTotpCode accepts each code only once: a code for a time step at or
+// before the last accepted one is rejected.
+func (s *Server) checkTotpCode(
+	ctx context.Context,
+	mfa *models.UserMfa,
+	code string,
+) (bool, error) {
+	step, ok := totp.Validate(mfa.TotpSecret, strings.TrimSpace(code), s.now())
+	if !ok || step <= mfa.LastUsedStep {
+		return false, nil
+	}
+	// Concurrent requests with the same code have all read the same last
+	// step, so only the one whose write goes through is accepted.
+	used, err := s.store.UseUserMfaStep(ctx, mfa.UserId, step)
+	if err != nil || !used {
+		return false, err
+	}
+	mfa.LastUsedStep = step
+	return true, nil
+}
+
+// checkSecondFactor accepts either a TOTP code or one of the user's recovery
+// codes, which is used up in the process.
+func (s *Server) checkSecondFactor(
+	ctx context.Context,
+	mfa *models.UserMfa,
+	code, recoveryCode string,
+) (bool, error) {
+	if recoveryCode == "" {
+		return s.checkTotpCode(ctx, mfa, code)
+	}
+	_, err := s.store.ConsumeUserToken(
+		ctx,
+		hashRecoveryCode(mfa.UserId, recoveryCode),
+		models.UserTokenPurposeMfaRecovery,
+	)
+	if errors.Is(err, data.ErrResourceNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	s.recordMfaAction(ctx, mfa.UserId, mfaRecoveryCodeUsedAuditAction)
+	return true, nil
+}
+
+// issueRecoveryCodes replaces the user's recovery codes and returns the new
+// ones; only their hashes are stored.
+func (s *Server) issueRecoveryCodes(ctx context.Context, userId int) ([]string, error) {
+	err := s.store.DeleteUserTokens(ctx, userId, models.UserTokenPurposeMfaRecovery)
+	if err != nil {
+		return nil, err
+	}
+	codes := make([]string, mfaRecoveryCodeCount)
+	for i := range codes {
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(secret[:5] + "-" + secret[5:10])
+		err = s.store.CreateUserToken(ctx, models.NewUserToken(
+			hashRecoveryCode(userId, code),
+			userId,
+			models.UserTokenPurposeMfaRecovery,
+			time.Time{},
+		))
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode includes the user ID so that a guessed code can only ever
+// match one of that user's codes.
+func hashRecoveryCode(userId int, code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return utils.HashToken(fmt.Sprintf("%d:%s", userId, code))
+}
+
+func (s *Server) recordMfaAction(ctx context.Context, userId int, action string) {
+	_, err := s.store.CreateAuditEvent(ctx, models.NewAuditEvent(
+		action,
+		userId,
+		fmt.Sprintf("user:%d", userId),
+		"",
+	))
+	if err != nil {
+		log.Println("error recording the audit event:", err)
+	}
+}
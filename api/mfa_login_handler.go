@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+)
+
+type MfaLoginRequest struct {
+	MfaToken     string `json:"mfaToken"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+func (s *Server) HandleMfaLogin(w http.ResponseWriter, r *http.Request) {
+	var body MfaLoginRequest
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		http.Error(w, "Error parsing the JSON body", http.StatusBadRequest)
+		return
+	}
+
+	userId, err := s.parseMfaChallengeToken(body.MfaToken)
+	if err != nil {
+		http.Error(w, "Invalid or expired MFA token", http.StatusUnauthorized)
+		return
+	}
+	user, err := s.store.GetUserById(r.Context(), userId)
+	if errors.Is(err, data.ErrResourceNotFound) {
+		http.Error(w, "Invalid or expired MFA token", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	throttleKeys := getLoginThrottleKeys(r, user.Email)
+	lockout, err := s.getLoginLockout(r.Context(), throttleKeys)
+	if err != nil {
+		log.Println("error checking the login lockout:", err)
+		http.Error(w, "Error checking the login lockout", http.StatusInternalServerError)
+		return
+	}
+	if lockout > 0 {
+		setRetryAfter(w, lockout)
+		http.Error(w, "Too many failed login attempts", http.StatusTooManyRequests)
+		return
+	}
+
+	mfa, err := s.getEnabledUserMfa(r.Context(), user.Id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if mfa == nil {
+		http.Error(w, "Invalid or expired MFA token", http.StatusUnauthorized)
+		return
+	}
+	ok, err := s.checkSecondFactor(r.Context(), mfa, body.Code, body.RecoveryCode)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		if err := s.recordFailedLogin(r.Context(), throttleKeys); err != nil {
+			log.Println("error recording the failed login:", err)
+		}
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	err = s.resetFailedLogins(
+		r.Context(),
+		[]loginThrottleKey{getEmailLoginThrottleKey(user.Email)},
+	)
+	if err != nil {
+		log.Println("error resetting the failed logins:", err)
+	}
+	if user.Disabled {
+		http.Error(w, accountDisabledMessage, http.StatusForbidden)
+		return
+	}
+
+	tokenString, err := s.startSession(w, user.Id)
+	if err != nil {
+		log.Println("error signing the JWT:", err)
+		http.Error(w, "Error creating the JWT", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	err = json.NewEncoder(w).Encode(LoginResponse{AccessToken: tokenString})
+	if err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+	"github.com/claudealdric/go-todolist-restful-api-server/totp"
+)
+
+func TestHandleMfaLogin(t *testing.T) {
+	user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+
+	setUp := func(t *testing.T) (*Server, *time.Time, string, []string) {
+		t.Helper()
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+		now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		server.now = func() time.Time { return now }
+		secret, recoveryCodes := enableTotp(t, server, user.Id)
+		now = now.Add(time.Minute)
+		return server, &now, secret, recoveryCodes
+	}
+
+	t.Run("logs in with the password and then a TOTP code", func(t *testing.T) {
+		server, now, secret, _ := setUp(t)
+
+		mfaToken := getMfaToken(t, server, user)
+		response := sendMfaLogin(t, server, MfaLoginRequest{
+			MfaToken: mfaToken,
+			Code:     getTotpCode(t, secret, *now),
+		})
+
+		assert.Status(t, response.Code, http.StatusOK)
+		var body LoginResponse
+		err := json.NewDecoder(response.Body).Decode(&body)
+		assert.HasNoError(t, err)
+		userId, err := server.parseAccessToken(body.AccessToken)
+		assert.HasNoError(t, err)
+		assert.Equals(t, userId, user.Id)
+	})
+
+	t.Run("rejects a TOTP code that was already used", func(t *testing.T) {
+		server, now, secret, _ := setUp(t)
+		code := getTotpCode(t, secret, *now)
+
+		response := sendMfaLogin(t, server, MfaLoginRequest{
+			MfaToken: getMfaToken(t, server, user),
+			Code:     code,
+		})
+		assert.Status(t, response.Code, http.StatusOK)
+
+		response = sendMfaLogin(t, server, MfaLoginRequest{
+			MfaToken: getMfaToken(t, server, user),
+			Code:     code,
+		})
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("accepts each recovery code once", func(t *testing.T) {
+		server, _, _, recoveryCodes := setUp(t)
+
+		response := sendMfaLogin(t, server, MfaLoginRequest{
+			MfaToken:     getMfaToken(t, server, user),
+			RecoveryCode: recoveryCodes[0],
+		})
+		assert.Status(t, response.Code, http.StatusOK)
+
+		response = sendMfaLogin(t, server, MfaLoginRequest{
+			MfaToken:     getMfaToken(t, server, user),
+			RecoveryCode: recoveryCodes[0],
+		})
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("responds with a 401 Unauthorized once the challenge expired", func(t *testing.T) {
+		server, now, secret, _ := setUp(t)
+
+		mfaToken := getMfaToken(t, server, user)
+		*now = now.Add(mfaChallengeTtl)
+		response := sendMfaLogin(t, server, MfaLoginRequest{
+			MfaToken: mfaToken,
+			Code:     getTotpCode(t, secret, *now),
+		})
+
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("locks out after too many wrong codes", func(t *testing.T) {
+		server, now, secret, _ := setUp(t)
+
+		mfaToken := getMfaToken(t, server, user)
+		for range maxFailedLoginsPerEmail {
+			response := sendMfaLogin(t, server, MfaLoginRequest{
+				MfaToken: mfaToken,
+				Code:     "000000",
+			})
+			assert.Status(t, response.Code, http.StatusUnauthorized)
+		}
+		response := sendMfaLogin(t, server, MfaLoginRequest{
+			MfaToken: mfaToken,
+			Code:     getTotpCode(t, secret, *now),
+		})
+
+		assert.Status(t, response.Code, http.StatusTooManyRequests)
+	})
+
+	t.Run("the MFA token is not an access token", func(t *testing.T) {
+		server, _, _, _ := setUp(t)
+
+		request := httptest.NewRequest(http.MethodGet, "/me", nil)
+		request.Header.Set("Authorization", "Bearer "+getMfaToken(t, server, user))
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("an access token is not an MFA token", func(t *testing.T) {
+		server, now, secret, _ := setUp(t)
+
+		accessToken, _, err := server.createAccessToken(user.Id)
+		assert.HasNoError(t, err)
+		response := sendMfaLogin(t, server, MfaLoginRequest{
+			MfaToken: accessToken,
+			Code:     getTotpCode(t, secret, *now),
+		})
+
+		assert.Status(t, response.Code, http.StatusUnauthorized)
+	})
+
+	t.Run("accepts codes from the adjacent time steps", func(t *testing.T) {
+		server, now, secret, _ := setUp(t)
+
+		response := sendMfaLogin(t, server, MfaLoginRequest{
+			MfaToken: getMfaToken(t, server, user),
+			Code:     getTotpCode(t, secret, now.Add(-totp.Period)),
+		})
+
+		assert.Status(t, response.Code, http.StatusOK)
+	})
+}
+
+func getMfaToken(t *testing.T, server *Server, user models.User) string {
+	t.Helper()
+	response := sendLogin(t, server, user.Email, user.Password)
+	assert.Status(t, response.Code, http.StatusOK)
+	var body LoginResponse
+	err := json.NewDecoder(response.Body).Decode(&body)
+	assert.HasNoError(t, err)
+	assert.Equals(t, body.MfaRequired, true)
+	assert.Equals(t, body.AccessToken, "")
+	assert.HasLength(t, response.Result().Cookies(), 0)
+	return body.MfaToken
+}
+
+func sendMfaLogin(
+	t *testing.T,
+	server *Server,
+	body MfaLoginRequest,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	jsonData, err := json.Marshal(body)
+	assert.HasNoError(t, err)
+	request := httptest.NewRequest(http.MethodPost, "/login/mfa", bytes.NewBuffer(jsonData))
+	response := httptest.NewRecorder()
+	server.Handler.ServeHTTP(response, request)
+	return response
+}
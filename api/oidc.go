@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/oidc"
+	"github.com/claudealdric/go-todolist-restful-api-server/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	oidcStateCookieName = "oidc_state"
+	oidcStateCookiePath = "/auth/oidc"
+	oidcStateTtl        = 10 * time.Minute
+	// oidcStateAudience keeps state cookies and access tokens, which are
+	// signed with the same key, from being used in place of each other.
+	oidcStateAudience = "oidc-state"
+)
+
+var errOidcEmailNotVerified = errors.New(
+	"the identity provider has not verified the email",
+)
+
+// oidcStateClaims keeps what the callback needs to check in a signed cookie,
+// so that no server-side state is needed between start and callback.
+type oidcStateClaims struct {
+	jwt.RegisteredClaims
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"codeVerifier"`
+}
+
+func (s *Server) setOidcStateCookie(
+	w http.ResponseWriter,
+	claims *oidcStateClaims,
+) error {
+	expirationTime := s.now().Add(oidcStateTtl)
+	claims.ExpiresAt = jwt.NewNumericDate(expirationTime)
+	claims.Audience = jwt.ClaimStrings{oidcStateAudience}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString(s.jwtKey)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    tokenString,
+		Path:     oidcStateCookiePath,
+		Expires:  expirationTime,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+func (s *Server) getOidcStateCookie(r *http.Request) (*oidcStateClaims, error) {
+	cookie, err := r.Cookie(oidcStateCookieName)
+	if err != nil {
+		return nil, err
+	}
+	var claims oidcStateClaims
+	_, err = jwt.ParseWithClaims(
+		cookie.Value,
+		&claims,
+		func(token *jwt.Token) (any, error) {
+			return s.jwtKey, nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithAudience(oidcStateAudience),
+		jwt.WithTimeFunc(s.now),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func clearOidcStateCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    "",
+		Path:     oidcStateCookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// linkOidcUser returns the account with the provider's verified email,
+// creating it on first sign-in.
+func (s *Server) linkOidcUser(
+	ctx context.Context,
+	claims *oidc.IdTokenClaims,
+) (*models.User, error) {
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errOidcEmailNotVerified
+	}
+
+	user, err := s.store.GetUserByEmail(ctx, claims.Email)
+	if errors.Is(err, data.ErrResourceNotFound) {
+		return s.createOidcUser(ctx, claims)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.Verified {
+		return user, nil
+	}
+
+	// Whoever signed up with this email without verifying it may not own it,
+	// so their password must not keep working on the linked account.
+	password, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.UpdateUserPassword(ctx, user.Id, password); err != nil {
+		return nil, err
+	}
+	user.Verified = true
+	return s.store.UpdateUser(ctx, user)
+}
+
+func (s *Server) createOidcUser(
+	ctx context.Context,
+	claims *oidc.IdTokenClaims,
+) (*models.User, error) {
+	// The account can only be reached through the identity provider until
+	// the user resets their password.
+	password, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	name := claims.Name
+	if name == "" {
+		name = claims.Email
+	}
+	user, err := s.store.CreateUser(
+		ctx,
+		models.NewCreateUserDTO(name, claims.Email, password),
+	)
+	if err != nil {
+		return nil, err
+	}
+	user.Verified = true
+	return s.store.UpdateUser(ctx, user)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/oidc"
+)
+
+func (s *Server) HandleOidcCallback(w http.ResponseWriter, r *http.Request) {
+	state, err := s.getOidcStateCookie(r)
+	clearOidcStateCookie(w)
+	if err != nil {
+		http.Error(w, "Missing or expired sign-in state", http.StatusBadRequest)
+		return
+	}
+	query := r.URL.Query()
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(state.State)) != 1 {
+		http.Error(w, "Invalid sign-in state", http.StatusBadRequest)
+		return
+	}
+	if errorCode := query.Get("error"); errorCode != "" {
+		http.Error(w, "The identity provider returned "+errorCode, http.StatusUnauthorized)
+		return
+	}
+	code := query.Get("code")
+	if code == "" {
+		http.Error(w, "Missing authorization code", http.StatusBadRequest)
+		return
+	}
+
+	idToken, err := s.oidcProvider.Exchange(r.Context(), code, state.CodeVerifier)
+	if err != nil {
+		log.Println("error exchanging the authorization code:", err)
+		http.Error(w, "Error contacting the identity provider", http.StatusBadGateway)
+		return
+	}
+	claims, err := s.oidcProvider.VerifyIdToken(r.Context(), idToken, state.Nonce)
+	if errors.Is(err, oidc.ErrInvalidIdToken) {
+		http.Error(w, "Invalid ID token", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		log.Println("error verifying the ID token:", err)
+		http.Error(w, "Error contacting the identity provider", http.StatusBadGateway)
+		return
+	}
+
+	user, err := s.linkOidcUser(r.Context(), claims)
+	if errors.Is(err, errOidcEmailNotVerified) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if user.Disabled {
+		http.Error(w, accountDisabledMessage, http.StatusForbidden)
+		return
+	}
+
+	s.completeLogin(w, r, user.Id)
+}
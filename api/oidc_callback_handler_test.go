@@ -0,0 +1,256 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/oidc/oidctest"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestHandleOidcCallback(t *testing.T) {
+	ctx := context.Background()
+	idp := oidctest.NewServer("client-id", "client-secret")
+	defer idp.Close()
+	idpUser := oidctest.User{
+		Subject:       "subject",
+		Email:         "Claude.Aldric@email.com",
+		EmailVerified: true,
+		Name:          "Claude Aldric",
+	}
+
+	t.Run("creates a verified account on first sign-in", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		server := newOidcTestServer(store, idp)
+		idp.SetUser(idpUser)
+
+		response := signInWithOidc(t, server, idp)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.HasLength(t, store.Users, 1)
+		user := store.Users[0]
+		assert.Equals(t, user.Email, "claude.aldric@email.com")
+		assert.Equals(t, user.Name, "Claude Aldric")
+		assert.Equals(t, user.Verified, true)
+		assertOidcSession(t, server, response, user.Id)
+	})
+
+	t.Run("links an existing verified account by email", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		user := *models.NewUser(1, "Claude", "claude.aldric@email.com", "password")
+		user.Verified = true
+		store.Users = []models.User{user}
+		server := newOidcTestServer(store, idp)
+		idp.SetUser(idpUser)
+
+		response := signInWithOidc(t, server, idp)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.Equals(t, store.Users, []models.User{user})
+		assertOidcSession(t, server, response, user.Id)
+	})
+
+	t.Run("invalidates the password of an unverified account it links", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		user := *models.NewUser(1, "Claude", "claude.aldric@email.com", "password")
+		store.Users = []models.User{user}
+		server := newOidcTestServer(store, idp)
+		idp.SetUser(idpUser)
+
+		response := signInWithOidc(t, server, idp)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.Equals(t, store.Users[0].Verified, true)
+		assert.Equals(t, store.ValidateUserCredentials(ctx, user.Email, "password"), false)
+	})
+
+	t.Run("responds with a 403 Forbidden when the email is not verified", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		server := newOidcTestServer(store, idp)
+		unverifiedUser := idpUser
+		unverifiedUser.EmailVerified = false
+		idp.SetUser(unverifiedUser)
+
+		response := signInWithOidc(t, server, idp)
+
+		assert.Status(t, response.Code, http.StatusForbidden)
+		assert.HasLength(t, store.Users, 0)
+	})
+
+	t.Run("responds with a 403 Forbidden for a disabled account", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		user := *models.NewUser(1, "Claude", "claude.aldric@email.com", "password")
+		user.Verified = true
+		user.Disabled = true
+		store.Users = []models.User{user}
+		server := newOidcTestServer(store, idp)
+		idp.SetUser(idpUser)
+
+		response := signInWithOidc(t, server, idp)
+
+		assert.Status(t, response.Code, http.StatusForbidden)
+	})
+
+	t.Run("sends users with MFA enabled through the MFA challenge", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		user := *models.NewUser(1, "Claude", "claude.aldric@email.com", "password")
+		user.Verified = true
+		store.Users = []models.User{user}
+		server := newOidcTestServer(store, idp)
+		now := time.Now()
+		server.now = func() time.Time { return now }
+		secret, _ := enableTotp(t, server, user.Id)
+		now = now.Add(time.Minute)
+		idp.SetUser(idpUser)
+
+		response := signInWithOidc(t, server, idp)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		var body LoginResponse
+		err := json.NewDecoder(response.Body).Decode(&body)
+		assert.HasNoError(t, err)
+		assert.Equals(t, body.MfaRequired, true)
+		assert.Equals(t, body.AccessToken, "")
+		for _, cookie := range response.Result().Cookies() {
+			assert.Equals(t, cookie.Name == accessTokenCookieName, false)
+		}
+
+		response = sendMfaLogin(t, server, MfaLoginRequest{
+			MfaToken: body.MfaToken,
+			Code:     getTotpCode(t, secret, now),
+		})
+		assert.Status(t, response.Code, http.StatusOK)
+	})
+
+	t.Run("responds with a 400 Bad Request when the state does not match", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		server := newOidcTestServer(store, idp)
+		idp.SetUser(idpUser)
+
+		startResponse := startOidcSignIn(t, server)
+		callbackUrl, err := idp.Authorize(startResponse.Header().Get("Location"))
+		assert.HasNoError(t, err)
+		query := callbackUrl.Query()
+		query.Set("state", "forged")
+		callbackUrl.RawQuery = query.Encode()
+		response := sendOidcCallback(server, callbackUrl.RequestURI(), startResponse)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.HasLength(t, store.Users, 0)
+	})
+
+	t.Run("responds with a 400 Bad Request without the state cookie", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		server := newOidcTestServer(store, idp)
+		idp.SetUser(idpUser)
+
+		startResponse := startOidcSignIn(t, server)
+		callbackUrl, err := idp.Authorize(startResponse.Header().Get("Location"))
+		assert.HasNoError(t, err)
+		response := sendOidcCallback(server, callbackUrl.RequestURI(), nil)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+	})
+
+	t.Run("responds with a 400 Bad Request when the state cookie has no audience", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		server := newOidcTestServer(store, idp)
+		idp.SetUser(idpUser)
+
+		startResponse := startOidcSignIn(t, server)
+		callbackUrl, err := idp.Authorize(startResponse.Header().Get("Location"))
+		assert.HasNoError(t, err)
+		stateRequest := httptest.NewRequest(http.MethodGet, callbackUrl.RequestURI(), nil)
+		for _, cookie := range startResponse.Result().Cookies() {
+			stateRequest.AddCookie(cookie)
+		}
+		claims, err := server.getOidcStateCookie(stateRequest)
+		assert.HasNoError(t, err)
+		claims.Audience = nil
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).
+			SignedString(server.jwtKey)
+		assert.HasNoError(t, err)
+		request := httptest.NewRequest(http.MethodGet, callbackUrl.RequestURI(), nil)
+		request.AddCookie(&http.Cookie{Name: oidcStateCookieName, Value: token})
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.HasLength(t, store.Users, 0)
+	})
+
+	t.Run("responds with a 502 Bad Gateway when the code exchange fails", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		server := newOidcTestServer(store, idp)
+		idp.SetUser(idpUser)
+
+		startResponse := startOidcSignIn(t, server)
+		callbackUrl, err := idp.Authorize(startResponse.Header().Get("Location"))
+		assert.HasNoError(t, err)
+		query := callbackUrl.Query()
+		query.Set("code", "unknown")
+		callbackUrl.RawQuery = query.Encode()
+		response := sendOidcCallback(server, callbackUrl.RequestURI(), startResponse)
+
+		assert.Status(t, response.Code, http.StatusBadGateway)
+	})
+}
+
+func startOidcSignIn(t *testing.T, server *Server) *httptest.ResponseRecorder {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodGet, "/auth/oidc/start", nil)
+	response := httptest.NewRecorder()
+	server.Handler.ServeHTTP(response, request)
+	assert.Status(t, response.Code, http.StatusFound)
+	return response
+}
+
+func sendOidcCallback(
+	server *Server,
+	requestUri string,
+	startResponse *httptest.ResponseRecorder,
+) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, requestUri, nil)
+	if startResponse != nil {
+		for _, cookie := range startResponse.Result().Cookies() {
+			request.AddCookie(cookie)
+		}
+	}
+	response := httptest.NewRecorder()
+	server.Handler.ServeHTTP(response, request)
+	return response
+}
+
+func signInWithOidc(
+	t *testing.T,
+	server *Server,
+	idp *oidctest.Server,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	startResponse := startOidcSignIn(t, server)
+	callbackUrl, err := idp.Authorize(startResponse.Header().Get("Location"))
+	assert.HasNoError(t, err)
+	return sendOidcCallback(server, callbackUrl.RequestURI(), startResponse)
+}
+
+func assertOidcSession(
+	t *testing.T,
+	server *Server,
+	response *httptest.ResponseRecorder,
+	userId int,
+) {
+	t.Helper()
+	var body LoginResponse
+	err := json.NewDecoder(response.Body).Decode(&body)
+	assert.HasNoError(t, err)
+	gotUserId, err := server.parseAccessToken(body.AccessToken)
+	assert.HasNoError(t, err)
+	assert.Equals(t, gotUserId, userId)
+}
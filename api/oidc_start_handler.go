@@ -0,0 +1,42 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/oidc"
+	"github.com/claudealdric/go-todolist-restful-api-server/utils"
+)
+
+func (s *Server) HandleOidcStart(w http.ResponseWriter, r *http.Request) {
+	var claims oidcStateClaims
+	var err error
+	for _, value := range []*string{&claims.State, &claims.Nonce} {
+		if *value, err = utils.GenerateToken(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if claims.CodeVerifier, err = oidc.NewCodeVerifier(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	authUrl, err := s.oidcProvider.AuthCodeUrl(
+		r.Context(),
+		claims.State,
+		claims.Nonce,
+		oidc.CodeChallenge(claims.CodeVerifier),
+	)
+	if err != nil {
+		log.Println("error building the authorization URL:", err)
+		http.Error(w, "Error contacting the identity provider", http.StatusBadGateway)
+		return
+	}
+
+	if err := s.setOidcStateCookie(w, &claims); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, authUrl, http.StatusFound)
+}
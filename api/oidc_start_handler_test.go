@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/oidc"
+	"github.com/claudealdric/go-todolist-restful-api-server/oidc/oidctest"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleOidcStart(t *testing.T) {
+	t.Run("redirects to the identity provider with PKCE", func(t *testing.T) {
+		idp := oidctest.NewServer("client-id", "client-secret")
+		defer idp.Close()
+		server := newOidcTestServer(testutils.NewMockStore(false), idp)
+
+		request := httptest.NewRequest(http.MethodGet, "/auth/oidc/start", nil)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusFound)
+		location, err := url.Parse(response.Header().Get("Location"))
+		assert.HasNoError(t, err)
+		assert.Equals(t, location.Path, "/authorize")
+		query := location.Query()
+		assert.Equals(t, query.Get("client_id"), "client-id")
+		assert.Equals(t, query.Get("redirect_uri"), oidcTestRedirectUrl)
+		assert.Equals(t, query.Get("code_challenge_method"), "S256")
+
+		state := getOidcStateFromResponse(t, server, response)
+		assert.Equals(t, query.Get("state"), state.State)
+		assert.Equals(t, query.Get("nonce"), state.Nonce)
+		assert.Equals(t, query.Get("code_challenge"), oidc.CodeChallenge(state.CodeVerifier))
+	})
+
+	t.Run("is not routed without an identity provider", func(t *testing.T) {
+		server := NewServer(testutils.NewMockStore(false))
+
+		request := httptest.NewRequest(http.MethodGet, "/auth/oidc/start", nil)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusNotFound)
+	})
+}
+
+const oidcTestRedirectUrl = "http://localhost/auth/oidc/callback"
+
+func newOidcTestServer(store data.Store, idp *oidctest.Server) *Server {
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       idp.URL,
+		ClientId:     idp.ClientId,
+		ClientSecret: idp.ClientSecret,
+		RedirectUrl:  oidcTestRedirectUrl,
+	})
+	return NewServer(store, WithOidcProvider(provider))
+}
+
+func getOidcStateFromResponse(
+	t *testing.T,
+	server *Server,
+	response *httptest.ResponseRecorder,
+) *oidcStateClaims {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback", nil)
+	for _, cookie := range response.Result().Cookies() {
+		request.AddCookie(cookie)
+	}
+	state, err := server.getOidcStateCookie(request)
+	assert.HasNoError(t, err)
+	return state
+}
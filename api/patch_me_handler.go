@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+var localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+func (s *Server) HandlePatchMe(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", jsonContentType)
+	var dto models.UpdateUserDTO
+	err := json.NewDecoder(r.Body).Decode(&dto)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user := *getAuthenticatedUser(r)
+	newEmail, err := applyUpdateUserDTO(&user, &dto)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if newEmail != "" {
+		if !s.store.ValidateUserCredentials(r.Context(), user.Email, dto.CurrentPassword) {
+			http.Error(w, "The current password is incorrect", http.StatusForbidden)
+			return
+		}
+		_, err := s.store.GetUserByEmail(r.Context(), newEmail)
+		if err == nil {
+			http.Error(w, "The email is already taken", http.StatusConflict)
+			return
+		}
+		if !errors.Is(err, data.ErrResourceNotFound) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	updatedUser, err := s.store.UpdateUser(r.Context(), &user)
+	if errors.Is(err, data.ErrConflict) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if newEmail != "" {
+		err := s.sendEmailChangeEmail(r.Context(), updatedUser, newEmail)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = json.NewEncoder(w).Encode(newUserResponse(updatedUser))
+	if err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
+
+// applyUpdateUserDTO validates and copies the provided fields onto the user,
+// except for a new email address, which it returns instead: the address only
+// changes once the user confirms it.
+func applyUpdateUserDTO(
+	user *models.User,
+	dto *models.UpdateUserDTO,
+) (string, error) {
+	newEmail := ""
+	if dto.Name != nil {
+		name := strings.TrimSpace(*dto.Name)
+		if name == "" {
+			return "", errors.New("the name cannot be empty")
+		}
+		user.Name = name
+	}
+	if dto.Email != nil {
+		email := data.NormalizeEmail(*dto.Email)
+		if !strings.Contains(email, "@") {
+			return "", fmt.Errorf("email %q is invalid", *dto.Email)
+		}
+		if email != data.NormalizeEmail(user.Email) {
+			newEmail = email
+		}
+	}
+	if dto.Timezone != nil {
+		if _, err := time.LoadLocation(*dto.Timezone); err != nil ||
+			*dto.Timezone == "" {
+			return "", fmt.Errorf("timezone %q is invalid", *dto.Timezone)
+		}
+		user.Timezone = *dto.Timezone
+	}
+	if dto.Locale != nil {
+		if !localePattern.MatchString(*dto.Locale) {
+			return "", fmt.Errorf("locale %q is invalid", *dto.Locale)
+		}
+		user.Locale = *dto.Locale
+	}
+	if dto.StartOfWeek != nil {
+		startOfWeek := strings.ToLower(*dto.StartOfWeek)
+		if !isWeekday(startOfWeek) {
+			return "", fmt.Errorf(
+				"start of week %q is invalid",
+				*dto.StartOfWeek,
+			)
+		}
+		user.StartOfWeek = startOfWeek
+	}
+	return newEmail, nil
+}
+
+func isWeekday(day string) bool {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.ToLower(weekday.String()) == day {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/mail"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandlePatchMe(t *testing.T) {
+	newVerifiedUser := func() models.User {
+		user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+		user.Verified = true
+		return user
+	}
+
+	t.Run("updates the profile and responds with a 200 OK", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		user := newVerifiedUser()
+		store.Users = []models.User{user}
+		mailer := mail.NewMemoryMailer()
+		server := NewServer(store, WithMailer(mailer))
+
+		name := "Claude"
+		timezone := "Asia/Manila"
+		locale := "en-PH"
+		startOfWeek := "Sunday"
+		response := sendPatchMe(t, server, user.Id, models.UpdateUserDTO{
+			Name:        &name,
+			Timezone:    &timezone,
+			Locale:      &locale,
+			StartOfWeek: &startOfWeek,
+		})
+
+		wantedUser := user
+		wantedUser.Name = name
+		wantedUser.Timezone = timezone
+		wantedUser.Locale = locale
+		wantedUser.StartOfWeek = "sunday"
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.Equals(t, getUserResponse(t, response.Body), newUserResponse(&wantedUser))
+		assert.Equals(t, store.Users[0], wantedUser)
+		assert.HasLength(t, mailer.Messages(), 0)
+	})
+
+	t.Run("keeps the email until the new address confirms the change", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		user := newVerifiedUser()
+		store.Users = []models.User{user}
+		mailer := mail.NewMemoryMailer()
+		server := NewServer(store, WithMailer(mailer))
+
+		email := "Claude@Email.com"
+		response := sendPatchMe(t, server, user.Id, models.UpdateUserDTO{
+			Email:           &email,
+			CurrentPassword: "password",
+		})
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.Equals(t, getUserResponse(t, response.Body), newUserResponse(&user))
+		assert.Equals(t, store.Users[0], user)
+		messages := mailer.Messages()
+		assert.HasLength(t, messages, 1)
+		assert.Equals(t, messages[0].To, "claude@email.com")
+
+		response = sendConfirmEmailChange(t, server, getMailedToken(t, mailer))
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.Equals(t, store.Users[0].Email, "claude@email.com")
+		assert.Equals(t, store.Users[0].Verified, true)
+	})
+
+	t.Run("responds with a 403 Forbidden when changing the email without the password", func(t *testing.T) {
+		for _, password := range []string{"", "wrong password"} {
+			store := testutils.NewMockStore(false)
+			user := newVerifiedUser()
+			store.Users = []models.User{user}
+			mailer := mail.NewMemoryMailer()
+			server := NewServer(store, WithMailer(mailer))
+
+			email := "claude@email.com"
+			response := sendPatchMe(t, server, user.Id, models.UpdateUserDTO{
+				Email:           &email,
+				CurrentPassword: password,
+			})
+
+			assert.Status(t, response.Code, http.StatusForbidden)
+			assert.Calls(t, store.UpdateUserCalls, 0)
+			assert.HasLength(t, store.UserTokens, 0)
+			assert.HasLength(t, mailer.Messages(), 0)
+		}
+	})
+
+	t.Run("responds with a 403 Forbidden given an API token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		user := newVerifiedUser()
+		store.Users = []models.User{user}
+		server := NewServer(store)
+		token := createTestApiToken(t, server, user.Id, models.ApiTokenScopes...)
+
+		response := sendWithApiToken(server, http.MethodPatch, "/me", token)
+
+		assert.Status(t, response.Code, http.StatusForbidden)
+		assert.Calls(t, store.UpdateUserCalls, 0)
+	})
+
+	t.Run("keeps the user verified when the email only changes case", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		user := newVerifiedUser()
+		store.Users = []models.User{user}
+		mailer := mail.NewMemoryMailer()
+		server := NewServer(store, WithMailer(mailer))
+
+		email := "Claude.Aldric@Email.com"
+		response := sendPatchMe(t, server, user.Id, models.UpdateUserDTO{Email: &email})
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.Equals(t, store.Users[0].Verified, true)
+		assert.HasLength(t, mailer.Messages(), 0)
+	})
+
+	t.Run("responds with a 409 Conflict when the email is taken", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		user := newVerifiedUser()
+		otherUser := *models.NewUser(2, "John Doe", "john.doe@email.com", "password")
+		store.Users = []models.User{user, otherUser}
+		server := NewServer(store)
+
+		email := otherUser.Email
+		response := sendPatchMe(t, server, user.Id, models.UpdateUserDTO{
+			Email:           &email,
+			CurrentPassword: "password",
+		})
+
+		assert.Status(t, response.Code, http.StatusConflict)
+		assert.Equals(t, store.Users[0], user)
+		assert.HasLength(t, store.UserTokens, 0)
+	})
+
+	t.Run("responds with a 400 Bad Request given invalid fields", func(t *testing.T) {
+		empty := ""
+		invalidEmail := "not-an-email"
+		invalidTimezone := "Mars/Olympus_Mons"
+		invalidLocale := "english!"
+		invalidStartOfWeek := "someday"
+		tests := []struct {
+			name string
+			dto  models.UpdateUserDTO
+		}{
+			{"empty name", models.UpdateUserDTO{Name: &empty}},
+			{"invalid email", models.UpdateUserDTO{Email: &invalidEmail}},
+			{"empty timezone", models.UpdateUserDTO{Timezone: &empty}},
+			{"invalid timezone", models.UpdateUserDTO{Timezone: &invalidTimezone}},
+			{"invalid locale", models.UpdateUserDTO{Locale: &invalidLocale}},
+			{"invalid start of week", models.UpdateUserDTO{StartOfWeek: &invalidStartOfWeek}},
+		}
+
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				store := testutils.NewMockStore(false)
+				user := newVerifiedUser()
+				store.Users = []models.User{user}
+				server := NewServer(store)
+
+				response := sendPatchMe(t, server, user.Id, test.dto)
+
+				assert.Status(t, response.Code, http.StatusBadRequest)
+				assert.Calls(t, store.UpdateUserCalls, 0)
+			})
+		}
+	})
+
+	t.Run("responds with a 400 Bad Request given an invalid body", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		user := newVerifiedUser()
+		store.Users = []models.User{user}
+		server := NewServer(store)
+
+		request := httptest.NewRequest(
+			http.MethodPatch,
+			"/me",
+			bytes.NewBuffer([]byte(`{`)),
+		)
+		authenticateRequest(t, server, request, user.Id)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.Calls(t, store.UpdateUserCalls, 0)
+	})
+}
+
+func sendPatchMe(
+	t *testing.T,
+	server *Server,
+	userId int,
+	dto models.UpdateUserDTO,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	jsonData, err := json.Marshal(dto)
+	assert.HasNoError(t, err)
+	request := httptest.NewRequest(
+		http.MethodPatch,
+		"/me",
+		bytes.NewBuffer(jsonData),
+	)
+	authenticateRequest(t, server, request, userId)
+	response := httptest.NewRecorder()
+	server.Handler.ServeHTTP(response, request)
+	return response
+}
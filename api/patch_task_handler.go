@@ -3,9 +3,7 @@ package api
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"net/http"
-	"strconv"
 
 	"github.com/claudealdric/go-todolist-restful-api-server/data"
 	"github.com/claudealdric/go-todolist-restful-api-server/models"
@@ -13,25 +11,29 @@ import (
 
 func (s *Server) HandlePatchTask(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("content-type", jsonContentType)
-	id, err := strconv.Atoi(r.PathValue("id"))
+	var dto models.UpdateTaskDTO
+	err := json.NewDecoder(r.Body).Decode(&dto)
 	if err != nil {
-		http.Error(
-			w,
-			fmt.Sprintf("ID: %q is invalid", r.PathValue("id")),
-			http.StatusBadRequest,
-		)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	var task models.Task
-	err = json.NewDecoder(r.Body).Decode(&task)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	task, ok := s.getPathTask(w, r, models.ProjectRoleEditor)
+	if !ok {
 		return
 	}
-	task.Id = id
+	if dto.Title != nil {
+		task.Title = *dto.Title
+	}
+	if dto.AssigneeId != nil {
+		if !s.checkTaskAssignee(r.Context(), w, task.ProjectId, *dto.AssigneeId) {
+			return
+		}
+		task.AssigneeId = *dto.AssigneeId
+	}
 
-	updatedTask, err := s.store.UpdateTask(&task)
+	ctx := data.WithEditor(r.Context(), getAuthenticatedUser(r).Id)
+	updatedTask, err := s.store.UpdateTask(ctx, task)
 	if errors.Is(err, data.ErrResourceNotFound) {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
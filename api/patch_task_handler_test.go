@@ -14,24 +14,29 @@ import (
 )
 
 func TestHandlePatchTask(t *testing.T) {
+	user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+	editor := models.ProjectMember{
+		ProjectId: 1,
+		UserId:    user.Id,
+		Role:      models.ProjectRoleEditor,
+	}
+
 	t.Run("returns the updated task and responds with a 200 OK status", func(t *testing.T) {
 		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		data.ProjectMembers = []models.ProjectMember{editor}
 		server := NewServer(data)
 
 		task := data.Tasks[0]
 
 		newTitle := "Pack bags"
-		dto := models.UpdateTaskDTO{Title: &newTitle}
-		jsonData, err := json.Marshal(dto)
-		assert.HasNoError(t, err)
-
-		request := httptest.NewRequest(
-			http.MethodPatch,
-			fmt.Sprintf("/tasks/%d", task.Id),
-			bytes.NewBuffer(jsonData),
+		response := sendPatchTask(
+			t,
+			server,
+			user.Id,
+			fmt.Sprint(task.Id),
+			models.UpdateTaskDTO{Title: &newTitle},
 		)
-		response := httptest.NewRecorder()
-		server.Handler.ServeHTTP(response, request)
 
 		assert.ContentType(
 			t,
@@ -43,27 +48,88 @@ func TestHandlePatchTask(t *testing.T) {
 		assert.Equals(
 			t,
 			*testutils.GetTaskFromResponse(t, response.Body),
-			models.Task{Id: task.Id, Title: newTitle},
+			models.Task{Id: task.Id, Title: newTitle, ProjectId: task.ProjectId},
+		)
+	})
+
+	t.Run("reassigns the task to another member and keeps its title", func(t *testing.T) {
+		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		data.ProjectMembers = []models.ProjectMember{
+			editor,
+			{ProjectId: 1, UserId: 2, Role: models.ProjectRoleViewer},
+		}
+		data.Tasks[0].AssigneeId = user.Id
+		server := NewServer(data)
+
+		task := data.Tasks[0]
+		assigneeId := 2
+		response := sendPatchTask(
+			t,
+			server,
+			user.Id,
+			fmt.Sprint(task.Id),
+			models.UpdateTaskDTO{AssigneeId: &assigneeId},
+		)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		task.AssigneeId = assigneeId
+		assert.Equals(t, *testutils.GetTaskFromResponse(t, response.Body), task)
+	})
+
+	t.Run("unassigns the task given an assigneeId of 0", func(t *testing.T) {
+		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		data.ProjectMembers = []models.ProjectMember{editor}
+		data.Tasks[0].AssigneeId = user.Id
+		server := NewServer(data)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPatch,
+			fmt.Sprintf("/tasks/%d", data.Tasks[0].Id),
+			user.Id,
+			`{"assigneeId": 0}`,
 		)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.Equals(t, data.Tasks[0].AssigneeId, 0)
+	})
+
+	t.Run("responds with a 400 Bad Request when the assignee is not a member", func(t *testing.T) {
+		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		data.ProjectMembers = []models.ProjectMember{editor}
+		server := NewServer(data)
+
+		assigneeId := 2
+		response := sendPatchTask(
+			t,
+			server,
+			user.Id,
+			fmt.Sprint(data.Tasks[0].Id),
+			models.UpdateTaskDTO{AssigneeId: &assigneeId},
+		)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.Calls(t, data.UpdateTaskCalls, 0)
 	})
 
 	t.Run("responds with a 400 Bad Request with an invalid ID", func(t *testing.T) {
 		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
 		server := NewServer(data)
 
 		invalidId := "not-an-integer"
 		newTitle := "Pack bags"
-		dto := models.UpdateTaskDTO{Title: &newTitle}
-		jsonData, err := json.Marshal(dto)
-		assert.HasNoError(t, err)
-
-		request := httptest.NewRequest(
-			http.MethodPatch,
-			fmt.Sprintf("/tasks/%s", invalidId),
-			bytes.NewBuffer(jsonData),
+		response := sendPatchTask(
+			t,
+			server,
+			user.Id,
+			invalidId,
+			models.UpdateTaskDTO{Title: &newTitle},
 		)
-		response := httptest.NewRecorder()
-		server.Handler.ServeHTTP(response, request)
 
 		assert.Status(t, response.Code, http.StatusBadRequest)
 		assert.Calls(t, data.UpdateTaskCalls, 0)
@@ -71,46 +137,69 @@ func TestHandlePatchTask(t *testing.T) {
 
 	t.Run("responds with a 404 Not Found when the task cannot be found", func(t *testing.T) {
 		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		data.ProjectMembers = []models.ProjectMember{editor}
 		server := NewServer(data)
 
 		doesNotExistId := -1
 		newTitle := "Pack bags"
-		dto := models.UpdateTaskDTO{Title: &newTitle}
-		jsonData, err := json.Marshal(dto)
-		assert.HasNoError(t, err)
-
-		request := httptest.NewRequest(
-			http.MethodPatch,
-			fmt.Sprintf("/tasks/%d", doesNotExistId),
-			bytes.NewBuffer(jsonData),
+		response := sendPatchTask(
+			t,
+			server,
+			user.Id,
+			fmt.Sprint(doesNotExistId),
+			models.UpdateTaskDTO{Title: &newTitle},
 		)
-		response := httptest.NewRecorder()
-		server.Handler.ServeHTTP(response, request)
 
 		assert.Status(t, response.Code, http.StatusNotFound)
-		assert.Calls(t, data.UpdateTaskCalls, 1)
+		assert.Calls(t, data.UpdateTaskCalls, 0)
 	})
 
 	t.Run("responds with a 400 Bad Request when the body is invalid", func(t *testing.T) {
 		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		data.ProjectMembers = []models.ProjectMember{editor}
 		server := NewServer(data)
 
 		task := data.Tasks[0]
 
-		request := httptest.NewRequest(
+		response := sendAuthenticatedRequest(
+			t,
+			server,
 			http.MethodPatch,
 			fmt.Sprintf("/tasks/%d", task.Id),
-			bytes.NewBuffer([]byte(`{`)),
+			user.Id,
+			`{`,
 		)
-		response := httptest.NewRecorder()
-		server.Handler.ServeHTTP(response, request)
 
 		assert.Status(t, response.Code, http.StatusBadRequest)
 		assert.Calls(t, data.UpdateTaskCalls, 0)
 	})
 
+	t.Run("responds with a 403 Forbidden to viewers of the project", func(t *testing.T) {
+		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		viewer := editor
+		viewer.Role = models.ProjectRoleViewer
+		data.ProjectMembers = []models.ProjectMember{viewer}
+		server := NewServer(data)
+
+		newTitle := "Pack bags"
+		response := sendPatchTask(
+			t,
+			server,
+			user.Id,
+			fmt.Sprint(data.Tasks[0].Id),
+			models.UpdateTaskDTO{Title: &newTitle},
+		)
+
+		assert.Status(t, response.Code, http.StatusForbidden)
+		assert.Calls(t, data.UpdateTaskCalls, 0)
+	})
+
 	t.Run("responds with a 500 error when an unknown store error occurs", func(t *testing.T) {
 		data := testutils.NewMockStore(true)
+		data.ProjectMembers = []models.ProjectMember{editor}
 		server := NewServer(data)
 
 		task := data.Tasks[0]
@@ -125,16 +214,19 @@ func TestHandlePatchTask(t *testing.T) {
 			fmt.Sprintf("/tasks/%d", task.Id),
 			bytes.NewBuffer(jsonData),
 		)
+		request.SetPathValue("id", fmt.Sprint(task.Id))
 		response := httptest.NewRecorder()
-		server.Handler.ServeHTTP(response, request)
+		server.HandlePatchTask(response, withAuthenticatedUser(request, &user))
 
 		assert.Status(t, response.Code, http.StatusInternalServerError)
-		assert.Calls(t, data.UpdateTaskCalls, 1)
+		assert.Calls(t, data.GetTaskByIdCalls, 1)
 	})
 
-	t.Run("providing the ID in the request body does not override the ID URL param", func(t *testing.T) {
+	t.Run("providing the ID or project in the request body does not move the task", func(t *testing.T) {
 		data := testutils.NewMockStore(false)
-		unmodifiedTask := models.Task{Id: 2, Title: "Exercise"}
+		data.Users = []models.User{user}
+		data.ProjectMembers = []models.ProjectMember{editor}
+		unmodifiedTask := models.Task{Id: 2, Title: "Exercise", ProjectId: 1}
 		data.Tasks = append(data.Tasks, unmodifiedTask)
 		unmodifiedTaskIndex := len(data.Tasks) - 1
 		server := NewServer(data)
@@ -142,16 +234,13 @@ func TestHandlePatchTask(t *testing.T) {
 		taskToUpdate := data.Tasks[0]
 
 		newTitle := "Pack bags"
-		jsonData, err := json.Marshal(models.Task{Id: 2, Title: newTitle})
-		assert.HasNoError(t, err)
-
-		request := httptest.NewRequest(
-			http.MethodPatch,
-			fmt.Sprintf("/tasks/%d", taskToUpdate.Id),
-			bytes.NewBuffer(jsonData),
+		response := sendPatchTask(
+			t,
+			server,
+			user.Id,
+			fmt.Sprint(taskToUpdate.Id),
+			models.Task{Id: 2, Title: newTitle, ProjectId: 2},
 		)
-		response := httptest.NewRecorder()
-		server.Handler.ServeHTTP(response, request)
 
 		assert.ContentType(
 			t,
@@ -163,9 +252,29 @@ func TestHandlePatchTask(t *testing.T) {
 		assert.Equals(
 			t,
 			*testutils.GetTaskFromResponse(t, response.Body),
-			models.Task{Id: taskToUpdate.Id, Title: newTitle},
+			models.Task{Id: taskToUpdate.Id, Title: newTitle, ProjectId: 1},
 		)
 
 		assert.Equals(t, data.Tasks[unmodifiedTaskIndex], unmodifiedTask)
 	})
 }
+
+func sendPatchTask(
+	t *testing.T,
+	server *Server,
+	userId int,
+	id string,
+	body any,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	jsonData, err := json.Marshal(body)
+	assert.HasNoError(t, err)
+	return sendAuthenticatedRequest(
+		t,
+		server,
+		http.MethodPatch,
+		"/tasks/"+id,
+		userId,
+		string(jsonData),
+	)
+}
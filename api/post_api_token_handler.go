@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/utils"
+)
+
+// ApiTokenResponse leaves out the token's hash; Token is only set in the
+// response to its creation since it cannot be recovered afterwards.
+type ApiTokenResponse struct {
+	Id         int        `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	Token      string     `json:"token,omitempty"`
+}
+
+func newApiTokenResponse(token *models.ApiToken, tokenString string) ApiTokenResponse {
+	return ApiTokenResponse{
+		Id:         token.Id,
+		Name:       token.Name,
+		Scopes:     token.Scopes,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		CreatedAt:  token.CreatedAt,
+		Token:      tokenString,
+	}
+}
+
+func (s *Server) HandlePostApiToken(w http.ResponseWriter, r *http.Request) {
+	var dto models.CreateApiTokenDTO
+	err := json.NewDecoder(r.Body).Decode(&dto)
+	if err != nil {
+		http.Error(w, "Error parsing the JSON body", http.StatusBadRequest)
+		return
+	}
+	if err := s.validateCreateApiTokenDTO(&dto); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tokenString, err := generateApiToken()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	token, err := s.store.CreateApiToken(r.Context(), &models.ApiToken{
+		UserId:    getAuthenticatedUser(r).Id,
+		Name:      strings.TrimSpace(dto.Name),
+		Hash:      utils.HashToken(tokenString),
+		Scopes:    dto.Scopes,
+		ExpiresAt: dto.ExpiresAt,
+		CreatedAt: s.now().UTC(),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(newApiTokenResponse(token, tokenString))
+	if err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
+
+func (s *Server) validateCreateApiTokenDTO(dto *models.CreateApiTokenDTO) error {
+	if strings.TrimSpace(dto.Name) == "" {
+		return errors.New("the name cannot be empty")
+	}
+	if len(dto.Scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range dto.Scopes {
+		if !slices.Contains(models.ApiTokenScopes, scope) {
+			return fmt.Errorf("scope %q is invalid", scope)
+		}
+	}
+	if dto.ExpiresAt != nil && !dto.ExpiresAt.After(s.now()) {
+		return errors.New("the expiry must be in the future")
+	}
+	return nil
+}
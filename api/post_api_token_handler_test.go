@@ -0,0 +1,128 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+	"github.com/claudealdric/go-todolist-restful-api-server/utils"
+)
+
+func TestHandlePostApiToken(t *testing.T) {
+	user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+
+	t.Run("creates the token and shows it once", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+		now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		server.now = func() time.Time { return now }
+		expiresAt := now.Add(30 * 24 * time.Hour)
+
+		response := sendPostApiToken(t, server, user.Id, models.CreateApiTokenDTO{
+			Name:      " CI ",
+			Scopes:    []string{models.ApiTokenScopeReadTasks},
+			ExpiresAt: &expiresAt,
+		})
+
+		assert.Status(t, response.Code, http.StatusCreated)
+		var body ApiTokenResponse
+		err := json.NewDecoder(response.Body).Decode(&body)
+		assert.HasNoError(t, err)
+		assert.Equals(t, strings.HasPrefix(body.Token, apiTokenPrefix), true)
+		assert.Equals(t, body.Name, "CI")
+		assert.Equals(t, body.Scopes, []string{models.ApiTokenScopeReadTasks})
+		assert.Equals(t, *body.ExpiresAt, expiresAt)
+		assert.Equals(t, body.CreatedAt, now)
+
+		assert.HasLength(t, store.ApiTokens, 1)
+		assert.Equals(t, store.ApiTokens[0].UserId, user.Id)
+		assert.Equals(t, store.ApiTokens[0].Hash, utils.HashToken(body.Token))
+	})
+
+	t.Run("responds with a 400 Bad Request given an invalid token", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour)
+		tests := []struct {
+			name string
+			dto  models.CreateApiTokenDTO
+		}{
+			{"empty name", models.CreateApiTokenDTO{Name: " ", Scopes: []string{models.ApiTokenScopeReadTasks}}},
+			{"no scopes", models.CreateApiTokenDTO{Name: "CI"}},
+			{"unknown scope", models.CreateApiTokenDTO{Name: "CI", Scopes: []string{"admin"}}},
+			{"past expiry", models.CreateApiTokenDTO{Name: "CI", Scopes: []string{models.ApiTokenScopeReadTasks}, ExpiresAt: &past}},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				store := testutils.NewMockStore(false)
+				store.Users = []models.User{user}
+				server := NewServer(store)
+
+				response := sendPostApiToken(t, server, user.Id, test.dto)
+
+				assert.Status(t, response.Code, http.StatusBadRequest)
+				assert.HasLength(t, store.ApiTokens, 0)
+			})
+		}
+	})
+
+	t.Run("responds with a 403 Forbidden when using an API token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+		token := createTestApiToken(t, server, user.Id, models.ApiTokenScopes...)
+
+		jsonData, err := json.Marshal(models.CreateApiTokenDTO{
+			Name:   "Escalated",
+			Scopes: models.ApiTokenScopes,
+		})
+		assert.HasNoError(t, err)
+		request := httptest.NewRequest(http.MethodPost, "/me/tokens", bytes.NewBuffer(jsonData))
+		request.Header.Set("Authorization", "Bearer "+token)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusForbidden)
+		assert.HasLength(t, store.ApiTokens, 1)
+	})
+}
+
+func sendPostApiToken(
+	t *testing.T,
+	server *Server,
+	userId int,
+	dto models.CreateApiTokenDTO,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	jsonData, err := json.Marshal(dto)
+	assert.HasNoError(t, err)
+	request := httptest.NewRequest(http.MethodPost, "/me/tokens", bytes.NewBuffer(jsonData))
+	authenticateRequest(t, server, request, userId)
+	response := httptest.NewRecorder()
+	server.Handler.ServeHTTP(response, request)
+	return response
+}
+
+func createTestApiToken(
+	t *testing.T,
+	server *Server,
+	userId int,
+	scopes ...string,
+) string {
+	t.Helper()
+	response := sendPostApiToken(t, server, userId, models.CreateApiTokenDTO{
+		Name:   "Test",
+		Scopes: scopes,
+	})
+	assert.Status(t, response.Code, http.StatusCreated)
+	var body ApiTokenResponse
+	err := json.NewDecoder(response.Body).Decode(&body)
+	assert.HasNoError(t, err)
+	return body.Token
+}
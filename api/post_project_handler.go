@@ -0,0 +1,40 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+func (s *Server) HandlePostProject(w http.ResponseWriter, r *http.Request) {
+	var dto models.CreateProjectDTO
+	err := json.NewDecoder(r.Body).Decode(&dto)
+	if err != nil {
+		http.Error(w, "Error parsing the JSON body", http.StatusBadRequest)
+		return
+	}
+	dto.Name = strings.TrimSpace(dto.Name)
+	if dto.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+
+	project, err := s.store.CreateProject(r.Context(), &dto, getAuthenticatedUser(r).Id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(ProjectResponse{
+		Project: *project,
+		Role:    models.ProjectRoleOwner,
+	})
+	if err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandlePostProject(t *testing.T) {
+	user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+
+	t.Run("creates the project with the user as its owner", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPost,
+			"/projects",
+			user.Id,
+			`{"name":" Groceries "}`,
+		)
+
+		assert.Status(t, response.Code, http.StatusCreated)
+		var project ProjectResponse
+		err := json.NewDecoder(response.Body).Decode(&project)
+		assert.HasNoError(t, err)
+		assert.Equals(t, project.Name, "Groceries")
+		assert.Equals(t, project.Role, models.ProjectRoleOwner)
+		assert.Contains(t, store.ProjectMembers, models.ProjectMember{
+			ProjectId: project.Id,
+			UserId:    user.Id,
+			Role:      models.ProjectRoleOwner,
+		})
+	})
+
+	t.Run("responds with a 400 Bad Request without a name", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPost,
+			"/projects",
+			user.Id,
+			`{"name":"  "}`,
+		)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.HasLength(t, store.ProjectMembers, 0)
+	})
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+func (s *Server) HandlePostProjectInvitation(w http.ResponseWriter, r *http.Request) {
+	projectId, ok := getPathId(w, r, "id")
+	if !ok {
+		return
+	}
+	var dto models.CreateProjectInvitationDTO
+	err := json.NewDecoder(r.Body).Decode(&dto)
+	if err != nil {
+		http.Error(w, "Error parsing the JSON body", http.StatusBadRequest)
+		return
+	}
+	dto.Email = data.NormalizeEmail(dto.Email)
+	if dto.Email == "" {
+		http.Error(w, "email is required", http.StatusBadRequest)
+		return
+	}
+	if !isValidProjectRole(dto.Role) {
+		http.Error(w, fmt.Sprintf("Role %q is invalid", dto.Role), http.StatusBadRequest)
+		return
+	}
+	if _, ok := s.checkProjectAccess(w, r, projectId, models.ProjectRoleOwner); !ok {
+		return
+	}
+
+	invitee, err := s.store.GetUserByEmail(r.Context(), dto.Email)
+	if err == nil {
+		_, err = s.store.GetProjectMember(r.Context(), projectId, invitee.Id)
+		if err == nil {
+			http.Error(w, "The user already is a member of the project", http.StatusConflict)
+			return
+		}
+	}
+	if err != nil && !errors.Is(err, data.ErrResourceNotFound) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	project, err := s.store.GetProjectById(r.Context(), projectId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	inviter := getAuthenticatedUser(r)
+	invitation, err := s.store.CreateProjectInvitation(r.Context(), &models.ProjectInvitation{
+		ProjectId: projectId,
+		Email:     dto.Email,
+		Role:      dto.Role,
+		InvitedBy: inviter.Id,
+		CreatedAt: s.now().UTC(),
+	})
+	if errors.Is(err, data.ErrConflict) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := s.sendProjectInvitationEmail(invitation, project, inviter); err != nil {
+		log.Println("error sending the project invitation email:", err)
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(invitation); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
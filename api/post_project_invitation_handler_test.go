@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/mail"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandlePostProjectInvitation(t *testing.T) {
+	owner := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+	editor := *models.NewUser(2, "John Doe", "john.doe@email.com", "password")
+	members := []models.ProjectMember{
+		{ProjectId: 1, UserId: owner.Id, Role: models.ProjectRoleOwner},
+		{ProjectId: 1, UserId: editor.Id, Role: models.ProjectRoleEditor},
+	}
+
+	t.Run("invites the email and notifies it", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{owner, editor}
+		store.ProjectMembers = members
+		mailer := mail.NewMemoryMailer()
+		server := NewServer(store, WithMailer(mailer))
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPost,
+			"/projects/1/invitations",
+			owner.Id,
+			`{"email":"Jane.Doe@email.com","role":"editor"}`,
+		)
+
+		assert.Status(t, response.Code, http.StatusCreated)
+		var invitation models.ProjectInvitation
+		err := json.NewDecoder(response.Body).Decode(&invitation)
+		assert.HasNoError(t, err)
+		assert.Equals(t, invitation.Email, "jane.doe@email.com")
+		assert.Equals(t, invitation.Role, models.ProjectRoleEditor)
+		assert.Equals(t, invitation.InvitedBy, owner.Id)
+		assert.HasLength(t, store.ProjectInvitations, 1)
+		messages := mailer.Messages()
+		assert.HasLength(t, messages, 1)
+		assert.Equals(t, messages[0].To, "jane.doe@email.com")
+	})
+
+	t.Run("responds with a 403 Forbidden to members who are not owners", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{owner, editor}
+		store.ProjectMembers = members
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPost,
+			"/projects/1/invitations",
+			editor.Id,
+			`{"email":"jane.doe@email.com","role":"editor"}`,
+		)
+
+		assert.Status(t, response.Code, http.StatusForbidden)
+		assert.HasLength(t, store.ProjectInvitations, 0)
+	})
+
+	t.Run("responds with a 409 Conflict when the user already is a member", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{owner, editor}
+		store.ProjectMembers = members
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPost,
+			"/projects/1/invitations",
+			owner.Id,
+			`{"email":"john.doe@email.com","role":"viewer"}`,
+		)
+
+		assert.Status(t, response.Code, http.StatusConflict)
+	})
+
+	t.Run("responds with a 409 Conflict when the email already is invited", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{owner, editor}
+		store.ProjectMembers = members
+		server := NewServer(store)
+
+		body := `{"email":"jane.doe@email.com","role":"viewer"}`
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPost,
+			"/projects/1/invitations",
+			owner.Id,
+			body,
+		)
+		assert.Status(t, response.Code, http.StatusCreated)
+		response = sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPost,
+			"/projects/1/invitations",
+			owner.Id,
+			body,
+		)
+
+		assert.Status(t, response.Code, http.StatusConflict)
+	})
+
+	t.Run("responds with a 400 Bad Request given an invalid role", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{owner, editor}
+		store.ProjectMembers = members
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPost,
+			"/projects/1/invitations",
+			owner.Id,
+			`{"email":"jane.doe@email.com","role":"admin"}`,
+		)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+	})
+}
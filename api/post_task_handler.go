@@ -16,7 +16,17 @@ func (s *Server) HandlePostTask(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	task, err := s.store.CreateTask(&dto)
+	if dto.ProjectId == 0 {
+		http.Error(w, "projectId is required", http.StatusBadRequest)
+		return
+	}
+	if _, ok := s.checkProjectAccess(w, r, dto.ProjectId, models.ProjectRoleEditor); !ok {
+		return
+	}
+	if !s.checkTaskAssignee(r.Context(), w, dto.ProjectId, dto.AssigneeId) {
+		return
+	}
+	task, err := s.store.CreateTask(r.Context(), &dto)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -13,20 +13,21 @@ import (
 )
 
 func TestHandlePostTask(t *testing.T) {
+	user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+	editor := models.ProjectMember{
+		ProjectId: 1,
+		UserId:    user.Id,
+		Role:      models.ProjectRoleEditor,
+	}
+
 	t.Run("creates and returns the task with a 201 Status Created", func(t *testing.T) {
 		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		data.ProjectMembers = []models.ProjectMember{editor}
 		server := NewServer(data)
 
-		newTask := models.NewTask(2, "Exercise")
-		jsonData, err := json.Marshal(newTask)
-		assert.HasNoError(t, err)
-		request := httptest.NewRequest(
-			http.MethodPost,
-			"/tasks",
-			bytes.NewBuffer(jsonData),
-		)
-		response := httptest.NewRecorder()
-		server.Handler.ServeHTTP(response, request)
+		newTask := models.NewTask(2, "Exercise", 1)
+		response := sendPostTask(t, server, user.Id, newTask)
 
 		assert.ContentType(
 			t,
@@ -44,27 +45,97 @@ func TestHandlePostTask(t *testing.T) {
 
 	t.Run("responds with a 400 Bad Request given an invalid body", func(t *testing.T) {
 		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
 		server := NewServer(data)
 
 		invalidJson := `{`
-		request := httptest.NewRequest(
+		response := sendAuthenticatedRequest(
+			t,
+			server,
 			http.MethodPost,
 			"/tasks",
-			bytes.NewBuffer([]byte(invalidJson)),
+			user.Id,
+			invalidJson,
 		)
-		response := httptest.NewRecorder()
-		server.Handler.ServeHTTP(response, request)
 
 		assert.Status(t, response.Code, http.StatusBadRequest)
 		assert.Calls(t, data.CreateTaskCalls, 0)
 	})
 
+	t.Run("assigns the task to a member of the project", func(t *testing.T) {
+		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		data.ProjectMembers = []models.ProjectMember{editor}
+		server := NewServer(data)
+
+		dto := models.NewCreateTaskDTO("Exercise", 1)
+		dto.AssigneeId = user.Id
+		response := sendPostTask(t, server, user.Id, dto)
+
+		assert.Status(t, response.Code, http.StatusCreated)
+		assert.Equals(
+			t,
+			testutils.GetTaskFromResponse(t, response.Body).AssigneeId,
+			user.Id,
+		)
+	})
+
+	t.Run("responds with a 400 Bad Request when the assignee is not a member", func(t *testing.T) {
+		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		data.ProjectMembers = []models.ProjectMember{editor}
+		server := NewServer(data)
+
+		dto := models.NewCreateTaskDTO("Exercise", 1)
+		dto.AssigneeId = 2
+		response := sendPostTask(t, server, user.Id, dto)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.Calls(t, data.CreateTaskCalls, 0)
+	})
+
+	t.Run("responds with a 400 Bad Request without a project", func(t *testing.T) {
+		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		server := NewServer(data)
+
+		response := sendPostTask(t, server, user.Id, models.NewCreateTaskDTO("Exercise", 0))
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.Calls(t, data.CreateTaskCalls, 0)
+	})
+
+	t.Run("responds with a 403 Forbidden to viewers of the project", func(t *testing.T) {
+		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		viewer := editor
+		viewer.Role = models.ProjectRoleViewer
+		data.ProjectMembers = []models.ProjectMember{viewer}
+		server := NewServer(data)
+
+		response := sendPostTask(t, server, user.Id, models.NewCreateTaskDTO("Exercise", 1))
+
+		assert.Status(t, response.Code, http.StatusForbidden)
+		assert.Calls(t, data.CreateTaskCalls, 0)
+	})
+
+	t.Run("responds with a 404 Not Found for a project the user is not a member of", func(t *testing.T) {
+		data := testutils.NewMockStore(false)
+		data.Users = []models.User{user}
+		server := NewServer(data)
+
+		response := sendPostTask(t, server, user.Id, models.NewCreateTaskDTO("Exercise", 1))
+
+		assert.Status(t, response.Code, http.StatusNotFound)
+		assert.Calls(t, data.CreateTaskCalls, 0)
+	})
+
 	t.Run("responds with a 500 error when the store task creation fails", func(t *testing.T) {
 		data := testutils.NewMockStore(true)
+		data.ProjectMembers = []models.ProjectMember{editor}
 		server := NewServer(data)
 
-		newTask := models.NewTask(2, "Exercise")
-		jsonData, err := json.Marshal(newTask)
+		jsonData, err := json.Marshal(models.NewCreateTaskDTO("Exercise", 1))
 		assert.HasNoError(t, err)
 		request := httptest.NewRequest(
 			http.MethodPost,
@@ -72,9 +143,28 @@ func TestHandlePostTask(t *testing.T) {
 			bytes.NewBuffer(jsonData),
 		)
 		response := httptest.NewRecorder()
-		server.Handler.ServeHTTP(response, request)
+		server.HandlePostTask(response, withAuthenticatedUser(request, &user))
 
 		assert.Status(t, response.Code, http.StatusInternalServerError)
 		assert.Calls(t, data.CreateTaskCalls, 1)
 	})
 }
+
+func sendPostTask(
+	t *testing.T,
+	server *Server,
+	userId int,
+	body any,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	jsonData, err := json.Marshal(body)
+	assert.HasNoError(t, err)
+	return sendAuthenticatedRequest(
+		t,
+		server,
+		http.MethodPost,
+		"/tasks",
+		userId,
+		string(jsonData),
+	)
+}
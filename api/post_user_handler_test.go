@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/claudealdric/go-todolist-restful-api-server/mail"
 	"github.com/claudealdric/go-todolist-restful-api-server/models"
 	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
 	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
@@ -59,6 +60,31 @@ func TestHandlePostUser(t *testing.T) {
 		assert.Equals(t, gotUser, wantedUser)
 	})
 
+	t.Run("creates the user unverified and emails a verification token", func(t *testing.T) {
+		data := testutils.NewMockStore(false)
+		mailer := mail.NewMemoryMailer()
+		server := NewServer(data, WithMailer(mailer))
+
+		response := sendPostUserRequest(t, server, models.NewCreateUserDTO(
+			"Claude Aldric",
+			"claude.aldric@email.com",
+			"password",
+		))
+		user := testutils.GetUserFromResponse(t, response.Body)
+
+		assert.Status(t, response.Code, http.StatusCreated)
+		assert.Equals(t, user.Verified, false)
+		messages := mailer.Messages()
+		assert.HasLength(t, messages, 1)
+		assert.Equals(t, messages[0].To, user.Email)
+		assert.HasLength(t, data.UserTokens, 1)
+		assert.Equals(
+			t,
+			data.UserTokens[0].Purpose,
+			models.UserTokenPurposeEmailVerification,
+		)
+	})
+
 	t.Run("responds with a 400 Bad Request given an invalid body", func(t *testing.T) {
 		data := testutils.NewMockStore(false)
 		server := NewServer(data)
@@ -76,6 +102,33 @@ func TestHandlePostUser(t *testing.T) {
 		assert.Calls(t, data.CreateUserCalls, 0)
 	})
 
+	t.Run("responds with a 409 Conflict when the email is already taken", func(t *testing.T) {
+		data := testutils.NewMockStore(false)
+		data.Users = []models.User{
+			*models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password"),
+		}
+		server := NewServer(data)
+
+		dto := models.CreateUserDTO{
+			Name:     "Someone Else",
+			Email:    " Claude.Aldric@Email.com ",
+			Password: "password",
+		}
+		jsonData, err := json.Marshal(dto)
+		assert.HasNoError(t, err)
+		request := httptest.NewRequest(
+			http.MethodPost,
+			"/users",
+			bytes.NewBuffer(jsonData),
+		)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusConflict)
+		assert.Calls(t, data.CreateUserCalls, 1)
+		assert.HasLength(t, data.Users, 1)
+	})
+
 	t.Run("responds with a 500 error when the store user creation fails", func(t *testing.T) {
 		data := testutils.NewMockStore(true)
 		server := NewServer(data)
@@ -99,3 +152,21 @@ func TestHandlePostUser(t *testing.T) {
 		assert.Calls(t, data.CreateUserCalls, 1)
 	})
 }
+
+func sendPostUserRequest(
+	t *testing.T,
+	server *Server,
+	dto *models.CreateUserDTO,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	jsonData, err := json.Marshal(dto)
+	assert.HasNoError(t, err)
+	request := httptest.NewRequest(
+		http.MethodPost,
+		"/users",
+		bytes.NewBuffer(jsonData),
+	)
+	response := httptest.NewRecorder()
+	server.Handler.ServeHTTP(response, request)
+	return response
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/mail"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+var projectRoleRanks = map[string]int{
+	models.ProjectRoleViewer: 1,
+	models.ProjectRoleEditor: 2,
+	models.ProjectRoleOwner:  3,
+}
+
+func isValidProjectRole(role string) bool {
+	_, ok := projectRoleRanks[role]
+	return ok
+}
+
+// checkProjectAccess returns the caller's membership of the project, writing
+// the error response itself when it returns false. Projects the caller is not
+// a member of are reported as not found so that they are not disclosed.
+func (s *Server) checkProjectAccess(
+	w http.ResponseWriter,
+	r *http.Request,
+	projectId int,
+	role string,
+) (*models.ProjectMember, bool) {
+	member, err := s.store.GetProjectMember(r.Context(), projectId, getAuthenticatedUser(r).Id)
+	if errors.Is(err, data.ErrResourceNotFound) {
+		http.Error(w, data.ErrResourceNotFound.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if projectRoleRanks[member.Role] < projectRoleRanks[role] {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+	return member, true
+}
+
+// checkTaskAssignee makes sure tasks are only assigned to members of their
+// project, writing the error response itself when it returns false.
+func (s *Server) checkTaskAssignee(
+	ctx context.Context,
+	w http.ResponseWriter,
+	projectId int,
+	assigneeId int,
+) bool {
+	if assigneeId == 0 {
+		return true
+	}
+	_, err := s.store.GetProjectMember(ctx, projectId, assigneeId)
+	if errors.Is(err, data.ErrResourceNotFound) {
+		http.Error(
+			w,
+			"assigneeId must be a member of the project",
+			http.StatusBadRequest,
+		)
+		return false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+// getPathId parses the named path value, writing the error response itself
+// when it returns false.
+func getPathId(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue(name))
+	if err != nil {
+		http.Error(
+			w,
+			fmt.Sprintf("ID: %q is invalid", r.PathValue(name)),
+			http.StatusBadRequest,
+		)
+		return 0, false
+	}
+	return id, true
+}
+
+// isLastProjectOwner reports whether the member is the only owner left, who
+// can neither leave nor be demoted since nobody could manage the project.
+func (s *Server) isLastProjectOwner(
+	ctx context.Context,
+	member *models.ProjectMember,
+) (bool, error) {
+	if member.Role != models.ProjectRoleOwner {
+		return false, nil
+	}
+	members, err := s.store.GetProjectMembers(ctx, member.ProjectId)
+	if err != nil {
+		return false, err
+	}
+	owners := 0
+	for _, m := range members {
+		if m.Role == models.ProjectRoleOwner {
+			owners++
+		}
+	}
+	return owners == 1, nil
+}
+
+func (s *Server) sendProjectInvitationEmail(
+	invitation *models.ProjectInvitation,
+	project *models.Project,
+	inviter *models.User,
+) error {
+	return s.mailer.Send(mail.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("%s shared %q with you", inviter.Name, project.Name),
+		Body: fmt.Sprintf(
+			"Hi,\n\n%s invited you to %q as %s. Sign in or sign up with this email to accept the invitation.\n",
+			inviter.Name,
+			project.Name,
+			invitation.Role,
+		),
+	})
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+type SetProjectMemberRoleRequest struct {
+	Role string `json:"role"`
+}
+
+func (s *Server) HandlePutProjectMember(w http.ResponseWriter, r *http.Request) {
+	projectId, ok := getPathId(w, r, "id")
+	if !ok {
+		return
+	}
+	userId, ok := getPathId(w, r, "userId")
+	if !ok {
+		return
+	}
+	var body SetProjectMemberRoleRequest
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		http.Error(w, "Error parsing the JSON body", http.StatusBadRequest)
+		return
+	}
+	if !isValidProjectRole(body.Role) {
+		http.Error(w, fmt.Sprintf("Role %q is invalid", body.Role), http.StatusBadRequest)
+		return
+	}
+	if _, ok := s.checkProjectAccess(w, r, projectId, models.ProjectRoleOwner); !ok {
+		return
+	}
+
+	member, err := s.store.GetProjectMember(r.Context(), projectId, userId)
+	if errors.Is(err, data.ErrResourceNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if body.Role != models.ProjectRoleOwner {
+		isLastOwner, err := s.isLastProjectOwner(r.Context(), member)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if isLastOwner {
+			http.Error(w, "A project needs at least one owner", http.StatusConflict)
+			return
+		}
+	}
+
+	member.Role = body.Role
+	if err := s.store.SaveProjectMember(r.Context(), member); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	if err := json.NewEncoder(w).Encode(member); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandlePutProjectMember(t *testing.T) {
+	owner := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+	viewer := *models.NewUser(2, "John Doe", "john.doe@email.com", "password")
+	members := []models.ProjectMember{
+		{ProjectId: 1, UserId: owner.Id, Role: models.ProjectRoleOwner},
+		{ProjectId: 1, UserId: viewer.Id, Role: models.ProjectRoleViewer},
+	}
+
+	t.Run("changes the role of the member", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{owner, viewer}
+		store.ProjectMembers = members
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPut,
+			"/projects/1/members/2",
+			owner.Id,
+			`{"role":"editor"}`,
+		)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.Equals(t, store.ProjectMembers[1].Role, models.ProjectRoleEditor)
+	})
+
+	t.Run("responds with a 403 Forbidden to members who are not owners", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{owner, viewer}
+		store.ProjectMembers = members
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPut,
+			"/projects/1/members/2",
+			viewer.Id,
+			`{"role":"owner"}`,
+		)
+
+		assert.Status(t, response.Code, http.StatusForbidden)
+	})
+
+	t.Run("responds with a 409 Conflict when demoting the last owner", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{owner, viewer}
+		store.ProjectMembers = members
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPut,
+			"/projects/1/members/1",
+			owner.Id,
+			`{"role":"editor"}`,
+		)
+
+		assert.Status(t, response.Code, http.StatusConflict)
+		assert.Equals(t, store.ProjectMembers[0].Role, models.ProjectRoleOwner)
+	})
+
+	t.Run("responds with a 404 Not Found for users outside the project", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{owner, viewer}
+		store.ProjectMembers = members
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPut,
+			"/projects/1/members/3",
+			owner.Id,
+			`{"role":"editor"}`,
+		)
+
+		assert.Status(t, response.Code, http.StatusNotFound)
+	})
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+func (s *Server) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	var body ResetPasswordRequest
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		http.Error(w, "Error parsing the JSON body", http.StatusBadRequest)
+		return
+	}
+	if body.Password == "" {
+		http.Error(w, "The password cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	userToken, err := s.consumeUserToken(
+		r.Context(),
+		body.Token,
+		models.UserTokenPurposePasswordReset,
+	)
+	if errors.Is(err, errInvalidUserToken) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = s.store.UpdateUserPassword(r.Context(), userToken.UserId, body.Password)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = s.store.DeleteUserTokens(
+		r.Context(),
+		userToken.UserId,
+		models.UserTokenPurposePasswordReset,
+	)
+	if err != nil {
+		log.Println("error deleting the remaining password reset tokens:", err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/mail"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHandleResetPassword(t *testing.T) {
+	t.Run("updates the password and responds with 204 No Content", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{
+			*models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password"),
+		}
+		mailer := mail.NewMemoryMailer()
+		server := NewServer(store, WithMailer(mailer))
+		sendForgotPassword(t, server, "claude.aldric@email.com")
+		token := getMailedToken(t, mailer)
+
+		response := sendResetPassword(t, server, token, "new password")
+
+		assert.Status(t, response.Code, http.StatusNoContent)
+		assert.HasNoError(t, bcrypt.CompareHashAndPassword(
+			[]byte(store.Users[0].Password),
+			[]byte("new password"),
+		))
+		assert.HasLength(t, store.UserTokens, 0)
+	})
+
+	t.Run("rejects a token that was already used", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{
+			*models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password"),
+		}
+		mailer := mail.NewMemoryMailer()
+		server := NewServer(store, WithMailer(mailer))
+		sendForgotPassword(t, server, "claude.aldric@email.com")
+		token := getMailedToken(t, mailer)
+
+		sendResetPassword(t, server, token, "new password")
+		response := sendResetPassword(t, server, token, "another password")
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.HasNoError(t, bcrypt.CompareHashAndPassword(
+			[]byte(store.Users[0].Password),
+			[]byte("new password"),
+		))
+	})
+
+	t.Run("rejects an expired token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{
+			*models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password"),
+		}
+		mailer := mail.NewMemoryMailer()
+		server := NewServer(store, WithMailer(mailer))
+		now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		server.now = func() time.Time { return now }
+		sendForgotPassword(t, server, "claude.aldric@email.com")
+		token := getMailedToken(t, mailer)
+
+		now = now.Add(passwordResetTokenTtl)
+		response := sendResetPassword(t, server, token, "new password")
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.Equals(t, store.Users[0].Password, "password")
+	})
+
+	t.Run("rejects an email verification token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		mailer := mail.NewMemoryMailer()
+		server := NewServer(store, WithMailer(mailer))
+		sendPostUserRequest(t, server, models.NewCreateUserDTO(
+			"Claude Aldric",
+			"claude.aldric@email.com",
+			"password",
+		))
+		token := getMailedToken(t, mailer)
+
+		response := sendResetPassword(t, server, token, "new password")
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+	})
+
+	t.Run("responds with a 400 Bad Request given an empty password", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		server := NewServer(store)
+
+		response := sendResetPassword(t, server, "token", "")
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+	})
+
+	t.Run("responds with a 400 Bad Request given an invalid body", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		server := NewServer(store)
+
+		request := httptest.NewRequest(
+			http.MethodPost,
+			"/password/reset",
+			bytes.NewBuffer([]byte(`{`)),
+		)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+	})
+}
+
+func sendResetPassword(
+	t *testing.T,
+	server *Server,
+	token, password string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	jsonData, err := json.Marshal(
+		ResetPasswordRequest{Token: token, Password: password},
+	)
+	assert.HasNoError(t, err)
+	request := httptest.NewRequest(
+		http.MethodPost,
+		"/password/reset",
+		bytes.NewBuffer(jsonData),
+	)
+	response := httptest.NewRecorder()
+	server.Handler.ServeHTTP(response, request)
+	return response
+}
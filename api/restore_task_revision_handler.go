@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+// HandleRestoreTaskRevision puts the title and assignee of a revision back.
+// The restore is an update like any other, so the version it replaces
+// becomes a revision too.
+func (s *Server) HandleRestoreTaskRevision(w http.ResponseWriter, r *http.Request) {
+	reviser, ok := s.store.(data.TaskReviser)
+	if !ok {
+		http.Error(w, data.ErrTaskRevisionsNotRecorded.Error(), http.StatusNotImplemented)
+		return
+	}
+	task, ok := s.getPathTask(w, r, models.ProjectRoleEditor)
+	if !ok {
+		return
+	}
+	revisionNumber, ok := getPathId(w, r, "rev")
+	if !ok {
+		return
+	}
+	revision, err := reviser.GetTaskRevision(r.Context(), task.Id, revisionNumber)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrTaskRevisionsNotRecorded):
+			http.Error(w, err.Error(), http.StatusNotImplemented)
+		case errors.Is(err, data.ErrResourceNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	// The assignee may have left the project since.
+	if !s.checkTaskAssignee(r.Context(), w, task.ProjectId, revision.AssigneeId) {
+		return
+	}
+	task.Title = revision.Title
+	task.AssigneeId = revision.AssigneeId
+
+	ctx := data.WithEditor(r.Context(), getAuthenticatedUser(r).Id)
+	updatedTask, err := s.store.UpdateTask(ctx, task)
+	if errors.Is(err, data.ErrResourceNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("content-type", jsonContentType)
+	if err := json.NewEncoder(w).Encode(updatedTask); err != nil {
+		log.Println("error encoding the restored task:", err)
+	}
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleRestoreTaskRevision(t *testing.T) {
+	user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+	editor := models.ProjectMember{
+		ProjectId: 1,
+		UserId:    user.Id,
+		Role:      models.ProjectRoleEditor,
+	}
+	revision := models.TaskRevision{
+		TaskId:        1,
+		Revision:      1,
+		Title:         "Pack shoes",
+		AssigneeId:    user.Id,
+		ChangedFields: []string{models.TaskFieldTitle, models.TaskFieldAssigneeId},
+	}
+
+	t.Run("puts the title and assignee of the revision back", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		store.ProjectMembers = []models.ProjectMember{editor}
+		store.TaskRevisions = []models.TaskRevision{revision}
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPost,
+			fmt.Sprintf("/tasks/%d/revisions/1/restore", revision.TaskId),
+			user.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.Calls(t, store.UpdateTaskCalls, 1)
+		want := models.Task{
+			Id:         revision.TaskId,
+			Title:      revision.Title,
+			ProjectId:  1,
+			AssigneeId: revision.AssigneeId,
+		}
+		assert.Equals(t, *testutils.GetTaskFromResponse(t, response.Body), want)
+		assert.Equals(t, store.Tasks[0], want)
+	})
+
+	t.Run("responds with a 404 Not Found when the revision does not exist", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		store.ProjectMembers = []models.ProjectMember{editor}
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPost,
+			fmt.Sprintf("/tasks/%d/revisions/1/restore", revision.TaskId),
+			user.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusNotFound)
+		assert.Calls(t, store.UpdateTaskCalls, 0)
+	})
+
+	t.Run("responds with a 400 Bad Request when the assignee left the project", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		store.ProjectMembers = []models.ProjectMember{editor}
+		leftRevision := revision
+		leftRevision.AssigneeId = 2
+		store.TaskRevisions = []models.TaskRevision{leftRevision}
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPost,
+			fmt.Sprintf("/tasks/%d/revisions/1/restore", revision.TaskId),
+			user.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.Calls(t, store.UpdateTaskCalls, 0)
+	})
+
+	t.Run("responds with a 403 Forbidden to viewers", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		viewer := editor
+		viewer.Role = models.ProjectRoleViewer
+		store.ProjectMembers = []models.ProjectMember{viewer}
+		store.TaskRevisions = []models.TaskRevision{revision}
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPost,
+			fmt.Sprintf("/tasks/%d/revisions/1/restore", revision.TaskId),
+			user.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusForbidden)
+		assert.Calls(t, store.UpdateTaskCalls, 0)
+	})
+
+	t.Run("responds with a 501 Not Implemented when the store keeps no revisions", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		store.ProjectMembers = []models.ProjectMember{editor}
+		server := NewServer(struct{ data.Store }{store})
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPost,
+			fmt.Sprintf("/tasks/%d/revisions/1/restore", revision.TaskId),
+			user.Id,
+			"",
+		)
+
+		assert.Status(t, response.Code, http.StatusNotImplemented)
+		assert.Calls(t, store.UpdateTaskCalls, 0)
+	})
+}
@@ -3,6 +3,8 @@ package api
 import (
 	"fmt"
 	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
 )
 
 type Router struct {
@@ -13,14 +15,127 @@ func NewRouter(s *Server) *Router {
 	r := Router{}
 	r.Get("/{$}", s.HandleRoot)
 
-	r.Get("/tasks", s.HandleGetTasks)
-	r.Get("/tasks/{id}", s.HandleGetTaskById)
-	r.Patch("/tasks/{id}", s.HandlePatchTask)
-	r.Post("/tasks", s.HandlePostTask)
-	r.Delete("/tasks/{id}", s.HandleDeleteTask)
+	r.Get("/tasks", s.requireScope(models.ApiTokenScopeReadTasks, s.HandleGetTasks))
+	r.Get(
+		"/tasks/{id}",
+		s.requireScope(models.ApiTokenScopeReadTasks, s.HandleGetTaskById),
+	)
+	r.Get(
+		"/tasks/{id}/history",
+		s.requireScope(models.ApiTokenScopeReadTasks, s.HandleGetTaskHistory),
+	)
+	r.Get(
+		"/tasks/{id}/revisions",
+		s.requireScope(models.ApiTokenScopeReadTasks, s.HandleGetTaskRevisions),
+	)
+	r.Post(
+		"/tasks/{id}/revisions/{rev}/restore",
+		s.requireScope(models.ApiTokenScopeWriteTasks, s.HandleRestoreTaskRevision),
+	)
+	r.Patch(
+		"/tasks/{id}",
+		s.requireScope(models.ApiTokenScopeWriteTasks, s.HandlePatchTask),
+	)
+	r.Post("/tasks", s.requireScope(models.ApiTokenScopeWriteTasks, s.HandlePostTask))
+	r.Delete(
+		"/tasks/{id}",
+		s.requireScope(models.ApiTokenScopeWriteTasks, s.HandleDeleteTask),
+	)
+
+	r.Get(
+		"/projects",
+		s.requireScope(models.ApiTokenScopeReadTasks, s.HandleGetProjects),
+	)
+	r.Post(
+		"/projects",
+		s.requireScope(models.ApiTokenScopeWriteTasks, s.HandlePostProject),
+	)
+	r.Get(
+		"/projects/{id}/members",
+		s.requireScope(models.ApiTokenScopeReadTasks, s.HandleGetProjectMembers),
+	)
+	r.Put(
+		"/projects/{id}/members/{userId}",
+		s.requireSession(s.HandlePutProjectMember),
+	)
+	r.Delete(
+		"/projects/{id}/members/{userId}",
+		s.requireSession(s.HandleDeleteProjectMember),
+	)
+	r.Post(
+		"/projects/{id}/invitations",
+		s.requireSession(s.HandlePostProjectInvitation),
+	)
 
 	r.Post("/users", s.HandlePostUser)
+	r.Post("/users/verify", s.HandleVerifyUser)
+	r.Post("/users/email/confirm", s.HandleConfirmEmailChange)
 	r.Post("/login", s.HandleLogin)
+	r.Post("/login/mfa", s.HandleMfaLogin)
+	r.Post("/password/forgot", s.HandleForgotPassword)
+	r.Post("/password/reset", s.HandleResetPassword)
+	if s.oidcProvider != nil {
+		r.Get("/auth/oidc/start", s.HandleOidcStart)
+		r.Get("/auth/oidc/callback", s.HandleOidcCallback)
+	}
+
+	r.Get("/me", s.requireScope(models.ApiTokenScopeReadProfile, s.HandleGetMe))
+	r.Patch("/me", s.requireSession(s.HandlePatchMe))
+	r.Post("/me/password", s.requireSession(s.HandleChangePassword))
+	r.Delete("/me", s.requireSession(s.HandleDeleteMe))
+	r.Get("/me/tokens", s.requireSession(s.HandleGetApiTokens))
+	r.Post("/me/tokens", s.requireSession(s.HandlePostApiToken))
+	r.Delete("/me/tokens/{id}", s.requireSession(s.HandleDeleteApiToken))
+	r.Post("/me/mfa/totp", s.requireSession(s.HandleEnrollTotp))
+	r.Post("/me/mfa/totp/verify", s.requireSession(s.HandleVerifyTotp))
+	r.Delete("/me/mfa", s.requireSession(s.HandleDisableMfa))
+	r.Get("/me/invitations", s.requireSession(s.HandleGetInvitations))
+	r.Post(
+		"/me/invitations/{id}/accept",
+		s.requireSession(s.HandleAcceptInvitation),
+	)
+	r.Post(
+		"/me/invitations/{id}/decline",
+		s.requireSession(s.HandleDeclineInvitation),
+	)
+
+	r.Get("/admin/users", s.authorize(permissionReadUsers, s.HandleAdminGetUsers))
+	r.Post(
+		"/admin/users/{id}/disable",
+		s.authorize(permissionManageUsers, s.HandleAdminDisableUser),
+	)
+	r.Post(
+		"/admin/users/{id}/enable",
+		s.authorize(permissionManageUsers, s.HandleAdminEnableUser),
+	)
+	r.Put(
+		"/admin/users/{id}/role",
+		s.authorize(permissionManageUsers, s.HandleAdminSetUserRole),
+	)
+	r.Post(
+		"/admin/users/{id}/password-reset",
+		s.authorize(permissionManageUsers, s.HandleAdminResetUserPassword),
+	)
+	r.Post(
+		"/admin/users/{id}/impersonate",
+		s.authorize(permissionImpersonateUsers, s.HandleAdminImpersonateUser),
+	)
+	r.Get(
+		"/admin/audit-events",
+		s.authorize(permissionReadAuditEvents, s.HandleAdminGetAuditEvents),
+	)
+	if s.backups != nil {
+		r.Post(
+			"/admin/backups",
+			s.authorize(permissionManageBackups, s.HandleAdminPostBackup),
+		)
+	}
+	if _, ok := s.store.(cacheStatser); ok {
+		r.Get(
+			"/admin/cache",
+			s.authorize(permissionReadCacheStats, s.HandleAdminGetCacheStats),
+		)
+	}
 	return &r
 }
 
@@ -40,6 +155,10 @@ func (r *Router) Post(pattern string, handlerFunc http.HandlerFunc) {
 	r.getHandlerFuncPattern(http.MethodPost, pattern, handlerFunc)
 }
 
+func (r *Router) Put(pattern string, handlerFunc http.HandlerFunc) {
+	r.getHandlerFuncPattern(http.MethodPut, pattern, handlerFunc)
+}
+
 func (r *Router) getHandlerFuncPattern(
 	method, pattern string,
 	handlerFunc http.HandlerFunc,
@@ -1,18 +1,82 @@
 package api
 
 import (
+	"context"
 	"net/http"
+	"time"
 
+	"github.com/claudealdric/go-todolist-restful-api-server/backup"
 	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/mail"
+	"github.com/claudealdric/go-todolist-restful-api-server/oidc"
 )
 
 type Server struct {
-	store data.Store
+	store          data.Store
+	mailer         mail.Mailer
+	oidcProvider   *oidc.Provider
+	backups        Backuper
+	jwtKey         []byte
+	accessTokenTtl time.Duration
+	now            func() time.Time
 	http.Handler
 }
 
-func NewServer(store data.Store) *Server {
-	server := &Server{store: store}
+type ServerOption func(*Server)
+
+// Backuper takes snapshots of the database on demand.
+type Backuper interface {
+	Backup(ctx context.Context) (*backup.Snapshot, error)
+}
+
+func WithMailer(mailer mail.Mailer) ServerOption {
+	return func(s *Server) {
+		s.mailer = mailer
+	}
+}
+
+// WithOidcProvider enables signing in through the identity provider under
+// /auth/oidc.
+func WithOidcProvider(provider *oidc.Provider) ServerOption {
+	return func(s *Server) {
+		s.oidcProvider = provider
+	}
+}
+
+// WithBackups lets admins take snapshots of the database under
+// /admin/backups.
+func WithBackups(backups Backuper) ServerOption {
+	return func(s *Server) {
+		s.backups = backups
+	}
+}
+
+// WithJwtKey signs and verifies the tokens the server hands out with key.
+func WithJwtKey(key []byte) ServerOption {
+	return func(s *Server) {
+		s.jwtKey = key
+	}
+}
+
+// WithAccessTokenTtl sets how long access tokens from regular logins stay
+// valid.
+func WithAccessTokenTtl(ttl time.Duration) ServerOption {
+	return func(s *Server) {
+		s.accessTokenTtl = ttl
+	}
+}
+
+func NewServer(store data.Store, options ...ServerOption) *Server {
+	server := &Server{
+		store:          store,
+		mailer:         mail.NewMemoryMailer(),
+		jwtKey:         defaultJwtKey,
+		accessTokenTtl: defaultAccessTokenTtl,
+		now:            time.Now,
+	}
+	for _, option := range options {
+		option(server)
+	}
 	router := NewRouter(server)
 	server.Handler = router
 	return server
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/mail"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/utils"
+)
+
+const (
+	emailChangeTokenTtl       = 24 * time.Hour
+	emailVerificationTokenTtl = 24 * time.Hour
+	passwordResetTokenTtl     = time.Hour
+)
+
+var errInvalidUserToken = errors.New("invalid or expired token")
+
+// issueUserToken replaces any outstanding token of the same purpose for the
+// user and returns the new token; only its hash is stored.
+func (s *Server) issueUserToken(
+	ctx context.Context,
+	userId int,
+	purpose string,
+	ttl time.Duration,
+) (string, error) {
+	return s.issueEmailToken(ctx, userId, purpose, ttl, "")
+}
+
+// issueEmailToken is issueUserToken for a token that carries an email
+// address.
+func (s *Server) issueEmailToken(
+	ctx context.Context,
+	userId int,
+	purpose string,
+	ttl time.Duration,
+	email string,
+) (string, error) {
+	token, err := utils.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.store.DeleteUserTokens(ctx, userId, purpose); err != nil {
+		return "", err
+	}
+	userToken := models.NewUserToken(
+		utils.HashToken(token),
+		userId,
+		purpose,
+		s.now().Add(ttl),
+	)
+	userToken.Email = email
+	err = s.store.CreateUserToken(ctx, userToken)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *Server) consumeUserToken(
+	ctx context.Context,
+	token, purpose string,
+) (*models.UserToken, error) {
+	userToken, err := s.store.ConsumeUserToken(ctx, utils.HashToken(token), purpose)
+	if errors.Is(err, data.ErrResourceNotFound) {
+		return nil, errInvalidUserToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !s.now().Before(userToken.ExpiresAt) {
+		return nil, errInvalidUserToken
+	}
+	return userToken, nil
+}
+
+func (s *Server) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	token, err := s.issueUserToken(
+		ctx,
+		user.Id,
+		models.UserTokenPurposeEmailVerification,
+		emailVerificationTokenTtl,
+	)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse this token to verify your email: %s\n\nIt expires in %s.\n",
+			user.Name,
+			token,
+			emailVerificationTokenTtl,
+		),
+	})
+}
+
+func (s *Server) sendPasswordResetEmail(ctx context.Context, user *models.User) error {
+	token, err := s.issueUserToken(
+		ctx,
+		user.Id,
+		models.UserTokenPurposePasswordReset,
+		passwordResetTokenTtl,
+	)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse this token to reset your password: %s\n\nIt expires in %s. If you did not ask for a reset, you can ignore this email.\n",
+			user.Name,
+			token,
+			passwordResetTokenTtl,
+		),
+	})
+}
+
+// sendEmailChangeEmail mails the token that confirms the new address to that
+// address, so that only its owner can move the account there.
+func (s *Server) sendEmailChangeEmail(
+	ctx context.Context,
+	user *models.User,
+	newEmail string,
+) error {
+	token, err := s.issueEmailToken(
+		ctx,
+		user.Id,
+		models.UserTokenPurposeEmailChange,
+		emailChangeTokenTtl,
+		newEmail,
+	)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(mail.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse this token to confirm your new email: %s\n\nIt expires in %s. Until then, your account keeps its current email.\n",
+			user.Name,
+			token,
+			emailChangeTokenTtl,
+		),
+	})
+}
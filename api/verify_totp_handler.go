@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+)
+
+type VerifyTotpRequest struct {
+	Code string `json:"code"`
+}
+
+type VerifyTotpResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+func (s *Server) HandleVerifyTotp(w http.ResponseWriter, r *http.Request) {
+	var body VerifyTotpRequest
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		http.Error(w, "Error parsing the JSON body", http.StatusBadRequest)
+		return
+	}
+
+	user := getAuthenticatedUser(r)
+	mfa, err := s.store.GetUserMfa(r.Context(), user.Id)
+	if errors.Is(err, data.ErrResourceNotFound) {
+		http.Error(w, "No two-factor enrollment in progress", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if mfa.Enabled {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	ok, err := s.checkTotpCode(r.Context(), mfa, body.Code)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "Invalid code", http.StatusBadRequest)
+		return
+	}
+
+	recoveryCodes, err := s.issueRecoveryCodes(r.Context(), user.Id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	mfa.Enabled = true
+	if err := s.store.SaveUserMfa(r.Context(), mfa); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.recordMfaAction(r.Context(), user.Id, mfaEnabledAuditAction)
+
+	w.Header().Set("content-type", jsonContentType)
+	err = json.NewEncoder(w).Encode(VerifyTotpResponse{RecoveryCodes: recoveryCodes})
+	if err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+	"github.com/claudealdric/go-todolist-restful-api-server/totp"
+)
+
+func TestHandleVerifyTotp(t *testing.T) {
+	user := *models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password")
+
+	t.Run("enables MFA and returns hashed recovery codes", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+		now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		server.now = func() time.Time { return now }
+
+		_, recoveryCodes := enableTotp(t, server, user.Id)
+
+		mfa := store.UserMfa[user.Id]
+		assert.Equals(t, mfa.Enabled, true)
+		assert.Equals(t, mfa.LastUsedStep, totp.Step(now))
+		assert.HasLength(t, recoveryCodes, mfaRecoveryCodeCount)
+		assert.HasLength(t, store.UserTokens, mfaRecoveryCodeCount)
+		for i, code := range recoveryCodes {
+			assert.Equals(t, store.UserTokens[i].Hash, hashRecoveryCode(user.Id, code))
+			assert.Equals(t, store.UserTokens[i].Purpose, models.UserTokenPurposeMfaRecovery)
+		}
+		assert.HasLength(t, store.AuditEvents, 1)
+		assert.Equals(t, store.AuditEvents[0].Action, mfaEnabledAuditAction)
+	})
+
+	t.Run("responds with a 400 Bad Request given a wrong code", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+		sendAuthenticatedRequest(t, server, http.MethodPost, "/me/mfa/totp", user.Id, "")
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPost,
+			"/me/mfa/totp/verify",
+			user.Id,
+			`{"code":"000000"}`,
+		)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.Equals(t, store.UserMfa[user.Id].Enabled, false)
+		assert.HasLength(t, store.UserTokens, 0)
+	})
+
+	t.Run("responds with a 400 Bad Request without an enrollment", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		store.Users = []models.User{user}
+		server := NewServer(store)
+
+		response := sendAuthenticatedRequest(
+			t,
+			server,
+			http.MethodPost,
+			"/me/mfa/totp/verify",
+			user.Id,
+			`{"code":"000000"}`,
+		)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+	})
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+type VerifyUserRequest struct {
+	Token string `json:"token"`
+}
+
+func (s *Server) HandleVerifyUser(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", jsonContentType)
+	var body VerifyUserRequest
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		http.Error(w, "Error parsing the JSON body", http.StatusBadRequest)
+		return
+	}
+
+	userToken, err := s.consumeUserToken(
+		r.Context(),
+		body.Token,
+		models.UserTokenPurposeEmailVerification,
+	)
+	if errors.Is(err, errInvalidUserToken) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	user, err := s.store.GetUserById(r.Context(), userToken.UserId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	user.Verified = true
+	user, err = s.store.UpdateUser(r.Context(), user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(newUserResponse(user))
+	if err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/mail"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestHandleVerifyUser(t *testing.T) {
+	t.Run("verifies the user and returns it with a 200 OK", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		mailer := mail.NewMemoryMailer()
+		server := NewServer(store, WithMailer(mailer))
+		sendPostUserRequest(t, server, models.NewCreateUserDTO(
+			"Claude Aldric",
+			"claude.aldric@email.com",
+			"password",
+		))
+
+		response := sendVerifyUser(t, server, getMailedToken(t, mailer))
+
+		assert.Status(t, response.Code, http.StatusOK)
+		assert.ContentType(
+			t,
+			testutils.GetContentTypeFromResponse(response),
+			jsonContentType,
+		)
+		user := getUserResponse(t, response.Body)
+		assert.Equals(t, user.Verified, true)
+		assert.Equals(t, store.Users[0].Verified, true)
+		assert.HasLength(t, store.UserTokens, 0)
+	})
+
+	t.Run("rejects an expired token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		mailer := mail.NewMemoryMailer()
+		server := NewServer(store, WithMailer(mailer))
+		now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		server.now = func() time.Time { return now }
+		sendPostUserRequest(t, server, models.NewCreateUserDTO(
+			"Claude Aldric",
+			"claude.aldric@email.com",
+			"password",
+		))
+
+		now = now.Add(emailVerificationTokenTtl)
+		response := sendVerifyUser(t, server, getMailedToken(t, mailer))
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.Equals(t, store.Users[0].Verified, false)
+	})
+
+	t.Run("rejects an unknown token", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		server := NewServer(store)
+
+		response := sendVerifyUser(t, server, "does-not-exist")
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+		assert.Calls(t, store.UpdateUserCalls, 0)
+	})
+
+	t.Run("responds with a 400 Bad Request given an invalid body", func(t *testing.T) {
+		store := testutils.NewMockStore(false)
+		server := NewServer(store)
+
+		request := httptest.NewRequest(
+			http.MethodPost,
+			"/users/verify",
+			bytes.NewBuffer([]byte(`{`)),
+		)
+		response := httptest.NewRecorder()
+		server.Handler.ServeHTTP(response, request)
+
+		assert.Status(t, response.Code, http.StatusBadRequest)
+	})
+}
+
+func sendVerifyUser(
+	t *testing.T,
+	server *Server,
+	token string,
+) *httptest.ResponseRecorder {
+	t.Helper()
+	jsonData, err := json.Marshal(VerifyUserRequest{Token: token})
+	assert.HasNoError(t, err)
+	request := httptest.NewRequest(
+		http.MethodPost,
+		"/users/verify",
+		bytes.NewBuffer(jsonData),
+	)
+	response := httptest.NewRecorder()
+	server.Handler.ServeHTTP(response, request)
+	return response
+}
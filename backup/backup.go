@@ -0,0 +1,274 @@
+// Package backup takes consistent snapshots of the SQLite database while the
+// server runs, keeps the most recent ones and restores them.
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+)
+
+const (
+	snapshotPrefix = "snapshot-"
+	snapshotExt    = ".db"
+	timeFormat     = "20060102T150405.000Z"
+
+	// previousSuffix marks the database a restore replaced, so that the
+	// restore can be undone by hand.
+	previousSuffix = ".before-restore"
+)
+
+var ErrNoSnapshot = errors.New("no snapshot")
+
+// Source is a database that can be copied while in use.
+type Source interface {
+	BackupTo(ctx context.Context, path string) error
+}
+
+type Snapshot struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+	Path      string    `json:"-"`
+}
+
+type Manager struct {
+	mu     sync.Mutex
+	source Source
+	dir    string
+	keep   int
+	now    func() time.Time
+}
+
+// NewManager writes the snapshots of source to dir and deletes all but the
+// keep most recent ones.
+func NewManager(source Source, dir string, keep int) *Manager {
+	return &Manager{source: source, dir: dir, keep: keep, now: time.Now}
+}
+
+// Backup takes a snapshot and checks it before it counts as one, so that
+// restoring never runs into a broken snapshot.
+func (m *Manager) Backup(ctx context.Context) (*Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.dir, 0o700); err != nil {
+		return nil, err
+	}
+	name := snapshotPrefix + m.now().UTC().Format(timeFormat) + snapshotExt
+	path := filepath.Join(m.dir, name)
+	partial := path + ".partial"
+	os.Remove(partial)
+	if err := m.source.BackupTo(ctx, partial); err != nil {
+		os.Remove(partial)
+		return nil, fmt.Errorf("problem copying the database, %v", err)
+	}
+	if err := Validate(ctx, partial); err != nil {
+		os.Remove(partial)
+		return nil, fmt.Errorf("problem checking the snapshot, %v", err)
+	}
+	if err := os.Rename(partial, path); err != nil {
+		os.Remove(partial)
+		return nil, err
+	}
+
+	snapshot, err := newSnapshot(m.dir, name)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, m.prune()
+}
+
+func (m *Manager) prune() error {
+	snapshots, err := List(m.dir)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for len(snapshots) > m.keep {
+		errs = append(errs, os.Remove(snapshots[0].Path))
+		snapshots = snapshots[1:]
+	}
+	return errors.Join(errs...)
+}
+
+// Run takes a snapshot every interval until ctx is done.
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			snapshot, err := m.Backup(ctx)
+			if err != nil {
+				log.Println("error backing up the database:", err)
+				continue
+			}
+			log.Println("backed up the database to", snapshot.Path)
+		}
+	}
+}
+
+// List returns the snapshots in dir from the oldest to the most recent.
+func List(dir string) ([]Snapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		snapshot, err := newSnapshot(dir, entry.Name())
+		if errors.Is(err, ErrNoSnapshot) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, *snapshot)
+	}
+	slices.SortFunc(snapshots, func(a, b Snapshot) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return snapshots, nil
+}
+
+func newSnapshot(dir, name string) (*Snapshot, error) {
+	timestamp, ok := strings.CutPrefix(name, snapshotPrefix)
+	if !ok {
+		return nil, ErrNoSnapshot
+	}
+	timestamp, ok = strings.CutSuffix(timestamp, snapshotExt)
+	if !ok {
+		return nil, ErrNoSnapshot
+	}
+	createdAt, err := time.Parse(timeFormat, timestamp)
+	if err != nil {
+		return nil, ErrNoSnapshot
+	}
+	path := filepath.Join(dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{
+		Name:      name,
+		Size:      info.Size(),
+		CreatedAt: createdAt,
+		Path:      path,
+	}, nil
+}
+
+// Find returns the most recent snapshot in dir taken at or before at.
+func Find(dir string, at time.Time) (*Snapshot, error) {
+	snapshots, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if !snapshots[i].CreatedAt.After(at) {
+			return &snapshots[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w taken at or before %s in %s", ErrNoSnapshot, at, dir)
+}
+
+// Validate checks that the file at path is an intact database of the store.
+func Validate(ctx context.Context, path string) error {
+	// Opening a file that does not exist would create an empty database.
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	db, err := sql.Open(data.SqliteDriver, path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return data.CheckSqliteDb(ctx, db)
+}
+
+// Restore validates the snapshot at path and swaps it in for the database of
+// dsn, failing with data.ErrStoreLocked while the server uses it. The
+// replaced database is kept next to it with the .before-restore suffix.
+func Restore(ctx context.Context, path, dsn string) error {
+	dbPath := data.SqlitePath(dsn)
+	if dbPath == "" || dbPath == ":memory:" {
+		return fmt.Errorf("cannot restore to the in-memory database %q", dsn)
+	}
+	unlock, err := data.LockSqlite(dsn)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := Validate(ctx, path); err != nil {
+		return fmt.Errorf("the snapshot is not usable, %v", err)
+	}
+	restoring := dbPath + ".restoring"
+	if err := copyFile(path, restoring); err != nil {
+		os.Remove(restoring)
+		return err
+	}
+
+	// The database is linked rather than moved aside, so that it stays in
+	// place until the rename replaces it in one step.
+	os.Remove(dbPath + previousSuffix)
+	err = os.Link(dbPath, dbPath+previousSuffix)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		err = copyFile(dbPath, dbPath+previousSuffix)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		os.Remove(restoring)
+		return err
+	}
+	// The journal files belong to the replaced database and would corrupt the
+	// restored one.
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		os.Remove(dbPath + previousSuffix + suffix)
+		err := os.Rename(dbPath+suffix, dbPath+previousSuffix+suffix)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			os.Remove(restoring)
+			return err
+		}
+	}
+	return os.Rename(restoring, dbPath)
+}
+
+func copyFile(from, to string) error {
+	source, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	target, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(target, source); err != nil {
+		target.Close()
+		return err
+	}
+	if err := target.Sync(); err != nil {
+		target.Close()
+		return err
+	}
+	return target.Close()
+}
@@ -0,0 +1,194 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("takes snapshots and keeps the most recent ones", func(t *testing.T) {
+		store, _ := newSqliteStore(t)
+		dir := filepath.Join(t.TempDir(), "backups")
+		manager := NewManager(store, dir, 2)
+		now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+		manager.now = func() time.Time { return now }
+
+		var taken []*Snapshot
+		for range 3 {
+			snapshot, err := manager.Backup(ctx)
+			assert.HasNoError(t, err)
+			assert.Equals(t, snapshot.CreatedAt, now)
+			assert.HasNoError(t, Validate(ctx, snapshot.Path))
+			taken = append(taken, snapshot)
+			now = now.Add(time.Hour)
+		}
+
+		snapshots, err := List(dir)
+		assert.HasNoError(t, err)
+		assert.Equals(t, snapshots, []Snapshot{*taken[1], *taken[2]})
+		assert.Equals(t, taken[1].Name, "snapshot-20261019T130000.000Z.db")
+	})
+
+	t.Run("does not keep a snapshot that failed", func(t *testing.T) {
+		dir := t.TempDir()
+		manager := NewManager(failingSource{}, dir, 2)
+
+		_, err := manager.Backup(ctx)
+		assert.HasError(t, err)
+		entries, err := os.ReadDir(dir)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, entries, 0)
+	})
+}
+
+func TestFind(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newSqliteStore(t)
+	dir := t.TempDir()
+	manager := NewManager(store, dir, 5)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	manager.now = func() time.Time { return now }
+	first, err := manager.Backup(ctx)
+	assert.HasNoError(t, err)
+	now = now.Add(time.Hour)
+	second, err := manager.Backup(ctx)
+	assert.HasNoError(t, err)
+
+	t.Run("returns the last snapshot taken by then", func(t *testing.T) {
+		snapshot, err := Find(dir, first.CreatedAt.Add(30*time.Minute))
+		assert.HasNoError(t, err)
+		assert.Equals(t, snapshot, first)
+		snapshot, err = Find(dir, second.CreatedAt)
+		assert.HasNoError(t, err)
+		assert.Equals(t, snapshot, second)
+	})
+
+	t.Run("returns ErrNoSnapshot before the first snapshot", func(t *testing.T) {
+		_, err := Find(dir, first.CreatedAt.Add(-time.Second))
+		assert.Equals(t, errors.Is(err, ErrNoSnapshot), true)
+	})
+}
+
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("swaps the snapshot in and keeps the replaced database", func(t *testing.T) {
+		store, dbPath := newSqliteStore(t)
+		manager := NewManager(store, t.TempDir(), 1)
+		snapshot, err := manager.Backup(ctx)
+		assert.HasNoError(t, err)
+		_, err = store.CreateTask(ctx, models.NewCreateTaskDTO("Not backed up", 1))
+		assert.HasNoError(t, err)
+		wantedTasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+
+		assert.HasNoError(t, Restore(ctx, snapshot.Path, dbPath))
+
+		restored := openSqliteStore(t, dbPath)
+		tasks, err := restored.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, tasks, wantedTasks[:len(wantedTasks)-1])
+		previous := openSqliteStore(t, dbPath+previousSuffix)
+		tasks, err = previous.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, tasks, wantedTasks)
+	})
+
+	t.Run("leaves the database alone when the snapshot is broken", func(t *testing.T) {
+		_, dbPath := newSqliteStore(t)
+		before, err := os.ReadFile(dbPath)
+		assert.HasNoError(t, err)
+		snapshot := filepath.Join(t.TempDir(), "snapshot.db")
+		assert.HasNoError(t, os.WriteFile(snapshot, []byte("not a database"), 0o600))
+
+		assert.HasError(t, Restore(ctx, snapshot, dbPath))
+		after, err := os.ReadFile(dbPath)
+		assert.HasNoError(t, err)
+		assert.Equals(t, string(after), string(before))
+		_, err = os.Stat(dbPath + previousSuffix)
+		assert.Equals(t, errors.Is(err, os.ErrNotExist), true)
+	})
+
+	t.Run("refuses to run while the server holds the database", func(t *testing.T) {
+		store, dbPath := newSqliteStore(t)
+		snapshot, err := NewManager(store, t.TempDir(), 1).Backup(ctx)
+		assert.HasNoError(t, err)
+		unlock, err := data.LockSqlite(dbPath)
+		assert.HasNoError(t, err)
+		defer unlock()
+
+		err = Restore(ctx, snapshot.Path, dbPath)
+		assert.Equals(t, errors.Is(err, data.ErrStoreLocked), true)
+		_, err = os.Stat(dbPath + previousSuffix)
+		assert.Equals(t, errors.Is(err, os.ErrNotExist), true)
+	})
+
+	t.Run("restores to the file of a DSN with parameters", func(t *testing.T) {
+		store, dbPath := newSqliteStore(t)
+		snapshot, err := NewManager(store, t.TempDir(), 1).Backup(ctx)
+		assert.HasNoError(t, err)
+		dsn := "file:" + dbPath + "?_pragma=busy_timeout(5000)"
+
+		unlock, err := data.LockSqlite(dsn)
+		assert.HasNoError(t, err)
+		err = Restore(ctx, snapshot.Path, dbPath)
+		assert.Equals(t, errors.Is(err, data.ErrStoreLocked), true)
+		assert.HasNoError(t, unlock())
+
+		assert.HasNoError(t, Restore(ctx, snapshot.Path, dsn))
+		_, err = os.Stat(dbPath + previousSuffix)
+		assert.HasNoError(t, err)
+	})
+
+	t.Run("refuses an in-memory database", func(t *testing.T) {
+		store, _ := newSqliteStore(t)
+		snapshot, err := NewManager(store, t.TempDir(), 1).Backup(ctx)
+		assert.HasNoError(t, err)
+
+		assert.HasError(t, Restore(ctx, snapshot.Path, ":memory:"))
+	})
+
+	t.Run("refuses a snapshot that does not exist", func(t *testing.T) {
+		_, dbPath := newSqliteStore(t)
+		err := Restore(ctx, filepath.Join(t.TempDir(), "missing.db"), dbPath)
+		assert.HasError(t, err)
+	})
+}
+
+type failingSource struct{}
+
+func (failingSource) BackupTo(ctx context.Context, path string) error {
+	if err := os.WriteFile(path, []byte("half a"), 0o600); err != nil {
+		return err
+	}
+	return errors.New("disk full")
+}
+
+func newSqliteStore(t *testing.T) (*data.SqliteStore, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "data.db")
+	db, err := sql.Open(data.SqliteDriver, path)
+	assert.HasNoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	data.InitDb(db)
+	return data.NewSqliteStore(db), path
+}
+
+func openSqliteStore(t *testing.T, path string) *data.SqliteStore {
+	t.Helper()
+	db, err := sql.Open(data.SqliteDriver, path)
+	assert.HasNoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return data.NewSqliteStore(db)
+}
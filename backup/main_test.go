@@ -0,0 +1,14 @@
+package backup
+
+import (
+	"os"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestMain(m *testing.M) {
+	data.SetPasswordCost(bcrypt.MinCost)
+	os.Exit(m.Run())
+}
@@ -0,0 +1,421 @@
+// Package config gathers the server settings from, in increasing order of
+// precedence, the defaults, a YAML file, environment variables and flags.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	BackendBolt     = "bolt"
+	BackendEvents   = "events"
+	BackendFile     = "file"
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+	BackendSqlite   = "sqlite"
+)
+
+var Backends = []string{
+	BackendBolt,
+	BackendEvents,
+	BackendFile,
+	BackendMemory,
+	BackendPostgres,
+	BackendSqlite,
+}
+
+// defaultDsns are used when no DSN is given. The memory backend keeps no
+// snapshot by default and postgres has no sensible default.
+var defaultDsns = map[string]string{
+	BackendBolt:   "./data/data.bolt",
+	BackendEvents: "./data/data.db",
+	BackendFile:   "./data/data.json",
+	BackendSqlite: "./data/data.db",
+}
+
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+// minJwtSecretLength is the length of an HS256 key.
+const minJwtSecretLength = 32
+
+const redacted = "[redacted]"
+
+type Config struct {
+	Backend    string   `yaml:"backend"`
+	Dsn        string   `yaml:"dsn"`
+	ListenAddr string   `yaml:"listenAddr"`
+	Jwt        Jwt      `yaml:"jwt"`
+	LogLevel   string   `yaml:"logLevel"`
+	Timeouts   Timeouts `yaml:"timeouts"`
+	Backup     Backup   `yaml:"backup"`
+	Cache      Cache    `yaml:"cache"`
+
+	// PrintConfig asks for the configuration to be printed instead of
+	// starting the server.
+	PrintConfig bool `yaml:"-"`
+}
+
+type Jwt struct {
+	// Secret signs the access tokens. When it is empty, the server makes up
+	// one that only lasts until it stops.
+	Secret         string        `yaml:"secret"`
+	AccessTokenTtl time.Duration `yaml:"accessTokenTtl"`
+}
+
+type Timeouts struct {
+	Read     time.Duration `yaml:"read"`
+	Write    time.Duration `yaml:"write"`
+	Idle     time.Duration `yaml:"idle"`
+	Shutdown time.Duration `yaml:"shutdown"`
+}
+
+// Backup applies to the sqlite backend only.
+type Backup struct {
+	Dir string `yaml:"dir"`
+	// Interval is the time between scheduled snapshots, which are off when
+	// it is zero.
+	Interval time.Duration `yaml:"interval"`
+	Keep     int           `yaml:"keep"`
+}
+
+// Cache keeps recently read tasks in memory. It is off when Size is zero,
+// and should stay off when several servers share a database, as each one
+// only sees the others' changes once its entries expire.
+type Cache struct {
+	Size int           `yaml:"size"`
+	Ttl  time.Duration `yaml:"ttl"`
+}
+
+func Default() *Config {
+	return &Config{
+		Backend:    BackendSqlite,
+		ListenAddr: ":8080",
+		Jwt:        Jwt{AccessTokenTtl: 24 * time.Hour},
+		LogLevel:   "info",
+		Timeouts: Timeouts{
+			Read:     15 * time.Second,
+			Write:    15 * time.Second,
+			Idle:     time.Minute,
+			Shutdown: 10 * time.Second,
+		},
+		Backup: Backup{
+			Dir:      "./data/backups",
+			Interval: 24 * time.Hour,
+			Keep:     7,
+		},
+		Cache: Cache{Ttl: time.Minute},
+	}
+}
+
+// setting is configurable through both a flag and an environment variable.
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	field func(c *Config) any
+}
+
+var settings = []setting{
+	{
+		"backend", "STORE_BACKEND",
+		"store backend: " + strings.Join(Backends, ", "),
+		func(c *Config) any { return &c.Backend },
+	},
+	{
+		"dsn", "STORE_DSN",
+		"path of the database file, or the URL of the postgres database",
+		func(c *Config) any { return &c.Dsn },
+	},
+	{
+		"listen-addr", "LISTEN_ADDR",
+		"address to listen on",
+		func(c *Config) any { return &c.ListenAddr },
+	},
+	{
+		"jwt-secret", "JWT_SECRET",
+		"secret that signs the access tokens",
+		func(c *Config) any { return &c.Jwt.Secret },
+	},
+	{
+		"access-token-ttl", "ACCESS_TOKEN_TTL",
+		"how long access tokens stay valid",
+		func(c *Config) any { return &c.Jwt.AccessTokenTtl },
+	},
+	{
+		"log-level", "LOG_LEVEL",
+		"minimum level of the logs: " + strings.Join(LogLevels, ", "),
+		func(c *Config) any { return &c.LogLevel },
+	},
+	{
+		"read-timeout", "READ_TIMEOUT",
+		"maximum time to read a request",
+		func(c *Config) any { return &c.Timeouts.Read },
+	},
+	{
+		"write-timeout", "WRITE_TIMEOUT",
+		"maximum time to write a response",
+		func(c *Config) any { return &c.Timeouts.Write },
+	},
+	{
+		"idle-timeout", "IDLE_TIMEOUT",
+		"how long idle keep-alive connections stay open",
+		func(c *Config) any { return &c.Timeouts.Idle },
+	},
+	{
+		"shutdown-timeout", "SHUTDOWN_TIMEOUT",
+		"how long to wait for requests to finish when stopping",
+		func(c *Config) any { return &c.Timeouts.Shutdown },
+	},
+	{
+		"backup-dir", "BACKUP_DIR",
+		"directory of the sqlite snapshots",
+		func(c *Config) any { return &c.Backup.Dir },
+	},
+	{
+		"backup-interval", "BACKUP_INTERVAL",
+		"time between sqlite snapshots, 0 to only take them on demand",
+		func(c *Config) any { return &c.Backup.Interval },
+	},
+	{
+		"backup-keep", "BACKUP_KEEP",
+		"number of sqlite snapshots to keep",
+		func(c *Config) any { return &c.Backup.Keep },
+	},
+	{
+		"cache-size", "CACHE_SIZE",
+		"number of task reads to cache, 0 to turn the cache off",
+		func(c *Config) any { return &c.Cache.Size },
+	},
+	{
+		"cache-ttl", "CACHE_TTL",
+		"how long cached task reads are served",
+		func(c *Config) any { return &c.Cache.Ttl },
+	},
+}
+
+// Load reads the configuration for the command line args, which exclude the
+// program name, and validates it.
+func Load(args []string, getenv func(string) string) (*Config, error) {
+	flags := flag.NewFlagSet("server", flag.ContinueOnError)
+	configFile := flags.String(
+		"config",
+		"",
+		"path of a YAML configuration file, also set by CONFIG_FILE",
+	)
+	printConfig := flags.Bool(
+		"print-config",
+		false,
+		"print the configuration and exit",
+	)
+	flagValues := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flagValues[s.flag] = flags.String(
+			s.flag,
+			"",
+			fmt.Sprintf("%s, also set by %s", s.usage, s.env),
+		)
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	config := Default()
+	path := *configFile
+	if path == "" {
+		path = getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		if err := config.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	// DATABASE_URL predates the other variables and still selects postgres.
+	if url := getenv("DATABASE_URL"); url != "" {
+		config.Backend = BackendPostgres
+		config.Dsn = url
+	}
+	for _, s := range settings {
+		if value := getenv(s.env); value != "" {
+			if err := set(s.field(config), value); err != nil {
+				return nil, fmt.Errorf("%s: %v", s.env, err)
+			}
+		}
+	}
+	setFlags := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	for _, s := range settings {
+		if setFlags[s.flag] {
+			if err := set(s.field(config), *flagValues[s.flag]); err != nil {
+				return nil, fmt.Errorf("-%s: %v", s.flag, err)
+			}
+		}
+	}
+
+	if config.Dsn == "" {
+		config.Dsn = DefaultDsn(config.Backend)
+	}
+	config.PrintConfig = *printConfig
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// DefaultDsn is the DSN of the backend when none is given.
+func DefaultDsn(backend string) string {
+	return defaultDsns[backend]
+}
+
+func (c *Config) readFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("problem reading the config file, %v", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	err = decoder.Decode(c)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("problem parsing %s, %v", path, err)
+	}
+	return nil
+}
+
+func set(field any, value string) error {
+	switch field := field.(type) {
+	case *string:
+		*field = value
+	case *time.Duration:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*field = duration
+	case *int:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*field = number
+	default:
+		panic(fmt.Sprintf("unsupported setting type %T", field))
+	}
+	return nil
+}
+
+// Validate reports every problem with the configuration at once.
+func (c *Config) Validate() error {
+	var errs []error
+	if !slices.Contains(Backends, c.Backend) {
+		errs = append(errs, fmt.Errorf(
+			"backend %q is not one of %s",
+			c.Backend,
+			strings.Join(Backends, ", "),
+		))
+	} else if c.Dsn == "" && c.Backend != BackendMemory {
+		errs = append(errs, fmt.Errorf("the %s backend needs a DSN", c.Backend))
+	}
+	if _, _, err := net.SplitHostPort(c.ListenAddr); err != nil {
+		errs = append(errs, fmt.Errorf("listen address: %v", err))
+	}
+	if c.Jwt.Secret != "" && len(c.Jwt.Secret) < minJwtSecretLength {
+		errs = append(errs, fmt.Errorf(
+			"the JWT secret must be at least %d bytes long",
+			minJwtSecretLength,
+		))
+	}
+	if c.Jwt.AccessTokenTtl <= 0 {
+		errs = append(errs, errors.New("the access token TTL must be positive"))
+	}
+	if !slices.Contains(LogLevels, c.LogLevel) {
+		errs = append(errs, fmt.Errorf(
+			"log level %q is not one of %s",
+			c.LogLevel,
+			strings.Join(LogLevels, ", "),
+		))
+	}
+	timeouts := map[string]time.Duration{
+		"read":     c.Timeouts.Read,
+		"write":    c.Timeouts.Write,
+		"idle":     c.Timeouts.Idle,
+		"shutdown": c.Timeouts.Shutdown,
+	}
+	for _, name := range []string{"read", "write", "idle", "shutdown"} {
+		if timeouts[name] < 0 {
+			errs = append(errs, fmt.Errorf("the %s timeout cannot be negative", name))
+		}
+	}
+	if c.Backup.Dir == "" {
+		errs = append(errs, errors.New("the backup directory cannot be empty"))
+	}
+	if c.Backup.Interval < 0 {
+		errs = append(errs, errors.New("the backup interval cannot be negative"))
+	}
+	if c.Backup.Keep < 1 {
+		errs = append(errs, errors.New("at least one backup must be kept"))
+	}
+	if c.Cache.Size < 0 {
+		errs = append(errs, errors.New("the cache size cannot be negative"))
+	}
+	if c.Cache.Size > 0 && c.Cache.Ttl <= 0 {
+		errs = append(errs, errors.New("the cache TTL must be positive"))
+	}
+	return errors.Join(errs...)
+}
+
+// SlogLevel is the LogLevel for log/slog.
+func (c *Config) SlogLevel() slog.Level {
+	var level slog.Level
+	level.UnmarshalText([]byte(c.LogLevel))
+	return level
+}
+
+// Print writes the configuration as YAML, hiding the secrets.
+func (c *Config) Print(w io.Writer) error {
+	printed := *c
+	if printed.Jwt.Secret != "" {
+		printed.Jwt.Secret = redacted
+	}
+	printed.Dsn = redactDsn(printed.Dsn)
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&printed); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// redactDsn hides the password of URLs and key=value DSNs.
+func redactDsn(dsn string) string {
+	if before, after, ok := strings.Cut(dsn, "://"); ok {
+		credentials, host, ok := strings.Cut(after, "@")
+		if !ok {
+			return dsn
+		}
+		if user, _, ok := strings.Cut(credentials, ":"); ok {
+			return before + "://" + user + ":" + redacted + "@" + host
+		}
+		return dsn
+	}
+	fields := strings.Fields(dsn)
+	for i, field := range fields {
+		if strings.HasPrefix(field, "password=") {
+			fields[i] = "password=" + redacted
+			return strings.Join(fields, " ")
+		}
+	}
+	return dsn
+}
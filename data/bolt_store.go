@@ -0,0 +1,1588 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	bolterrors "go.etcd.io/bbolt/errors"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+const boltOpenTimeout = time.Second
+
+// The records are stored as JSON under their big-endian IDs so that the
+// buckets iterate in ID order. The index buckets map a prefix such as a
+// project ID, followed by the ID of the record, to nothing.
+var (
+	boltApiTokens            = []byte("api_tokens")
+	boltApiTokensByHash      = []byte("api_tokens_by_hash")
+	boltApiTokensByUser      = []byte("api_tokens_by_user")
+	boltAuditEvents          = []byte("audit_events")
+	boltLoginAttempts        = []byte("login_attempts")
+	boltProjectInvitations   = []byte("project_invitations")
+	boltInvitationsByEmail   = []byte("project_invitations_by_email")
+	boltInvitationsByProject = []byte("project_invitations_by_project")
+	boltProjectMembers       = []byte("project_members")
+	boltProjectMembersByUser = []byte("project_members_by_user")
+	boltProjects             = []byte("projects")
+	boltTasks                = []byte("tasks")
+	boltTasksByAssignee      = []byte("tasks_by_assignee")
+	boltTasksByProject       = []byte("tasks_by_project")
+	boltUserMfa              = []byte("user_mfa")
+	boltUserTokens           = []byte("user_tokens")
+	boltUserTokensByUser     = []byte("user_tokens_by_user")
+	boltUsers                = []byte("users")
+	boltUsersByEmail         = []byte("users_by_email")
+	boltBuckets              = [][]byte{
+		boltApiTokens,
+		boltApiTokensByHash,
+		boltApiTokensByUser,
+		boltAuditEvents,
+		boltLoginAttempts,
+		boltProjectInvitations,
+		boltInvitationsByEmail,
+		boltInvitationsByProject,
+		boltProjectMembers,
+		boltProjectMembersByUser,
+		boltProjects,
+		boltTasks,
+		boltTasksByAssignee,
+		boltTasksByProject,
+		boltUserMfa,
+		boltUserTokens,
+		boltUserTokensByUser,
+		boltUsers,
+		boltUsersByEmail,
+	}
+)
+
+// BoltStore keeps the data in a single bbolt file. Every method runs in one
+// transaction, so a change that touches several records and their indexes
+// is either written completely or not at all.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: boltOpenTimeout})
+	if errors.Is(err, bolterrors.ErrTimeout) {
+		return nil, fmt.Errorf("%s: %w", path, ErrStoreLocked)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("problem opening %s, %v", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range boltBuckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("problem creating the buckets, %v", err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+func (b *BoltStore) Close() error {
+	return b.db.Close()
+}
+
+func (b *BoltStore) view(ctx context.Context, fn func(tx *bolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.db.View(fn)
+}
+
+func (b *BoltStore) update(ctx context.Context, fn func(tx *bolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.db.Update(fn)
+}
+
+func (b *BoltStore) GetTaskById(ctx context.Context, id int) (*models.Task, error) {
+	var task models.Task
+	err := b.view(ctx, func(tx *bolt.Tx) error {
+		found, err := boltGet(tx.Bucket(boltTasks), boltId(id), &task)
+		if err == nil && !found {
+			return fmt.Errorf("task with ID %d: %w", id, ErrResourceNotFound)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (b *BoltStore) GetTasks(ctx context.Context) ([]models.Task, error) {
+	var tasks []models.Task
+	err := b.view(ctx, func(tx *bolt.Tx) (err error) {
+		tasks, err = boltList[models.Task](tx.Bucket(boltTasks))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (b *BoltStore) GetTasksByProjectId(
+	ctx context.Context,
+	projectId int,
+) ([]models.Task, error) {
+	var tasks []models.Task
+	err := b.view(ctx, func(tx *bolt.Tx) (err error) {
+		tasks, err = boltListIndexed[models.Task](
+			tx.Bucket(boltTasksByProject),
+			boltId(projectId),
+			tx.Bucket(boltTasks),
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (b *BoltStore) CreateTask(
+	ctx context.Context,
+	dto *models.CreateTaskDTO,
+) (*models.Task, error) {
+	var task models.Task
+	err := b.update(ctx, func(tx *bolt.Tx) error {
+		id, err := tx.Bucket(boltTasks).NextSequence()
+		if err != nil {
+			return err
+		}
+		task = models.Task{
+			Id:         int(id),
+			Title:      dto.Title,
+			ProjectId:  dto.ProjectId,
+			AssigneeId: dto.AssigneeId,
+		}
+		return putBoltTask(tx, nil, &task)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (b *BoltStore) DeleteTaskById(ctx context.Context, id int) error {
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		var task models.Task
+		found, err := boltGet(tx.Bucket(boltTasks), boltId(id), &task)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("error with task ID %d: %w", id, ErrResourceNotFound)
+		}
+		return deleteBoltTask(tx, &task)
+	})
+}
+
+func (b *BoltStore) UpdateTask(
+	ctx context.Context,
+	task *models.Task,
+) (*models.Task, error) {
+	var updatedTask models.Task
+	err := b.update(ctx, func(tx *bolt.Tx) error {
+		var existingTask models.Task
+		found, err := boltGet(tx.Bucket(boltTasks), boltId(task.Id), &existingTask)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("task with ID %d: %w", task.Id, ErrResourceNotFound)
+		}
+		updatedTask = existingTask
+		updatedTask.Title = task.Title
+		updatedTask.AssigneeId = task.AssigneeId
+		return putBoltTask(tx, &existingTask, &updatedTask)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &updatedTask, nil
+}
+
+func (b *BoltStore) GetUserByEmail(
+	ctx context.Context,
+	email string,
+) (*models.User, error) {
+	email = NormalizeEmail(email)
+	var user models.User
+	err := b.view(ctx, func(tx *bolt.Tx) error {
+		id := tx.Bucket(boltUsersByEmail).Get([]byte(email))
+		if id == nil {
+			return fmt.Errorf("user with email %s: %w", email, ErrResourceNotFound)
+		}
+		_, err := boltGet(tx.Bucket(boltUsers), id, &user)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (b *BoltStore) CreateUser(
+	ctx context.Context,
+	dto *models.CreateUserDTO,
+) (*models.User, error) {
+	email := NormalizeEmail(dto.Email)
+	hashedPassword, err := hashPassword(dto.Password)
+	if err != nil {
+		return nil, err
+	}
+	var user models.User
+	err = b.update(ctx, func(tx *bolt.Tx) error {
+		if tx.Bucket(boltUsersByEmail).Get([]byte(email)) != nil {
+			return fmt.Errorf("user with email %s: %w", email, ErrConflict)
+		}
+		id, err := tx.Bucket(boltUsers).NextSequence()
+		if err != nil {
+			return err
+		}
+		user = *models.NewUser(int(id), dto.Name, email, string(hashedPassword))
+		return putBoltUser(tx, nil, &user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (b *BoltStore) GetUsers(ctx context.Context) ([]models.User, error) {
+	var users []models.User
+	err := b.view(ctx, func(tx *bolt.Tx) (err error) {
+		users, err = boltList[models.User](tx.Bucket(boltUsers))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (b *BoltStore) GetUserById(ctx context.Context, id int) (*models.User, error) {
+	var user models.User
+	err := b.view(ctx, func(tx *bolt.Tx) error {
+		found, err := boltGet(tx.Bucket(boltUsers), boltId(id), &user)
+		if err == nil && !found {
+			return fmt.Errorf("user with ID %d: %w", id, ErrResourceNotFound)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (b *BoltStore) UpdateUser(
+	ctx context.Context,
+	user *models.User,
+) (*models.User, error) {
+	var updatedUser models.User
+	err := b.update(ctx, func(tx *bolt.Tx) error {
+		var existingUser models.User
+		found, err := boltGet(tx.Bucket(boltUsers), boltId(user.Id), &existingUser)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("user with ID %d: %w", user.Id, ErrResourceNotFound)
+		}
+		email := NormalizeEmail(user.Email)
+		id := tx.Bucket(boltUsersByEmail).Get([]byte(email))
+		if id != nil && boltIdFrom(id) != user.Id {
+			return fmt.Errorf("user with email %s: %w", email, ErrConflict)
+		}
+		updatedUser = *user
+		updatedUser.Email = email
+		updatedUser.Password = existingUser.Password
+		updatedUser.PasswordChangedAt = existingUser.PasswordChangedAt
+		return putBoltUser(tx, &existingUser, &updatedUser)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &updatedUser, nil
+}
+
+func (b *BoltStore) DeleteUserById(ctx context.Context, id int) error {
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		var user models.User
+		found, err := boltGet(tx.Bucket(boltUsers), boltId(id), &user)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("user with ID %d: %w", id, ErrResourceNotFound)
+		}
+		if err := tx.Bucket(boltUsers).Delete(boltId(id)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(boltUsersByEmail).Delete([]byte(user.Email)); err != nil {
+			return err
+		}
+		if err := deleteBoltUserTokens(tx, id, ""); err != nil {
+			return err
+		}
+		if err := deleteBoltApiTokens(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Bucket(boltUserMfa).Delete(boltId(id)); err != nil {
+			return err
+		}
+		return removeBoltUserFromProjects(tx, id)
+	})
+}
+
+func (b *BoltStore) UpdateUserPassword(
+	ctx context.Context,
+	id int,
+	password string,
+) error {
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		var user models.User
+		found, err := boltGet(tx.Bucket(boltUsers), boltId(id), &user)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("user with ID %d: %w", id, ErrResourceNotFound)
+		}
+		user.Password = string(hashedPassword)
+		passwordChangedAt := time.Now().UTC()
+		user.PasswordChangedAt = &passwordChangedAt
+		if err := boltPut(tx.Bucket(boltUsers), boltId(id), &user); err != nil {
+			return err
+		}
+		return deleteBoltApiTokens(tx, id)
+	})
+}
+
+func (b *BoltStore) ValidateUserCredentials(
+	ctx context.Context,
+	email, password string,
+) bool {
+	user, err := b.GetUserByEmail(ctx, email)
+	if err != nil {
+		simulatePasswordCheck(password)
+		return false
+	}
+	return checkPassword([]byte(user.Password), password)
+}
+
+func (b *BoltStore) DeleteUserMfa(ctx context.Context, userId int) error {
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		return tx.Bucket(boltUserMfa).Delete(boltId(userId))
+	})
+}
+
+func (b *BoltStore) GetUserMfa(
+	ctx context.Context,
+	userId int,
+) (*models.UserMfa, error) {
+	var mfa models.UserMfa
+	err := b.view(ctx, func(tx *bolt.Tx) error {
+		found, err := boltGet(tx.Bucket(boltUserMfa), boltId(userId), &mfa)
+		if err == nil && !found {
+			return fmt.Errorf(
+				"MFA settings of user with ID %d: %w",
+				userId,
+				ErrResourceNotFound,
+			)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &mfa, nil
+}
+
+func (b *BoltStore) SaveUserMfa(ctx context.Context, mfa *models.UserMfa) error {
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		return boltPut(tx.Bucket(boltUserMfa), boltId(mfa.UserId), mfa)
+	})
+}
+
+func (b *BoltStore) UseUserMfaStep(
+	ctx context.Context,
+	userId int,
+	step int64,
+) (bool, error) {
+	var used bool
+	err := b.update(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltUserMfa)
+		var mfa models.UserMfa
+		found, err := boltGet(bucket, boltId(userId), &mfa)
+		if err != nil || !found || mfa.LastUsedStep >= step {
+			return err
+		}
+		mfa.LastUsedStep = step
+		used = true
+		return boltPut(bucket, boltId(userId), &mfa)
+	})
+	if err != nil {
+		return false, err
+	}
+	return used, nil
+}
+
+func (b *BoltStore) DeleteLoginAttempt(ctx context.Context, key string) error {
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		return tx.Bucket(boltLoginAttempts).Delete([]byte(key))
+	})
+}
+
+func (b *BoltStore) GetLoginAttempt(
+	ctx context.Context,
+	key string,
+) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := b.view(ctx, func(tx *bolt.Tx) error {
+		found, err := boltGet(tx.Bucket(boltLoginAttempts), []byte(key), &attempt)
+		if err == nil && !found {
+			return fmt.Errorf(
+				"login attempt with key %s: %w",
+				key,
+				ErrResourceNotFound,
+			)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (b *BoltStore) SaveLoginAttempt(
+	ctx context.Context,
+	attempt *models.LoginAttempt,
+) error {
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		return boltPut(tx.Bucket(boltLoginAttempts), []byte(attempt.Key), attempt)
+	})
+}
+
+func (b *BoltStore) IncrementLoginFailures(
+	ctx context.Context,
+	key string,
+	failedAt time.Time,
+	expireAfter time.Duration,
+) (*models.LoginAttempt, error) {
+	attempt := models.LoginAttempt{Key: key}
+	err := b.update(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltLoginAttempts)
+		if _, err := boltGet(bucket, []byte(key), &attempt); err != nil {
+			return err
+		}
+		countLoginFailure(&attempt, failedAt, expireAfter)
+		return boltPut(bucket, []byte(key), &attempt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (b *BoltStore) LockLoginAttempt(
+	ctx context.Context,
+	key string,
+	failures int,
+	lockedUntil time.Time,
+) error {
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltLoginAttempts)
+		var attempt models.LoginAttempt
+		found, err := boltGet(bucket, []byte(key), &attempt)
+		if err != nil || !found || attempt.Failures != failures {
+			return err
+		}
+		attempt.LockedUntil = lockedUntil
+		return boltPut(bucket, []byte(key), &attempt)
+	})
+}
+
+func (b *BoltStore) CreateAuditEvent(
+	ctx context.Context,
+	event *models.AuditEvent,
+) (*models.AuditEvent, error) {
+	createdEvent := *event
+	if createdEvent.CreatedAt.IsZero() {
+		createdEvent.CreatedAt = time.Now().UTC()
+	}
+	err := b.update(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltAuditEvents)
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		createdEvent.Id = int(id)
+		return boltPut(bucket, boltId(createdEvent.Id), &createdEvent)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &createdEvent, nil
+}
+
+func (b *BoltStore) GetAuditEvents(
+	ctx context.Context,
+) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	err := b.view(ctx, func(tx *bolt.Tx) (err error) {
+		events, err = boltList[models.AuditEvent](tx.Bucket(boltAuditEvents))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (b *BoltStore) ConsumeUserToken(
+	ctx context.Context,
+	hash, purpose string,
+) (*models.UserToken, error) {
+	var token models.UserToken
+	err := b.update(ctx, func(tx *bolt.Tx) error {
+		found, err := boltGet(tx.Bucket(boltUserTokens), []byte(hash), &token)
+		if err != nil {
+			return err
+		}
+		if !found || token.Purpose != purpose {
+			return fmt.Errorf("%s token: %w", purpose, ErrResourceNotFound)
+		}
+		return deleteBoltUserToken(tx, &token)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (b *BoltStore) CreateUserToken(
+	ctx context.Context,
+	token *models.UserToken,
+) error {
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		err := boltPut(tx.Bucket(boltUserTokens), []byte(token.Hash), token)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(boltUserTokensByUser).Put(
+			boltKey(boltId(token.UserId), []byte(token.Hash)),
+			nil,
+		)
+	})
+}
+
+func (b *BoltStore) DeleteUserTokens(
+	ctx context.Context,
+	userId int,
+	purpose string,
+) error {
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		return deleteBoltUserTokens(tx, userId, purpose)
+	})
+}
+
+func (b *BoltStore) CreateApiToken(
+	ctx context.Context,
+	token *models.ApiToken,
+) (*models.ApiToken, error) {
+	createdToken := *token
+	if createdToken.CreatedAt.IsZero() {
+		createdToken.CreatedAt = time.Now().UTC()
+	}
+	err := b.update(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltApiTokens)
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		createdToken.Id = int(id)
+		return putBoltApiToken(tx, &createdToken)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &createdToken, nil
+}
+
+func (b *BoltStore) DeleteApiToken(ctx context.Context, id, userId int) error {
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		var token models.ApiToken
+		found, err := boltGet(tx.Bucket(boltApiTokens), boltId(id), &token)
+		if err != nil {
+			return err
+		}
+		if !found || token.UserId != userId {
+			return fmt.Errorf("API token with ID %d: %w", id, ErrResourceNotFound)
+		}
+		return deleteBoltApiToken(tx, &token)
+	})
+}
+
+func (b *BoltStore) GetApiTokenByHash(
+	ctx context.Context,
+	hash string,
+) (*models.ApiToken, error) {
+	var token models.ApiToken
+	err := b.view(ctx, func(tx *bolt.Tx) error {
+		id := tx.Bucket(boltApiTokensByHash).Get([]byte(hash))
+		if id == nil {
+			return fmt.Errorf("API token: %w", ErrResourceNotFound)
+		}
+		_, err := boltGet(tx.Bucket(boltApiTokens), id, &token)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (b *BoltStore) GetApiTokensByUserId(
+	ctx context.Context,
+	userId int,
+) ([]models.ApiToken, error) {
+	var tokens []models.ApiToken
+	err := b.view(ctx, func(tx *bolt.Tx) (err error) {
+		tokens, err = boltListIndexed[models.ApiToken](
+			tx.Bucket(boltApiTokensByUser),
+			boltId(userId),
+			tx.Bucket(boltApiTokens),
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (b *BoltStore) UpdateApiTokenLastUsedAt(
+	ctx context.Context,
+	id int,
+	lastUsedAt time.Time,
+) error {
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		var token models.ApiToken
+		found, err := boltGet(tx.Bucket(boltApiTokens), boltId(id), &token)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("API token with ID %d: %w", id, ErrResourceNotFound)
+		}
+		token.LastUsedAt = &lastUsedAt
+		return boltPut(tx.Bucket(boltApiTokens), boltId(id), &token)
+	})
+}
+
+func (b *BoltStore) CreateProject(
+	ctx context.Context,
+	dto *models.CreateProjectDTO,
+	ownerId int,
+) (*models.Project, error) {
+	var project models.Project
+	err := b.update(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltProjects)
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		project = models.Project{Id: int(id), Name: dto.Name}
+		if err := boltPut(bucket, boltId(project.Id), &project); err != nil {
+			return err
+		}
+		return putBoltProjectMember(tx, &models.ProjectMember{
+			ProjectId: project.Id,
+			UserId:    ownerId,
+			Role:      models.ProjectRoleOwner,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (b *BoltStore) GetProjectById(
+	ctx context.Context,
+	id int,
+) (*models.Project, error) {
+	var project models.Project
+	err := b.view(ctx, func(tx *bolt.Tx) error {
+		found, err := boltGet(tx.Bucket(boltProjects), boltId(id), &project)
+		if err == nil && !found {
+			return fmt.Errorf("project with ID %d: %w", id, ErrResourceNotFound)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (b *BoltStore) DeleteProjectMember(
+	ctx context.Context,
+	projectId, userId int,
+) error {
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		key := boltKey(boltId(projectId), boltId(userId))
+		if tx.Bucket(boltProjectMembers).Get(key) == nil {
+			return fmt.Errorf(
+				"user with ID %d in project with ID %d: %w",
+				userId,
+				projectId,
+				ErrResourceNotFound,
+			)
+		}
+		if err := deleteBoltProjectMember(tx, projectId, userId); err != nil {
+			return err
+		}
+		return unassignBoltTasks(tx, userId, func(task *models.Task) bool {
+			return task.ProjectId == projectId
+		})
+	})
+}
+
+func (b *BoltStore) GetProjectMember(
+	ctx context.Context,
+	projectId, userId int,
+) (*models.ProjectMember, error) {
+	var member models.ProjectMember
+	err := b.view(ctx, func(tx *bolt.Tx) error {
+		found, err := boltGet(
+			tx.Bucket(boltProjectMembers),
+			boltKey(boltId(projectId), boltId(userId)),
+			&member,
+		)
+		if err == nil && !found {
+			return fmt.Errorf(
+				"user with ID %d in project with ID %d: %w",
+				userId,
+				projectId,
+				ErrResourceNotFound,
+			)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (b *BoltStore) GetProjectMembers(
+	ctx context.Context,
+	projectId int,
+) ([]models.ProjectMember, error) {
+	var members []models.ProjectMember
+	err := b.view(ctx, func(tx *bolt.Tx) error {
+		return boltScan(
+			tx.Bucket(boltProjectMembers),
+			boltId(projectId),
+			func(_, value []byte) error {
+				var member models.ProjectMember
+				if err := json.Unmarshal(value, &member); err != nil {
+					return err
+				}
+				members = append(members, member)
+				return nil
+			},
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (b *BoltStore) GetProjectMembersByUserId(
+	ctx context.Context,
+	userId int,
+) ([]models.ProjectMember, error) {
+	var members []models.ProjectMember
+	err := b.view(ctx, func(tx *bolt.Tx) error {
+		return boltScan(
+			tx.Bucket(boltProjectMembersByUser),
+			boltId(userId),
+			func(projectId, _ []byte) error {
+				var member models.ProjectMember
+				_, err := boltGet(
+					tx.Bucket(boltProjectMembers),
+					boltKey(projectId, boltId(userId)),
+					&member,
+				)
+				if err != nil {
+					return err
+				}
+				members = append(members, member)
+				return nil
+			},
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (b *BoltStore) SaveProjectMember(
+	ctx context.Context,
+	member *models.ProjectMember,
+) error {
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		return putBoltProjectMember(tx, member)
+	})
+}
+
+// AcceptProjectInvitation turns the invitation into a membership, keeping the
+// role of users who already are members.
+func (b *BoltStore) AcceptProjectInvitation(
+	ctx context.Context,
+	id, userId int,
+) (*models.ProjectMember, error) {
+	var member models.ProjectMember
+	err := b.update(ctx, func(tx *bolt.Tx) error {
+		var invitation models.ProjectInvitation
+		found, err := boltGet(
+			tx.Bucket(boltProjectInvitations),
+			boltId(id),
+			&invitation,
+		)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf(
+				"project invitation with ID %d: %w",
+				id,
+				ErrResourceNotFound,
+			)
+		}
+		if err := deleteBoltProjectInvitation(tx, &invitation); err != nil {
+			return err
+		}
+		found, err = boltGet(
+			tx.Bucket(boltProjectMembers),
+			boltKey(boltId(invitation.ProjectId), boltId(userId)),
+			&member,
+		)
+		if err != nil || found {
+			return err
+		}
+		member = models.ProjectMember{
+			ProjectId: invitation.ProjectId,
+			UserId:    userId,
+			Role:      invitation.Role,
+		}
+		return putBoltProjectMember(tx, &member)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (b *BoltStore) CreateProjectInvitation(
+	ctx context.Context,
+	invitation *models.ProjectInvitation,
+) (*models.ProjectInvitation, error) {
+	createdInvitation := *invitation
+	createdInvitation.Email = NormalizeEmail(invitation.Email)
+	if createdInvitation.CreatedAt.IsZero() {
+		createdInvitation.CreatedAt = time.Now().UTC()
+	}
+	err := b.update(ctx, func(tx *bolt.Tx) error {
+		invitations, err := boltListIndexed[models.ProjectInvitation](
+			tx.Bucket(boltInvitationsByEmail),
+			boltEmailPrefix(createdInvitation.Email),
+			tx.Bucket(boltProjectInvitations),
+		)
+		if err != nil {
+			return err
+		}
+		for _, inv := range invitations {
+			if inv.ProjectId == createdInvitation.ProjectId {
+				return fmt.Errorf(
+					"invitation of %s to project with ID %d: %w",
+					createdInvitation.Email,
+					createdInvitation.ProjectId,
+					ErrConflict,
+				)
+			}
+		}
+
+		bucket := tx.Bucket(boltProjectInvitations)
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		createdInvitation.Id = int(id)
+		return putBoltProjectInvitation(tx, &createdInvitation)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &createdInvitation, nil
+}
+
+func (b *BoltStore) DeleteProjectInvitation(ctx context.Context, id int) error {
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		var invitation models.ProjectInvitation
+		found, err := boltGet(
+			tx.Bucket(boltProjectInvitations),
+			boltId(id),
+			&invitation,
+		)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf(
+				"project invitation with ID %d: %w",
+				id,
+				ErrResourceNotFound,
+			)
+		}
+		return deleteBoltProjectInvitation(tx, &invitation)
+	})
+}
+
+func (b *BoltStore) GetProjectInvitationById(
+	ctx context.Context,
+	id int,
+) (*models.ProjectInvitation, error) {
+	var invitation models.ProjectInvitation
+	err := b.view(ctx, func(tx *bolt.Tx) error {
+		found, err := boltGet(
+			tx.Bucket(boltProjectInvitations),
+			boltId(id),
+			&invitation,
+		)
+		if err == nil && !found {
+			return fmt.Errorf(
+				"project invitation with ID %d: %w",
+				id,
+				ErrResourceNotFound,
+			)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (b *BoltStore) GetProjectInvitationsByEmail(
+	ctx context.Context,
+	email string,
+) ([]models.ProjectInvitation, error) {
+	var invitations []models.ProjectInvitation
+	err := b.view(ctx, func(tx *bolt.Tx) (err error) {
+		invitations, err = boltListIndexed[models.ProjectInvitation](
+			tx.Bucket(boltInvitationsByEmail),
+			boltEmailPrefix(NormalizeEmail(email)),
+			tx.Bucket(boltProjectInvitations),
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (b *BoltStore) ImportProject(
+	ctx context.Context,
+	project *models.Project,
+) error {
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltProjects)
+		if err := advanceBoltSequence(bucket, project.Id); err != nil {
+			return err
+		}
+		return boltPut(bucket, boltId(project.Id), project)
+	})
+}
+
+func (b *BoltStore) ImportTask(ctx context.Context, task *models.Task) error {
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		var existingTask models.Task
+		found, err := boltGet(tx.Bucket(boltTasks), boltId(task.Id), &existingTask)
+		if err != nil {
+			return err
+		}
+		if err := advanceBoltSequence(tx.Bucket(boltTasks), task.Id); err != nil {
+			return err
+		}
+		if !found {
+			return putBoltTask(tx, nil, task)
+		}
+		return putBoltTask(tx, &existingTask, task)
+	})
+}
+
+func (b *BoltStore) ImportUser(ctx context.Context, user *models.User) error {
+	importedUser := *user
+	importedUser.Email = NormalizeEmail(user.Email)
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		id := tx.Bucket(boltUsersByEmail).Get([]byte(importedUser.Email))
+		if id != nil && boltIdFrom(id) != user.Id {
+			return fmt.Errorf(
+				"user with email %s: %w",
+				importedUser.Email,
+				ErrConflict,
+			)
+		}
+		var existingUser models.User
+		found, err := boltGet(tx.Bucket(boltUsers), boltId(user.Id), &existingUser)
+		if err != nil {
+			return err
+		}
+		if err := advanceBoltSequence(tx.Bucket(boltUsers), user.Id); err != nil {
+			return err
+		}
+		if !found {
+			return putBoltUser(tx, nil, &importedUser)
+		}
+		return putBoltUser(tx, &existingUser, &importedUser)
+	})
+}
+
+func (b *BoltStore) ImportApiToken(ctx context.Context, token *models.ApiToken) error {
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		id := tx.Bucket(boltApiTokensByHash).Get([]byte(token.Hash))
+		if id != nil && boltIdFrom(id) != token.Id {
+			return fmt.Errorf("API token with ID %d: %w", token.Id, ErrConflict)
+		}
+		var existingToken models.ApiToken
+		found, err := boltGet(tx.Bucket(boltApiTokens), boltId(token.Id), &existingToken)
+		if err != nil {
+			return err
+		}
+		if found {
+			if err := deleteBoltApiToken(tx, &existingToken); err != nil {
+				return err
+			}
+		}
+		if err := advanceBoltSequence(tx.Bucket(boltApiTokens), token.Id); err != nil {
+			return err
+		}
+		return putBoltApiToken(tx, token)
+	})
+}
+
+func (b *BoltStore) ImportAuditEvent(
+	ctx context.Context,
+	event *models.AuditEvent,
+) error {
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltAuditEvents)
+		if err := advanceBoltSequence(bucket, event.Id); err != nil {
+			return err
+		}
+		return boltPut(bucket, boltId(event.Id), event)
+	})
+}
+
+func (b *BoltStore) ImportProjectInvitation(
+	ctx context.Context,
+	invitation *models.ProjectInvitation,
+) error {
+	importedInvitation := *invitation
+	importedInvitation.Email = NormalizeEmail(invitation.Email)
+	return b.update(ctx, func(tx *bolt.Tx) error {
+		invitations, err := boltListIndexed[models.ProjectInvitation](
+			tx.Bucket(boltInvitationsByEmail),
+			boltEmailPrefix(importedInvitation.Email),
+			tx.Bucket(boltProjectInvitations),
+		)
+		if err != nil {
+			return err
+		}
+		for _, inv := range invitations {
+			if inv.Id != invitation.Id && inv.ProjectId == importedInvitation.ProjectId {
+				return fmt.Errorf(
+					"invitation of %s to project with ID %d: %w",
+					importedInvitation.Email,
+					importedInvitation.ProjectId,
+					ErrConflict,
+				)
+			}
+		}
+		var existingInvitation models.ProjectInvitation
+		found, err := boltGet(
+			tx.Bucket(boltProjectInvitations),
+			boltId(invitation.Id),
+			&existingInvitation,
+		)
+		if err != nil {
+			return err
+		}
+		if found {
+			if err := deleteBoltProjectInvitation(tx, &existingInvitation); err != nil {
+				return err
+			}
+		}
+		bucket := tx.Bucket(boltProjectInvitations)
+		if err := advanceBoltSequence(bucket, invitation.Id); err != nil {
+			return err
+		}
+		return putBoltProjectInvitation(tx, &importedInvitation)
+	})
+}
+
+// ImportTaskRevision fails since BoltStore does not keep the revisions.
+func (b *BoltStore) ImportTaskRevision(
+	ctx context.Context,
+	revision *models.TaskRevision,
+) error {
+	return ErrTaskRevisionsNotRecorded
+}
+
+func (b *BoltStore) GetProjectInvitations(
+	ctx context.Context,
+) ([]models.ProjectInvitation, error) {
+	var invitations []models.ProjectInvitation
+	err := b.view(ctx, func(tx *bolt.Tx) (err error) {
+		invitations, err = boltList[models.ProjectInvitation](
+			tx.Bucket(boltProjectInvitations),
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (b *BoltStore) GetUserTokens(ctx context.Context) ([]models.UserToken, error) {
+	var tokens []models.UserToken
+	err := b.view(ctx, func(tx *bolt.Tx) (err error) {
+		tokens, err = boltList[models.UserToken](tx.Bucket(boltUserTokens))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// advanceBoltSequence makes sure the bucket hands out IDs after id.
+func advanceBoltSequence(bucket *bolt.Bucket, id int) error {
+	if uint64(id) <= bucket.Sequence() {
+		return nil
+	}
+	return bucket.SetSequence(uint64(id))
+}
+
+// putBoltTask writes the task and moves its index entries from where old,
+// which is nil for new tasks, had them.
+func putBoltTask(tx *bolt.Tx, old, task *models.Task) error {
+	if old != nil {
+		if err := deleteBoltTaskIndexes(tx, old); err != nil {
+			return err
+		}
+	}
+	if err := boltPut(tx.Bucket(boltTasks), boltId(task.Id), task); err != nil {
+		return err
+	}
+	err := tx.Bucket(boltTasksByProject).Put(
+		boltKey(boltId(task.ProjectId), boltId(task.Id)),
+		nil,
+	)
+	if err != nil || task.AssigneeId == 0 {
+		return err
+	}
+	return tx.Bucket(boltTasksByAssignee).Put(
+		boltKey(boltId(task.AssigneeId), boltId(task.Id)),
+		nil,
+	)
+}
+
+func deleteBoltTask(tx *bolt.Tx, task *models.Task) error {
+	if err := deleteBoltTaskIndexes(tx, task); err != nil {
+		return err
+	}
+	return tx.Bucket(boltTasks).Delete(boltId(task.Id))
+}
+
+func deleteBoltTaskIndexes(tx *bolt.Tx, task *models.Task) error {
+	err := tx.Bucket(boltTasksByProject).Delete(
+		boltKey(boltId(task.ProjectId), boltId(task.Id)),
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(boltTasksByAssignee).Delete(
+		boltKey(boltId(task.AssigneeId), boltId(task.Id)),
+	)
+}
+
+// unassignBoltTasks unassigns the tasks of the user that match.
+func unassignBoltTasks(
+	tx *bolt.Tx,
+	userId int,
+	matches func(task *models.Task) bool,
+) error {
+	tasks, err := boltListIndexed[models.Task](
+		tx.Bucket(boltTasksByAssignee),
+		boltId(userId),
+		tx.Bucket(boltTasks),
+	)
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		if !matches(&task) {
+			continue
+		}
+		unassignedTask := task
+		unassignedTask.AssigneeId = 0
+		if err := putBoltTask(tx, &task, &unassignedTask); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func putBoltUser(tx *bolt.Tx, old, user *models.User) error {
+	if old != nil && old.Email != user.Email {
+		if err := tx.Bucket(boltUsersByEmail).Delete([]byte(old.Email)); err != nil {
+			return err
+		}
+	}
+	if err := boltPut(tx.Bucket(boltUsers), boltId(user.Id), user); err != nil {
+		return err
+	}
+	return tx.Bucket(boltUsersByEmail).Put([]byte(user.Email), boltId(user.Id))
+}
+
+func putBoltProjectMember(tx *bolt.Tx, member *models.ProjectMember) error {
+	err := boltPut(
+		tx.Bucket(boltProjectMembers),
+		boltKey(boltId(member.ProjectId), boltId(member.UserId)),
+		member,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(boltProjectMembersByUser).Put(
+		boltKey(boltId(member.UserId), boltId(member.ProjectId)),
+		nil,
+	)
+}
+
+func deleteBoltProjectMember(tx *bolt.Tx, projectId, userId int) error {
+	err := tx.Bucket(boltProjectMembers).Delete(
+		boltKey(boltId(projectId), boltId(userId)),
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(boltProjectMembersByUser).Delete(
+		boltKey(boltId(userId), boltId(projectId)),
+	)
+}
+
+func putBoltProjectInvitation(tx *bolt.Tx, invitation *models.ProjectInvitation) error {
+	err := boltPut(tx.Bucket(boltProjectInvitations), boltId(invitation.Id), invitation)
+	if err != nil {
+		return err
+	}
+	err = tx.Bucket(boltInvitationsByEmail).Put(
+		boltKey(boltEmailPrefix(invitation.Email), boltId(invitation.Id)),
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(boltInvitationsByProject).Put(
+		boltKey(boltId(invitation.ProjectId), boltId(invitation.Id)),
+		nil,
+	)
+}
+
+func deleteBoltProjectInvitation(
+	tx *bolt.Tx,
+	invitation *models.ProjectInvitation,
+) error {
+	err := tx.Bucket(boltProjectInvitations).Delete(boltId(invitation.Id))
+	if err != nil {
+		return err
+	}
+	err = tx.Bucket(boltInvitationsByEmail).Delete(
+		boltKey(boltEmailPrefix(invitation.Email), boltId(invitation.Id)),
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(boltInvitationsByProject).Delete(
+		boltKey(boltId(invitation.ProjectId), boltId(invitation.Id)),
+	)
+}
+
+func deleteBoltUserToken(tx *bolt.Tx, token *models.UserToken) error {
+	if err := tx.Bucket(boltUserTokens).Delete([]byte(token.Hash)); err != nil {
+		return err
+	}
+	return tx.Bucket(boltUserTokensByUser).Delete(
+		boltKey(boltId(token.UserId), []byte(token.Hash)),
+	)
+}
+
+// deleteBoltUserTokens deletes the tokens of the user with the purpose, or
+// all of them when purpose is empty.
+func deleteBoltUserTokens(tx *bolt.Tx, userId int, purpose string) error {
+	tokens, err := boltListIndexed[models.UserToken](
+		tx.Bucket(boltUserTokensByUser),
+		boltId(userId),
+		tx.Bucket(boltUserTokens),
+	)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if purpose != "" && token.Purpose != purpose {
+			continue
+		}
+		if err := deleteBoltUserToken(tx, &token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func putBoltApiToken(tx *bolt.Tx, token *models.ApiToken) error {
+	err := boltPut(tx.Bucket(boltApiTokens), boltId(token.Id), token)
+	if err != nil {
+		return err
+	}
+	err = tx.Bucket(boltApiTokensByHash).Put([]byte(token.Hash), boltId(token.Id))
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(boltApiTokensByUser).Put(
+		boltKey(boltId(token.UserId), boltId(token.Id)),
+		nil,
+	)
+}
+
+func deleteBoltApiToken(tx *bolt.Tx, token *models.ApiToken) error {
+	if err := tx.Bucket(boltApiTokens).Delete(boltId(token.Id)); err != nil {
+		return err
+	}
+	if err := tx.Bucket(boltApiTokensByHash).Delete([]byte(token.Hash)); err != nil {
+		return err
+	}
+	return tx.Bucket(boltApiTokensByUser).Delete(
+		boltKey(boltId(token.UserId), boltId(token.Id)),
+	)
+}
+
+func deleteBoltApiTokens(tx *bolt.Tx, userId int) error {
+	tokens, err := boltListIndexed[models.ApiToken](
+		tx.Bucket(boltApiTokensByUser),
+		boltId(userId),
+		tx.Bucket(boltApiTokens),
+	)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		if err := deleteBoltApiToken(tx, &token); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeBoltUserFromProjects removes the user from their projects, unassigning
+// their tasks, and deletes the projects nobody is left in, along with their
+// tasks and invitations.
+func removeBoltUserFromProjects(tx *bolt.Tx, userId int) error {
+	var projectIds [][]byte
+	err := boltScan(
+		tx.Bucket(boltProjectMembersByUser),
+		boltId(userId),
+		func(projectId, _ []byte) error {
+			projectIds = append(projectIds, bytes.Clone(projectId))
+			return nil
+		},
+	)
+	if err != nil {
+		return err
+	}
+	for _, projectId := range projectIds {
+		err := deleteBoltProjectMember(tx, boltIdFrom(projectId), userId)
+		if err != nil {
+			return err
+		}
+	}
+	err = unassignBoltTasks(tx, userId, func(*models.Task) bool { return true })
+	if err != nil {
+		return err
+	}
+
+	projects, err := boltList[models.Project](tx.Bucket(boltProjects))
+	if err != nil {
+		return err
+	}
+	for _, project := range projects {
+		prefix := boltId(project.Id)
+		key, _ := tx.Bucket(boltProjectMembers).Cursor().Seek(prefix)
+		if bytes.HasPrefix(key, prefix) {
+			continue
+		}
+		if err := deleteBoltProject(tx, project.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteBoltProject(tx *bolt.Tx, projectId int) error {
+	tasks, err := boltListIndexed[models.Task](
+		tx.Bucket(boltTasksByProject),
+		boltId(projectId),
+		tx.Bucket(boltTasks),
+	)
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		if err := deleteBoltTask(tx, &task); err != nil {
+			return err
+		}
+	}
+	invitations, err := boltListIndexed[models.ProjectInvitation](
+		tx.Bucket(boltInvitationsByProject),
+		boltId(projectId),
+		tx.Bucket(boltProjectInvitations),
+	)
+	if err != nil {
+		return err
+	}
+	for _, invitation := range invitations {
+		if err := deleteBoltProjectInvitation(tx, &invitation); err != nil {
+			return err
+		}
+	}
+	return tx.Bucket(boltProjects).Delete(boltId(projectId))
+}
+
+func boltId(id int) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(id))
+}
+
+func boltIdFrom(key []byte) int {
+	return int(binary.BigEndian.Uint64(key))
+}
+
+func boltKey(prefix, id []byte) []byte {
+	return append(bytes.Clone(prefix), id...)
+}
+
+// boltEmailPrefix ends the email with a byte emails cannot contain so that
+// one email is never the prefix of another.
+func boltEmailPrefix(email string) []byte {
+	return append([]byte(email), 0)
+}
+
+func boltPut(bucket *bolt.Bucket, key []byte, value any) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key, encoded)
+}
+
+func boltGet(bucket *bolt.Bucket, key []byte, value any) (bool, error) {
+	encoded := bucket.Get(key)
+	if encoded == nil {
+		return false, nil
+	}
+	return true, json.Unmarshal(encoded, value)
+}
+
+func boltList[T any](bucket *bolt.Bucket) ([]T, error) {
+	var values []T
+	err := bucket.ForEach(func(_, encoded []byte) error {
+		var value T
+		if err := json.Unmarshal(encoded, &value); err != nil {
+			return err
+		}
+		values = append(values, value)
+		return nil
+	})
+	return values, err
+}
+
+// boltScan calls fn with the rest of each key in the bucket that starts with
+// prefix, in key order.
+func boltScan(
+	bucket *bolt.Bucket,
+	prefix []byte,
+	fn func(rest, value []byte) error,
+) error {
+	cursor := bucket.Cursor()
+	for key, value := cursor.Seek(prefix); bytes.HasPrefix(key, prefix); key, value = cursor.Next() {
+		if err := fn(key[len(prefix):], value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// boltListIndexed looks up the records whose keys follow prefix in the index.
+func boltListIndexed[T any](
+	index *bolt.Bucket,
+	prefix []byte,
+	records *bolt.Bucket,
+) ([]T, error) {
+	var values []T
+	err := boltScan(index, prefix, func(key, _ []byte) error {
+		var value T
+		found, err := boltGet(records, key, &value)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("index entry without a record, %x", key)
+		}
+		values = append(values, value)
+		return nil
+	})
+	return values, err
+}
@@ -0,0 +1,78 @@
+package data_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestBoltStoreReopen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "data.db")
+
+	store, err := data.NewBoltStore(path)
+	assert.HasNoError(t, err)
+	user, err := store.CreateUser(ctx, &models.CreateUserDTO{
+		Name:     "Harry Potter",
+		Email:    "harry@hogwarts.edu",
+		Password: "Caput Draconis",
+	})
+	assert.HasNoError(t, err)
+	project, err := store.CreateProject(
+		ctx,
+		&models.CreateProjectDTO{Name: "Quidditch"},
+		user.Id,
+	)
+	assert.HasNoError(t, err)
+	task, err := store.CreateTask(ctx, &models.CreateTaskDTO{
+		Title:      "Catch the snitch",
+		ProjectId:  project.Id,
+		AssigneeId: user.Id,
+	})
+	assert.HasNoError(t, err)
+	assert.HasNoError(t, store.Close())
+
+	store, err = data.NewBoltStore(path)
+	assert.HasNoError(t, err)
+	defer store.Close()
+
+	reloadedUser, err := store.GetUserByEmail(ctx, user.Email)
+	assert.HasNoError(t, err)
+	assert.Equals(t, *reloadedUser, *user)
+	tasks, err := store.GetTasksByProjectId(ctx, project.Id)
+	assert.HasNoError(t, err)
+	assert.Equals(t, tasks, []models.Task{*task})
+
+	nextTask, err := store.CreateTask(ctx, models.NewCreateTaskDTO("Win", project.Id))
+	assert.HasNoError(t, err)
+	assert.Equals(t, nextTask.Id, task.Id+1)
+}
+
+func TestBoltStoreLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.db")
+
+	store, err := data.NewBoltStore(path)
+	assert.HasNoError(t, err)
+	defer store.Close()
+
+	_, err = data.NewBoltStore(path)
+	assert.ErrorContains(t, err, data.ErrStoreLocked)
+}
+
+func TestBoltStoreCancelledContext(t *testing.T) {
+	store, err := data.NewBoltStore(filepath.Join(t.TempDir(), "data.db"))
+	assert.HasNoError(t, err)
+	defer store.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = store.CreateTask(ctx, models.NewCreateTaskDTO("Buy milk", 1))
+	assert.ErrorContains(t, err, context.Canceled)
+	_, err = store.GetTasks(ctx)
+	assert.ErrorContains(t, err, context.Canceled)
+}
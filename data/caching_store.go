@@ -0,0 +1,264 @@
+package data
+
+import (
+	"container/list"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+// CachingStore serves GetTaskById, GetTasks and GetTasksByProjectId from an
+// LRU cache in front of another store, and drops the cached tasks a write
+// through it may have changed. Writes that bypass it, such as those of
+// another server sharing the database, show once the entries expire.
+//
+// Entries are kept per user, as set with WithReader, so that a cached read
+// is only ever served to the user it was made for. Writes drop the entries
+// of every user, since tasks are shared within projects.
+type CachingStore struct {
+	Store
+
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[cacheKey]*list.Element
+	// recent holds the entries from the most to the least recently used.
+	recent *list.List
+	// generation changes with every invalidation, so that reads that started
+	// before it do not cache what they got.
+	generation uint64
+	stats      CacheStats
+}
+
+type CacheStats struct {
+	Hits          int64 `json:"hits"`
+	Misses        int64 `json:"misses"`
+	Evictions     int64 `json:"evictions"`
+	Invalidations int64 `json:"invalidations"`
+}
+
+type cacheKind int
+
+const (
+	cachedTask cacheKind = iota
+	cachedTasks
+	cachedProjectTasks
+)
+
+type cacheKey struct {
+	userId int
+	kind   cacheKind
+	id     int
+}
+
+type cacheEntry struct {
+	key       cacheKey
+	task      models.Task
+	tasks     []models.Task
+	expiresAt time.Time
+}
+
+// NewCachingStore caches up to size task reads of store for ttl each.
+func NewCachingStore(store Store, size int, ttl time.Duration) *CachingStore {
+	return &CachingStore{
+		Store:   store,
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[cacheKey]*list.Element),
+		recent:  list.New(),
+	}
+}
+
+// Stats counts the cache hits, misses, evictions and invalidations so far.
+func (c *CachingStore) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+func (c *CachingStore) GetTaskById(ctx context.Context, id int) (*models.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	key := cacheKey{readerFromContext(ctx), cachedTask, id}
+	entry, generation, ok := c.get(key)
+	if ok {
+		return &entry.task, nil
+	}
+	task, err := c.Store.GetTaskById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.put(&cacheEntry{key: key, task: *task}, generation)
+	return task, nil
+}
+
+func (c *CachingStore) GetTasks(ctx context.Context) ([]models.Task, error) {
+	key := cacheKey{readerFromContext(ctx), cachedTasks, 0}
+	return c.getTasks(ctx, key, func() ([]models.Task, error) {
+		return c.Store.GetTasks(ctx)
+	})
+}
+
+func (c *CachingStore) GetTasksByProjectId(
+	ctx context.Context,
+	projectId int,
+) ([]models.Task, error) {
+	key := cacheKey{readerFromContext(ctx), cachedProjectTasks, projectId}
+	return c.getTasks(ctx, key, func() ([]models.Task, error) {
+		return c.Store.GetTasksByProjectId(ctx, projectId)
+	})
+}
+
+func (c *CachingStore) getTasks(
+	ctx context.Context,
+	key cacheKey,
+	read func() ([]models.Task, error),
+) ([]models.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	entry, generation, ok := c.get(key)
+	if ok {
+		return slices.Clone(entry.tasks), nil
+	}
+	tasks, err := read()
+	if err != nil {
+		return nil, err
+	}
+	c.put(&cacheEntry{key: key, tasks: slices.Clone(tasks)}, generation)
+	return tasks, nil
+}
+
+func (c *CachingStore) CreateTask(
+	ctx context.Context,
+	dto *models.CreateTaskDTO,
+) (*models.Task, error) {
+	defer c.invalidate()
+	return c.Store.CreateTask(ctx, dto)
+}
+
+func (c *CachingStore) DeleteTaskById(ctx context.Context, id int) error {
+	defer c.invalidate()
+	return c.Store.DeleteTaskById(ctx, id)
+}
+
+func (c *CachingStore) UpdateTask(
+	ctx context.Context,
+	task *models.Task,
+) (*models.Task, error) {
+	defer c.invalidate()
+	return c.Store.UpdateTask(ctx, task)
+}
+
+// DeleteProjectMember unassigns the tasks of the member.
+func (c *CachingStore) DeleteProjectMember(
+	ctx context.Context,
+	projectId, userId int,
+) error {
+	defer c.invalidate()
+	return c.Store.DeleteProjectMember(ctx, projectId, userId)
+}
+
+// DeleteUserById unassigns the tasks of the user and deletes those of the
+// projects nobody is left in.
+func (c *CachingStore) DeleteUserById(ctx context.Context, id int) error {
+	defer c.invalidate()
+	return c.Store.DeleteUserById(ctx, id)
+}
+
+// GetTaskHistory passes through to stores that record the history.
+func (c *CachingStore) GetTaskHistory(
+	ctx context.Context,
+	id int,
+) ([]models.TaskEvent, error) {
+	historian, ok := c.Store.(TaskHistorian)
+	if !ok {
+		return nil, ErrTaskHistoryNotRecorded
+	}
+	return historian.GetTaskHistory(ctx, id)
+}
+
+// GetTaskRevisions passes through to stores that record the revisions.
+func (c *CachingStore) GetTaskRevisions(
+	ctx context.Context,
+	taskId int,
+) ([]models.TaskRevision, error) {
+	reviser, ok := c.Store.(TaskReviser)
+	if !ok {
+		return nil, ErrTaskRevisionsNotRecorded
+	}
+	return reviser.GetTaskRevisions(ctx, taskId)
+}
+
+func (c *CachingStore) GetTaskRevision(
+	ctx context.Context,
+	taskId, revision int,
+) (*models.TaskRevision, error) {
+	reviser, ok := c.Store.(TaskReviser)
+	if !ok {
+		return nil, ErrTaskRevisionsNotRecorded
+	}
+	return reviser.GetTaskRevision(ctx, taskId, revision)
+}
+
+// get returns the entry for key, or the generation to put the entry with
+// after reading it from the store.
+func (c *CachingStore) get(key cacheKey) (*cacheEntry, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, c.generation, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.recent.Remove(element)
+		delete(c.entries, key)
+		c.stats.Misses++
+		return nil, c.generation, false
+	}
+	c.recent.MoveToFront(element)
+	c.stats.Hits++
+	// The entry is copied so that callers cannot change the cached task.
+	copied := *entry
+	return &copied, c.generation, true
+}
+
+func (c *CachingStore) put(entry *cacheEntry, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation || c.size <= 0 {
+		return
+	}
+	entry.expiresAt = c.now().Add(c.ttl)
+	if element, ok := c.entries[entry.key]; ok {
+		element.Value = entry
+		c.recent.MoveToFront(element)
+		return
+	}
+	c.entries[entry.key] = c.recent.PushFront(entry)
+	for c.recent.Len() > c.size {
+		oldest := c.recent.Back()
+		c.recent.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+		c.stats.Evictions++
+	}
+}
+
+// invalidate drops every entry. A write can change which tasks GetTasks and
+// every project list return, which are most of the entries anyway.
+func (c *CachingStore) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	clear(c.entries)
+	c.recent.Init()
+	c.stats.Invalidations++
+}
@@ -0,0 +1,170 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestCachingStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("serves repeated reads from the cache", func(t *testing.T) {
+		store := newTestCachingStore(t, 10, time.Minute)
+
+		for range 3 {
+			task, err := store.GetTaskById(ctx, 1)
+			assert.HasNoError(t, err)
+			assert.Equals(t, task.Title, "Buy milk")
+			tasks, err := store.GetTasksByProjectId(ctx, 1)
+			assert.HasNoError(t, err)
+			assert.HasLength(t, tasks, 2)
+		}
+		assert.Equals(t, store.Stats(), CacheStats{Hits: 4, Misses: 2})
+	})
+
+	t.Run("returns what a write through it changed", func(t *testing.T) {
+		store := newTestCachingStore(t, 10, time.Minute)
+		_, err := store.GetTaskById(ctx, 1)
+		assert.HasNoError(t, err)
+		_, err = store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+
+		_, err = store.UpdateTask(ctx, models.NewTask(1, "Buy oat milk", 1))
+		assert.HasNoError(t, err)
+		task, err := store.GetTaskById(ctx, 1)
+		assert.HasNoError(t, err)
+		assert.Equals(t, task.Title, "Buy oat milk")
+
+		assert.HasNoError(t, store.DeleteTaskById(ctx, 2))
+		tasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, tasks, []models.Task{*task})
+		assert.Equals(t, store.Stats().Invalidations, int64(2))
+	})
+
+	t.Run("keeps the entries of each user apart", func(t *testing.T) {
+		store := newTestCachingStore(t, 10, time.Minute)
+		for _, userId := range []int{1, 2, 1} {
+			_, err := store.GetTaskById(WithReader(ctx, userId), 1)
+			assert.HasNoError(t, err)
+			_, err = store.GetTasks(WithReader(ctx, userId))
+			assert.HasNoError(t, err)
+		}
+		assert.Equals(t, store.Stats(), CacheStats{Hits: 2, Misses: 4})
+
+		_, err := store.UpdateTask(
+			WithReader(ctx, 2),
+			models.NewTask(1, "Buy oat milk", 1),
+		)
+		assert.HasNoError(t, err)
+		task, err := store.GetTaskById(WithReader(ctx, 1), 1)
+		assert.HasNoError(t, err)
+		assert.Equals(t, task.Title, "Buy oat milk")
+	})
+
+	t.Run("expires entries after the TTL", func(t *testing.T) {
+		store := newTestCachingStore(t, 10, time.Minute)
+		now := time.Now()
+		store.now = func() time.Time { return now }
+		_, err := store.GetTaskById(ctx, 1)
+		assert.HasNoError(t, err)
+		_, err = store.Store.UpdateTask(ctx, models.NewTask(1, "Buy oat milk", 1))
+		assert.HasNoError(t, err)
+
+		task, err := store.GetTaskById(ctx, 1)
+		assert.HasNoError(t, err)
+		assert.Equals(t, task.Title, "Buy milk")
+		now = now.Add(time.Minute)
+		task, err = store.GetTaskById(ctx, 1)
+		assert.HasNoError(t, err)
+		assert.Equals(t, task.Title, "Buy oat milk")
+	})
+
+	t.Run("evicts the least recently used entry", func(t *testing.T) {
+		store := newTestCachingStore(t, 2, time.Minute)
+		for _, id := range []int{1, 2, 1} {
+			_, err := store.GetTaskById(ctx, id)
+			assert.HasNoError(t, err)
+		}
+		_, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+
+		_, err = store.GetTaskById(ctx, 1)
+		assert.HasNoError(t, err)
+		_, err = store.GetTaskById(ctx, 2)
+		assert.HasNoError(t, err)
+		assert.Equals(t, store.Stats(), CacheStats{Hits: 2, Misses: 4, Evictions: 2})
+	})
+
+	t.Run("does not let callers change the cached tasks", func(t *testing.T) {
+		store := newTestCachingStore(t, 10, time.Minute)
+		task, err := store.GetTaskById(ctx, 1)
+		assert.HasNoError(t, err)
+		task.Title = "Changed"
+		tasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		tasks[0].Title = "Changed"
+
+		task, err = store.GetTaskById(ctx, 1)
+		assert.HasNoError(t, err)
+		assert.Equals(t, task.Title, "Buy milk")
+		task.Title = "Changed"
+		task, err = store.GetTaskById(ctx, 1)
+		assert.HasNoError(t, err)
+		assert.Equals(t, task.Title, "Buy milk")
+		tasks, err = store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, tasks[0].Title, "Buy milk")
+	})
+
+	t.Run("does not cache a read that started before a write", func(t *testing.T) {
+		store := newTestCachingStore(t, 10, time.Minute)
+		_, generation, _ := store.get(cacheKey{0, cachedTask, 1})
+		stale, err := store.Store.GetTaskById(ctx, 1)
+		assert.HasNoError(t, err)
+		_, err = store.UpdateTask(ctx, models.NewTask(1, "Buy oat milk", 1))
+		assert.HasNoError(t, err)
+		store.put(&cacheEntry{key: cacheKey{0, cachedTask, 1}, task: *stale}, generation)
+
+		task, err := store.GetTaskById(ctx, 1)
+		assert.HasNoError(t, err)
+		assert.Equals(t, task.Title, "Buy oat milk")
+	})
+
+	t.Run("does not cache errors", func(t *testing.T) {
+		store := newTestCachingStore(t, 10, time.Minute)
+		for range 2 {
+			_, err := store.GetTaskById(ctx, 10)
+			assert.Equals(t, errors.Is(err, ErrResourceNotFound), true)
+		}
+		assert.Equals(t, store.Stats().Misses, int64(2))
+	})
+
+	t.Run("caches nothing with a size of 0", func(t *testing.T) {
+		store := newTestCachingStore(t, 0, time.Minute)
+		for range 2 {
+			_, err := store.GetTaskById(ctx, 1)
+			assert.HasNoError(t, err)
+		}
+		assert.Equals(t, store.Stats().Hits, int64(0))
+	})
+}
+
+func newTestCachingStore(t *testing.T, size int, ttl time.Duration) *CachingStore {
+	t.Helper()
+	store, err := NewMemoryStore()
+	assert.HasNoError(t, err)
+	for _, title := range []string{"Buy milk", "Walk the dog"} {
+		_, err := store.CreateTask(
+			context.Background(),
+			models.NewCreateTaskDTO(title, 1),
+		)
+		assert.HasNoError(t, err)
+	}
+	return NewCachingStore(store, size, ttl)
+}
@@ -0,0 +1,7 @@
+package data
+
+import "strings"
+
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
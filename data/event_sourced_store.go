@@ -0,0 +1,479 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+// defaultTaskSnapshotEvery is the number of events between snapshots, which
+// bounds how many events opening the store replays.
+const defaultTaskSnapshotEvery = 100
+
+// EventSourcedStore keeps the tasks of a SQLite database as the log of the
+// changes made to them, and serves them from the state the log adds up to.
+// Everything else is stored as by SqliteStore. The state lives in memory, so
+// only one process may use the database at a time.
+type EventSourcedStore struct {
+	*SqliteStore
+
+	mu            sync.RWMutex
+	tasks         map[int]models.Task
+	lastTaskId    int
+	lastEventId   int
+	snapshotEvery int
+	now           func() time.Time
+}
+
+type EventSourcedStoreOption func(*EventSourcedStore)
+
+// WithTaskSnapshotEvery saves the state after every given number of events.
+func WithTaskSnapshotEvery(events int) EventSourcedStoreOption {
+	return func(e *EventSourcedStore) {
+		e.snapshotEvery = events
+	}
+}
+
+// NewEventSourcedStore rebuilds the state from the last snapshot and the
+// events after it. The first time, the log starts with the tasks already in
+// the tasks table.
+func NewEventSourcedStore(
+	ctx context.Context,
+	db *sql.DB,
+	options ...EventSourcedStoreOption,
+) (*EventSourcedStore, error) {
+	e := &EventSourcedStore{
+		SqliteStore:   NewSqliteStore(db),
+		tasks:         make(map[int]models.Task),
+		snapshotEvery: defaultTaskSnapshotEvery,
+		now:           time.Now,
+	}
+	for _, option := range options {
+		option(e)
+	}
+	if err := e.load(ctx); err != nil {
+		return nil, fmt.Errorf("problem replaying the task events, %v", err)
+	}
+	if e.lastEventId == 0 {
+		if err := e.importTasksTable(ctx); err != nil {
+			return nil, fmt.Errorf("problem importing the tasks, %v", err)
+		}
+	}
+	return e, nil
+}
+
+func (e *EventSourcedStore) load(ctx context.Context) error {
+	var tasks string
+	err := e.queryRow(ctx, `
+		select event_id, last_task_id, tasks
+		from task_snapshots
+		order by event_id desc
+		limit 1
+	`).Scan(&e.lastEventId, &e.lastTaskId, &tasks)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err == nil {
+		var snapshot []models.Task
+		if err := json.Unmarshal([]byte(tasks), &snapshot); err != nil {
+			return err
+		}
+		for _, task := range snapshot {
+			e.tasks[task.Id] = task
+		}
+	}
+
+	rows, err := e.query(ctx, `
+		select `+taskEventColumns+`
+		from task_events
+		where id > ?
+		order by id
+	`, e.lastEventId)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		event, err := scanTaskEvent(rows)
+		if err != nil {
+			return err
+		}
+		e.apply(event)
+	}
+	return rows.Err()
+}
+
+func (e *EventSourcedStore) importTasksTable(ctx context.Context) error {
+	tasks, err := e.SqliteStore.GetTasks(ctx)
+	if err != nil {
+		return err
+	}
+	var events []models.TaskEvent
+	for _, task := range tasks {
+		events = append(events, models.TaskEvent{Type: models.TaskCreated, Task: task})
+	}
+	if err := e.append(ctx, events...); err != nil {
+		return err
+	}
+	// The IDs of deleted tasks are not handed out again.
+	var lastTaskId int
+	err = e.queryRow(ctx, `
+		select seq from sqlite_sequence where name = 'tasks'
+	`).Scan(&lastTaskId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	e.lastTaskId = max(e.lastTaskId, lastTaskId)
+	return nil
+}
+
+// append records the events and applies them. The caller holds e.mu.
+func (e *EventSourcedStore) append(ctx context.Context, events ...models.TaskEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for i := range events {
+		events[i].CreatedAt = e.now().UTC()
+		result, err := e.execTx(ctx, tx, `
+			insert into task_events
+				(type, task_id, title, project_id, assignee_id, created_at)
+			values (?, ?, ?, ?, ?, ?)
+		`,
+			events[i].Type,
+			events[i].Task.Id,
+			events[i].Task.Title,
+			events[i].Task.ProjectId,
+			events[i].Task.AssigneeId,
+			events[i].CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		events[i].Id = int(id)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	previousEventId := e.lastEventId
+	for _, event := range events {
+		e.apply(event)
+	}
+	if e.lastEventId/e.snapshotEvery > previousEventId/e.snapshotEvery {
+		// The events are saved, so a missing snapshot only slows down the
+		// next start.
+		if err := e.snapshot(ctx); err != nil {
+			log.Println("error saving a snapshot of the tasks:", err)
+		}
+	}
+	return nil
+}
+
+func (e *EventSourcedStore) apply(event models.TaskEvent) {
+	if event.Type == models.TaskDeleted {
+		delete(e.tasks, event.Task.Id)
+	} else {
+		e.tasks[event.Task.Id] = event.Task
+	}
+	e.lastTaskId = max(e.lastTaskId, event.Task.Id)
+	e.lastEventId = event.Id
+}
+
+// snapshot saves the state and drops the snapshots it replaces. The caller
+// holds e.mu.
+func (e *EventSourcedStore) snapshot(ctx context.Context) error {
+	tasks, err := json.Marshal(e.sortedTasks(func(models.Task) bool { return true }))
+	if err != nil {
+		return err
+	}
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = e.execTx(ctx, tx, `
+		insert into task_snapshots (event_id, last_task_id, tasks, created_at)
+		values (?, ?, ?, ?)
+	`, e.lastEventId, e.lastTaskId, string(tasks), e.now().UTC())
+	if err != nil {
+		return err
+	}
+	_, err = e.execTx(ctx, tx, `
+		delete from task_snapshots where event_id < ?
+	`, e.lastEventId)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (e *EventSourcedStore) sortedTasks(keep func(models.Task) bool) []models.Task {
+	var tasks []models.Task
+	for _, task := range e.tasks {
+		if keep(task) {
+			tasks = append(tasks, task)
+		}
+	}
+	slices.SortFunc(tasks, func(a, b models.Task) int { return a.Id - b.Id })
+	return tasks
+}
+
+func (e *EventSourcedStore) CreateTask(
+	ctx context.Context,
+	dto *models.CreateTaskDTO,
+) (*models.Task, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	task := models.Task{
+		Id:         e.lastTaskId + 1,
+		Title:      dto.Title,
+		ProjectId:  dto.ProjectId,
+		AssigneeId: dto.AssigneeId,
+	}
+	err := e.append(ctx, models.TaskEvent{Type: models.TaskCreated, Task: task})
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (e *EventSourcedStore) DeleteTaskById(ctx context.Context, id int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	task, ok := e.tasks[id]
+	if !ok {
+		return fmt.Errorf("task with ID %d: %w", id, ErrResourceNotFound)
+	}
+	return e.append(ctx, models.TaskEvent{Type: models.TaskDeleted, Task: task})
+}
+
+func (e *EventSourcedStore) GetTaskById(ctx context.Context, id int) (*models.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	task, ok := e.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("task with ID %d: %w", id, ErrResourceNotFound)
+	}
+	return &task, nil
+}
+
+func (e *EventSourcedStore) GetTasks(ctx context.Context) ([]models.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.sortedTasks(func(models.Task) bool { return true }), nil
+}
+
+func (e *EventSourcedStore) GetTasksByProjectId(
+	ctx context.Context,
+	projectId int,
+) ([]models.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.sortedTasks(func(task models.Task) bool {
+		return task.ProjectId == projectId
+	}), nil
+}
+
+// UpdateTask records a TaskRenamed event when the title changes and a
+// TaskAssigned event when the assignee does.
+func (e *EventSourcedStore) UpdateTask(
+	ctx context.Context,
+	task *models.Task,
+) (*models.Task, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	current, ok := e.tasks[task.Id]
+	if !ok {
+		return nil, fmt.Errorf("task with ID %d: %w", task.Id, ErrResourceNotFound)
+	}
+	updated := current
+	var events []models.TaskEvent
+	if task.Title != current.Title {
+		updated.Title = task.Title
+		events = append(events, models.TaskEvent{Type: models.TaskRenamed, Task: updated})
+	}
+	if task.AssigneeId != current.AssigneeId {
+		updated.AssigneeId = task.AssigneeId
+		events = append(events, models.TaskEvent{Type: models.TaskAssigned, Task: updated})
+	}
+	if err := e.append(ctx, events...); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// ImportTask records the task as created, or as deleted and created again
+// when it replaces a task of another project.
+func (e *EventSourcedStore) ImportTask(ctx context.Context, task *models.Task) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	current, ok := e.tasks[task.Id]
+	if !ok {
+		return e.append(ctx, models.TaskEvent{Type: models.TaskCreated, Task: *task})
+	}
+	if current.ProjectId != task.ProjectId {
+		return e.append(
+			ctx,
+			models.TaskEvent{Type: models.TaskDeleted, Task: current},
+			models.TaskEvent{Type: models.TaskCreated, Task: *task},
+		)
+	}
+	var events []models.TaskEvent
+	if task.Title != current.Title {
+		current.Title = task.Title
+		events = append(events, models.TaskEvent{Type: models.TaskRenamed, Task: current})
+	}
+	if task.AssigneeId != current.AssigneeId {
+		current.AssigneeId = task.AssigneeId
+		events = append(events, models.TaskEvent{Type: models.TaskAssigned, Task: current})
+	}
+	return e.append(ctx, events...)
+}
+
+// DeleteProjectMember records the unassignment of the member's tasks.
+func (e *EventSourcedStore) DeleteProjectMember(
+	ctx context.Context,
+	projectId, userId int,
+) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.SqliteStore.DeleteProjectMember(ctx, projectId, userId); err != nil {
+		return err
+	}
+	var events []models.TaskEvent
+	for _, task := range e.sortedTasks(func(task models.Task) bool {
+		return task.ProjectId == projectId && task.AssigneeId == userId
+	}) {
+		task.AssigneeId = 0
+		events = append(events, models.TaskEvent{Type: models.TaskAssigned, Task: task})
+	}
+	return e.append(ctx, events...)
+}
+
+// DeleteUserById records the unassignment of the user's tasks and the
+// deletion of the tasks of the projects the user was the last member of.
+func (e *EventSourcedStore) DeleteUserById(ctx context.Context, id int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.SqliteStore.DeleteUserById(ctx, id); err != nil {
+		return err
+	}
+	deletedProjects := make(map[int]bool)
+	var events []models.TaskEvent
+	for _, task := range e.sortedTasks(func(models.Task) bool { return true }) {
+		deleted, ok := deletedProjects[task.ProjectId]
+		if !ok {
+			_, err := e.GetProjectById(ctx, task.ProjectId)
+			if err != nil && !errors.Is(err, ErrResourceNotFound) {
+				return err
+			}
+			deleted = err != nil
+			deletedProjects[task.ProjectId] = deleted
+		}
+		if deleted {
+			events = append(events, models.TaskEvent{Type: models.TaskDeleted, Task: task})
+		} else if task.AssigneeId == id {
+			task.AssigneeId = 0
+			events = append(events, models.TaskEvent{Type: models.TaskAssigned, Task: task})
+		}
+	}
+	return e.append(ctx, events...)
+}
+
+func (e *EventSourcedStore) GetTaskHistory(
+	ctx context.Context,
+	id int,
+) ([]models.TaskEvent, error) {
+	rows, err := e.query(ctx, `
+		select `+taskEventColumns+`
+		from task_events
+		where task_id = ?
+		order by id
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []models.TaskEvent
+	for rows.Next() {
+		event, err := scanTaskEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("task with ID %d: %w", id, ErrResourceNotFound)
+	}
+	return events, nil
+}
+
+// GetTaskRevisions is not served from the task_revisions table, which only
+// SqliteStore writes to; the history of the task covers its revisions.
+func (e *EventSourcedStore) GetTaskRevisions(
+	ctx context.Context,
+	taskId int,
+) ([]models.TaskRevision, error) {
+	return nil, ErrTaskRevisionsNotRecorded
+}
+
+func (e *EventSourcedStore) GetTaskRevision(
+	ctx context.Context,
+	taskId, revision int,
+) (*models.TaskRevision, error) {
+	return nil, ErrTaskRevisionsNotRecorded
+}
+
+func (e *EventSourcedStore) ImportTaskRevision(
+	ctx context.Context,
+	revision *models.TaskRevision,
+) error {
+	return ErrTaskRevisionsNotRecorded
+}
+
+const taskEventColumns = `
+	id, type, task_id, title, project_id, assignee_id, created_at
+`
+
+func scanTaskEvent(row rowScanner) (models.TaskEvent, error) {
+	var event models.TaskEvent
+	err := row.Scan(
+		&event.Id,
+		&event.Type,
+		&event.Task.Id,
+		&event.Task.Title,
+		&event.Task.ProjectId,
+		&event.Task.AssigneeId,
+		&event.CreatedAt,
+	)
+	return event, err
+}
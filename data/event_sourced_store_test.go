@@ -0,0 +1,144 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestEventSourcedStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("records every change made to a task", func(t *testing.T) {
+		store := newTestEventSourcedStore(t, openTestEventSourcedDb(t))
+		task, err := store.CreateTask(ctx, models.NewCreateTaskDTO("Buy milk", 1))
+		assert.HasNoError(t, err)
+		renamed := *task
+		renamed.Title = "Buy oat milk"
+		_, err = store.UpdateTask(ctx, &renamed)
+		assert.HasNoError(t, err)
+		assigned := renamed
+		assigned.AssigneeId = 1
+		_, err = store.UpdateTask(ctx, &assigned)
+		assert.HasNoError(t, err)
+		_, err = store.UpdateTask(ctx, &assigned)
+		assert.HasNoError(t, err)
+		assert.HasNoError(t, store.DeleteTaskById(ctx, task.Id))
+
+		history, err := store.GetTaskHistory(ctx, task.Id)
+		assert.HasNoError(t, err)
+		var types []string
+		var states []models.Task
+		for _, event := range history {
+			types = append(types, event.Type)
+			states = append(states, event.Task)
+			if event.CreatedAt.IsZero() {
+				t.Errorf("got event %+v without a time", event)
+			}
+		}
+		assert.Equals(t, types, []string{
+			models.TaskCreated,
+			models.TaskRenamed,
+			models.TaskAssigned,
+			models.TaskDeleted,
+		})
+		assert.Equals(t, states, []models.Task{*task, renamed, assigned, assigned})
+	})
+
+	t.Run("returns an `ErrResourceNotFound` error for a task without history", func(t *testing.T) {
+		store := newTestEventSourcedStore(t, openTestEventSourcedDb(t))
+		_, err := store.GetTaskHistory(ctx, 10)
+		assert.Equals(t, errors.Is(err, ErrResourceNotFound), true)
+	})
+
+	t.Run("rebuilds the tasks from the last snapshot and the events after it", func(t *testing.T) {
+		db := openTestEventSourcedDb(t)
+		store := newTestEventSourcedStore(t, db, WithTaskSnapshotEvery(3))
+		for _, title := range []string{"Buy milk", "Walk the dog", "Cook dinner"} {
+			_, err := store.CreateTask(ctx, models.NewCreateTaskDTO(title, 1))
+			assert.HasNoError(t, err)
+		}
+		_, err := store.UpdateTask(ctx, models.NewTask(2, "Walk the cat", 1))
+		assert.HasNoError(t, err)
+		assert.HasNoError(t, store.DeleteTaskById(ctx, 1))
+		assert.HasNoError(t, store.DeleteTaskById(ctx, 3))
+		_, err = store.CreateTask(ctx, models.NewCreateTaskDTO("Water plants", 1))
+		assert.HasNoError(t, err)
+		tasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+
+		var snapshotEventIds []int
+		rows, err := db.Query(`select event_id from task_snapshots`)
+		assert.HasNoError(t, err)
+		for rows.Next() {
+			var id int
+			assert.HasNoError(t, rows.Scan(&id))
+			snapshotEventIds = append(snapshotEventIds, id)
+		}
+		assert.HasNoError(t, rows.Close())
+		assert.Equals(t, snapshotEventIds, []int{6})
+
+		reopened := newTestEventSourcedStore(t, db, WithTaskSnapshotEvery(3))
+		reopenedTasks, err := reopened.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, reopenedTasks, tasks)
+		task, err := reopened.CreateTask(ctx, models.NewCreateTaskDTO("Read", 1))
+		assert.HasNoError(t, err)
+		assert.Equals(t, task.Id, 5)
+	})
+
+	t.Run("starts the log with the tasks already in the database", func(t *testing.T) {
+		db := openTestEventSourcedDb(t)
+		sqliteStore := NewSqliteStore(db)
+		for _, title := range []string{"Buy milk", "Walk the dog", "Cook dinner"} {
+			_, err := sqliteStore.CreateTask(ctx, models.NewCreateTaskDTO(title, 1))
+			assert.HasNoError(t, err)
+		}
+		assert.HasNoError(t, sqliteStore.DeleteTaskById(ctx, 3))
+		tasks, err := sqliteStore.GetTasks(ctx)
+		assert.HasNoError(t, err)
+
+		store := newTestEventSourcedStore(t, db)
+		importedTasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, importedTasks, tasks)
+		history, err := store.GetTaskHistory(ctx, tasks[0].Id)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, history, 1)
+		assert.Equals(t, history[0].Type, models.TaskCreated)
+
+		task, err := store.CreateTask(ctx, models.NewCreateTaskDTO("Read", 1))
+		assert.HasNoError(t, err)
+		assert.Equals(t, task.Id, 4)
+
+		reopened := newTestEventSourcedStore(t, db)
+		reopenedTasks, err := reopened.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, reopenedTasks, append(tasks, *task))
+	})
+}
+
+func openTestEventSourcedDb(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := OpenSqlite(context.Background(), filepath.Join(t.TempDir(), "data.db"))
+	assert.HasNoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	InitEmptyDb(db)
+	return db
+}
+
+func newTestEventSourcedStore(
+	t *testing.T,
+	db *sql.DB,
+	options ...EventSourcedStoreOption,
+) *EventSourcedStore {
+	t.Helper()
+	store, err := NewEventSourcedStore(context.Background(), db, options...)
+	assert.HasNoError(t, err)
+	return store
+}
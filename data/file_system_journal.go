@@ -0,0 +1,277 @@
+package data
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"maps"
+	"os"
+	"slices"
+)
+
+// journalRecord holds the items a single write put or deleted and the
+// counters it moved. Applying a record twice gives the same data, so the
+// journal can safely be replayed over a file it was already folded into.
+type journalRecord struct {
+	Counters map[string]int               `json:"counters,omitempty"`
+	Deletes  map[string][]string          `json:"deletes,omitempty"`
+	Puts     map[string][]json.RawMessage `json:"puts,omitempty"`
+}
+
+type journalCollection interface {
+	replay(data *storeData) journalReplay
+	sort(data *storeData)
+}
+
+// journalReplay applies the records of a journal to one collection, keyed
+// so that each record costs as much as its own changes, and puts the
+// collection back in order once at the end.
+type journalReplay interface {
+	apply(record *journalRecord) error
+	finish()
+}
+
+type keyedReplay[T any] struct {
+	collection keyedCollection[T]
+	data       *storeData
+	records    map[string]T
+}
+
+var journalCollections = map[string]journalCollection{
+	apiTokenRecords.name:          apiTokenRecords,
+	auditEventRecords.name:        auditEventRecords,
+	loginAttemptRecords.name:      loginAttemptRecords,
+	projectInvitationRecords.name: projectInvitationRecords,
+	projectMemberRecords.name:     projectMemberRecords,
+	projectRecords.name:           projectRecords,
+	taskRevisionRecords.name:      taskRevisionRecords,
+	taskRecords.name:              taskRecords,
+	userMfaRecords.name:           userMfaRecords,
+	userTokenRecords.name:         userTokenRecords,
+	userRecords.name:              userRecords,
+}
+
+var journalCounters = map[string]func(*storeData) *int{
+	"lastApiTokenId":   func(d *storeData) *int { return &d.LastApiTokenId },
+	"lastAuditEventId": func(d *storeData) *int { return &d.LastAuditEventId },
+	"lastProjectId":    func(d *storeData) *int { return &d.LastProjectId },
+	"lastProjectInvitationId": func(d *storeData) *int {
+		return &d.LastProjectInvitationId
+	},
+	"lastTaskId": func(d *storeData) *int { return &d.LastTaskId },
+	"lastUserId": func(d *storeData) *int { return &d.LastUserId },
+}
+
+func (c keyedCollection[T]) replay(data *storeData) journalReplay {
+	records := map[string]T{}
+	for _, record := range *c.items(data) {
+		records[c.key(record)] = record
+	}
+	return &keyedReplay[T]{collection: c, data: data, records: records}
+}
+
+func (r *keyedReplay[T]) apply(record *journalRecord) error {
+	c := r.collection
+	for _, key := range record.Deletes[c.name] {
+		delete(r.records, key)
+	}
+	for _, value := range record.Puts[c.name] {
+		var item T
+		if err := json.Unmarshal(value, &item); err != nil {
+			return err
+		}
+		r.records[c.key(item)] = item
+	}
+	return nil
+}
+
+func (r *keyedReplay[T]) finish() {
+	items := r.collection.items(r.data)
+	*items = slices.AppendSeq((*items)[:0], maps.Values(r.records))
+	r.collection.sort(r.data)
+}
+
+func (r *journalRecord) isEmpty() bool {
+	return len(r.Counters) == 0 && len(r.Deletes) == 0 && len(r.Puts) == 0
+}
+
+// openJournal replays the journal left next to the file. Without journal
+// mode the changes are folded into the file and the journal is removed.
+func (f *FileSystemStore) openJournal() error {
+	path := f.path + ".journal"
+	flags := os.O_RDWR | os.O_APPEND
+	if f.compactAfter > 0 {
+		flags |= os.O_CREATE
+	}
+	file, err := os.OpenFile(path, flags, 0o644)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	records, size, err := replayJournal(file, f.data)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Truncate(size); err != nil {
+		file.Close()
+		return err
+	}
+
+	if f.compactAfter == 0 {
+		file.Close()
+		if records > 0 {
+			if err := writeSnapshot(f.path, f.data); err != nil {
+				return err
+			}
+		}
+		return os.Remove(path)
+	}
+	f.journal = file
+	f.journalRecords = records
+	return nil
+}
+
+// replayJournal applies the records of the journal to data and returns how
+// many there were and the size they take up. A last record without its
+// newline was cut short by a crash before the write was acknowledged, or is
+// still being written, so it is left out.
+func replayJournal(r io.Reader, data *storeData) (int, int64, error) {
+	reader := bufio.NewReader(r)
+	replays := map[string]journalReplay{}
+	var offset int64
+	records := 0
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		var record journalRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			return 0, 0, fmt.Errorf("corrupt journal record at offset %d: %w", offset, err)
+		}
+		if err := record.replay(data, replays); err != nil {
+			return 0, 0, fmt.Errorf("corrupt journal record at offset %d: %w", offset, err)
+		}
+		offset += int64(len(line))
+		records++
+	}
+	for _, replay := range replays {
+		replay.finish()
+	}
+	return records, offset, nil
+}
+
+// replay only keys the collections the journal changes, the first time a
+// record changes them.
+func (r *journalRecord) replay(
+	data *storeData,
+	replays map[string]journalReplay,
+) error {
+	for name, collection := range journalCollections {
+		if len(r.Deletes[name]) == 0 && len(r.Puts[name]) == 0 {
+			continue
+		}
+		replay, ok := replays[name]
+		if !ok {
+			replay = collection.replay(data)
+			replays[name] = replay
+		}
+		if err := replay.apply(r); err != nil {
+			return err
+		}
+	}
+	for name, value := range r.Counters {
+		if counter, ok := journalCounters[name]; ok {
+			*counter(data) = value
+		}
+	}
+	return nil
+}
+
+func (f *FileSystemStore) appendToJournal(edit *storeEdit) error {
+	record, err := edit.journalRecord()
+	if err != nil {
+		return fmt.Errorf("error writing to the journal: %w", err)
+	}
+	if !record.isEmpty() {
+		if err := f.writeJournalRecord(record); err != nil {
+			return fmt.Errorf("error writing to the journal: %w", err)
+		}
+		f.journalRecords++
+	}
+
+	if f.journalRecords >= f.compactAfter {
+		// The change is already safe in the journal, so a failed compaction
+		// is only retried with the next write.
+		if err := f.compact(edit.storeData); err != nil {
+			log.Printf("error compacting the journal: %v", err)
+		}
+	}
+	return nil
+}
+
+func (f *FileSystemStore) writeJournalRecord(record *journalRecord) error {
+	if f.journalErr != nil {
+		return f.journalErr
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	info, err := f.journal.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := f.journal.Write(append(line, '\n')); err != nil {
+		// Drop whatever part of the record made it, so that the next one
+		// does not start in the middle of a line. Should that fail too, no
+		// record can be appended safely anymore.
+		if truncateErr := f.journal.Truncate(info.Size()); truncateErr != nil {
+			f.journalErr = fmt.Errorf(
+				"the journal ends in a partial record, %v",
+				truncateErr,
+			)
+			return errors.Join(err, f.journalErr)
+		}
+		return err
+	}
+	return f.journal.Sync()
+}
+
+// Compact folds the journal into the file right away.
+func (f *FileSystemStore) Compact(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if f.journal == nil {
+		return nil
+	}
+	return f.compact(f.data)
+}
+
+func (f *FileSystemStore) compact(data *storeData) error {
+	if err := writeSnapshot(f.path, data); err != nil {
+		return err
+	}
+	if err := f.journal.Truncate(0); err != nil {
+		return err
+	}
+	if err := f.journal.Sync(); err != nil {
+		return err
+	}
+	f.journalRecords = 0
+	return nil
+}
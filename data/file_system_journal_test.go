@@ -0,0 +1,180 @@
+package data_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestFileSystemStoreJournal(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("appends each change to the journal and replays it when reopened", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, "")
+		defer cleanDatabase()
+		store, err := data.NewFileSystemStore(database, data.WithJournal(100))
+		assert.HasNoError(t, err)
+
+		for _, title := range []string{"Buy milk", "Walk the dog", "Cook dinner"} {
+			_, err := store.CreateTask(ctx, models.NewCreateTaskDTO(title, 1))
+			assert.HasNoError(t, err)
+		}
+		_, err = store.UpdateTask(ctx, models.NewTask(2, "Walk the cat", 1))
+		assert.HasNoError(t, err)
+		assert.HasNoError(t, store.DeleteTaskById(ctx, 1))
+		tasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.HasNoError(t, store.Close())
+
+		assert.Equals(t, countJournalRecords(t, database.Name()), 5)
+		snapshot, err := os.ReadFile(database.Name())
+		assert.HasNoError(t, err)
+		assert.Equals(t, string(snapshot), "{}")
+
+		store = reopenFileSystemStore(t, database.Name(), data.WithJournal(100))
+		reloadedTasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, reloadedTasks, tasks)
+		task, err := store.CreateTask(ctx, models.NewCreateTaskDTO("Water plants", 1))
+		assert.HasNoError(t, err)
+		assert.Equals(t, task.Id, 4)
+	})
+
+	t.Run("replays the task revisions", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, "")
+		defer cleanDatabase()
+		store, err := data.NewFileSystemStore(database, data.WithJournal(100))
+		assert.HasNoError(t, err)
+
+		_, err = store.CreateTask(ctx, models.NewCreateTaskDTO("Buy milk", 1))
+		assert.HasNoError(t, err)
+		for _, title := range []string{"Buy oat milk", "Buy soy milk"} {
+			_, err := store.UpdateTask(data.WithEditor(ctx, 1), models.NewTask(1, title, 1))
+			assert.HasNoError(t, err)
+		}
+		revisions, err := store.GetTaskRevisions(ctx, 1)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, revisions, 2)
+		assert.HasNoError(t, store.Close())
+
+		store = reopenFileSystemStore(t, database.Name(), data.WithJournal(100))
+		reloadedRevisions, err := store.GetTaskRevisions(ctx, 1)
+		assert.HasNoError(t, err)
+		assert.Equals(t, reloadedRevisions, revisions)
+	})
+
+	t.Run("keeps imported tasks in ID order when replaying", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, "")
+		defer cleanDatabase()
+		store, err := data.NewFileSystemStore(database, data.WithJournal(100))
+		assert.HasNoError(t, err)
+
+		assert.HasNoError(t, store.ImportTask(ctx, models.NewTask(3, "Cook dinner", 1)))
+		assert.HasNoError(t, store.ImportTask(ctx, models.NewTask(1, "Buy milk", 1)))
+		tasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.HasNoError(t, store.Close())
+
+		store = reopenFileSystemStore(t, database.Name(), data.WithJournal(100))
+		reloadedTasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, reloadedTasks, tasks)
+		assert.Equals(t, reloadedTasks[0].Id, 1)
+	})
+
+	t.Run("folds the journal into the file once enough changes piled up", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, "")
+		defer cleanDatabase()
+		store, err := data.NewFileSystemStore(database, data.WithJournal(2))
+		assert.HasNoError(t, err)
+
+		_, err = store.CreateTask(ctx, models.NewCreateTaskDTO("Buy milk", 1))
+		assert.HasNoError(t, err)
+		assert.Equals(t, countJournalRecords(t, database.Name()), 1)
+		_, err = store.CreateTask(ctx, models.NewCreateTaskDTO("Walk the dog", 1))
+		assert.HasNoError(t, err)
+		assert.Equals(t, countJournalRecords(t, database.Name()), 0)
+		_, err = store.CreateTask(ctx, models.NewCreateTaskDTO("Cook dinner", 1))
+		assert.HasNoError(t, err)
+		assert.HasNoError(t, store.Close())
+
+		store = reopenFileSystemStore(t, database.Name())
+		tasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, tasks, 3)
+		_, err = os.Stat(database.Name() + ".journal")
+		assert.Equals(t, os.IsNotExist(err), true)
+	})
+
+	t.Run("drops a last record cut short by a crash", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, "")
+		defer cleanDatabase()
+		store, err := data.NewFileSystemStore(database, data.WithJournal(100))
+		assert.HasNoError(t, err)
+		for _, title := range []string{"Buy milk", "Walk the dog"} {
+			_, err := store.CreateTask(ctx, models.NewCreateTaskDTO(title, 1))
+			assert.HasNoError(t, err)
+		}
+		assert.HasNoError(t, store.Close())
+		appendToJournal(t, database.Name(), `{"puts":{"tasks":[{"id":3,"tit`)
+
+		store = reopenFileSystemStore(t, database.Name(), data.WithJournal(100))
+		tasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, tasks, 2)
+		task, err := store.CreateTask(ctx, models.NewCreateTaskDTO("Cook dinner", 1))
+		assert.HasNoError(t, err)
+		assert.Equals(t, task.Id, 3)
+		assert.Equals(t, countJournalRecords(t, database.Name()), 3)
+	})
+
+	t.Run("refuses a journal with a corrupt record", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, "")
+		defer cleanDatabase()
+		appendToJournal(t, database.Name(), "not json\n{}\n")
+
+		_, err := data.NewFileSystemStore(database, data.WithJournal(100))
+		assert.HasError(t, err)
+	})
+}
+
+func reopenFileSystemStore(
+	t *testing.T,
+	name string,
+	options ...data.FileSystemStoreOption,
+) *data.FileSystemStore {
+	t.Helper()
+	file, err := os.OpenFile(name, os.O_RDWR, 0)
+	assert.HasNoError(t, err)
+	t.Cleanup(func() { file.Close() })
+	store, err := data.NewFileSystemStore(file, options...)
+	assert.HasNoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func countJournalRecords(t *testing.T, name string) int {
+	t.Helper()
+	content, err := os.ReadFile(name + ".journal")
+	assert.HasNoError(t, err)
+	return bytes.Count(content, []byte("\n"))
+}
+
+func appendToJournal(t *testing.T, name, content string) {
+	t.Helper()
+	file, err := os.OpenFile(
+		name+".journal",
+		os.O_WRONLY|os.O_APPEND|os.O_CREATE,
+		0o644,
+	)
+	assert.HasNoError(t, err)
+	defer file.Close()
+	_, err = file.WriteString(content)
+	assert.HasNoError(t, err)
+}
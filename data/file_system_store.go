@@ -1,236 +1,286 @@
 package data
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"slices"
 
 	"github.com/claudealdric/go-todolist-restful-api-server/models"
-	"github.com/claudealdric/go-todolist-restful-api-server/utils"
-	"golang.org/x/crypto/bcrypt"
 )
 
+var ErrStoreLocked = errors.New("store is locked by another process")
+
+// FileSystemStore serves every read from the data it loaded once and
+// replaces the whole file on each write, unless it runs WithJournal. The file
+// stays locked against other processes until Close is called.
 type FileSystemStore struct {
-	encoder    *json.Encoder
-	decoder    *json.Decoder
-	lastTaskId int
-	lastUserId int
-}
+	*MemoryStore
 
-func NewFileSystemStore(file *os.File) (*FileSystemStore, error) {
-	err := initializeDBFile(file)
+	path string
+	lock *fileLock
 
-	if err != nil {
-		return nil, fmt.Errorf("problem initializing player db file, %v", err)
-	}
+	compactAfter   int
+	journal        *os.File
+	journalRecords int
+	// journalErr stops the writes once the journal could not be left ending
+	// in a complete record.
+	journalErr error
+}
 
-	if err != nil {
-		return nil, fmt.Errorf(
-			"problem loading player store from file %s, %v",
-			file.Name(),
-			err,
-		)
-	}
+type FileSystemStoreOption func(*FileSystemStore)
 
-	return &FileSystemStore{
-		json.NewEncoder(&tape{file}),
-		json.NewDecoder(&tape{file}),
-		0,
-		0,
-	}, nil
+// WithJournal appends each change as one line to a journal next to the file
+// instead of rewriting the file, which only happens once compactAfter
+// changes have piled up.
+func WithJournal(compactAfter int) FileSystemStoreOption {
+	return func(f *FileSystemStore) {
+		f.compactAfter = max(compactAfter, 1)
+	}
 }
 
-func (f *FileSystemStore) GetTaskById(id int) (*models.Task, error) {
-	tasks, err := f.getTasksFromFile()
+func NewFileSystemStore(
+	file *os.File,
+	options ...FileSystemStoreOption,
+) (*FileSystemStore, error) {
+	lock, err := lockFile(file.Name() + ".lock")
 	if err != nil {
 		return nil, err
 	}
-	task, ok := utils.SliceFind(tasks, func(t models.Task) bool {
-		return t.Id == id
-	})
-	if !ok {
-		return nil, fmt.Errorf(
-			"task with ID %d: %w",
-			id,
-			ErrResourceNotFound,
-		)
-	}
-	return &task, nil
-}
 
-func (f *FileSystemStore) GetTasks() ([]models.Task, error) {
-	tasks, err := f.getTasksFromFile()
+	err = initializeDBFile(file)
 	if err != nil {
-		return nil, err
+		lock.unlock()
+		return nil, fmt.Errorf("problem initializing player db file, %v", err)
 	}
-	return tasks, nil
-}
-
-func (f *FileSystemStore) CreateTask(dto *models.CreateTaskDTO) (*models.Task, error) {
-	tasks, err := f.GetTasks()
+	data, err := readFile(file)
 	if err != nil {
+		lock.unlock()
 		return nil, err
 	}
-	newId := f.getNewTaskId()
-	task := models.Task{
-		Id:    newId,
-		Title: dto.Title,
+
+	store := &FileSystemStore{
+		MemoryStore: &MemoryStore{data: data},
+		path:        file.Name(),
+		lock:        lock,
 	}
-	tasks = append(tasks, task)
-	err = f.overwriteFile(tasks)
-	if err != nil {
-		return nil, err
+	store.persist = store.writeToDisk
+	for _, option := range options {
+		option(store)
 	}
-	return &task, nil
+	if err := store.openJournal(); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("problem opening the journal, %v", err)
+	}
+	if err := store.moveUnassignedTasksToProject(context.Background()); err != nil {
+		store.Close()
+		return nil, fmt.Errorf("problem moving tasks to a project, %v", err)
+	}
+	return store, nil
 }
 
-func (f *FileSystemStore) DeleteTaskById(id int) error {
-	tasks, err := f.GetTasks()
-	if err != nil {
-		return err
-	}
-	i := slices.IndexFunc(tasks, func(task models.Task) bool {
-		return task.Id == id
-	})
-	if i == -1 {
-		return fmt.Errorf("error with task ID %d: %w", id, ErrResourceNotFound)
+// ReadFileSystemStore loads the file at path and its journal into a
+// MemoryStore without taking the lock, so that the data can be read while a
+// server runs on the file. Changes to the returned store are not saved.
+func ReadFileSystemStore(path string) (*MemoryStore, error) {
+	// A compaction between reading the file and the journal can take records
+	// out of the journal that the file read did not hold yet, so the reading
+	// starts over when the file was replaced in the meantime.
+	for range fileReadAttempts {
+		data, info, err := readFileAndJournal(path)
+		if err != nil {
+			return nil, err
+		}
+		current, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if os.SameFile(info, current) {
+			return &MemoryStore{data: data}, nil
+		}
 	}
-	tasks = slices.DeleteFunc(tasks, func(task models.Task) bool {
-		return task.Id == id
-	})
-	return f.overwriteFile(tasks)
+	return nil, fmt.Errorf("%s kept changing while it was read", path)
 }
 
-func (f *FileSystemStore) UpdateTask(task *models.Task) (*models.Task, error) {
-	tasks, err := f.GetTasks()
+const fileReadAttempts = 10
+
+func readFileAndJournal(path string) (*storeData, os.FileInfo, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-
-	taskToUpdate, err := f.GetTaskById(task.Id)
+	defer file.Close()
+	info, err := file.Stat()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-
-	taskToUpdate.Title = task.Title
-	for i, t := range tasks {
-		if t.Id == task.Id {
-			tasks[i] = *taskToUpdate
-			break
+	data := &storeData{}
+	if info.Size() > 0 {
+		data, err = readFile(file)
+		if err != nil {
+			return nil, nil, err
 		}
 	}
-	err = f.overwriteFile(tasks)
+
+	journal, err := os.Open(path + ".journal")
+	if errors.Is(err, fs.ErrNotExist) {
+		return data, info, nil
+	}
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-
-	return taskToUpdate, nil
+	defer journal.Close()
+	if _, _, err := replayJournal(journal, data); err != nil {
+		return nil, nil, err
+	}
+	return data, info, nil
 }
 
-func (f *FileSystemStore) GetUserByEmail(email string) (*models.User, error) {
-	var user models.User
-	users, err := f.getUsersFromFile()
-	if err != nil {
-		return nil, err
+// Close releases the lock on the file; the store cannot be written to
+// afterwards.
+func (f *FileSystemStore) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil
 	}
-	user, ok := utils.SliceFind(users, func(u models.User) bool {
-		return u.Email == email
-	})
-	if !ok {
-		return nil, fmt.Errorf(
-			"user with email %s: %w",
-			email,
-			ErrResourceNotFound,
-		)
+	f.closed = true
+	var err error
+	if f.journal != nil {
+		err = f.journal.Close()
+		f.journal = nil
+	}
+	if unlockErr := f.lock.unlock(); err == nil {
+		err = unlockErr
 	}
-	return &user, nil
+	return err
 }
 
-func (f *FileSystemStore) CreateUser(dto *models.CreateUserDTO) (*models.User, error) {
-	users, err := f.GetUsers()
+// Tasks were shared by everyone before projects existed, so they move to a
+// project owned by the first user, who can then invite the others.
+func (f *FileSystemStore) moveUnassignedTasksToProject(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, err := f.editData(ctx)
 	if err != nil {
-		return nil, err
-	}
-	newId := f.getNewUserId()
-	hashedPassword, err := bcrypt.GenerateFromPassword(
-		[]byte(dto.Password),
-		bcrypt.DefaultCost,
-	)
-	if err != nil {
-		return nil, err
+		return err
 	}
-	user := models.User{
-		Id:       newId,
-		Name:     dto.Name,
-		Email:    dto.Email,
-		Password: string(hashedPassword),
+	if len(data.Users) == 0 || !slices.ContainsFunc(
+		data.Tasks,
+		func(t models.Task) bool { return t.ProjectId == 0 },
+	) {
+		return nil
 	}
-	users = append(users, user)
-	err = f.overwriteFile(users)
-	if err != nil {
-		return nil, err
+	owner := slices.MinFunc(data.Users, func(a, b models.User) int {
+		return a.Id - b.Id
+	})
+	data.LastProjectId++
+	projectRecords.put(data, models.Project{
+		Id:   data.LastProjectId,
+		Name: "Tasks",
+	})
+	projectMemberRecords.put(data, models.ProjectMember{
+		ProjectId: data.LastProjectId,
+		UserId:    owner.Id,
+		Role:      models.ProjectRoleOwner,
+	})
+	for _, task := range data.Tasks {
+		if task.ProjectId == 0 {
+			task.ProjectId = data.LastProjectId
+			taskRecords.put(data, task)
+		}
 	}
-	return &user, nil
+	return f.writeData(ctx, data)
 }
 
-func (f *FileSystemStore) GetUsers() ([]models.User, error) {
-	users, err := f.getUsersFromFile()
-	if err != nil {
-		return nil, err
+// writeToDisk only lets the edit stand once it is safely on disk.
+func (f *FileSystemStore) writeToDisk(edit *storeEdit) error {
+	if f.journal != nil {
+		return f.appendToJournal(edit)
 	}
-	return users, nil
+	return writeSnapshot(f.path, edit.storeData)
 }
 
-func (f *FileSystemStore) ValidateUserCredentials(email, password string) bool {
-	user, err := f.GetUserByEmail(email)
+func writeSnapshot(path string, data *storeData) error {
+	content, err := json.Marshal(data)
 	if err != nil {
-		return false
+		return fmt.Errorf("error writing to file: %w", err)
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	err = writeFileAtomically(path, append(content, '\n'))
 	if err != nil {
-		return false
+		return fmt.Errorf("error writing to file: %w", err)
 	}
-	return true
+	return nil
 }
 
-func (f *FileSystemStore) getTasksFromFile() ([]models.Task, error) {
-	var tasks []models.Task
-	err := f.decoder.Decode(&tasks)
+func readFile(file *os.File) (*storeData, error) {
+	_, err := file.Seek(0, io.SeekStart)
 	if err != nil {
 		return nil, fmt.Errorf("error reading the file: %w", err)
 	}
-	return tasks, nil
-}
-
-func (f *FileSystemStore) getUsersFromFile() ([]models.User, error) {
-	var users []models.User
-	err := f.decoder.Decode(&users)
+	content, err := io.ReadAll(file)
 	if err != nil {
 		return nil, fmt.Errorf("error reading the file: %w", err)
 	}
-	return users, nil
-}
 
-func (f *FileSystemStore) overwriteFile(data any) error {
-	err := f.encoder.Encode(data)
+	var data storeData
+	content = bytes.TrimSpace(content)
+	if len(content) > 0 && content[0] == '[' {
+		// Files written before users were stored alongside tasks only hold
+		// the list of tasks.
+		err = json.Unmarshal(content, &data.Tasks)
+		for _, task := range data.Tasks {
+			data.LastTaskId = max(data.LastTaskId, task.Id)
+		}
+	} else {
+		err = json.Unmarshal(content, &data)
+	}
 	if err != nil {
-		return fmt.Errorf("error writing to file: %w", err)
+		return nil, fmt.Errorf("error reading the file: %w", err)
 	}
-	return nil
+	for _, collection := range journalCollections {
+		collection.sort(&data)
+	}
+	return &data, nil
 }
 
-func (f *FileSystemStore) getNewTaskId() int {
-	newTaskId := f.lastTaskId + 1
-	f.lastTaskId = newTaskId
-	return newTaskId
-}
+// writeFileAtomically writes to a temporary file next to the destination and
+// renames it over the destination, so that a crash leaves either the old or
+// the new content behind but never a mix of both.
+func writeFileAtomically(path string, content []byte) error {
+	tempFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
 
-func (f *FileSystemStore) getNewUserId() int {
-	newUserId := f.lastUserId + 1
-	f.lastUserId = newUserId
-	return newUserId
+	if info, err := os.Stat(path); err == nil {
+		if err := tempFile.Chmod(info.Mode()); err != nil {
+			tempFile.Close()
+			return err
+		}
+	}
+	if _, err := tempFile.Write(content); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Sync(); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tempFile.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
 }
 
 func initializeDBFile(file *os.File) error {
@@ -255,7 +305,7 @@ func initializeDBFile(file *os.File) error {
 	}
 
 	if info.Size() == 0 {
-		_, err := file.Write([]byte("[]"))
+		_, err := file.Write([]byte("{}"))
 
 		if err != nil {
 			return fmt.Errorf(
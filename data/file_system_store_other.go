@@ -0,0 +1,41 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package data
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// Without flock the lock file is created exclusively instead, which a crash
+// leaves behind; it then has to be removed by hand.
+type fileLock struct {
+	file *os.File
+}
+
+func lockFile(path string) (*fileLock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, fs.ErrExist) {
+		return nil, fmt.Errorf("%s: %w", path, ErrStoreLocked)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &fileLock{file}, nil
+}
+
+func (l *fileLock) unlock() error {
+	err := l.file.Close()
+	if removeErr := os.Remove(l.file.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// Directories cannot be synced on every platform, so the rename is trusted
+// to be durable on its own.
+func syncDir(path string) error {
+	return nil
+}
@@ -1,7 +1,14 @@
 package data_test
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/claudealdric/go-todolist-restful-api-server/data"
 	"github.com/claudealdric/go-todolist-restful-api-server/models"
@@ -12,7 +19,8 @@ import (
 )
 
 func TestFileSystemStoreTasks(t *testing.T) {
-	initialTasks := []models.Task{*models.NewTask(1, "Buy groceries")}
+	ctx := context.Background()
+	initialTasks := []models.Task{*models.NewTask(1, "Buy groceries", 1)}
 	jsonTasks, err := utils.ConvertToJSON(initialTasks)
 	assert.HasNoError(t, err)
 
@@ -33,7 +41,7 @@ func TestFileSystemStoreTasks(t *testing.T) {
 
 		assert.HasNoError(t, err)
 
-		tasks, err := store.GetTasks()
+		tasks, err := store.GetTasks(ctx)
 
 		assert.HasNoError(t, err)
 		assert.Equals(t, tasks, initialTasks)
@@ -48,7 +56,7 @@ func TestFileSystemStoreTasks(t *testing.T) {
 		assert.HasNoError(t, err)
 
 		wantedTask := initialTasks[0]
-		got, err := store.GetTaskById(wantedTask.Id)
+		got, err := store.GetTaskById(ctx, wantedTask.Id)
 
 		assert.HasNoError(t, err)
 		assert.Equals(t, *got, wantedTask)
@@ -63,7 +71,7 @@ func TestFileSystemStoreTasks(t *testing.T) {
 		assert.HasNoError(t, err)
 
 		doesNotExistId := -1
-		_, err = store.GetTaskById(doesNotExistId)
+		_, err = store.GetTaskById(ctx, doesNotExistId)
 
 		assert.ErrorContains(t, err, data.ErrResourceNotFound)
 	})
@@ -76,10 +84,10 @@ func TestFileSystemStoreTasks(t *testing.T) {
 
 		assert.HasNoError(t, err)
 
-		newTask, err := store.CreateTask(&models.CreateTaskDTO{"Launder clothes"})
+		newTask, err := store.CreateTask(ctx, models.NewCreateTaskDTO("Launder clothes", 1))
 		assert.HasNoError(t, err)
 
-		tasks, err := store.GetTasks()
+		tasks, err := store.GetTasks(ctx)
 		assert.HasNoError(t, err)
 
 		assert.Contains(t, tasks, *newTask)
@@ -94,8 +102,8 @@ func TestFileSystemStoreTasks(t *testing.T) {
 		assert.HasNoError(t, err)
 
 		taskToDelete := initialTasks[0]
-		store.DeleteTaskById(taskToDelete.Id)
-		tasks, err := store.GetTasks()
+		store.DeleteTaskById(ctx, taskToDelete.Id)
+		tasks, err := store.GetTasks(ctx)
 
 		assert.HasNoError(t, err)
 		assert.DoesNotContain(t, tasks, taskToDelete)
@@ -110,7 +118,7 @@ func TestFileSystemStoreTasks(t *testing.T) {
 		assert.HasNoError(t, err)
 
 		doesNotExistId := -1
-		err = store.DeleteTaskById(doesNotExistId)
+		err = store.DeleteTaskById(ctx, doesNotExistId)
 
 		assert.ErrorContains(t, err, data.ErrResourceNotFound)
 	})
@@ -125,16 +133,23 @@ func TestFileSystemStoreTasks(t *testing.T) {
 		task := initialTasks[0]
 		newTitle := "Buy food"
 		updatedTask, err := store.UpdateTask(
+			ctx,
 			&models.Task{
-				Id:    task.Id,
-				Title: newTitle,
+				Id:         task.Id,
+				Title:      newTitle,
+				AssigneeId: 1,
 			},
 		)
 		assert.HasNoError(t, err)
-		wantedTask := models.Task{Id: task.Id, Title: newTitle}
+		wantedTask := models.Task{
+			Id:         task.Id,
+			Title:      newTitle,
+			ProjectId:  task.ProjectId,
+			AssigneeId: 1,
+		}
 		assert.Equals(t, *updatedTask, wantedTask)
 
-		retrievedTask, err := store.GetTaskById(task.Id)
+		retrievedTask, err := store.GetTaskById(ctx, task.Id)
 		assert.HasNoError(t, err)
 		assert.Equals(t, *retrievedTask, wantedTask)
 	})
@@ -148,6 +163,7 @@ func TestFileSystemStoreTasks(t *testing.T) {
 
 		newTitle := "Buy food"
 		_, err = store.UpdateTask(
+			ctx,
 			&models.Task{
 				Id:    -1,
 				Title: newTitle,
@@ -157,7 +173,504 @@ func TestFileSystemStoreTasks(t *testing.T) {
 	})
 }
 
+func TestFileSystemStoreTasksAndUsers(t *testing.T) {
+	ctx := context.Background()
+	t.Run("stores tasks and users side by side", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, "")
+		defer cleanDatabase()
+
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+
+		task, err := store.CreateTask(ctx, models.NewCreateTaskDTO("Buy groceries", 1))
+		assert.HasNoError(t, err)
+		user, err := store.CreateUser(
+			ctx,
+			models.NewCreateUserDTO("John Doe", "john.doe@email.com", "password"),
+		)
+		assert.HasNoError(t, err)
+
+		tasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, tasks, []models.Task{*task})
+		users, err := store.GetUsers(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, users, []models.User{*user})
+	})
+
+	t.Run("does not reuse the ID of a deleted task", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, "")
+		defer cleanDatabase()
+
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+
+		task, err := store.CreateTask(ctx, models.NewCreateTaskDTO("Buy groceries", 1))
+		assert.HasNoError(t, err)
+		assert.HasNoError(t, store.DeleteTaskById(ctx, task.Id))
+		newTask, err := store.CreateTask(ctx, models.NewCreateTaskDTO("Pack clothes", 1))
+		assert.HasNoError(t, err)
+
+		assert.Equals(t, newTask.Id, task.Id+1)
+	})
+}
+
+func TestFileSystemStoreLoginAttempts(t *testing.T) {
+	ctx := context.Background()
+	t.Run("GetLoginAttempt returns an `ErrResourceNotFound` error if there is none", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, "")
+		defer cleanDatabase()
+
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+
+		_, err = store.GetLoginAttempt(ctx, "email:john.doe@email.com")
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("SaveLoginAttempt creates, updates and DeleteLoginAttempt removes", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, "")
+		defer cleanDatabase()
+
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+
+		now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		attempt := models.LoginAttempt{
+			Key:           "email:john.doe@email.com",
+			Failures:      1,
+			LastFailureAt: now,
+		}
+		assert.HasNoError(t, store.SaveLoginAttempt(ctx, &attempt))
+		attempt.Failures = 2
+		attempt.LockedUntil = now.Add(time.Minute)
+		assert.HasNoError(t, store.SaveLoginAttempt(ctx, &attempt))
+
+		got, err := store.GetLoginAttempt(ctx, attempt.Key)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, attempt)
+
+		assert.HasNoError(t, store.DeleteLoginAttempt(ctx, attempt.Key))
+		_, err = store.GetLoginAttempt(ctx, attempt.Key)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+}
+
+func TestFileSystemStoreUserMfa(t *testing.T) {
+	ctx := context.Background()
+	t.Run("SaveUserMfa creates, updates and DeleteUserMfa removes", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, "")
+		defer cleanDatabase()
+
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+
+		_, err = store.GetUserMfa(ctx, 1)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+
+		mfa := models.UserMfa{UserId: 1, TotpSecret: "secret"}
+		assert.HasNoError(t, store.SaveUserMfa(ctx, &mfa))
+		mfa.Enabled = true
+		mfa.LastUsedStep = 42
+		assert.HasNoError(t, store.SaveUserMfa(ctx, &mfa))
+
+		got, err := store.GetUserMfa(ctx, mfa.UserId)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, mfa)
+
+		assert.HasNoError(t, store.DeleteUserMfa(ctx, mfa.UserId))
+		_, err = store.GetUserMfa(ctx, mfa.UserId)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+}
+
+func TestFileSystemStoreProjects(t *testing.T) {
+	ctx := context.Background()
+	t.Run("moves the existing tasks to a project owned by the first user", func(t *testing.T) {
+		jsonData, err := utils.ConvertToJSON(map[string]any{
+			"tasks":      []models.Task{{Id: 1, Title: "Buy milk"}},
+			"lastTaskId": 1,
+			"users":      []models.User{{Id: 3, Name: "John Doe"}},
+			"lastUserId": 3,
+		})
+		assert.HasNoError(t, err)
+		database, cleanDatabase := testutils.CreateTempFile(t, string(jsonData))
+		defer cleanDatabase()
+
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+
+		member, err := store.GetProjectMember(ctx, 1, 3)
+		assert.HasNoError(t, err)
+		assert.Equals(t, member.Role, models.ProjectRoleOwner)
+		tasks, err := store.GetTasksByProjectId(ctx, 1)
+		assert.HasNoError(t, err)
+		assert.Equals(t, tasks, []models.Task{*models.NewTask(1, "Buy milk", 1)})
+	})
+
+	t.Run("projects, members and invitations", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, "")
+		defer cleanDatabase()
+
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+		owner, err := store.CreateUser(
+			ctx,
+			models.NewCreateUserDTO("John Doe", "john.doe@email.com", "password"),
+		)
+		assert.HasNoError(t, err)
+		user, err := store.CreateUser(
+			ctx,
+			models.NewCreateUserDTO("Jane Doe", "jane.doe@email.com", "password"),
+		)
+		assert.HasNoError(t, err)
+
+		project, err := store.CreateProject(ctx, &models.CreateProjectDTO{Name: "Home"}, owner.Id)
+		assert.HasNoError(t, err)
+		got, err := store.GetProjectById(ctx, project.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, *project)
+		_, err = store.GetProjectById(ctx, -1)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+
+		invitation, err := store.CreateProjectInvitation(ctx, &models.ProjectInvitation{
+			ProjectId: project.Id,
+			Email:     "Jane.Doe@email.com ",
+			Role:      models.ProjectRoleEditor,
+			InvitedBy: owner.Id,
+		})
+		assert.HasNoError(t, err)
+		assert.Equals(t, invitation.Email, user.Email)
+		_, err = store.CreateProjectInvitation(ctx, invitation)
+		assert.ErrorContains(t, err, data.ErrConflict)
+
+		invitations, err := store.GetProjectInvitationsByEmail(ctx, user.Email)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, invitations, 1)
+
+		member, err := store.AcceptProjectInvitation(ctx, invitation.Id, user.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, member.Role, models.ProjectRoleEditor)
+		_, err = store.AcceptProjectInvitation(ctx, invitation.Id, user.Id)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+
+		members, err := store.GetProjectMembers(ctx, project.Id)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, members, 2)
+
+		member.Role = models.ProjectRoleViewer
+		assert.HasNoError(t, store.SaveProjectMember(ctx, member))
+		got2, err := store.GetProjectMember(ctx, project.Id, user.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got2, *member)
+
+		dto := models.NewCreateTaskDTO("Buy milk", project.Id)
+		dto.AssigneeId = user.Id
+		task, err := store.CreateTask(ctx, dto)
+		assert.HasNoError(t, err)
+
+		assert.HasNoError(t, store.DeleteProjectMember(ctx, project.Id, user.Id))
+		task, err = store.GetTaskById(ctx, task.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, task.AssigneeId, 0)
+		err = store.DeleteProjectMember(ctx, project.Id, user.Id)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("DeleteUserById deletes the projects the user was the last member of", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, "")
+		defer cleanDatabase()
+
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+		user, err := store.CreateUser(
+			ctx,
+			models.NewCreateUserDTO("John Doe", "john.doe@email.com", "password"),
+		)
+		assert.HasNoError(t, err)
+		project, err := store.CreateProject(ctx, &models.CreateProjectDTO{Name: "Home"}, user.Id)
+		assert.HasNoError(t, err)
+		_, err = store.CreateTask(ctx, models.NewCreateTaskDTO("Buy milk", project.Id))
+		assert.HasNoError(t, err)
+
+		assert.HasNoError(t, store.DeleteUserById(ctx, user.Id))
+
+		_, err = store.GetProjectById(ctx, project.Id)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+		tasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, tasks, 0)
+	})
+}
+
+func TestFileSystemStoreCancelledContext(t *testing.T) {
+	database, cleanDatabase := testutils.CreateTempFile(t, "")
+	defer cleanDatabase()
+
+	store, err := data.NewFileSystemStore(database)
+	assert.HasNoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = store.CreateTask(ctx, models.NewCreateTaskDTO("Buy milk", 1))
+	assert.ErrorContains(t, err, context.Canceled)
+
+	tasks, err := store.GetTasks(context.Background())
+	assert.HasNoError(t, err)
+	assert.HasLength(t, tasks, 0)
+}
+
+func TestFileSystemStoreConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	database, cleanDatabase := testutils.CreateTempFile(t, "")
+	defer cleanDatabase()
+
+	store, err := data.NewFileSystemStore(database)
+	assert.HasNoError(t, err)
+	defer store.Close()
+
+	const goroutines = 20
+	const tasksPerGoroutine = 10
+	var wg sync.WaitGroup
+	for i := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range tasksPerGoroutine {
+				task, err := store.CreateTask(
+					ctx,
+					models.NewCreateTaskDTO(fmt.Sprintf("Task %d-%d", i, j), 1),
+				)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				task.Title += " (edited)"
+				if _, err := store.UpdateTask(ctx, task); err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := store.GetTasksByProjectId(ctx, 1); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	tasks, err := store.GetTasks(ctx)
+	assert.HasNoError(t, err)
+	assert.HasLength(t, tasks, goroutines*tasksPerGoroutine)
+	ids := map[int]bool{}
+	for _, task := range tasks {
+		ids[task.Id] = true
+		assert.Equals(t, strings.HasSuffix(task.Title, " (edited)"), true)
+	}
+	assert.Equals(t, len(ids), goroutines*tasksPerGoroutine)
+
+	assert.HasNoError(t, store.Close())
+	reopened, err := os.OpenFile(database.Name(), os.O_RDWR, 0)
+	assert.HasNoError(t, err)
+	defer reopened.Close()
+	store, err = data.NewFileSystemStore(reopened)
+	assert.HasNoError(t, err)
+	reloadedTasks, err := store.GetTasks(ctx)
+	assert.HasNoError(t, err)
+	assert.Equals(t, reloadedTasks, tasks)
+	tempFiles, err := filepath.Glob(database.Name() + ".*.tmp")
+	assert.HasNoError(t, err)
+	assert.HasLength(t, tempFiles, 0)
+}
+
+func TestFileSystemStoreLock(t *testing.T) {
+	database, cleanDatabase := testutils.CreateTempFile(t, "")
+	defer cleanDatabase()
+
+	store, err := data.NewFileSystemStore(database)
+	assert.HasNoError(t, err)
+
+	_, err = data.NewFileSystemStore(database)
+	assert.ErrorContains(t, err, data.ErrStoreLocked)
+
+	assert.HasNoError(t, store.Close())
+	_, err = store.CreateTask(
+		context.Background(),
+		models.NewCreateTaskDTO("Buy milk", 1),
+	)
+	assert.HasError(t, err)
+
+	store, err = data.NewFileSystemStore(database)
+	assert.HasNoError(t, err)
+	assert.HasNoError(t, store.Close())
+}
+
+func TestReadFileSystemStore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("reads the store while it is open, with its journal", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, "")
+		defer cleanDatabase()
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+		defer store.Close()
+		for _, title := range []string{"Buy milk", "Walk the dog"} {
+			_, err := store.CreateTask(ctx, models.NewCreateTaskDTO(title, 1))
+			assert.HasNoError(t, err)
+		}
+
+		copied, err := data.ReadFileSystemStore(database.Name())
+		assert.HasNoError(t, err)
+
+		want, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		got, err := copied.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, got, want)
+	})
+}
+
+func TestFileSystemStoreAuditEvents(t *testing.T) {
+	ctx := context.Background()
+	t.Run("CreateAuditEvent stores the event with an ID and a timestamp", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, "")
+		defer cleanDatabase()
+
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+
+		event, err := store.CreateAuditEvent(ctx, models.NewAuditEvent(
+			"login.locked",
+			0,
+			"email:john.doe@email.com",
+			"locked",
+		))
+		assert.HasNoError(t, err)
+		assert.Equals(t, event.Id, 1)
+		assert.Equals(t, event.CreatedAt.IsZero(), false)
+
+		events, err := store.GetAuditEvents(ctx)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, events, 1)
+		assert.Equals(t, events[0].Action, event.Action)
+		assert.Equals(t, events[0].Subject, event.Subject)
+	})
+}
+
+func TestFileSystemStoreUserTokens(t *testing.T) {
+	ctx := context.Background()
+	t.Run("ConsumeUserToken returns the token only once", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, "")
+		defer cleanDatabase()
+
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+
+		token := models.NewUserToken(
+			"hash",
+			1,
+			models.UserTokenPurposePasswordReset,
+			time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		)
+		assert.HasNoError(t, store.CreateUserToken(ctx, token))
+
+		_, err = store.ConsumeUserToken(
+			ctx,
+			token.Hash,
+			models.UserTokenPurposeEmailVerification,
+		)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+
+		got, err := store.ConsumeUserToken(ctx, token.Hash, token.Purpose)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, *token)
+
+		_, err = store.ConsumeUserToken(ctx, token.Hash, token.Purpose)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("DeleteUserTokens only deletes the tokens of the user for the purpose", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, "")
+		defer cleanDatabase()
+
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+
+		expiresAt := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		purpose := models.UserTokenPurposePasswordReset
+		otherPurpose := models.UserTokenPurposeEmailVerification
+		assert.HasNoError(t, store.CreateUserToken(
+			ctx,
+			models.NewUserToken("a", 1, purpose, expiresAt),
+		))
+		assert.HasNoError(t, store.CreateUserToken(
+			ctx,
+			models.NewUserToken("b", 1, otherPurpose, expiresAt),
+		))
+		assert.HasNoError(t, store.CreateUserToken(
+			ctx,
+			models.NewUserToken("c", 2, purpose, expiresAt),
+		))
+
+		assert.HasNoError(t, store.DeleteUserTokens(ctx, 1, purpose))
+
+		_, err = store.ConsumeUserToken(ctx, "a", purpose)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+		_, err = store.ConsumeUserToken(ctx, "b", otherPurpose)
+		assert.HasNoError(t, err)
+		_, err = store.ConsumeUserToken(ctx, "c", purpose)
+		assert.HasNoError(t, err)
+	})
+}
+
+func TestFileSystemStoreApiTokens(t *testing.T) {
+	ctx := context.Background()
+	t.Run("stores, finds, tracks and deletes API tokens", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, "")
+		defer cleanDatabase()
+
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+
+		token, err := store.CreateApiToken(ctx, &models.ApiToken{
+			UserId:    1,
+			Name:      "CI",
+			Hash:      "hash",
+			Scopes:    []string{models.ApiTokenScopeReadTasks},
+			CreatedAt: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		})
+		assert.HasNoError(t, err)
+		assert.Equals(t, token.Id, 1)
+		_, err = store.CreateApiToken(ctx, &models.ApiToken{
+			UserId: 2,
+			Name:   "Other",
+			Hash:   "other-hash",
+			Scopes: []string{models.ApiTokenScopeReadTasks},
+		})
+		assert.HasNoError(t, err)
+
+		got, err := store.GetApiTokenByHash(ctx, "hash")
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, *token)
+
+		lastUsedAt := time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC)
+		assert.HasNoError(t, store.UpdateApiTokenLastUsedAt(ctx, token.Id, lastUsedAt))
+		tokens, err := store.GetApiTokensByUserId(ctx, 1)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, tokens, 1)
+		assert.Equals(t, *tokens[0].LastUsedAt, lastUsedAt)
+
+		err = store.DeleteApiToken(ctx, token.Id, 2)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+		assert.HasNoError(t, store.DeleteApiToken(ctx, token.Id, 1))
+		_, err = store.GetApiTokenByHash(ctx, "hash")
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+}
+
 func TestFileSystemStoreUsers(t *testing.T) {
+	ctx := context.Background()
 	initialUsers := []models.User{
 		models.User{
 			Id:       1,
@@ -166,7 +679,10 @@ func TestFileSystemStoreUsers(t *testing.T) {
 			Password: "password",
 		},
 	}
-	jsonUsers, err := utils.ConvertToJSON(initialUsers)
+	jsonUsers, err := utils.ConvertToJSON(map[string]any{
+		"users":      initialUsers,
+		"lastUserId": 1,
+	})
 	assert.HasNoError(t, err)
 
 	t.Run("works with an empty file", func(t *testing.T) {
@@ -191,7 +707,7 @@ func TestFileSystemStoreUsers(t *testing.T) {
 			Email:    "john.doe@email.com",
 			Password: "password",
 		}
-		newUser, err := store.CreateUser(&dto)
+		newUser, err := store.CreateUser(ctx, &dto)
 		gotUser := models.User{
 			Id:    newUser.Id,
 			Name:  newUser.Name,
@@ -209,11 +725,44 @@ func TestFileSystemStoreUsers(t *testing.T) {
 		))
 		assert.Equals(t, gotUser, wantedUser)
 
-		users, err := store.GetUsers()
+		users, err := store.GetUsers(ctx)
 		assert.HasNoError(t, err)
 		assert.Contains(t, users, *newUser)
 	})
 
+	t.Run("CreateUser normalizes the email", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, "")
+		defer cleanDatabase()
+
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+
+		dto := models.NewCreateUserDTO("John Doe", " John.Doe@Email.com ", "password")
+		newUser, err := store.CreateUser(ctx, dto)
+		assert.HasNoError(t, err)
+		assert.Equals(t, newUser.Email, "john.doe@email.com")
+	})
+
+	t.Run("CreateUser returns an `ErrConflict` error if the email is taken", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, string(jsonUsers))
+		defer cleanDatabase()
+
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+
+		dto := models.NewCreateUserDTO(
+			"Someone Else",
+			"CLAUDE.ALDRIC@email.com",
+			"password",
+		)
+		_, err = store.CreateUser(ctx, dto)
+		assert.ErrorContains(t, err, data.ErrConflict)
+
+		users, err := store.GetUsers(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, users, initialUsers)
+	})
+
 	t.Run("GetUserByEmail returns the correct user if it exists", func(t *testing.T) {
 		database, cleanDatabase := testutils.CreateTempFile(t, string(jsonUsers))
 		defer cleanDatabase()
@@ -222,7 +771,21 @@ func TestFileSystemStoreUsers(t *testing.T) {
 		assert.HasNoError(t, err)
 
 		wantedUser := initialUsers[0]
-		got, err := store.GetUserByEmail(wantedUser.Email)
+		got, err := store.GetUserByEmail(ctx, wantedUser.Email)
+
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, wantedUser)
+	})
+
+	t.Run("GetUserByEmail ignores case and surrounding whitespace", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, string(jsonUsers))
+		defer cleanDatabase()
+
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+
+		wantedUser := initialUsers[0]
+		got, err := store.GetUserByEmail(ctx, " Claude.Aldric@Email.com")
 
 		assert.HasNoError(t, err)
 		assert.Equals(t, *got, wantedUser)
@@ -236,8 +799,116 @@ func TestFileSystemStoreUsers(t *testing.T) {
 		assert.HasNoError(t, err)
 
 		doesNotExistEmail := "does.not@exist.com"
-		_, err = store.GetUserByEmail(doesNotExistEmail)
+		_, err = store.GetUserByEmail(ctx, doesNotExistEmail)
+
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("GetUserById returns the correct user if it exists", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, string(jsonUsers))
+		defer cleanDatabase()
+
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+
+		got, err := store.GetUserById(ctx, initialUsers[0].Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, initialUsers[0])
+
+		_, err = store.GetUserById(ctx, -1)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("UpdateUser updates and returns the user", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, string(jsonUsers))
+		defer cleanDatabase()
+
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+
+		user := initialUsers[0]
+		user.Name = "Claude"
+		user.Email = "Claude@Email.com"
+		user.Verified = true
+		user.Role = models.RoleAdmin
+		user.Disabled = true
+		updatedUser, err := store.UpdateUser(ctx, &user)
+		assert.HasNoError(t, err)
+
+		wantedUser := user
+		wantedUser.Email = "claude@email.com"
+		assert.Equals(t, *updatedUser, wantedUser)
+		got, err := store.GetUserById(ctx, user.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, wantedUser)
+	})
+
+	t.Run("UpdateUser returns an `ErrConflict` error if the email is taken", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, string(jsonUsers))
+		defer cleanDatabase()
+
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+
+		otherUser, err := store.CreateUser(
+			ctx,
+			models.NewCreateUserDTO("John Doe", "john.doe@email.com", "password"),
+		)
+		assert.HasNoError(t, err)
+		otherUser.Email = initialUsers[0].Email
+		_, err = store.UpdateUser(ctx, otherUser)
+
+		assert.ErrorContains(t, err, data.ErrConflict)
+	})
+
+	t.Run("DeleteUserById deletes the user and their tokens", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, string(jsonUsers))
+		defer cleanDatabase()
+
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+
+		user := initialUsers[0]
+		purpose := models.UserTokenPurposePasswordReset
+		expiresAt := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		assert.HasNoError(t, store.CreateUserToken(
+			ctx,
+			models.NewUserToken("hash", user.Id, purpose, expiresAt),
+		))
+		_, err = store.CreateApiToken(ctx, &models.ApiToken{
+			UserId: user.Id,
+			Name:   "CI",
+			Hash:   "api-hash",
+			Scopes: []string{models.ApiTokenScopeReadTasks},
+		})
+		assert.HasNoError(t, err)
+
+		assert.HasNoError(t, store.DeleteUserById(ctx, user.Id))
+
+		users, err := store.GetUsers(ctx)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, users, 0)
+		_, err = store.ConsumeUserToken(ctx, "hash", purpose)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+		_, err = store.GetApiTokenByHash(ctx, "api-hash")
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+
+		err = store.DeleteUserById(ctx, user.Id)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("UpdateUserPassword hashes and stores the new password", func(t *testing.T) {
+		database, cleanDatabase := testutils.CreateTempFile(t, string(jsonUsers))
+		defer cleanDatabase()
+
+		store, err := data.NewFileSystemStore(database)
+		assert.HasNoError(t, err)
+
+		user := initialUsers[0]
+		assert.HasNoError(t, store.UpdateUserPassword(ctx, user.Id, "new password"))
+		assert.Equals(t, store.ValidateUserCredentials(ctx, user.Email, "new password"), true)
 
+		err = store.UpdateUserPassword(ctx, -1, "new password")
 		assert.ErrorContains(t, err, data.ErrResourceNotFound)
 	})
 
@@ -249,7 +920,7 @@ func TestFileSystemStoreUsers(t *testing.T) {
 
 		assert.HasNoError(t, err)
 
-		users, err := store.GetUsers()
+		users, err := store.GetUsers(ctx)
 
 		assert.HasNoError(t, err)
 		assert.Equals(t, users, initialUsers)
@@ -263,7 +934,7 @@ func TestFileSystemStoreUsers(t *testing.T) {
 		)
 		hashedPassword, err := bcrypt.GenerateFromPassword(
 			[]byte(createUserDTO.Password),
-			bcrypt.DefaultCost,
+			bcrypt.MinCost,
 		)
 		assert.HasNoError(t, err)
 
@@ -275,7 +946,10 @@ func TestFileSystemStoreUsers(t *testing.T) {
 				Password: string(hashedPassword),
 			},
 		}
-		jsonUsers, err := utils.ConvertToJSON(initialUsers)
+		jsonUsers, err := utils.ConvertToJSON(map[string]any{
+			"users":      initialUsers,
+			"lastUserId": 1,
+		})
 		assert.HasNoError(t, err)
 
 		database, cleanDatabase := testutils.CreateTempFile(t, string(jsonUsers))
@@ -314,7 +988,7 @@ func TestFileSystemStoreUsers(t *testing.T) {
 			t.Run(test.name, func(t *testing.T) {
 				assert.Equals(
 					t,
-					store.ValidateUserCredentials(test.email, test.password),
+					store.ValidateUserCredentials(ctx, test.email, test.password),
 					test.want,
 				)
 			})
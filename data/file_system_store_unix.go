@@ -0,0 +1,59 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package data
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+type fileLock struct {
+	file *os.File
+}
+
+// lockFile takes an exclusive lock on the file at path, creating it when
+// needed, and fails right away when another process holds it.
+func lockFile(path string) (*fileLock, error) {
+	for {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			file.Close()
+			return nil, fmt.Errorf("%s: %w", path, ErrStoreLocked)
+		}
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		// The previous holder removes the file before unlocking it, so the
+		// lock only counts when nobody removed the file in the meantime.
+		lockedInfo, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && os.SameFile(info, lockedInfo) {
+			return &fileLock{file}, nil
+		}
+		file.Close()
+	}
+}
+
+func (l *fileLock) unlock() error {
+	os.Remove(l.file.Name())
+	return l.file.Close()
+}
+
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
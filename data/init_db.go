@@ -2,17 +2,347 @@ package data
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 
 	"github.com/claudealdric/go-todolist-restful-api-server/models"
-	"golang.org/x/crypto/bcrypt"
 )
 
 func InitDb(db *sql.DB) {
+	initDb(db, true)
+}
+
+// InitEmptyDb creates the tables like InitDb without seeding them, for
+// databases that data is copied into.
+func InitEmptyDb(db *sql.DB) {
+	initDb(db, false)
+}
+
+func initDb(db *sql.DB, seed bool) {
 	createUsersTable(db)
-	seedUsersTable(db)
+	normalizeUsersEmails(db)
+	if addColumnIfNotExists(db, "users", "verified", "integer not null default 0") {
+		markExistingUsersVerified(db)
+	}
+	addColumnIfNotExists(
+		db,
+		"users",
+		"timezone",
+		fmt.Sprintf("text not null default '%s'", models.DefaultTimezone),
+	)
+	addColumnIfNotExists(
+		db,
+		"users",
+		"locale",
+		fmt.Sprintf("text not null default '%s'", models.DefaultLocale),
+	)
+	addColumnIfNotExists(
+		db,
+		"users",
+		"start_of_week",
+		fmt.Sprintf("text not null default '%s'", models.DefaultStartOfWeek),
+	)
+	if addColumnIfNotExists(
+		db,
+		"users",
+		"role",
+		fmt.Sprintf("text not null default '%s'", models.RoleUser),
+	) {
+		promoteFirstUserToAdmin(db)
+	}
+	addColumnIfNotExists(db, "users", "disabled", "integer not null default 0")
+	addColumnIfNotExists(db, "users", "password_changed_at", "datetime")
 	createTasksTable(db)
-	seedTasksTable(db)
+	if seed {
+		seedUsersTable(db)
+		seedTasksTable(db)
+	}
+	createProjectsTable(db)
+	createProjectMembersTable(db)
+	createProjectInvitationsTable(db)
+	if addColumnIfNotExists(db, "tasks", "project_id", "integer not null default 0") {
+		moveExistingTasksToProject(db)
+	}
+	addColumnIfNotExists(db, "tasks", "assignee_id", "integer not null default 0")
+	createLoginAttemptsTable(db)
+	createAuditEventsTable(db)
+	addColumnIfNotExists(db, "audit_events", "impersonator_id", "integer not null default 0")
+	createUserTokensTable(db)
+	addColumnIfNotExists(db, "user_tokens", "email", "text not null default ''")
+	createApiTokensTable(db)
+	createUserMfaTable(db)
+	createTaskEventsTable(db)
+	createTaskSnapshotsTable(db)
+	createTaskRevisionsTable(db)
+	createIndexes(db)
+}
+
+// createIndexes covers the lookups the store makes by something other than
+// the primary key.
+func createIndexes(db *sql.DB) {
+	_, err := db.Exec(`
+		create index if not exists project_members_user_id_idx
+			on project_members (user_id);
+		create index if not exists project_invitations_email_idx
+			on project_invitations (email);
+		create index if not exists tasks_project_id_idx on tasks (project_id);
+		create index if not exists tasks_assignee_id_idx on tasks (assignee_id);
+		create index if not exists user_tokens_user_id_idx
+			on user_tokens (user_id, purpose);
+		create index if not exists api_tokens_user_id_idx on api_tokens (user_id);
+		create index if not exists task_events_task_id_idx on task_events (task_id);
+	`)
+	if err != nil {
+		log.Fatalln("failed creating the indexes:", err)
+	}
+}
+
+func addColumnIfNotExists(db *sql.DB, table, column, definition string) bool {
+	var count int
+	err := db.QueryRow(`
+		select count(*) from pragma_table_info(?) where name = ?
+	`, table, column).Scan(&count)
+	if err != nil {
+		log.Fatalf("failed inspecting the %s table: %v", table, err)
+	}
+	if count > 0 {
+		return false
+	}
+	_, err = db.Exec(fmt.Sprintf(
+		"alter table %s add column %s %s",
+		table,
+		column,
+		definition,
+	))
+	if err != nil {
+		log.Fatalf("failed adding the %s.%s column: %v", table, column, err)
+	}
+	return true
+}
+
+// Accounts created before email verification existed are trusted as they
+// are; only new sign-ups have to verify their email.
+func markExistingUsersVerified(db *sql.DB) {
+	_, err := db.Exec(`update users set verified = 1`)
+	if err != nil {
+		log.Fatalln("failed marking the existing users as verified:", err)
+	}
+}
+
+// The first account is the one seeded for the owner of the instance, so it
+// keeps being able to manage the others once roles exist.
+func promoteFirstUserToAdmin(db *sql.DB) {
+	_, err := db.Exec(`
+		update users
+		set role = ?
+		where id = (select min(id) from users)
+	`, models.RoleAdmin)
+	if err != nil {
+		log.Fatalln("failed promoting the first user to admin:", err)
+	}
+}
+
+// Tasks were shared by everyone before projects existed, so they all move to
+// a project owned by the first user, who can then invite the others.
+func moveExistingTasksToProject(db *sql.DB) {
+	tx, err := db.Begin()
+	if err != nil {
+		log.Fatalln("failed moving the existing tasks to a project:", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`insert into projects (name) values ('Tasks')`)
+	if err != nil {
+		log.Fatalln("failed creating the project of the existing tasks:", err)
+	}
+	projectId, err := result.LastInsertId()
+	if err != nil {
+		log.Fatalln("failed creating the project of the existing tasks:", err)
+	}
+	_, err = tx.Exec(`
+		insert into project_members (project_id, user_id, role)
+		select ?, min(id), ? from users having count(*) > 0
+	`, projectId, models.ProjectRoleOwner)
+	if err != nil {
+		log.Fatalln("failed adding the owner of the existing tasks:", err)
+	}
+	_, err = tx.Exec(`update tasks set project_id = ?`, projectId)
+	if err != nil {
+		log.Fatalln("failed moving the existing tasks to a project:", err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Fatalln("failed moving the existing tasks to a project:", err)
+	}
+}
+
+func createProjectsTable(db *sql.DB) {
+	_, err := db.Exec(`
+		create table if not exists projects (
+			id integer primary key autoincrement,
+			name text not null
+		)
+	`)
+	if err != nil {
+		log.Fatalln("failed creating the projects table:", err)
+	}
+}
+
+func createProjectMembersTable(db *sql.DB) {
+	_, err := db.Exec(`
+		create table if not exists project_members (
+			project_id integer not null,
+			user_id integer not null,
+			role text not null,
+			primary key (project_id, user_id)
+		)
+	`)
+	if err != nil {
+		log.Fatalln("failed creating the project_members table:", err)
+	}
+}
+
+func createProjectInvitationsTable(db *sql.DB) {
+	_, err := db.Exec(`
+		create table if not exists project_invitations (
+			id integer primary key autoincrement,
+			project_id integer not null,
+			email text not null,
+			role text not null,
+			invited_by integer not null,
+			created_at datetime not null,
+			unique (project_id, email)
+		)
+	`)
+	if err != nil {
+		log.Fatalln("failed creating the project_invitations table:", err)
+	}
+}
+
+func createUserTokensTable(db *sql.DB) {
+	_, err := db.Exec(`
+		create table if not exists user_tokens (
+			hash text primary key,
+			user_id integer not null,
+			purpose text not null,
+			expires_at datetime not null
+		)
+	`)
+	if err != nil {
+		log.Fatalln("failed creating the user_tokens table:", err)
+	}
+}
+
+func createApiTokensTable(db *sql.DB) {
+	_, err := db.Exec(`
+		create table if not exists api_tokens (
+			id integer primary key autoincrement,
+			user_id integer not null,
+			name text not null,
+			hash text not null unique,
+			scopes text not null,
+			expires_at datetime,
+			last_used_at datetime,
+			created_at datetime not null
+		)
+	`)
+	if err != nil {
+		log.Fatalln("failed creating the api_tokens table:", err)
+	}
+}
+
+func createUserMfaTable(db *sql.DB) {
+	_, err := db.Exec(`
+		create table if not exists user_mfa (
+			user_id integer primary key,
+			totp_secret text not null,
+			enabled integer not null default 0,
+			last_used_step integer not null default 0
+		)
+	`)
+	if err != nil {
+		log.Fatalln("failed creating the user_mfa table:", err)
+	}
+}
+
+// createTaskEventsTable creates the log of task changes that the
+// EventSourcedStore keeps instead of the tasks table.
+func createTaskEventsTable(db *sql.DB) {
+	_, err := db.Exec(`
+		create table if not exists task_events (
+			id integer primary key autoincrement,
+			type text not null,
+			task_id integer not null,
+			title text not null,
+			project_id integer not null,
+			assignee_id integer not null,
+			created_at datetime not null
+		)
+	`)
+	if err != nil {
+		log.Fatalln("failed creating the task_events table:", err)
+	}
+}
+
+func createTaskSnapshotsTable(db *sql.DB) {
+	_, err := db.Exec(`
+		create table if not exists task_snapshots (
+			event_id integer primary key,
+			last_task_id integer not null,
+			tasks text not null,
+			created_at datetime not null
+		)
+	`)
+	if err != nil {
+		log.Fatalln("failed creating the task_snapshots table:", err)
+	}
+}
+
+func createTaskRevisionsTable(db *sql.DB) {
+	_, err := db.Exec(`
+		create table if not exists task_revisions (
+			task_id integer not null,
+			revision integer not null,
+			title text not null,
+			assignee_id integer not null,
+			changed_fields text not null,
+			editor_id integer not null,
+			created_at datetime not null,
+			primary key (task_id, revision)
+		)
+	`)
+	if err != nil {
+		log.Fatalln("failed creating the task_revisions table:", err)
+	}
+}
+
+func createAuditEventsTable(db *sql.DB) {
+	_, err := db.Exec(`
+		create table if not exists audit_events (
+			id integer primary key autoincrement,
+			action text not null,
+			actor_id integer not null default 0,
+			subject text not null,
+			details text not null default '',
+			created_at datetime not null
+		)
+	`)
+	if err != nil {
+		log.Fatalln("failed creating the audit_events table:", err)
+	}
+}
+
+func createLoginAttemptsTable(db *sql.DB) {
+	_, err := db.Exec(`
+		create table if not exists login_attempts (
+			key text primary key,
+			failures integer not null,
+			last_failure_at datetime not null,
+			locked_until datetime not null
+		)
+	`)
+	if err != nil {
+		log.Fatalln("failed creating the login_attempts table:", err)
+	}
 }
 
 func createTasksTable(db *sql.DB) {
@@ -41,24 +371,71 @@ func createUsersTable(db *sql.DB) {
 	}
 }
 
+// normalizeUsersEmails lowercases and trims the users emails. Since the email
+// is unique, only one account of those whose emails differ by case or
+// surrounding spaces can have it normalized: the one already holding the
+// normalized address, or else the oldest. That account signs in with the
+// address and keeps new sign-ups from taking it; the others cannot sign in,
+// and are reported so that they can be merged by hand.
+func normalizeUsersEmails(db *sql.DB) {
+	_, err := db.Exec(`
+		update users
+		set email = lower(trim(email))
+		where email != lower(trim(email))
+			and not exists (
+				select 1
+				from users other
+				where other.id != users.id
+					and lower(trim(other.email)) = lower(trim(users.email))
+					and (other.email = lower(trim(other.email)) or other.id < users.id)
+			)
+	`)
+	if err != nil {
+		log.Fatalln("failed normalizing the users emails:", err)
+	}
+
+	rows, err := db.Query(`
+		select id, email
+		from users
+		where email != lower(trim(email))
+		order by id
+	`)
+	if err != nil {
+		log.Fatalln("failed looking for users sharing an email:", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var email string
+		if err := rows.Scan(&id, &email); err != nil {
+			log.Fatalln("failed looking for users sharing an email:", err)
+		}
+		log.Printf(
+			"user %d cannot sign in, another user has the email %q once normalized, merge them by hand\n",
+			id,
+			email,
+		)
+	}
+	if err := rows.Err(); err != nil {
+		log.Fatalln("failed looking for users sharing an email:", err)
+	}
+}
+
 func seedUsersTable(db *sql.DB) {
 	dto := models.NewCreateUserDTO(
 		"Claude Aldric",
 		"cvaldric@gmail.com",
 		"Caput Draconis",
 	)
-	hashedPassword, err := bcrypt.GenerateFromPassword(
-		[]byte(dto.Password),
-		bcrypt.DefaultCost,
-	)
+	hashedPassword, err := hashPassword(dto.Password)
 	if err != nil {
 		log.Fatalln("failed at hashing the password:", err)
 	}
 	_, err = db.Exec(`
-		insert into users (name, email, password)
-		select ?, ?, ?
+		insert into users (name, email, password, verified, role)
+		select ?, ?, ?, 1, ?
 		where not exists (select 1 from users)
-	`, dto.Name, dto.Email, hashedPassword)
+	`, dto.Name, dto.Email, hashedPassword, models.RoleAdmin)
 	if err != nil {
 		log.Fatalln("failed seeding the users table:", err)
 	}
@@ -0,0 +1,21 @@
+package data
+
+import (
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+// countLoginFailure adds a failure to the attempt, starting the count over
+// when the previous failure is older than expireAfter.
+func countLoginFailure(
+	attempt *models.LoginAttempt,
+	failedAt time.Time,
+	expireAfter time.Duration,
+) {
+	if failedAt.Sub(attempt.LastFailureAt) > expireAfter {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = failedAt
+}
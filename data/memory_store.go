@@ -0,0 +1,1397 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/utils"
+)
+
+var errStoreClosed = errors.New("store is closed")
+
+// MemoryStore keeps everything in memory, so its data is lost when the
+// process exits unless it runs WithSnapshot.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	data   *storeData
+	closed bool
+
+	// persist is given each write and can reject it by returning an error.
+	persist      func(edit *storeEdit) error
+	snapshotPath string
+}
+
+type MemoryStoreOption func(*MemoryStore)
+
+// WithSnapshot loads the data from the file at path when it exists and
+// writes the data back to it on Close.
+func WithSnapshot(path string) MemoryStoreOption {
+	return func(m *MemoryStore) {
+		m.snapshotPath = path
+	}
+}
+
+type storeData struct {
+	ApiTokens               []models.ApiToken          `json:"apiTokens"`
+	AuditEvents             []models.AuditEvent        `json:"auditEvents"`
+	LastApiTokenId          int                        `json:"lastApiTokenId"`
+	LastAuditEventId        int                        `json:"lastAuditEventId"`
+	LastProjectId           int                        `json:"lastProjectId"`
+	LastProjectInvitationId int                        `json:"lastProjectInvitationId"`
+	LastTaskId              int                        `json:"lastTaskId"`
+	LastUserId              int                        `json:"lastUserId"`
+	LoginAttempts           []models.LoginAttempt      `json:"loginAttempts"`
+	ProjectInvitations      []models.ProjectInvitation `json:"projectInvitations"`
+	ProjectMembers          []models.ProjectMember     `json:"projectMembers"`
+	Projects                []models.Project           `json:"projects"`
+	TaskRevisions           []models.TaskRevision      `json:"taskRevisions"`
+	Tasks                   []models.Task              `json:"tasks"`
+	UserMfa                 []models.UserMfa           `json:"userMfa"`
+	UserTokens              []models.UserToken         `json:"userTokens"`
+	Users                   []models.User              `json:"users"`
+}
+
+func NewMemoryStore(options ...MemoryStoreOption) (*MemoryStore, error) {
+	store := &MemoryStore{data: &storeData{}}
+	for _, option := range options {
+		option(store)
+	}
+	if store.snapshotPath == "" {
+		return store, nil
+	}
+
+	file, err := os.Open(store.snapshotPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("problem opening the snapshot, %v", err)
+	}
+	defer file.Close()
+	data, err := readFile(file)
+	if err != nil {
+		return nil, err
+	}
+	store.data = data
+	return store, nil
+}
+
+// Close writes the snapshot when the store has one; the store cannot be
+// written to afterwards.
+func (m *MemoryStore) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	if m.snapshotPath == "" {
+		return nil
+	}
+	return writeSnapshot(m.snapshotPath, m.data)
+}
+
+func (m *MemoryStore) GetTaskById(ctx context.Context, id int) (*models.Task, error) {
+	tasks, err := m.GetTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	task, ok := utils.SliceFind(tasks, func(t models.Task) bool {
+		return t.Id == id
+	})
+	if !ok {
+		return nil, fmt.Errorf(
+			"task with ID %d: %w",
+			id,
+			ErrResourceNotFound,
+		)
+	}
+	return &task, nil
+}
+
+func (m *MemoryStore) GetTasks(ctx context.Context) ([]models.Task, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, err := m.readData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(data.Tasks), nil
+}
+
+func (m *MemoryStore) GetTasksByProjectId(
+	ctx context.Context,
+	projectId int,
+) ([]models.Task, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, err := m.readData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []models.Task
+	for _, task := range data.Tasks {
+		if task.ProjectId == projectId {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+func (m *MemoryStore) CreateTask(
+	ctx context.Context,
+	dto *models.CreateTaskDTO,
+) (*models.Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data.LastTaskId++
+	task := models.Task{
+		Id:         data.LastTaskId,
+		Title:      dto.Title,
+		ProjectId:  dto.ProjectId,
+		AssigneeId: dto.AssigneeId,
+	}
+	taskRecords.put(data, task)
+	err = m.writeData(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (m *MemoryStore) DeleteTaskById(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(data.Tasks, func(task models.Task) bool {
+		return task.Id == id
+	})
+	if i == -1 {
+		return fmt.Errorf("error with task ID %d: %w", id, ErrResourceNotFound)
+	}
+	taskRecords.deleteAt(data, i)
+	taskRevisionRecords.deleteFunc(data, func(r models.TaskRevision) bool {
+		return r.TaskId == id
+	})
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) UpdateTask(
+	ctx context.Context,
+	task *models.Task,
+) (*models.Task, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(data.Tasks, func(t models.Task) bool {
+		return t.Id == task.Id
+	})
+	if i == -1 {
+		return nil, fmt.Errorf(
+			"task with ID %d: %w",
+			task.Id,
+			ErrResourceNotFound,
+		)
+	}
+
+	oldTask := data.Tasks[i]
+	updatedTask := oldTask
+	updatedTask.Title = task.Title
+	updatedTask.AssigneeId = task.AssigneeId
+	taskRecords.put(data, updatedTask)
+	revision := models.NewTaskRevision(&oldTask, &updatedTask, editorFromContext(ctx))
+	if revision != nil {
+		revision.Revision = 1
+		for _, r := range data.TaskRevisions {
+			if r.TaskId == task.Id {
+				revision.Revision = max(revision.Revision, r.Revision+1)
+			}
+		}
+		revision.CreatedAt = time.Now().UTC()
+		taskRevisionRecords.put(data, *revision)
+	}
+	err = m.writeData(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &updatedTask, nil
+}
+
+func (m *MemoryStore) GetTaskRevisions(
+	ctx context.Context,
+	taskId int,
+) ([]models.TaskRevision, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, err := m.readData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var revisions []models.TaskRevision
+	for _, revision := range data.TaskRevisions {
+		if revision.TaskId == taskId {
+			revisions = append(revisions, revision)
+		}
+	}
+	return revisions, nil
+}
+
+func (m *MemoryStore) GetTaskRevision(
+	ctx context.Context,
+	taskId, revision int,
+) (*models.TaskRevision, error) {
+	revisions, err := m.GetTaskRevisions(ctx, taskId)
+	if err != nil {
+		return nil, err
+	}
+	taskRevision, ok := utils.SliceFind(revisions, func(r models.TaskRevision) bool {
+		return r.Revision == revision
+	})
+	if !ok {
+		return nil, fmt.Errorf(
+			"revision %d of task with ID %d: %w",
+			revision,
+			taskId,
+			ErrResourceNotFound,
+		)
+	}
+	return &taskRevision, nil
+}
+
+func (m *MemoryStore) GetUserByEmail(
+	ctx context.Context,
+	email string,
+) (*models.User, error) {
+	users, err := m.GetUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	email = NormalizeEmail(email)
+	user, ok := utils.SliceFind(users, func(u models.User) bool {
+		return NormalizeEmail(u.Email) == email
+	})
+	if !ok {
+		return nil, fmt.Errorf(
+			"user with email %s: %w",
+			email,
+			ErrResourceNotFound,
+		)
+	}
+	return &user, nil
+}
+
+func (m *MemoryStore) CreateUser(
+	ctx context.Context,
+	dto *models.CreateUserDTO,
+) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	email := NormalizeEmail(dto.Email)
+	if slices.ContainsFunc(data.Users, func(u models.User) bool {
+		return NormalizeEmail(u.Email) == email
+	}) {
+		return nil, fmt.Errorf("user with email %s: %w", email, ErrConflict)
+	}
+	hashedPassword, err := hashPassword(dto.Password)
+	if err != nil {
+		return nil, err
+	}
+	data.LastUserId++
+	user := *models.NewUser(
+		data.LastUserId,
+		dto.Name,
+		email,
+		string(hashedPassword),
+	)
+	userRecords.put(data, user)
+	err = m.writeData(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (m *MemoryStore) GetUsers(ctx context.Context) ([]models.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, err := m.readData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(data.Users), nil
+}
+
+func (m *MemoryStore) GetUserById(ctx context.Context, id int) (*models.User, error) {
+	users, err := m.GetUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, ok := utils.SliceFind(users, func(u models.User) bool {
+		return u.Id == id
+	})
+	if !ok {
+		return nil, fmt.Errorf("user with ID %d: %w", id, ErrResourceNotFound)
+	}
+	return &user, nil
+}
+
+func (m *MemoryStore) UpdateUser(
+	ctx context.Context,
+	user *models.User,
+) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(data.Users, func(u models.User) bool {
+		return u.Id == user.Id
+	})
+	if i == -1 {
+		return nil, fmt.Errorf(
+			"user with ID %d: %w",
+			user.Id,
+			ErrResourceNotFound,
+		)
+	}
+	email := NormalizeEmail(user.Email)
+	if slices.ContainsFunc(data.Users, func(u models.User) bool {
+		return u.Id != user.Id && NormalizeEmail(u.Email) == email
+	}) {
+		return nil, fmt.Errorf("user with email %s: %w", email, ErrConflict)
+	}
+
+	updatedUser := *user
+	updatedUser.Email = email
+	updatedUser.Password = data.Users[i].Password
+	updatedUser.PasswordChangedAt = data.Users[i].PasswordChangedAt
+	userRecords.put(data, updatedUser)
+	err = m.writeData(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return &updatedUser, nil
+}
+
+func (m *MemoryStore) DeleteUserById(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(data.Users, func(u models.User) bool {
+		return u.Id == id
+	})
+	if i == -1 {
+		return fmt.Errorf("user with ID %d: %w", id, ErrResourceNotFound)
+	}
+	userRecords.deleteAt(data, i)
+	userTokenRecords.deleteFunc(data, func(t models.UserToken) bool {
+		return t.UserId == id
+	})
+	apiTokenRecords.deleteFunc(data, func(t models.ApiToken) bool {
+		return t.UserId == id
+	})
+	userMfaRecords.deleteFunc(data, func(m models.UserMfa) bool {
+		return m.UserId == id
+	})
+	removeUserFromProjects(data, id)
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) UpdateUserPassword(
+	ctx context.Context,
+	id int,
+	password string,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(data.Users, func(u models.User) bool {
+		return u.Id == id
+	})
+	if i == -1 {
+		return fmt.Errorf("user with ID %d: %w", id, ErrResourceNotFound)
+	}
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	user := data.Users[i]
+	user.Password = string(hashedPassword)
+	passwordChangedAt := time.Now().UTC()
+	user.PasswordChangedAt = &passwordChangedAt
+	userRecords.put(data, user)
+	apiTokenRecords.deleteFunc(data, func(t models.ApiToken) bool {
+		return t.UserId == id
+	})
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) ValidateUserCredentials(
+	ctx context.Context,
+	email, password string,
+) bool {
+	user, err := m.GetUserByEmail(ctx, email)
+	if err != nil {
+		simulatePasswordCheck(password)
+		return false
+	}
+	return checkPassword([]byte(user.Password), password)
+}
+
+func (m *MemoryStore) DeleteUserMfa(ctx context.Context, userId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	userMfaRecords.deleteFunc(data, func(m models.UserMfa) bool {
+		return m.UserId == userId
+	})
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) GetUserMfa(
+	ctx context.Context,
+	userId int,
+) (*models.UserMfa, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, err := m.readData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	mfa, ok := utils.SliceFind(data.UserMfa, func(m models.UserMfa) bool {
+		return m.UserId == userId
+	})
+	if !ok {
+		return nil, fmt.Errorf(
+			"MFA settings of user with ID %d: %w",
+			userId,
+			ErrResourceNotFound,
+		)
+	}
+	return &mfa, nil
+}
+
+func (m *MemoryStore) SaveUserMfa(ctx context.Context, mfa *models.UserMfa) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	userMfaRecords.put(data, *mfa)
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) UseUserMfaStep(
+	ctx context.Context,
+	userId int,
+	step int64,
+) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return false, err
+	}
+	i := slices.IndexFunc(data.UserMfa, func(m models.UserMfa) bool {
+		return m.UserId == userId
+	})
+	if i == -1 || data.UserMfa[i].LastUsedStep >= step {
+		return false, nil
+	}
+	mfa := data.UserMfa[i]
+	mfa.LastUsedStep = step
+	userMfaRecords.put(data, mfa)
+	return true, m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) DeleteLoginAttempt(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	loginAttemptRecords.deleteFunc(data, func(a models.LoginAttempt) bool {
+		return a.Key == key
+	})
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) GetLoginAttempt(
+	ctx context.Context,
+	key string,
+) (*models.LoginAttempt, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, err := m.readData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	attempt, ok := utils.SliceFind(
+		data.LoginAttempts,
+		func(a models.LoginAttempt) bool {
+			return a.Key == key
+		},
+	)
+	if !ok {
+		return nil, fmt.Errorf(
+			"login attempt with key %s: %w",
+			key,
+			ErrResourceNotFound,
+		)
+	}
+	return &attempt, nil
+}
+
+func (m *MemoryStore) SaveLoginAttempt(
+	ctx context.Context,
+	attempt *models.LoginAttempt,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	loginAttemptRecords.put(data, *attempt)
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) IncrementLoginFailures(
+	ctx context.Context,
+	key string,
+	failedAt time.Time,
+	expireAfter time.Duration,
+) (*models.LoginAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	attempt, ok := utils.SliceFind(
+		data.LoginAttempts,
+		func(a models.LoginAttempt) bool {
+			return a.Key == key
+		},
+	)
+	if !ok {
+		attempt = models.LoginAttempt{Key: key}
+	}
+	countLoginFailure(&attempt, failedAt, expireAfter)
+	loginAttemptRecords.put(data, attempt)
+	if err := m.writeData(ctx, data); err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (m *MemoryStore) LockLoginAttempt(
+	ctx context.Context,
+	key string,
+	failures int,
+	lockedUntil time.Time,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	attempt, ok := utils.SliceFind(
+		data.LoginAttempts,
+		func(a models.LoginAttempt) bool {
+			return a.Key == key
+		},
+	)
+	if !ok || attempt.Failures != failures {
+		return nil
+	}
+	attempt.LockedUntil = lockedUntil
+	loginAttemptRecords.put(data, attempt)
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) CreateAuditEvent(
+	ctx context.Context,
+	event *models.AuditEvent,
+) (*models.AuditEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data.LastAuditEventId++
+	createdEvent := *event
+	createdEvent.Id = data.LastAuditEventId
+	if createdEvent.CreatedAt.IsZero() {
+		createdEvent.CreatedAt = time.Now().UTC()
+	}
+	auditEventRecords.put(data, createdEvent)
+	err = m.writeData(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return &createdEvent, nil
+}
+
+func (m *MemoryStore) GetAuditEvents(
+	ctx context.Context,
+) ([]models.AuditEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, err := m.readData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(data.AuditEvents), nil
+}
+
+func (m *MemoryStore) ConsumeUserToken(
+	ctx context.Context,
+	hash, purpose string,
+) (*models.UserToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(data.UserTokens, func(t models.UserToken) bool {
+		return t.Hash == hash && t.Purpose == purpose
+	})
+	if i == -1 {
+		return nil, fmt.Errorf("%s token: %w", purpose, ErrResourceNotFound)
+	}
+	token := data.UserTokens[i]
+	userTokenRecords.deleteAt(data, i)
+	err = m.writeData(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (m *MemoryStore) CreateUserToken(
+	ctx context.Context,
+	token *models.UserToken,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	userTokenRecords.put(data, *token)
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) DeleteUserTokens(
+	ctx context.Context,
+	userId int,
+	purpose string,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	userTokenRecords.deleteFunc(data, func(t models.UserToken) bool {
+		return t.UserId == userId && t.Purpose == purpose
+	})
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) CreateApiToken(
+	ctx context.Context,
+	token *models.ApiToken,
+) (*models.ApiToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data.LastApiTokenId++
+	createdToken := *token
+	createdToken.Id = data.LastApiTokenId
+	if createdToken.CreatedAt.IsZero() {
+		createdToken.CreatedAt = time.Now().UTC()
+	}
+	apiTokenRecords.put(data, createdToken)
+	err = m.writeData(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return &createdToken, nil
+}
+
+func (m *MemoryStore) DeleteApiToken(ctx context.Context, id, userId int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(data.ApiTokens, func(t models.ApiToken) bool {
+		return t.Id == id && t.UserId == userId
+	})
+	if i == -1 {
+		return fmt.Errorf("API token with ID %d: %w", id, ErrResourceNotFound)
+	}
+	apiTokenRecords.deleteAt(data, i)
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) GetApiTokenByHash(
+	ctx context.Context,
+	hash string,
+) (*models.ApiToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, err := m.readData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	token, ok := utils.SliceFind(data.ApiTokens, func(t models.ApiToken) bool {
+		return t.Hash == hash
+	})
+	if !ok {
+		return nil, fmt.Errorf("API token: %w", ErrResourceNotFound)
+	}
+	return &token, nil
+}
+
+func (m *MemoryStore) GetApiTokensByUserId(
+	ctx context.Context,
+	userId int,
+) ([]models.ApiToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, err := m.readData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var tokens []models.ApiToken
+	for _, token := range data.ApiTokens {
+		if token.UserId == userId {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (m *MemoryStore) UpdateApiTokenLastUsedAt(
+	ctx context.Context,
+	id int,
+	lastUsedAt time.Time,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(data.ApiTokens, func(t models.ApiToken) bool {
+		return t.Id == id
+	})
+	if i == -1 {
+		return fmt.Errorf("API token with ID %d: %w", id, ErrResourceNotFound)
+	}
+	token := data.ApiTokens[i]
+	token.LastUsedAt = &lastUsedAt
+	apiTokenRecords.put(data, token)
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) CreateProject(
+	ctx context.Context,
+	dto *models.CreateProjectDTO,
+	ownerId int,
+) (*models.Project, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data.LastProjectId++
+	project := models.Project{Id: data.LastProjectId, Name: dto.Name}
+	projectRecords.put(data, project)
+	projectMemberRecords.put(data, models.ProjectMember{
+		ProjectId: project.Id,
+		UserId:    ownerId,
+		Role:      models.ProjectRoleOwner,
+	})
+	err = m.writeData(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (m *MemoryStore) GetProjectById(
+	ctx context.Context,
+	id int,
+) (*models.Project, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, err := m.readData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	project, ok := utils.SliceFind(data.Projects, func(p models.Project) bool {
+		return p.Id == id
+	})
+	if !ok {
+		return nil, fmt.Errorf("project with ID %d: %w", id, ErrResourceNotFound)
+	}
+	return &project, nil
+}
+
+func (m *MemoryStore) DeleteProjectMember(
+	ctx context.Context,
+	projectId, userId int,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(data.ProjectMembers, func(m models.ProjectMember) bool {
+		return m.ProjectId == projectId && m.UserId == userId
+	})
+	if i == -1 {
+		return fmt.Errorf(
+			"user with ID %d in project with ID %d: %w",
+			userId,
+			projectId,
+			ErrResourceNotFound,
+		)
+	}
+	projectMemberRecords.deleteAt(data, i)
+	for _, task := range data.Tasks {
+		if task.ProjectId == projectId && task.AssigneeId == userId {
+			task.AssigneeId = 0
+			taskRecords.put(data, task)
+		}
+	}
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) GetProjectMember(
+	ctx context.Context,
+	projectId, userId int,
+) (*models.ProjectMember, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, err := m.readData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	member, ok := utils.SliceFind(
+		data.ProjectMembers,
+		func(m models.ProjectMember) bool {
+			return m.ProjectId == projectId && m.UserId == userId
+		},
+	)
+	if !ok {
+		return nil, fmt.Errorf(
+			"user with ID %d in project with ID %d: %w",
+			userId,
+			projectId,
+			ErrResourceNotFound,
+		)
+	}
+	return &member, nil
+}
+
+func (m *MemoryStore) GetProjectMembers(
+	ctx context.Context,
+	projectId int,
+) ([]models.ProjectMember, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, err := m.readData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var members []models.ProjectMember
+	for _, member := range data.ProjectMembers {
+		if member.ProjectId == projectId {
+			members = append(members, member)
+		}
+	}
+	slices.SortFunc(members, func(a, b models.ProjectMember) int {
+		return a.UserId - b.UserId
+	})
+	return members, nil
+}
+
+func (m *MemoryStore) GetProjectMembersByUserId(
+	ctx context.Context,
+	userId int,
+) ([]models.ProjectMember, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, err := m.readData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var members []models.ProjectMember
+	for _, member := range data.ProjectMembers {
+		if member.UserId == userId {
+			members = append(members, member)
+		}
+	}
+	slices.SortFunc(members, func(a, b models.ProjectMember) int {
+		return a.ProjectId - b.ProjectId
+	})
+	return members, nil
+}
+
+func (m *MemoryStore) SaveProjectMember(
+	ctx context.Context,
+	member *models.ProjectMember,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	projectMemberRecords.put(data, *member)
+	return m.writeData(ctx, data)
+}
+
+// AcceptProjectInvitation turns the invitation into a membership, keeping the
+// role of users who already are members.
+func (m *MemoryStore) AcceptProjectInvitation(
+	ctx context.Context,
+	id, userId int,
+) (*models.ProjectMember, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(
+		data.ProjectInvitations,
+		func(inv models.ProjectInvitation) bool {
+			return inv.Id == id
+		},
+	)
+	if i == -1 {
+		return nil, fmt.Errorf(
+			"project invitation with ID %d: %w",
+			id,
+			ErrResourceNotFound,
+		)
+	}
+	invitation := data.ProjectInvitations[i]
+	projectInvitationRecords.deleteAt(data, i)
+	member, ok := utils.SliceFind(
+		data.ProjectMembers,
+		func(m models.ProjectMember) bool {
+			return m.ProjectId == invitation.ProjectId && m.UserId == userId
+		},
+	)
+	if !ok {
+		member = models.ProjectMember{
+			ProjectId: invitation.ProjectId,
+			UserId:    userId,
+			Role:      invitation.Role,
+		}
+		projectMemberRecords.put(data, member)
+	}
+	err = m.writeData(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (m *MemoryStore) CreateProjectInvitation(
+	ctx context.Context,
+	invitation *models.ProjectInvitation,
+) (*models.ProjectInvitation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	createdInvitation := *invitation
+	createdInvitation.Email = NormalizeEmail(invitation.Email)
+	if slices.ContainsFunc(
+		data.ProjectInvitations,
+		func(inv models.ProjectInvitation) bool {
+			return inv.ProjectId == createdInvitation.ProjectId &&
+				inv.Email == createdInvitation.Email
+		},
+	) {
+		return nil, fmt.Errorf(
+			"invitation of %s to project with ID %d: %w",
+			createdInvitation.Email,
+			createdInvitation.ProjectId,
+			ErrConflict,
+		)
+	}
+	data.LastProjectInvitationId++
+	createdInvitation.Id = data.LastProjectInvitationId
+	if createdInvitation.CreatedAt.IsZero() {
+		createdInvitation.CreatedAt = time.Now().UTC()
+	}
+	projectInvitationRecords.put(data, createdInvitation)
+	err = m.writeData(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return &createdInvitation, nil
+}
+
+func (m *MemoryStore) DeleteProjectInvitation(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(
+		data.ProjectInvitations,
+		func(inv models.ProjectInvitation) bool {
+			return inv.Id == id
+		},
+	)
+	if i == -1 {
+		return fmt.Errorf(
+			"project invitation with ID %d: %w",
+			id,
+			ErrResourceNotFound,
+		)
+	}
+	projectInvitationRecords.deleteAt(data, i)
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) GetProjectInvitationById(
+	ctx context.Context,
+	id int,
+) (*models.ProjectInvitation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, err := m.readData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	invitation, ok := utils.SliceFind(
+		data.ProjectInvitations,
+		func(inv models.ProjectInvitation) bool {
+			return inv.Id == id
+		},
+	)
+	if !ok {
+		return nil, fmt.Errorf(
+			"project invitation with ID %d: %w",
+			id,
+			ErrResourceNotFound,
+		)
+	}
+	return &invitation, nil
+}
+
+func (m *MemoryStore) GetProjectInvitationsByEmail(
+	ctx context.Context,
+	email string,
+) ([]models.ProjectInvitation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, err := m.readData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	email = NormalizeEmail(email)
+	var invitations []models.ProjectInvitation
+	for _, invitation := range data.ProjectInvitations {
+		if invitation.Email == email {
+			invitations = append(invitations, invitation)
+		}
+	}
+	return invitations, nil
+}
+
+func (m *MemoryStore) ImportProject(
+	ctx context.Context,
+	project *models.Project,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	projectRecords.put(data, *project)
+	data.LastProjectId = max(data.LastProjectId, project.Id)
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) ImportTask(ctx context.Context, task *models.Task) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	taskRecords.put(data, *task)
+	data.LastTaskId = max(data.LastTaskId, task.Id)
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) ImportUser(ctx context.Context, user *models.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	importedUser := *user
+	importedUser.Email = NormalizeEmail(user.Email)
+	if slices.ContainsFunc(data.Users, func(u models.User) bool {
+		return u.Id != user.Id && NormalizeEmail(u.Email) == importedUser.Email
+	}) {
+		return fmt.Errorf(
+			"user with email %s: %w",
+			importedUser.Email,
+			ErrConflict,
+		)
+	}
+	userRecords.put(data, importedUser)
+	data.LastUserId = max(data.LastUserId, user.Id)
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) ImportApiToken(ctx context.Context, token *models.ApiToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	if slices.ContainsFunc(data.ApiTokens, func(t models.ApiToken) bool {
+		return t.Id != token.Id && t.Hash == token.Hash
+	}) {
+		return fmt.Errorf("API token with ID %d: %w", token.Id, ErrConflict)
+	}
+	apiTokenRecords.put(data, *token)
+	data.LastApiTokenId = max(data.LastApiTokenId, token.Id)
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) ImportAuditEvent(
+	ctx context.Context,
+	event *models.AuditEvent,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	auditEventRecords.put(data, *event)
+	data.LastAuditEventId = max(data.LastAuditEventId, event.Id)
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) ImportProjectInvitation(
+	ctx context.Context,
+	invitation *models.ProjectInvitation,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	importedInvitation := *invitation
+	importedInvitation.Email = NormalizeEmail(invitation.Email)
+	if slices.ContainsFunc(
+		data.ProjectInvitations,
+		func(inv models.ProjectInvitation) bool {
+			return inv.Id != invitation.Id &&
+				inv.ProjectId == importedInvitation.ProjectId &&
+				inv.Email == importedInvitation.Email
+		},
+	) {
+		return fmt.Errorf(
+			"invitation of %s to project with ID %d: %w",
+			importedInvitation.Email,
+			importedInvitation.ProjectId,
+			ErrConflict,
+		)
+	}
+	projectInvitationRecords.put(data, importedInvitation)
+	data.LastProjectInvitationId = max(data.LastProjectInvitationId, invitation.Id)
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) ImportTaskRevision(
+	ctx context.Context,
+	revision *models.TaskRevision,
+) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.editData(ctx)
+	if err != nil {
+		return err
+	}
+	taskRevisionRecords.put(data, *revision)
+	return m.writeData(ctx, data)
+}
+
+func (m *MemoryStore) GetProjectInvitations(
+	ctx context.Context,
+) ([]models.ProjectInvitation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, err := m.readData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(data.ProjectInvitations), nil
+}
+
+func (m *MemoryStore) GetUserTokens(ctx context.Context) ([]models.UserToken, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, err := m.readData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tokens := slices.Clone(data.UserTokens)
+	slices.SortFunc(tokens, func(a, b models.UserToken) int {
+		return strings.Compare(a.Hash, b.Hash)
+	})
+	return tokens, nil
+}
+
+// removeUserFromProjects removes the user from their projects, unassigning
+// their tasks, and deletes the projects nobody is left in, along with their
+// tasks and invitations.
+func removeUserFromProjects(data *storeEdit, userId int) {
+	projectMemberRecords.deleteFunc(data, func(m models.ProjectMember) bool {
+		return m.UserId == userId
+	})
+	for _, task := range data.Tasks {
+		if task.AssigneeId == userId {
+			task.AssigneeId = 0
+			taskRecords.put(data, task)
+		}
+	}
+	isAbandoned := func(projectId int) bool {
+		return !slices.ContainsFunc(
+			data.ProjectMembers,
+			func(m models.ProjectMember) bool {
+				return m.ProjectId == projectId
+			},
+		)
+	}
+	var abandonedProjectIds []int
+	projectRecords.deleteFunc(data, func(p models.Project) bool {
+		if isAbandoned(p.Id) {
+			abandonedProjectIds = append(abandonedProjectIds, p.Id)
+			return true
+		}
+		return false
+	})
+	var deletedTaskIds []int
+	taskRecords.deleteFunc(data, func(t models.Task) bool {
+		if slices.Contains(abandonedProjectIds, t.ProjectId) {
+			deletedTaskIds = append(deletedTaskIds, t.Id)
+			return true
+		}
+		return false
+	})
+	taskRevisionRecords.deleteFunc(data, func(r models.TaskRevision) bool {
+		return slices.Contains(deletedTaskIds, r.TaskId)
+	})
+	projectInvitationRecords.deleteFunc(
+		data,
+		func(inv models.ProjectInvitation) bool {
+			return slices.Contains(abandonedProjectIds, inv.ProjectId)
+		},
+	)
+}
+
+// readData returns the loaded data, which callers must hold m.mu for and
+// must not change.
+func (m *MemoryStore) readData(ctx context.Context) (*storeData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.data, nil
+}
+
+// editData starts a write to the loaded data, which callers must hold m.mu
+// for writing around and finish with writeData before they return. Callers
+// check whether the write can be made before they change anything.
+func (m *MemoryStore) editData(ctx context.Context) (*storeEdit, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if m.closed {
+		return nil, errStoreClosed
+	}
+	return newStoreEdit(m.data), nil
+}
+
+// writeData takes the edit back unless persist accepted it, so a failed
+// write leaves both the loaded and the persisted data unchanged.
+func (m *MemoryStore) writeData(ctx context.Context, edit *storeEdit) error {
+	err := ctx.Err()
+	if err == nil && m.persist != nil {
+		err = m.persist(edit)
+	}
+	if err != nil {
+		edit.rollBack()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package data
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+// storeEdit is a single write to the loaded data. It changes the data in
+// place, so that the write reads its own changes, and remembers which
+// records it changed and how to take the changes back, so that a write that
+// cannot be persisted leaves the data as it was. Either costs as much as
+// the write itself rather than as much as the data.
+type storeEdit struct {
+	*storeData
+
+	before  storeData
+	changes []recordChange
+	// changeIndex finds the change of a record, so that a record changed
+	// twice ends up in the journal once, as it was left.
+	changeIndex map[string]int
+	undo        []func()
+}
+
+type recordChange struct {
+	collection string
+	key        string
+	record     any
+	deleted    bool
+}
+
+func newStoreEdit(data *storeData) *storeEdit {
+	return &storeEdit{
+		storeData:   data,
+		before:      *data,
+		changeIndex: map[string]int{},
+	}
+}
+
+func (e *storeEdit) track(collection, key string, record any, deleted bool) {
+	change := recordChange{collection, key, record, deleted}
+	id := collection + "\x00" + key
+	if i, ok := e.changeIndex[id]; ok {
+		e.changes[i] = change
+		return
+	}
+	e.changeIndex[id] = len(e.changes)
+	e.changes = append(e.changes, change)
+}
+
+// rollBack takes back every change of the edit, the last one first.
+func (e *storeEdit) rollBack() {
+	for i := len(e.undo) - 1; i >= 0; i-- {
+		e.undo[i]()
+	}
+	for _, counter := range journalCounters {
+		*counter(e.storeData) = *counter(&e.before)
+	}
+	e.undo = nil
+	e.changes = nil
+	clear(e.changeIndex)
+}
+
+// journalRecord holds what the edit changed, as it was left.
+func (e *storeEdit) journalRecord() (*journalRecord, error) {
+	var record journalRecord
+	for _, change := range e.changes {
+		if change.deleted {
+			if record.Deletes == nil {
+				record.Deletes = map[string][]string{}
+			}
+			record.Deletes[change.collection] = append(
+				record.Deletes[change.collection],
+				change.key,
+			)
+			continue
+		}
+		value, err := json.Marshal(change.record)
+		if err != nil {
+			return nil, err
+		}
+		if record.Puts == nil {
+			record.Puts = map[string][]json.RawMessage{}
+		}
+		record.Puts[change.collection] = append(record.Puts[change.collection], value)
+	}
+	for name, counter := range journalCounters {
+		if *counter(e.storeData) == *counter(&e.before) {
+			continue
+		}
+		if record.Counters == nil {
+			record.Counters = map[string]int{}
+		}
+		record.Counters[name] = *counter(e.storeData)
+	}
+	return &record, nil
+}
+
+// keyedCollection is one list of records in storeData, kept in the order of
+// compare so that records are found by binary search.
+type keyedCollection[T any] struct {
+	name    string
+	items   func(*storeData) *[]T
+	key     func(T) string
+	compare func(a, b T) int
+}
+
+// put adds the record, or replaces the one it compares equal to.
+func (c keyedCollection[T]) put(e *storeEdit, record T) {
+	items := c.items(e.storeData)
+	i, found := slices.BinarySearchFunc(*items, record, c.compare)
+	if found {
+		old := (*items)[i]
+		(*items)[i] = record
+		e.undo = append(e.undo, func() { (*c.items(e.storeData))[i] = old })
+	} else {
+		*items = slices.Insert(*items, i, record)
+		e.undo = append(e.undo, func() {
+			items := c.items(e.storeData)
+			*items = slices.Delete(*items, i, i+1)
+		})
+	}
+	e.track(c.name, c.key(record), record, false)
+}
+
+// deleteAt deletes the record at index i.
+func (c keyedCollection[T]) deleteAt(e *storeEdit, i int) {
+	items := c.items(e.storeData)
+	old := (*items)[i]
+	*items = slices.Delete(*items, i, i+1)
+	e.undo = append(e.undo, func() {
+		items := c.items(e.storeData)
+		*items = slices.Insert(*items, i, old)
+	})
+	e.track(c.name, c.key(old), nil, true)
+}
+
+// deleteFunc deletes the records del reports true for, calling it once for
+// each record.
+func (c keyedCollection[T]) deleteFunc(e *storeEdit, del func(T) bool) {
+	items := c.items(e.storeData)
+	old := *items
+	var kept []T
+	deleted := false
+	for i, record := range old {
+		if !del(record) {
+			if deleted {
+				kept = append(kept, record)
+			}
+			continue
+		}
+		if !deleted {
+			kept = slices.Clone(old[:i:i])
+			deleted = true
+		}
+		e.track(c.name, c.key(record), nil, true)
+	}
+	if !deleted {
+		return
+	}
+	// The old list is left untouched, so taking the deletions back is
+	// putting it back.
+	*items = kept
+	e.undo = append(e.undo, func() { *c.items(e.storeData) = old })
+}
+
+// sort puts the records in order, which the lists of files written before
+// they were kept in order need.
+func (c keyedCollection[T]) sort(data *storeData) {
+	slices.SortFunc(*c.items(data), c.compare)
+}
+
+func compareById[T any](id func(T) int) func(a, b T) int {
+	return func(a, b T) int { return cmp.Compare(id(a), id(b)) }
+}
+
+var (
+	apiTokenRecords = keyedCollection[models.ApiToken]{
+		name:    "apiTokens",
+		items:   func(d *storeData) *[]models.ApiToken { return &d.ApiTokens },
+		key:     func(t models.ApiToken) string { return strconv.Itoa(t.Id) },
+		compare: compareById(func(t models.ApiToken) int { return t.Id }),
+	}
+	auditEventRecords = keyedCollection[models.AuditEvent]{
+		name:    "auditEvents",
+		items:   func(d *storeData) *[]models.AuditEvent { return &d.AuditEvents },
+		key:     func(e models.AuditEvent) string { return strconv.Itoa(e.Id) },
+		compare: compareById(func(e models.AuditEvent) int { return e.Id }),
+	}
+	loginAttemptRecords = keyedCollection[models.LoginAttempt]{
+		name:  "loginAttempts",
+		items: func(d *storeData) *[]models.LoginAttempt { return &d.LoginAttempts },
+		key:   func(a models.LoginAttempt) string { return a.Key },
+		compare: func(a, b models.LoginAttempt) int {
+			return strings.Compare(a.Key, b.Key)
+		},
+	}
+	projectInvitationRecords = keyedCollection[models.ProjectInvitation]{
+		name: "projectInvitations",
+		items: func(d *storeData) *[]models.ProjectInvitation {
+			return &d.ProjectInvitations
+		},
+		key:     func(i models.ProjectInvitation) string { return strconv.Itoa(i.Id) },
+		compare: compareById(func(i models.ProjectInvitation) int { return i.Id }),
+	}
+	projectMemberRecords = keyedCollection[models.ProjectMember]{
+		name:  "projectMembers",
+		items: func(d *storeData) *[]models.ProjectMember { return &d.ProjectMembers },
+		key: func(m models.ProjectMember) string {
+			return fmt.Sprintf("%d:%d", m.ProjectId, m.UserId)
+		},
+		compare: func(a, b models.ProjectMember) int {
+			return cmp.Or(
+				cmp.Compare(a.ProjectId, b.ProjectId),
+				cmp.Compare(a.UserId, b.UserId),
+			)
+		},
+	}
+	projectRecords = keyedCollection[models.Project]{
+		name:    "projects",
+		items:   func(d *storeData) *[]models.Project { return &d.Projects },
+		key:     func(p models.Project) string { return strconv.Itoa(p.Id) },
+		compare: compareById(func(p models.Project) int { return p.Id }),
+	}
+	taskRevisionRecords = keyedCollection[models.TaskRevision]{
+		name:  "taskRevisions",
+		items: func(d *storeData) *[]models.TaskRevision { return &d.TaskRevisions },
+		key: func(r models.TaskRevision) string {
+			return fmt.Sprintf("%d:%d", r.TaskId, r.Revision)
+		},
+		compare: func(a, b models.TaskRevision) int {
+			return cmp.Or(
+				cmp.Compare(a.TaskId, b.TaskId),
+				cmp.Compare(a.Revision, b.Revision),
+			)
+		},
+	}
+	taskRecords = keyedCollection[models.Task]{
+		name:    "tasks",
+		items:   func(d *storeData) *[]models.Task { return &d.Tasks },
+		key:     func(t models.Task) string { return strconv.Itoa(t.Id) },
+		compare: compareById(func(t models.Task) int { return t.Id }),
+	}
+	userMfaRecords = keyedCollection[models.UserMfa]{
+		name:    "userMfa",
+		items:   func(d *storeData) *[]models.UserMfa { return &d.UserMfa },
+		key:     func(m models.UserMfa) string { return strconv.Itoa(m.UserId) },
+		compare: compareById(func(m models.UserMfa) int { return m.UserId }),
+	}
+	userTokenRecords = keyedCollection[models.UserToken]{
+		name:  "userTokens",
+		items: func(d *storeData) *[]models.UserToken { return &d.UserTokens },
+		key:   func(t models.UserToken) string { return t.Hash },
+		compare: func(a, b models.UserToken) int {
+			return strings.Compare(a.Hash, b.Hash)
+		},
+	}
+	userRecords = keyedCollection[models.User]{
+		name:    "users",
+		items:   func(d *storeData) *[]models.User { return &d.Users },
+		key:     func(u models.User) string { return strconv.Itoa(u.Id) },
+		compare: compareById(func(u models.User) int { return u.Id }),
+	}
+)
@@ -0,0 +1,81 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestStoreEdit(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("a write persist rejects leaves the data as it was", func(t *testing.T) {
+		store := newTestMemoryStore(t)
+		before, err := json.Marshal(store.data)
+		assert.HasNoError(t, err)
+		errDiskFull := errors.New("disk full")
+		store.persist = func(*storeEdit) error { return errDiskFull }
+
+		_, err = store.CreateTask(ctx, models.NewCreateTaskDTO("Cook dinner", 1))
+		assert.ErrorContains(t, err, errDiskFull)
+		_, err = store.UpdateTask(ctx, models.NewTask(1, "Buy oat milk", 1))
+		assert.ErrorContains(t, err, errDiskFull)
+		assert.ErrorContains(t, store.DeleteUserById(ctx, 1), errDiskFull)
+
+		after, err := json.Marshal(store.data)
+		assert.HasNoError(t, err)
+		assert.Equals(t, string(after), string(before))
+	})
+
+	t.Run("the journal record holds each record as the write left it", func(t *testing.T) {
+		store := newTestMemoryStore(t)
+		var record *journalRecord
+		store.persist = func(edit *storeEdit) (err error) {
+			record, err = edit.journalRecord()
+			return err
+		}
+
+		assert.HasNoError(t, store.DeleteUserById(ctx, 1))
+
+		assert.Equals(t, record.Puts, map[string][]json.RawMessage(nil))
+		assert.Equals(t, record.Counters, map[string]int(nil))
+		assert.Equals(t, record.Deletes, map[string][]string{
+			"users":          {"1"},
+			"projectMembers": {"1:1"},
+			"projects":       {"1"},
+			"tasks":          {"1", "2"},
+			"taskRevisions":  {"1:1"},
+		})
+	})
+}
+
+// newTestMemoryStore returns a store with a user owning a project with two
+// tasks, one of them revised once.
+func newTestMemoryStore(t *testing.T) *MemoryStore {
+	t.Helper()
+	ctx := context.Background()
+	store, err := NewMemoryStore()
+	assert.HasNoError(t, err)
+	user, err := store.CreateUser(
+		ctx,
+		models.NewCreateUserDTO("Claude", "claude@email.com", "password"),
+	)
+	assert.HasNoError(t, err)
+	project, err := store.CreateProject(
+		ctx,
+		&models.CreateProjectDTO{Name: "Groceries"},
+		user.Id,
+	)
+	assert.HasNoError(t, err)
+	for _, title := range []string{"Buy milk", "Buy eggs"} {
+		_, err := store.CreateTask(ctx, models.NewCreateTaskDTO(title, project.Id))
+		assert.HasNoError(t, err)
+	}
+	_, err = store.UpdateTask(ctx, models.NewTask(1, "Buy oat milk", project.Id))
+	assert.HasNoError(t, err)
+	return store
+}
@@ -0,0 +1,183 @@
+package data_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestMemoryStoreTasks(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("CreateTask, UpdateTask and DeleteTaskById change the stored tasks", func(t *testing.T) {
+		store, err := data.NewMemoryStore()
+		assert.HasNoError(t, err)
+
+		task, err := store.CreateTask(ctx, models.NewCreateTaskDTO("Buy milk", 1))
+		assert.HasNoError(t, err)
+		assert.Equals(t, *task, *models.NewTask(1, "Buy milk", 1))
+
+		task.Title = "Buy oat milk"
+		_, err = store.UpdateTask(ctx, task)
+		assert.HasNoError(t, err)
+		got, err := store.GetTaskById(ctx, task.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, *task)
+
+		assert.HasNoError(t, store.DeleteTaskById(ctx, task.Id))
+		_, err = store.GetTaskById(ctx, task.Id)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+		err = store.DeleteTaskById(ctx, task.Id)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("returned tasks do not change the stored tasks", func(t *testing.T) {
+		store, err := data.NewMemoryStore()
+		assert.HasNoError(t, err)
+		_, err = store.CreateTask(ctx, models.NewCreateTaskDTO("Buy milk", 1))
+		assert.HasNoError(t, err)
+
+		tasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		tasks[0].Title = "Buy bread"
+
+		tasks, err = store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, tasks[0].Title, "Buy milk")
+	})
+}
+
+func TestMemoryStoreUsers(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("CreateUser returns an `ErrConflict` error if the email is taken", func(t *testing.T) {
+		store, err := data.NewMemoryStore()
+		assert.HasNoError(t, err)
+
+		dto := models.NewCreateUserDTO("Claude Aldric", "claude@email.com", "password")
+		_, err = store.CreateUser(ctx, dto)
+		assert.HasNoError(t, err)
+		_, err = store.CreateUser(ctx, dto)
+		assert.ErrorContains(t, err, data.ErrConflict)
+
+		assert.Equals(
+			t,
+			store.ValidateUserCredentials(ctx, "claude@email.com", "password"),
+			true,
+		)
+	})
+}
+
+func TestMemoryStoreSnapshot(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("starts empty when the snapshot does not exist yet", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "snapshot.json")
+		store, err := data.NewMemoryStore(data.WithSnapshot(path))
+		assert.HasNoError(t, err)
+
+		tasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, tasks, 0)
+	})
+
+	t.Run("writes the data on Close and loads it back", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "snapshot.json")
+		store, err := data.NewMemoryStore(data.WithSnapshot(path))
+		assert.HasNoError(t, err)
+		task, err := store.CreateTask(ctx, models.NewCreateTaskDTO("Buy milk", 1))
+		assert.HasNoError(t, err)
+		assert.HasNoError(t, store.Close())
+
+		store, err = data.NewMemoryStore(data.WithSnapshot(path))
+		assert.HasNoError(t, err)
+		tasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, tasks, []models.Task{*task})
+
+		created, err := store.CreateTask(ctx, models.NewCreateTaskDTO("Buy bread", 1))
+		assert.HasNoError(t, err)
+		assert.Equals(t, created.Id, task.Id+1)
+	})
+
+	t.Run("can be read by a FileSystemStore", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "snapshot.json")
+		store, err := data.NewMemoryStore(data.WithSnapshot(path))
+		assert.HasNoError(t, err)
+		task, err := store.CreateTask(ctx, models.NewCreateTaskDTO("Buy milk", 1))
+		assert.HasNoError(t, err)
+		assert.HasNoError(t, store.Close())
+
+		fileStore := reopenFileSystemStore(t, path)
+		got, err := fileStore.GetTaskById(ctx, task.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, *task)
+	})
+}
+
+func TestMemoryStoreClose(t *testing.T) {
+	store, err := data.NewMemoryStore()
+	assert.HasNoError(t, err)
+	assert.HasNoError(t, store.Close())
+	assert.HasNoError(t, store.Close())
+
+	_, err = store.CreateTask(
+		context.Background(),
+		models.NewCreateTaskDTO("Buy milk", 1),
+	)
+	assert.HasError(t, err)
+}
+
+func TestMemoryStoreCancelledContext(t *testing.T) {
+	store, err := data.NewMemoryStore()
+	assert.HasNoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = store.CreateTask(ctx, models.NewCreateTaskDTO("Buy milk", 1))
+	assert.ErrorContains(t, err, context.Canceled)
+	_, err = store.GetTasks(ctx)
+	assert.ErrorContains(t, err, context.Canceled)
+}
+
+func TestMemoryStoreConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	store, err := data.NewMemoryStore()
+	assert.HasNoError(t, err)
+
+	const goroutines = 20
+	const tasksPerGoroutine = 10
+	var wg sync.WaitGroup
+	for i := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range tasksPerGoroutine {
+				_, err := store.CreateTask(
+					ctx,
+					models.NewCreateTaskDTO(fmt.Sprintf("Task %d-%d", i, j), 1),
+				)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := store.GetTasksByProjectId(ctx, 1); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	tasks, err := store.GetTasks(ctx)
+	assert.HasNoError(t, err)
+	assert.HasLength(t, tasks, goroutines*tasksPerGoroutine)
+}
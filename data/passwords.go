@@ -0,0 +1,34 @@
+package data
+
+import (
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordCost is the bcrypt cost of the password hashes made from now on.
+var passwordCost = bcrypt.DefaultCost
+
+// SetPasswordCost changes the bcrypt cost of the password hashes made from
+// now on, which tests lower to bcrypt.MinCost: hashing at the default cost
+// takes most of their time, and far more under the race detector.
+func SetPasswordCost(cost int) {
+	passwordCost = cost
+}
+
+func hashPassword(password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+}
+
+// Compared against when no user matches the email so that unknown accounts
+// take as long to reject as wrong passwords.
+var dummyPasswordHash = []byte(
+	"$2a$10$8I2.G30FbNko74lzUp58KuaL9rXqnjHETjQhUtDzVkvUXba1.C64a",
+)
+
+func checkPassword(hashedPassword []byte, password string) bool {
+	err := bcrypt.CompareHashAndPassword(hashedPassword, []byte(password))
+	return err == nil
+}
+
+func simulatePasswordCheck(password string) {
+	checkPassword(dummyPasswordHash, password)
+}
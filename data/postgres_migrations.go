@@ -0,0 +1,200 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+const (
+	postgresMaxOpenConns    = 25
+	postgresMaxIdleConns    = 25
+	postgresConnMaxLifetime = 30 * time.Minute
+	postgresConnMaxIdleTime = 5 * time.Minute
+)
+
+// OpenPostgres connects to the database at dsn, which is either a URL or a
+// list of key=value settings, and brings its schema up to date.
+func OpenPostgres(ctx context.Context, dsn string) (*sql.DB, error) {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(postgresMaxOpenConns)
+	db.SetMaxIdleConns(postgresMaxIdleConns)
+	db.SetConnMaxLifetime(postgresConnMaxLifetime)
+	db.SetConnMaxIdleTime(postgresConnMaxIdleTime)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("problem connecting to postgres, %v", err)
+	}
+	if err := MigratePostgres(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// postgresMigrations are applied in order and each only once; a migration
+// that has been released must never change, so fixes go into a new one.
+var postgresMigrations = []string{
+	`
+	create table users (
+		id bigint generated by default as identity primary key,
+		name text not null,
+		email text not null unique,
+		password text not null,
+		verified boolean not null default false,
+		timezone text not null default 'UTC',
+		locale text not null default 'en',
+		start_of_week text not null default 'monday',
+		role text not null default 'user',
+		disabled boolean not null default false
+	);
+
+	create table projects (
+		id bigint generated by default as identity primary key,
+		name text not null
+	);
+
+	create table project_members (
+		project_id bigint not null,
+		user_id bigint not null,
+		role text not null,
+		primary key (project_id, user_id)
+	);
+	create index project_members_user_id_idx on project_members (user_id);
+
+	create table project_invitations (
+		id bigint generated by default as identity primary key,
+		project_id bigint not null,
+		email text not null,
+		role text not null,
+		invited_by bigint not null,
+		created_at timestamptz not null,
+		unique (project_id, email)
+	);
+	create index project_invitations_email_idx on project_invitations (email);
+
+	create table tasks (
+		id bigint generated by default as identity primary key,
+		title text not null,
+		project_id bigint not null default 0,
+		assignee_id bigint not null default 0
+	);
+	create index tasks_project_id_idx on tasks (project_id);
+	create index tasks_assignee_id_idx on tasks (assignee_id);
+
+	create table login_attempts (
+		key text primary key,
+		failures integer not null,
+		last_failure_at timestamptz not null,
+		locked_until timestamptz not null
+	);
+
+	create table audit_events (
+		id bigint generated by default as identity primary key,
+		action text not null,
+		actor_id bigint not null default 0,
+		subject text not null,
+		details text not null,
+		created_at timestamptz not null
+	);
+
+	create table user_tokens (
+		hash text primary key,
+		user_id bigint not null,
+		purpose text not null,
+		expires_at timestamptz not null
+	);
+	create index user_tokens_user_id_idx on user_tokens (user_id, purpose);
+
+	create table api_tokens (
+		id bigint generated by default as identity primary key,
+		user_id bigint not null,
+		name text not null,
+		hash text not null unique,
+		scopes text not null,
+		expires_at timestamptz,
+		last_used_at timestamptz,
+		created_at timestamptz not null
+	);
+	create index api_tokens_user_id_idx on api_tokens (user_id);
+
+	create table user_mfa (
+		user_id bigint primary key,
+		totp_secret text not null,
+		enabled boolean not null,
+		last_used_step bigint not null
+	);
+	`,
+	`
+	alter table audit_events add column impersonator_id bigint not null default 0;
+	`,
+	`
+	alter table user_tokens add column email text not null default '';
+	`,
+	`
+	alter table users add column password_changed_at timestamptz;
+	`,
+}
+
+// MigratePostgres applies the migrations the database has not seen yet.
+// Servers starting at the same time wait for each other instead of applying
+// the same migration twice.
+func MigratePostgres(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, `
+		create table if not exists schema_migrations (
+			version integer primary key,
+			applied_at timestamptz not null default now()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("problem creating the schema_migrations table, %v", err)
+	}
+	for i, migration := range postgresMigrations {
+		if err := applyPostgresMigration(ctx, db, i+1, migration); err != nil {
+			return fmt.Errorf("problem applying migration %d, %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+func applyPostgresMigration(
+	ctx context.Context,
+	db *sql.DB,
+	version int,
+	migration string,
+) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `lock table schema_migrations in exclusive mode`)
+	if err != nil {
+		return err
+	}
+	var applied bool
+	err = tx.QueryRowContext(ctx, `
+		select exists (select 1 from schema_migrations where version = $1)
+	`, version).Scan(&applied)
+	if err != nil || applied {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, migration); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		insert into schema_migrations (version) values ($1)
+	`, version)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
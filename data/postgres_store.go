@@ -0,0 +1,1342 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+// PostgresStore expects a database that MigratePostgres brought up to date,
+// such as the ones OpenPostgres returns.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db}
+}
+
+func (s *PostgresStore) CreateTask(
+	ctx context.Context,
+	dto *models.CreateTaskDTO,
+) (*models.Task, error) {
+	return scanTask(s.db.QueryRowContext(ctx, `
+		insert into tasks (title, project_id, assignee_id)
+		values
+			($1, $2, $3)
+		returning `+taskColumns,
+		dto.Title,
+		dto.ProjectId,
+		dto.AssigneeId,
+	))
+}
+
+func (s *PostgresStore) DeleteTaskById(ctx context.Context, id int) error {
+	result, err := s.db.ExecContext(ctx, `delete from tasks where id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result, "task", id)
+}
+
+func (s *PostgresStore) GetTaskById(ctx context.Context, id int) (*models.Task, error) {
+	task, err := scanTask(s.db.QueryRowContext(
+		ctx,
+		`select `+taskColumns+` from tasks where id = $1`,
+		id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("task with ID %d: %w", id, ErrResourceNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func (s *PostgresStore) GetTasks(ctx context.Context) ([]models.Task, error) {
+	return s.queryTasks(ctx, `select `+taskColumns+` from tasks order by id`)
+}
+
+func (s *PostgresStore) GetTasksByProjectId(
+	ctx context.Context,
+	projectId int,
+) ([]models.Task, error) {
+	return s.queryTasks(ctx, `
+		select `+taskColumns+`
+		from tasks
+		where project_id = $1
+		order by id
+	`, projectId)
+}
+
+func (s *PostgresStore) queryTasks(
+	ctx context.Context,
+	query string,
+	args ...any,
+) ([]models.Task, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tasks []models.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, *task)
+	}
+	return tasks, rows.Err()
+}
+
+func (s *PostgresStore) UpdateTask(
+	ctx context.Context,
+	task *models.Task,
+) (*models.Task, error) {
+	updatedTask, err := scanTask(s.db.QueryRowContext(ctx, `
+		update tasks
+		set title = $1, assignee_id = $2
+		where id = $3
+		returning `+taskColumns,
+		task.Title,
+		task.AssigneeId,
+		task.Id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("task with ID %d: %w", task.Id, ErrResourceNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return updatedTask, nil
+}
+
+func (s *PostgresStore) CreateUser(
+	ctx context.Context,
+	dto *models.CreateUserDTO,
+) (*models.User, error) {
+	email := NormalizeEmail(dto.Email)
+	hashedPassword, err := hashPassword(dto.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := scanUser(s.db.QueryRowContext(ctx, `
+		insert into users (name, email, password)
+		values
+			($1, $2, $3)
+		returning `+userColumns,
+		dto.Name,
+		email,
+		string(hashedPassword),
+	))
+	if isPostgresUniqueViolation(err) {
+		return nil, fmt.Errorf("user with email %s: %w", email, ErrConflict)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *PostgresStore) DeleteUserById(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `delete from user_tokens where user_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `delete from api_tokens where user_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `delete from user_mfa where user_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if err := deletePostgresProjectMemberships(ctx, tx, id); err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx, `delete from users where id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if err := checkRowsAffected(result, "user", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) GetUserByEmail(
+	ctx context.Context,
+	email string,
+) (*models.User, error) {
+	email = NormalizeEmail(email)
+	user, err := scanUser(s.db.QueryRowContext(
+		ctx,
+		`select `+userColumns+` from users where email = $1`,
+		email,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf(
+			"user with email %s: %w",
+			email,
+			ErrResourceNotFound,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *PostgresStore) GetUserById(ctx context.Context, id int) (*models.User, error) {
+	user, err := scanUser(s.db.QueryRowContext(
+		ctx,
+		`select `+userColumns+` from users where id = $1`,
+		id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("user with ID %d: %w", id, ErrResourceNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *PostgresStore) GetUsers(ctx context.Context) ([]models.User, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`select `+userColumns+` from users order by id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var users []models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+func (s *PostgresStore) UpdateUser(
+	ctx context.Context,
+	user *models.User,
+) (*models.User, error) {
+	email := NormalizeEmail(user.Email)
+	updatedUser, err := scanUser(s.db.QueryRowContext(ctx, `
+		update users
+		set
+			name = $1,
+			email = $2,
+			verified = $3,
+			timezone = $4,
+			locale = $5,
+			start_of_week = $6,
+			role = $7,
+			disabled = $8
+		where id = $9
+		returning `+userColumns,
+		user.Name,
+		email,
+		user.Verified,
+		user.Timezone,
+		user.Locale,
+		user.StartOfWeek,
+		user.Role,
+		user.Disabled,
+		user.Id,
+	))
+	if isPostgresUniqueViolation(err) {
+		return nil, fmt.Errorf("user with email %s: %w", email, ErrConflict)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("user with ID %d: %w", user.Id, ErrResourceNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return updatedUser, nil
+}
+
+func (s *PostgresStore) UpdateUserPassword(
+	ctx context.Context,
+	id int,
+	password string,
+) error {
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		update users
+		set password = $1, password_changed_at = $2
+		where id = $3
+	`, string(hashedPassword), time.Now(), id)
+	if err != nil {
+		return err
+	}
+	if err := checkRowsAffected(result, "user", id); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `delete from api_tokens where user_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) ValidateUserCredentials(
+	ctx context.Context,
+	email, password string,
+) bool {
+	user, err := s.GetUserByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, ErrResourceNotFound) {
+			log.Printf("error retrieving user for validation: %v\n", err)
+		}
+		simulatePasswordCheck(password)
+		return false
+	}
+	return checkPassword([]byte(user.Password), password)
+}
+
+func (s *PostgresStore) CreateProject(
+	ctx context.Context,
+	dto *models.CreateProjectDTO,
+	ownerId int,
+) (*models.Project, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	project := models.Project{Name: dto.Name}
+	err = tx.QueryRowContext(ctx, `
+		insert into projects (name) values ($1) returning id
+	`, dto.Name).Scan(&project.Id)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `
+		insert into project_members (project_id, user_id, role)
+		values
+			($1, $2, $3)
+	`, project.Id, ownerId, models.ProjectRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (s *PostgresStore) GetProjectById(
+	ctx context.Context,
+	id int,
+) (*models.Project, error) {
+	var project models.Project
+	err := s.db.QueryRowContext(ctx, `
+		select id, name from projects where id = $1
+	`, id).Scan(&project.Id, &project.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("project with ID %d: %w", id, ErrResourceNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// DeleteProjectMember also unassigns the tasks of the project that were
+// assigned to the member.
+func (s *PostgresStore) DeleteProjectMember(
+	ctx context.Context,
+	projectId, userId int,
+) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		delete from project_members
+		where project_id = $1 and user_id = $2
+	`, projectId, userId)
+	if err != nil {
+		return err
+	}
+	if err := checkRowsAffected(result, "member of project", projectId); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		update tasks
+		set assignee_id = 0
+		where project_id = $1 and assignee_id = $2
+	`, projectId, userId)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *PostgresStore) GetProjectMember(
+	ctx context.Context,
+	projectId, userId int,
+) (*models.ProjectMember, error) {
+	member := models.ProjectMember{ProjectId: projectId, UserId: userId}
+	err := s.db.QueryRowContext(ctx, `
+		select role
+		from project_members
+		where project_id = $1 and user_id = $2
+	`, projectId, userId).Scan(&member.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf(
+			"user with ID %d in project with ID %d: %w",
+			userId,
+			projectId,
+			ErrResourceNotFound,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (s *PostgresStore) GetProjectMembers(
+	ctx context.Context,
+	projectId int,
+) ([]models.ProjectMember, error) {
+	return s.queryProjectMembers(ctx, `
+		select project_id, user_id, role
+		from project_members
+		where project_id = $1
+		order by user_id
+	`, projectId)
+}
+
+func (s *PostgresStore) GetProjectMembersByUserId(
+	ctx context.Context,
+	userId int,
+) ([]models.ProjectMember, error) {
+	return s.queryProjectMembers(ctx, `
+		select project_id, user_id, role
+		from project_members
+		where user_id = $1
+		order by project_id
+	`, userId)
+}
+
+func (s *PostgresStore) queryProjectMembers(
+	ctx context.Context,
+	query string,
+	args ...any,
+) ([]models.ProjectMember, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var members []models.ProjectMember
+	for rows.Next() {
+		var member models.ProjectMember
+		err := rows.Scan(&member.ProjectId, &member.UserId, &member.Role)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+func (s *PostgresStore) SaveProjectMember(
+	ctx context.Context,
+	member *models.ProjectMember,
+) error {
+	_, err := s.db.ExecContext(ctx, `
+		insert into project_members (project_id, user_id, role)
+		values
+			($1, $2, $3)
+		on conflict (project_id, user_id) do update set
+			role = excluded.role
+	`, member.ProjectId, member.UserId, member.Role)
+	return err
+}
+
+// AcceptProjectInvitation turns the invitation into a membership, keeping the
+// role of users who already are members.
+func (s *PostgresStore) AcceptProjectInvitation(
+	ctx context.Context,
+	id, userId int,
+) (*models.ProjectMember, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	member := models.ProjectMember{UserId: userId}
+	err = tx.QueryRowContext(ctx, `
+		delete from project_invitations
+		where id = $1
+		returning project_id, role
+	`, id).Scan(&member.ProjectId, &member.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf(
+			"project invitation with ID %d: %w",
+			id,
+			ErrResourceNotFound,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `
+		insert into project_members (project_id, user_id, role)
+		values
+			($1, $2, $3)
+		on conflict (project_id, user_id) do nothing
+	`, member.ProjectId, member.UserId, member.Role)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.QueryRowContext(ctx, `
+		select role
+		from project_members
+		where project_id = $1 and user_id = $2
+	`, member.ProjectId, member.UserId).Scan(&member.Role)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (s *PostgresStore) CreateProjectInvitation(
+	ctx context.Context,
+	invitation *models.ProjectInvitation,
+) (*models.ProjectInvitation, error) {
+	createdAt := invitation.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	createdInvitation, err := scanPostgresProjectInvitation(s.db.QueryRowContext(ctx, `
+		insert into project_invitations
+			(project_id, email, role, invited_by, created_at)
+		values
+			($1, $2, $3, $4, $5)
+		returning `+projectInvitationColumns,
+		invitation.ProjectId,
+		NormalizeEmail(invitation.Email),
+		invitation.Role,
+		invitation.InvitedBy,
+		createdAt,
+	))
+	if isPostgresUniqueViolation(err) {
+		return nil, fmt.Errorf(
+			"invitation of %s to project with ID %d: %w",
+			NormalizeEmail(invitation.Email),
+			invitation.ProjectId,
+			ErrConflict,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return createdInvitation, nil
+}
+
+func (s *PostgresStore) DeleteProjectInvitation(ctx context.Context, id int) error {
+	result, err := s.db.ExecContext(ctx, `
+		delete from project_invitations where id = $1
+	`, id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result, "project invitation", id)
+}
+
+func (s *PostgresStore) GetProjectInvitationById(
+	ctx context.Context,
+	id int,
+) (*models.ProjectInvitation, error) {
+	invitation, err := scanPostgresProjectInvitation(s.db.QueryRowContext(
+		ctx,
+		`select `+projectInvitationColumns+` from project_invitations where id = $1`,
+		id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf(
+			"project invitation with ID %d: %w",
+			id,
+			ErrResourceNotFound,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+func (s *PostgresStore) GetProjectInvitationsByEmail(
+	ctx context.Context,
+	email string,
+) ([]models.ProjectInvitation, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		select `+projectInvitationColumns+`
+		from project_invitations
+		where email = $1
+		order by id
+	`, NormalizeEmail(email))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var invitations []models.ProjectInvitation
+	for rows.Next() {
+		invitation, err := scanPostgresProjectInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, *invitation)
+	}
+	return invitations, rows.Err()
+}
+
+func (s *PostgresStore) CreateApiToken(
+	ctx context.Context,
+	token *models.ApiToken,
+) (*models.ApiToken, error) {
+	createdAt := token.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	return scanPostgresApiToken(s.db.QueryRowContext(ctx, `
+		insert into api_tokens (user_id, name, hash, scopes, expires_at, created_at)
+		values
+			($1, $2, $3, $4, $5, $6)
+		returning `+apiTokenColumns,
+		token.UserId,
+		token.Name,
+		token.Hash,
+		strings.Join(token.Scopes, " "),
+		token.ExpiresAt,
+		createdAt,
+	))
+}
+
+func (s *PostgresStore) DeleteApiToken(ctx context.Context, id, userId int) error {
+	result, err := s.db.ExecContext(ctx, `
+		delete from api_tokens where id = $1 and user_id = $2
+	`, id, userId)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result, "API token", id)
+}
+
+func (s *PostgresStore) GetApiTokenByHash(
+	ctx context.Context,
+	hash string,
+) (*models.ApiToken, error) {
+	token, err := scanPostgresApiToken(s.db.QueryRowContext(
+		ctx,
+		`select `+apiTokenColumns+` from api_tokens where hash = $1`,
+		hash,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("API token: %w", ErrResourceNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (s *PostgresStore) GetApiTokensByUserId(
+	ctx context.Context,
+	userId int,
+) ([]models.ApiToken, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		`select `+apiTokenColumns+` from api_tokens where user_id = $1 order by id`,
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tokens []models.ApiToken
+	for rows.Next() {
+		token, err := scanPostgresApiToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, rows.Err()
+}
+
+func (s *PostgresStore) UpdateApiTokenLastUsedAt(
+	ctx context.Context,
+	id int,
+	lastUsedAt time.Time,
+) error {
+	result, err := s.db.ExecContext(ctx, `
+		update api_tokens
+		set last_used_at = $1
+		where id = $2
+	`, lastUsedAt, id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result, "API token", id)
+}
+
+func (s *PostgresStore) ConsumeUserToken(
+	ctx context.Context,
+	hash, purpose string,
+) (*models.UserToken, error) {
+	var token models.UserToken
+	err := s.db.QueryRowContext(ctx, `
+		delete from user_tokens
+		where hash = $1 and purpose = $2
+		returning hash, user_id, purpose, expires_at, email
+	`, hash, purpose).Scan(
+		&token.Hash,
+		&token.UserId,
+		&token.Purpose,
+		&token.ExpiresAt,
+		&token.Email,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s token: %w", purpose, ErrResourceNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	token.ExpiresAt = token.ExpiresAt.UTC()
+	return &token, nil
+}
+
+func (s *PostgresStore) CreateUserToken(
+	ctx context.Context,
+	token *models.UserToken,
+) error {
+	_, err := s.db.ExecContext(ctx, `
+		insert into user_tokens (hash, user_id, purpose, expires_at, email)
+		values
+			($1, $2, $3, $4, $5)
+	`, token.Hash, token.UserId, token.Purpose, token.ExpiresAt, token.Email)
+	return err
+}
+
+func (s *PostgresStore) DeleteUserTokens(
+	ctx context.Context,
+	userId int,
+	purpose string,
+) error {
+	_, err := s.db.ExecContext(ctx, `
+		delete from user_tokens
+		where user_id = $1 and purpose = $2
+	`, userId, purpose)
+	return err
+}
+
+func (s *PostgresStore) DeleteUserMfa(ctx context.Context, userId int) error {
+	_, err := s.db.ExecContext(ctx, `delete from user_mfa where user_id = $1`, userId)
+	return err
+}
+
+func (s *PostgresStore) GetUserMfa(
+	ctx context.Context,
+	userId int,
+) (*models.UserMfa, error) {
+	var mfa models.UserMfa
+	err := s.db.QueryRowContext(ctx, `
+		select user_id, totp_secret, enabled, last_used_step
+		from user_mfa
+		where user_id = $1
+	`, userId).Scan(
+		&mfa.UserId,
+		&mfa.TotpSecret,
+		&mfa.Enabled,
+		&mfa.LastUsedStep,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf(
+			"MFA settings of user with ID %d: %w",
+			userId,
+			ErrResourceNotFound,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &mfa, nil
+}
+
+func (s *PostgresStore) SaveUserMfa(ctx context.Context, mfa *models.UserMfa) error {
+	_, err := s.db.ExecContext(ctx, `
+		insert into user_mfa (user_id, totp_secret, enabled, last_used_step)
+		values
+			($1, $2, $3, $4)
+		on conflict (user_id) do update set
+			totp_secret = excluded.totp_secret,
+			enabled = excluded.enabled,
+			last_used_step = excluded.last_used_step
+	`, mfa.UserId, mfa.TotpSecret, mfa.Enabled, mfa.LastUsedStep)
+	return err
+}
+
+func (s *PostgresStore) UseUserMfaStep(
+	ctx context.Context,
+	userId int,
+	step int64,
+) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `
+		update user_mfa
+		set last_used_step = $1
+		where user_id = $2 and last_used_step < $1
+	`, step, userId)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+func (s *PostgresStore) DeleteLoginAttempt(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `delete from login_attempts where key = $1`, key)
+	return err
+}
+
+func (s *PostgresStore) GetLoginAttempt(
+	ctx context.Context,
+	key string,
+) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := s.db.QueryRowContext(ctx, `
+		select key, failures, last_failure_at, locked_until
+		from login_attempts
+		where key = $1
+	`, key).Scan(
+		&attempt.Key,
+		&attempt.Failures,
+		&attempt.LastFailureAt,
+		&attempt.LockedUntil,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf(
+			"login attempt with key %s: %w",
+			key,
+			ErrResourceNotFound,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	attempt.LastFailureAt = attempt.LastFailureAt.UTC()
+	attempt.LockedUntil = attempt.LockedUntil.UTC()
+	return &attempt, nil
+}
+
+func (s *PostgresStore) SaveLoginAttempt(
+	ctx context.Context,
+	attempt *models.LoginAttempt,
+) error {
+	_, err := s.db.ExecContext(ctx, `
+		insert into login_attempts (key, failures, last_failure_at, locked_until)
+		values
+			($1, $2, $3, $4)
+		on conflict (key) do update set
+			failures = excluded.failures,
+			last_failure_at = excluded.last_failure_at,
+			locked_until = excluded.locked_until
+	`, attempt.Key, attempt.Failures, attempt.LastFailureAt, attempt.LockedUntil)
+	return err
+}
+
+func (s *PostgresStore) IncrementLoginFailures(
+	ctx context.Context,
+	key string,
+	failedAt time.Time,
+	expireAfter time.Duration,
+) (*models.LoginAttempt, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		insert into login_attempts (key, failures, last_failure_at, locked_until)
+		values
+			($1, 0, $2, $3)
+		on conflict (key) do nothing
+	`, key, failedAt, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	// The row lock makes concurrent failures wait for each other instead of
+	// reading the same count.
+	var attempt models.LoginAttempt
+	err = tx.QueryRowContext(ctx, `
+		select key, failures, last_failure_at, locked_until
+		from login_attempts
+		where key = $1
+		for update
+	`, key).Scan(
+		&attempt.Key,
+		&attempt.Failures,
+		&attempt.LastFailureAt,
+		&attempt.LockedUntil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	countLoginFailure(&attempt, failedAt, expireAfter)
+	_, err = tx.ExecContext(ctx, `
+		update login_attempts
+		set failures = $1, last_failure_at = $2
+		where key = $3
+	`, attempt.Failures, attempt.LastFailureAt, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (s *PostgresStore) LockLoginAttempt(
+	ctx context.Context,
+	key string,
+	failures int,
+	lockedUntil time.Time,
+) error {
+	_, err := s.db.ExecContext(ctx, `
+		update login_attempts
+		set locked_until = $1
+		where key = $2 and failures = $3
+	`, lockedUntil, key, failures)
+	return err
+}
+
+func (s *PostgresStore) CreateAuditEvent(
+	ctx context.Context,
+	event *models.AuditEvent,
+) (*models.AuditEvent, error) {
+	createdEvent := *event
+	if createdEvent.CreatedAt.IsZero() {
+		createdEvent.CreatedAt = time.Now()
+	}
+	err := s.db.QueryRowContext(ctx, `
+		insert into audit_events (
+			action,
+			actor_id,
+			impersonator_id,
+			subject,
+			details,
+			created_at
+		)
+		values
+			($1, $2, $3, $4, $5, $6)
+		returning id, created_at
+	`,
+		createdEvent.Action,
+		createdEvent.ActorId,
+		createdEvent.ImpersonatorId,
+		createdEvent.Subject,
+		createdEvent.Details,
+		createdEvent.CreatedAt,
+	).Scan(&createdEvent.Id, &createdEvent.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	createdEvent.CreatedAt = createdEvent.CreatedAt.UTC()
+	return &createdEvent, nil
+}
+
+func (s *PostgresStore) GetAuditEvents(ctx context.Context) ([]models.AuditEvent, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		select id, action, actor_id, impersonator_id, subject, details, created_at
+		from audit_events
+		order by id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []models.AuditEvent
+	for rows.Next() {
+		var event models.AuditEvent
+		err := rows.Scan(
+			&event.Id,
+			&event.Action,
+			&event.ActorId,
+			&event.ImpersonatorId,
+			&event.Subject,
+			&event.Details,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		event.CreatedAt = event.CreatedAt.UTC()
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// deletePostgresProjectMemberships removes the user from their projects,
+// unassigning their tasks, and deletes the projects nobody is left in, along
+// with their tasks and invitations.
+func deletePostgresProjectMemberships(
+	ctx context.Context,
+	tx *sql.Tx,
+	userId int,
+) error {
+	_, err := tx.ExecContext(ctx, `delete from project_members where user_id = $1`, userId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		update tasks set assignee_id = 0 where assignee_id = $1
+	`, userId)
+	if err != nil {
+		return err
+	}
+	const abandonedProjects = `
+		select id from projects
+		where id not in (select project_id from project_members)
+	`
+	_, err = tx.ExecContext(ctx, `delete from tasks where project_id in (`+abandonedProjects+`)`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		delete from project_invitations where project_id in (`+abandonedProjects+`)
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `delete from projects where id in (`+abandonedProjects+`)`)
+	return err
+}
+
+// Postgres hands timestamps back in the time zone of the session, so they
+// are moved to UTC like the other stores return them.
+func (s *PostgresStore) ImportProject(
+	ctx context.Context,
+	project *models.Project,
+) error {
+	_, err := s.db.ExecContext(ctx, `
+		insert into projects (id, name)
+		values ($1, $2)
+		on conflict (id) do update set name = excluded.name
+	`, project.Id, project.Name)
+	if err != nil {
+		return err
+	}
+	return s.advanceIdentity(ctx, "projects", project.Id)
+}
+
+func (s *PostgresStore) ImportTask(ctx context.Context, task *models.Task) error {
+	_, err := s.db.ExecContext(ctx, `
+		insert into tasks (`+taskColumns+`)
+		values ($1, $2, $3, $4)
+		on conflict (id) do update set
+			title = excluded.title,
+			project_id = excluded.project_id,
+			assignee_id = excluded.assignee_id
+	`, task.Id, task.Title, task.ProjectId, task.AssigneeId)
+	if err != nil {
+		return err
+	}
+	return s.advanceIdentity(ctx, "tasks", task.Id)
+}
+
+func (s *PostgresStore) ImportUser(ctx context.Context, user *models.User) error {
+	email := NormalizeEmail(user.Email)
+	_, err := s.db.ExecContext(ctx, `
+		insert into users (`+userColumns+`)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		on conflict (id) do update set
+			name = excluded.name,
+			email = excluded.email,
+			password = excluded.password,
+			verified = excluded.verified,
+			timezone = excluded.timezone,
+			locale = excluded.locale,
+			start_of_week = excluded.start_of_week,
+			role = excluded.role,
+			disabled = excluded.disabled,
+			password_changed_at = excluded.password_changed_at
+	`,
+		user.Id,
+		user.Name,
+		email,
+		user.Password,
+		user.Verified,
+		user.Timezone,
+		user.Locale,
+		user.StartOfWeek,
+		user.Role,
+		user.Disabled,
+		user.PasswordChangedAt,
+	)
+	if isPostgresUniqueViolation(err) {
+		return fmt.Errorf("user with email %s: %w", email, ErrConflict)
+	}
+	if err != nil {
+		return err
+	}
+	return s.advanceIdentity(ctx, "users", user.Id)
+}
+
+func (s *PostgresStore) ImportApiToken(ctx context.Context, token *models.ApiToken) error {
+	_, err := s.db.ExecContext(ctx, `
+		insert into api_tokens (`+apiTokenColumns+`)
+		values ($1, $2, $3, $4, $5, $6, $7, $8)
+		on conflict (id) do update set
+			user_id = excluded.user_id,
+			name = excluded.name,
+			hash = excluded.hash,
+			scopes = excluded.scopes,
+			expires_at = excluded.expires_at,
+			last_used_at = excluded.last_used_at,
+			created_at = excluded.created_at
+	`,
+		token.Id,
+		token.UserId,
+		token.Name,
+		token.Hash,
+		strings.Join(token.Scopes, " "),
+		token.ExpiresAt,
+		token.LastUsedAt,
+		token.CreatedAt,
+	)
+	if isPostgresUniqueViolation(err) {
+		return fmt.Errorf("API token with ID %d: %w", token.Id, ErrConflict)
+	}
+	if err != nil {
+		return err
+	}
+	return s.advanceIdentity(ctx, "api_tokens", token.Id)
+}
+
+func (s *PostgresStore) ImportAuditEvent(
+	ctx context.Context,
+	event *models.AuditEvent,
+) error {
+	_, err := s.db.ExecContext(ctx, `
+		insert into audit_events (
+			id,
+			action,
+			actor_id,
+			impersonator_id,
+			subject,
+			details,
+			created_at
+		)
+		values ($1, $2, $3, $4, $5, $6, $7)
+		on conflict (id) do update set
+			action = excluded.action,
+			actor_id = excluded.actor_id,
+			impersonator_id = excluded.impersonator_id,
+			subject = excluded.subject,
+			details = excluded.details,
+			created_at = excluded.created_at
+	`,
+		event.Id,
+		event.Action,
+		event.ActorId,
+		event.ImpersonatorId,
+		event.Subject,
+		event.Details,
+		event.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	return s.advanceIdentity(ctx, "audit_events", event.Id)
+}
+
+func (s *PostgresStore) ImportProjectInvitation(
+	ctx context.Context,
+	invitation *models.ProjectInvitation,
+) error {
+	email := NormalizeEmail(invitation.Email)
+	_, err := s.db.ExecContext(ctx, `
+		insert into project_invitations (`+projectInvitationColumns+`)
+		values ($1, $2, $3, $4, $5, $6)
+		on conflict (id) do update set
+			project_id = excluded.project_id,
+			email = excluded.email,
+			role = excluded.role,
+			invited_by = excluded.invited_by,
+			created_at = excluded.created_at
+	`,
+		invitation.Id,
+		invitation.ProjectId,
+		email,
+		invitation.Role,
+		invitation.InvitedBy,
+		invitation.CreatedAt,
+	)
+	if isPostgresUniqueViolation(err) {
+		return fmt.Errorf(
+			"invitation of %s to project with ID %d: %w",
+			email,
+			invitation.ProjectId,
+			ErrConflict,
+		)
+	}
+	if err != nil {
+		return err
+	}
+	return s.advanceIdentity(ctx, "project_invitations", invitation.Id)
+}
+
+// ImportTaskRevision fails since PostgresStore does not keep the revisions.
+func (s *PostgresStore) ImportTaskRevision(
+	ctx context.Context,
+	revision *models.TaskRevision,
+) error {
+	return ErrTaskRevisionsNotRecorded
+}
+
+func (s *PostgresStore) GetProjectInvitations(
+	ctx context.Context,
+) ([]models.ProjectInvitation, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		select `+projectInvitationColumns+`
+		from project_invitations
+		order by id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var invitations []models.ProjectInvitation
+	for rows.Next() {
+		invitation, err := scanPostgresProjectInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, *invitation)
+	}
+	return invitations, rows.Err()
+}
+
+func (s *PostgresStore) GetUserTokens(ctx context.Context) ([]models.UserToken, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		select hash, user_id, purpose, expires_at, email
+		from user_tokens
+		order by hash
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tokens []models.UserToken
+	for rows.Next() {
+		var token models.UserToken
+		err := rows.Scan(
+			&token.Hash,
+			&token.UserId,
+			&token.Purpose,
+			&token.ExpiresAt,
+			&token.Email,
+		)
+		if err != nil {
+			return nil, err
+		}
+		token.ExpiresAt = token.ExpiresAt.UTC()
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// advanceIdentity moves the ID sequence of the table past an imported ID,
+// which, unlike generated ones, does not advance it.
+func (s *PostgresStore) advanceIdentity(
+	ctx context.Context,
+	table string,
+	id int,
+) error {
+	_, err := s.db.ExecContext(ctx, `
+		select setval(sequence, $2)
+		from pg_get_serial_sequence($1, 'id') as sequence
+		where $2 > coalesce(pg_sequence_last_value(sequence::regclass), 0)
+	`, table, id)
+	return err
+}
+
+func scanPostgresProjectInvitation(row rowScanner) (*models.ProjectInvitation, error) {
+	invitation, err := scanProjectInvitation(row)
+	if err != nil {
+		return nil, err
+	}
+	invitation.CreatedAt = invitation.CreatedAt.UTC()
+	return invitation, nil
+}
+
+func scanPostgresApiToken(row rowScanner) (*models.ApiToken, error) {
+	token, err := scanApiToken(row)
+	if err != nil {
+		return nil, err
+	}
+	token.CreatedAt = token.CreatedAt.UTC()
+	if token.ExpiresAt != nil {
+		expiresAt := token.ExpiresAt.UTC()
+		token.ExpiresAt = &expiresAt
+	}
+	if token.LastUsedAt != nil {
+		lastUsedAt := token.LastUsedAt.UTC()
+		token.LastUsedAt = &lastUsedAt
+	}
+	return token, nil
+}
+
+func isPostgresUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
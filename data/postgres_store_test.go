@@ -0,0 +1,109 @@
+package data_test
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/data/storetest"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+// The tests run against the database at POSTGRES_TEST_DSN, which they empty,
+// or else against a throwaway server when initdb and pg_ctl are on the PATH.
+func TestPostgresStore(t *testing.T) {
+	ctx := context.Background()
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		dsn = startEphemeralPostgres(t)
+	}
+
+	t.Run("migrating twice leaves the schema as it is", func(t *testing.T) {
+		db, err := data.OpenPostgres(ctx, dsn)
+		assert.HasNoError(t, err)
+		defer db.Close()
+
+		assert.HasNoError(t, data.MigratePostgres(ctx, db))
+	})
+
+	storetest.Run(t, func(t *testing.T) data.Store {
+		db, err := data.OpenPostgres(ctx, dsn)
+		assert.HasNoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		_, err = db.Exec(`
+			truncate
+				api_tokens,
+				audit_events,
+				login_attempts,
+				project_invitations,
+				project_members,
+				projects,
+				tasks,
+				user_mfa,
+				user_tokens,
+				users
+			restart identity
+		`)
+		assert.HasNoError(t, err)
+		return data.NewPostgresStore(db)
+	})
+}
+
+func startEphemeralPostgres(t *testing.T) string {
+	t.Helper()
+	initdb, err := exec.LookPath("initdb")
+	if err != nil {
+		t.Skip("set POSTGRES_TEST_DSN or put initdb on the PATH to run the tests")
+	}
+	pgCtl, err := exec.LookPath("pg_ctl")
+	if err != nil {
+		t.Skip("set POSTGRES_TEST_DSN or put pg_ctl on the PATH to run the tests")
+	}
+
+	dir := t.TempDir()
+	dataDir := filepath.Join(dir, "data")
+	output, err := exec.Command(
+		initdb,
+		"--pgdata", dataDir,
+		"--username", "postgres",
+		"--auth", "trust",
+	).CombinedOutput()
+	if err != nil {
+		t.Skipf("could not initialize a postgres cluster: %v\n%s", err, output)
+	}
+
+	port := freePort(t)
+	output, err = exec.Command(
+		pgCtl,
+		"start",
+		"--pgdata", dataDir,
+		"--wait",
+		"--log", filepath.Join(dir, "postgres.log"),
+		"-o", fmt.Sprintf("-c listen_addresses='' -k %s -p %d", dir, port),
+	).CombinedOutput()
+	if err != nil {
+		t.Fatalf("could not start postgres: %v\n%s", err, output)
+	}
+	t.Cleanup(func() {
+		exec.Command(pgCtl, "stop", "--pgdata", dataDir, "--mode", "fast").Run()
+	})
+
+	return fmt.Sprintf(
+		"host=%s port=%d user=postgres dbname=postgres sslmode=disable",
+		dir,
+		port,
+	)
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.HasNoError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
@@ -0,0 +1,53 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+)
+
+// requiredSqliteTables have been in the schema from the start. InitDb adds
+// the others when the server starts, so older databases still qualify.
+var requiredSqliteTables = []string{"users", "tasks"}
+
+// BackupTo writes a consistent copy of the database to a new file at path
+// while the store stays in use. The file is created beforehand so that only
+// the owner can read it; vacuum into would leave it to the umask.
+func (s *SqliteStore) BackupTo(ctx context.Context, path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, "vacuum into ?", path)
+	return err
+}
+
+// CheckSqliteDb reports whether db is intact and holds the tables of the
+// store.
+func CheckSqliteDb(ctx context.Context, db *sql.DB) error {
+	var result string
+	err := db.QueryRowContext(ctx, "pragma integrity_check").Scan(&result)
+	if err != nil {
+		return err
+	}
+	if result != "ok" {
+		return fmt.Errorf("the database is corrupt: %s", result)
+	}
+	for _, table := range requiredSqliteTables {
+		var count int
+		err := db.QueryRowContext(ctx, `
+			select count(*) from sqlite_master where type = 'table' and name = ?
+		`, table).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("the database has no %s table", table)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestSqliteBackup(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := sql.Open(SqliteDriver, filepath.Join(dir, "data.db"))
+	assert.HasNoError(t, err)
+	defer db.Close()
+	InitDb(db)
+	store := NewSqliteStore(db)
+
+	t.Run("copies the database", func(t *testing.T) {
+		path := filepath.Join(dir, "backup.db")
+		assert.HasNoError(t, store.BackupTo(ctx, path))
+
+		backup, err := sql.Open(SqliteDriver, path)
+		assert.HasNoError(t, err)
+		defer backup.Close()
+		assert.HasNoError(t, CheckSqliteDb(ctx, backup))
+		tasks, err := NewSqliteStore(backup).GetTasks(ctx)
+		assert.HasNoError(t, err)
+		wantedTasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, tasks, wantedTasks)
+	})
+
+	t.Run("only lets the owner read the copy", func(t *testing.T) {
+		path := filepath.Join(dir, "private.db")
+		assert.HasNoError(t, store.BackupTo(ctx, path))
+
+		info, err := os.Stat(path)
+		assert.HasNoError(t, err)
+		assert.Equals(t, info.Mode().Perm(), os.FileMode(0o600))
+	})
+
+	t.Run("refuses to overwrite a file", func(t *testing.T) {
+		path := filepath.Join(dir, "existing.db")
+		assert.HasNoError(t, os.WriteFile(path, []byte("keep me"), 0o600))
+		assert.HasError(t, store.BackupTo(ctx, path))
+	})
+
+	t.Run("rejects files that are not databases of the store", func(t *testing.T) {
+		garbage := filepath.Join(dir, "garbage.db")
+		assert.HasNoError(t, os.WriteFile(garbage, []byte("not a database"), 0o600))
+		other := filepath.Join(dir, "other.db")
+		otherDb, err := sql.Open(SqliteDriver, other)
+		assert.HasNoError(t, err)
+		_, err = otherDb.Exec("create table notes (id integer primary key)")
+		assert.HasNoError(t, err)
+		assert.HasNoError(t, otherDb.Close())
+
+		for _, path := range []string{garbage, other} {
+			db, err := sql.Open(SqliteDriver, path)
+			assert.HasNoError(t, err)
+			assert.HasError(t, CheckSqliteDb(ctx, db))
+			db.Close()
+		}
+	})
+}
@@ -0,0 +1,13 @@
+//go:build !modernc
+
+package data
+
+import _ "github.com/mattn/go-sqlite3"
+
+// SqliteDriver is the database/sql driver that SQLite databases are opened
+// with. Building with the modernc tag swaps the cgo driver for a pure-Go one.
+const SqliteDriver = "sqlite3"
+
+// sqlitePragmas are the DSN parameters that make the driver set the pragmas of
+// OpenSqlite on every connection.
+const sqlitePragmas = "_journal_mode=WAL&_busy_timeout=5000&_synchronous=NORMAL"
@@ -0,0 +1,14 @@
+//go:build modernc
+
+package data
+
+import _ "modernc.org/sqlite"
+
+// SqliteDriver is the database/sql driver that SQLite databases are opened
+// with. This pure-Go driver lets the server be built with CGO_ENABLED=0.
+const SqliteDriver = "sqlite"
+
+// sqlitePragmas are the DSN parameters that make the driver set the pragmas of
+// OpenSqlite on every connection.
+const sqlitePragmas = "_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)" +
+	"&_pragma=synchronous(NORMAL)"
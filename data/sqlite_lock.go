@@ -0,0 +1,26 @@
+package data
+
+import "strings"
+
+// LockSqlite keeps the database of dsn from being swapped out, as a restore
+// does, while the server uses it. SQLite only locks the file during
+// transactions, which cannot tell an idle server from a stopped one. Other
+// processes can still read and write the database.
+func LockSqlite(dsn string) (unlock func() error, err error) {
+	path := SqlitePath(dsn)
+	if path == "" || path == ":memory:" {
+		return func() error { return nil }, nil
+	}
+	lock, err := lockFile(path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	return lock.unlock, nil
+}
+
+// SqlitePath returns the file name of the database of dsn, which can be a
+// "file:" URI with parameters. In-memory databases give "" or ":memory:".
+func SqlitePath(dsn string) string {
+	path, _, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?")
+	return path
+}
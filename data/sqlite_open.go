@@ -0,0 +1,35 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// OpenSqlite opens the database at path in WAL mode, so that reads do not wait
+// for writes, and with a busy timeout of 5 seconds, so that concurrent writes
+// wait for each other instead of failing with "database is locked". Syncing
+// is relaxed to what WAL mode needs to stay consistent after a crash.
+func OpenSqlite(ctx context.Context, path string) (*sql.DB, error) {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	db, err := sql.Open(SqliteDriver, path+separator+sqlitePragmas)
+	if err != nil {
+		return nil, err
+	}
+	var journalMode string
+	err = db.QueryRowContext(ctx, "pragma journal_mode").Scan(&journalMode)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	// In-memory databases have no use for a WAL and keep their own mode.
+	if journalMode != "wal" && journalMode != "memory" {
+		db.Close()
+		return nil, fmt.Errorf("could not switch %s to WAL mode", path)
+	}
+	return db, nil
+}
@@ -0,0 +1,133 @@
+package data
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestOpenSqlite(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("sets WAL mode and the busy timeout on every connection", func(t *testing.T) {
+		db, err := OpenSqlite(ctx, filepath.Join(t.TempDir(), "data.db"))
+		assert.HasNoError(t, err)
+		defer db.Close()
+
+		var journalMode string
+		err = db.QueryRow("pragma journal_mode").Scan(&journalMode)
+		assert.HasNoError(t, err)
+		assert.Equals(t, journalMode, "wal")
+		for range 3 {
+			conn, err := db.Conn(ctx)
+			assert.HasNoError(t, err)
+			defer conn.Close()
+			var busyTimeout int
+			err = conn.QueryRowContext(ctx, "pragma busy_timeout").Scan(&busyTimeout)
+			assert.HasNoError(t, err)
+			assert.Equals(t, busyTimeout, 5000)
+		}
+	})
+
+	t.Run("keeps parameters already in the path", func(t *testing.T) {
+		path := "file:" + filepath.Join(t.TempDir(), "data.db") + "?cache=private"
+		db, err := OpenSqlite(ctx, path)
+		assert.HasNoError(t, err)
+		defer db.Close()
+	})
+
+	t.Run("lets concurrent writes wait for each other", func(t *testing.T) {
+		db, err := OpenSqlite(ctx, filepath.Join(t.TempDir(), "data.db"))
+		assert.HasNoError(t, err)
+		defer db.Close()
+		InitEmptyDb(db)
+		store := NewSqliteStore(db)
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 8*25)
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for range 25 {
+					_, err := store.CreateTask(ctx, models.NewCreateTaskDTO("Write", 1))
+					errs <- err
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			assert.HasNoError(t, err)
+		}
+		tasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, tasks, 8*25)
+	})
+}
+
+func TestSqliteStoreStatements(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenSqlite(ctx, filepath.Join(t.TempDir(), "data.db"))
+	assert.HasNoError(t, err)
+	defer db.Close()
+	InitDb(db)
+	store := NewSqliteStore(db)
+
+	t.Run("prepares each query once", func(t *testing.T) {
+		for range 3 {
+			_, err := store.GetTaskById(ctx, 1)
+			assert.HasNoError(t, err)
+		}
+		assert.Equals(t, len(store.stmts), 1)
+	})
+
+	t.Run("prepares the queries again after Close", func(t *testing.T) {
+		assert.HasNoError(t, store.Close())
+		assert.Equals(t, len(store.stmts), 0)
+		_, err := store.GetTaskById(ctx, 1)
+		assert.HasNoError(t, err)
+	})
+
+	t.Run("reports queries that do not prepare", func(t *testing.T) {
+		var count int
+		err := store.queryRow(ctx, "select count(*) from nowhere").Scan(&count)
+		assert.HasError(t, err)
+		_, err = store.exec(ctx, "delete from nowhere")
+		assert.HasError(t, err)
+	})
+}
+
+func TestCreateIndexes(t *testing.T) {
+	db, err := OpenSqlite(context.Background(), filepath.Join(t.TempDir(), "data.db"))
+	assert.HasNoError(t, err)
+	defer db.Close()
+	InitEmptyDb(db)
+
+	queries := map[string]string{
+		"select id from tasks where project_id = 1":           "tasks_project_id_idx",
+		"select id from tasks where assignee_id = 1":          "tasks_assignee_id_idx",
+		"select role from project_members where user_id = 1":  "project_members_user_id_idx",
+		"select id from project_invitations where email = ''": "project_invitations_email_idx",
+	}
+	for query, index := range queries {
+		var plan strings.Builder
+		rows, err := db.Query("explain query plan " + query)
+		assert.HasNoError(t, err)
+		for rows.Next() {
+			var id, parent, unused int
+			var detail string
+			assert.HasNoError(t, rows.Scan(&id, &parent, &unused, &detail))
+			plan.WriteString(detail)
+		}
+		rows.Close()
+		if !strings.Contains(plan.String(), index) {
+			t.Errorf("got plan %q for %q, want it to use %s", plan.String(), query, index)
+		}
+	}
+}
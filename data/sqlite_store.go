@@ -1,28 +1,136 @@
 package data
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/claudealdric/go-todolist-restful-api-server/models"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type SqliteStore struct {
 	db *sql.DB
+
+	// stmts holds a prepared statement for every query run so far, so that
+	// SQLite parses each query once per connection instead of once per call.
+	stmtsMu sync.RWMutex
+	stmts   map[string]*sql.Stmt
 }
 
 func NewSqliteStore(db *sql.DB) *SqliteStore {
-	s := SqliteStore{db}
-	return &s
+	return &SqliteStore{db: db, stmts: make(map[string]*sql.Stmt)}
+}
+
+// Close releases the prepared statements but leaves the database open.
+func (s *SqliteStore) Close() error {
+	s.stmtsMu.Lock()
+	defer s.stmtsMu.Unlock()
+	var errs []error
+	for query, stmt := range s.stmts {
+		errs = append(errs, stmt.Close())
+		delete(s.stmts, query)
+	}
+	return errors.Join(errs...)
+}
+
+func (s *SqliteStore) prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	s.stmtsMu.RLock()
+	stmt, ok := s.stmts[query]
+	s.stmtsMu.RUnlock()
+	if ok {
+		return stmt, nil
+	}
+
+	s.stmtsMu.Lock()
+	defer s.stmtsMu.Unlock()
+	if stmt, ok := s.stmts[query]; ok {
+		return stmt, nil
+	}
+	stmt, err := s.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	s.stmts[query] = stmt
+	return stmt, nil
+}
+
+func (s *SqliteStore) exec(
+	ctx context.Context,
+	query string,
+	args ...any,
+) (sql.Result, error) {
+	stmt, err := s.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.ExecContext(ctx, args...)
+}
+
+func (s *SqliteStore) query(
+	ctx context.Context,
+	query string,
+	args ...any,
+) (*sql.Rows, error) {
+	stmt, err := s.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.QueryContext(ctx, args...)
+}
+
+func (s *SqliteStore) queryRow(
+	ctx context.Context,
+	query string,
+	args ...any,
+) *sql.Row {
+	stmt, err := s.prepare(ctx, query)
+	if err != nil {
+		// Running the query unprepared gets the error into the row.
+		return s.db.QueryRowContext(ctx, query, args...)
+	}
+	return stmt.QueryRowContext(ctx, args...)
+}
+
+func (s *SqliteStore) execTx(
+	ctx context.Context,
+	tx *sql.Tx,
+	query string,
+	args ...any,
+) (sql.Result, error) {
+	stmt, err := s.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return tx.StmtContext(ctx, stmt).ExecContext(ctx, args...)
 }
 
-func (s *SqliteStore) CreateTask(dto *models.CreateTaskDTO) (*models.Task, error) {
-	result, err := s.db.Exec(`
-		insert into tasks (title)
+func (s *SqliteStore) queryRowTx(
+	ctx context.Context,
+	tx *sql.Tx,
+	query string,
+	args ...any,
+) *sql.Row {
+	stmt, err := s.prepare(ctx, query)
+	if err != nil {
+		return tx.QueryRowContext(ctx, query, args...)
+	}
+	return tx.StmtContext(ctx, stmt).QueryRowContext(ctx, args...)
+}
+
+func (s *SqliteStore) CreateTask(
+	ctx context.Context,
+	dto *models.CreateTaskDTO,
+) (*models.Task, error) {
+	result, err := s.exec(ctx, `
+		insert into tasks (title, project_id, assignee_id)
 		values
-			(?)
-	`, dto.Title)
+			(?, ?, ?)
+	`, dto.Title, dto.ProjectId, dto.AssigneeId)
 	if err != nil {
 		return nil, err
 	}
@@ -32,23 +140,29 @@ func (s *SqliteStore) CreateTask(dto *models.CreateTaskDTO) (*models.Task, error
 		return nil, err
 	}
 
-	return models.NewTask(int(taskId), dto.Title), nil
+	task := models.NewTask(int(taskId), dto.Title, dto.ProjectId)
+	task.AssigneeId = dto.AssigneeId
+	return task, nil
 }
 
-func (s *SqliteStore) CreateUser(dto *models.CreateUserDTO) (*models.User, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword(
-		[]byte(dto.Password),
-		bcrypt.DefaultCost,
-	)
+func (s *SqliteStore) CreateUser(
+	ctx context.Context,
+	dto *models.CreateUserDTO,
+) (*models.User, error) {
+	email := NormalizeEmail(dto.Email)
+	hashedPassword, err := hashPassword(dto.Password)
 	if err != nil {
 		return nil, err
 	}
 
-	result, err := s.db.Exec(`
+	result, err := s.exec(ctx, `
 		insert into users (name, email, password)
 		values
 			(?, ?, ?)
-	`, dto.Name, dto.Email, hashedPassword)
+	`, dto.Name, email, hashedPassword)
+	if isUniqueConstraintError(err) {
+		return nil, fmt.Errorf("user with email %s: %w", email, ErrConflict)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -58,104 +172,1490 @@ func (s *SqliteStore) CreateUser(dto *models.CreateUserDTO) (*models.User, error
 		return nil, err
 	}
 
-	return models.NewUser(int(userId), dto.Name, dto.Email, dto.Password), nil
+	return models.NewUser(int(userId), dto.Name, email, string(hashedPassword)), nil
 }
 
-func (s *SqliteStore) DeleteTaskById(id int) error {
-	_, err := s.db.Exec(`
+func (s *SqliteStore) DeleteTaskById(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := s.execTx(ctx, tx, `
 		delete from tasks where id = ?
 	`, id)
-	return err
+	if err != nil {
+		return err
+	}
+	if err := checkRowsAffected(result, "task", id); err != nil {
+		return err
+	}
+	_, err = s.execTx(ctx, tx, `delete from task_revisions where task_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (s *SqliteStore) GetTaskById(id int) (*models.Task, error) {
-	var task models.Task
-	err := s.db.QueryRow(`select * from tasks where id = ?`, id).Scan(
-		&task.Id,
-		&task.Title,
-	)
+func (s *SqliteStore) GetTaskById(ctx context.Context, id int) (*models.Task, error) {
+	task, err := scanTask(s.queryRow(
+		ctx,
+		`select `+taskColumns+` from tasks where id = ?`,
+		id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("task with ID %d: %w", id, ErrResourceNotFound)
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &task, nil
+	return task, nil
+}
+
+func (s *SqliteStore) GetTasks(ctx context.Context) ([]models.Task, error) {
+	return s.queryTasks(ctx, `select `+taskColumns+` from tasks order by id`)
+}
+
+func (s *SqliteStore) GetTasksByProjectId(
+	ctx context.Context,
+	projectId int,
+) ([]models.Task, error) {
+	return s.queryTasks(ctx, `
+		select `+taskColumns+`
+		from tasks
+		where project_id = ?
+		order by id
+	`, projectId)
 }
 
-func (s *SqliteStore) GetTasks() ([]models.Task, error) {
-	rows, err := s.db.Query(`select * from tasks`)
+func (s *SqliteStore) queryTasks(
+	ctx context.Context,
+	query string,
+	args ...any,
+) ([]models.Task, error) {
+	rows, err := s.query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var tasks []models.Task
 	for rows.Next() {
-		var task models.Task
-		err := rows.Scan(&task.Id, &task.Title)
+		task, err := scanTask(rows)
 		if err != nil {
 			return nil, err
 		}
-		tasks = append(tasks, task)
+		tasks = append(tasks, *task)
 	}
-	return tasks, nil
+	return tasks, rows.Err()
 }
 
-func (s *SqliteStore) GetUsers() ([]models.User, error) {
-	rows, err := s.db.Query(`select * from users`)
+func (s *SqliteStore) GetUsers(ctx context.Context) ([]models.User, error) {
+	rows, err := s.query(
+		ctx,
+		`select `+userColumns+` from users order by id`,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var users []models.User
 	for rows.Next() {
-		var user models.User
-		err := rows.Scan(&user.Id, &user.Name, &user.Email, &user.Password)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, err
 		}
-		users = append(users, user)
+		users = append(users, *user)
 	}
-	return users, nil
+	return users, rows.Err()
 }
 
-func (s *SqliteStore) GetUserByEmail(email string) (*models.User, error) {
-	var user models.User
-	err := s.db.QueryRow(`select * from users where email = ?`, email).Scan(
-		&user.Id,
-		&user.Name,
-		&user.Email,
-		&user.Password,
+func (s *SqliteStore) GetUserByEmail(
+	ctx context.Context,
+	email string,
+) (*models.User, error) {
+	email = NormalizeEmail(email)
+	user, err := scanUser(s.queryRow(
+		ctx,
+		`select `+userColumns+` from users where email = ?`,
+		email,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf(
+			"user with email %s: %w",
+			email,
+			ErrResourceNotFound,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *SqliteStore) GetUserById(ctx context.Context, id int) (*models.User, error) {
+	user, err := scanUser(s.queryRow(
+		ctx,
+		`select `+userColumns+` from users where id = ?`,
+		id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("user with ID %d: %w", id, ErrResourceNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *SqliteStore) UpdateUser(
+	ctx context.Context,
+	user *models.User,
+) (*models.User, error) {
+	email := NormalizeEmail(user.Email)
+	result, err := s.exec(ctx, `
+		update users
+		set
+			name = ?,
+			email = ?,
+			verified = ?,
+			timezone = ?,
+			locale = ?,
+			start_of_week = ?,
+			role = ?,
+			disabled = ?
+		where id = ?
+	`,
+		user.Name,
+		email,
+		user.Verified,
+		user.Timezone,
+		user.Locale,
+		user.StartOfWeek,
+		user.Role,
+		user.Disabled,
+		user.Id,
 	)
+	if isUniqueConstraintError(err) {
+		return nil, fmt.Errorf("user with email %s: %w", email, ErrConflict)
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &user, nil
+	if err := checkRowsAffected(result, "user", user.Id); err != nil {
+		return nil, err
+	}
+	return s.GetUserById(ctx, user.Id)
+}
+
+func (s *SqliteStore) DeleteUserById(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = s.execTx(ctx, tx, `delete from user_tokens where user_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.execTx(ctx, tx, `delete from api_tokens where user_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	_, err = s.execTx(ctx, tx, `delete from user_mfa where user_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if err := s.deleteProjectMemberships(ctx, tx, id); err != nil {
+		return err
+	}
+	result, err := s.execTx(ctx, tx, `delete from users where id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if err := checkRowsAffected(result, "user", id); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (s *SqliteStore) UpdateTask(task *models.Task) (*models.Task, error) {
-	_, err := s.db.Exec(`
+func (s *SqliteStore) UpdateUserPassword(
+	ctx context.Context,
+	id int,
+	password string,
+) error {
+	hashedPassword, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := s.execTx(ctx, tx, `
+		update users
+		set password = ?, password_changed_at = ?
+		where id = ?
+	`, hashedPassword, time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+	if err := checkRowsAffected(result, "user", id); err != nil {
+		return err
+	}
+	_, err = s.execTx(ctx, tx, `delete from api_tokens where user_id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateTask keeps the version of the task it replaces as a revision.
+func (s *SqliteStore) UpdateTask(
+	ctx context.Context,
+	task *models.Task,
+) (*models.Task, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Reading the task through a write takes the write lock first, so that
+	// concurrent updates wait for each other instead of failing.
+	oldTask, err := scanTask(s.queryRowTx(ctx, tx, `
 		update tasks
-		set title = ?
+		set id = id
 		where id = ?
-	`, task.Title, task.Id)
+		returning `+taskColumns, task.Id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("task with ID %d: %w", task.Id, ErrResourceNotFound)
+	}
 	if err != nil {
 		return nil, err
 	}
-	updatedTask, err := s.GetTaskById(task.Id)
+	updatedTask := *oldTask
+	updatedTask.Title = task.Title
+	updatedTask.AssigneeId = task.AssigneeId
+	_, err = s.execTx(ctx, tx, `
+		update tasks
+		set title = ?, assignee_id = ?
+		where id = ?
+	`, updatedTask.Title, updatedTask.AssigneeId, updatedTask.Id)
 	if err != nil {
 		return nil, err
 	}
+	revision := models.NewTaskRevision(oldTask, &updatedTask, editorFromContext(ctx))
+	if revision != nil {
+		_, err = s.execTx(ctx, tx, `
+			insert into task_revisions (
+				task_id,
+				revision,
+				title,
+				assignee_id,
+				changed_fields,
+				editor_id,
+				created_at
+			)
+			select ?, coalesce(max(revision), 0) + 1, ?, ?, ?, ?, ?
+			from task_revisions
+			where task_id = ?
+		`,
+			revision.TaskId,
+			revision.Title,
+			revision.AssigneeId,
+			strings.Join(revision.ChangedFields, " "),
+			revision.EditorId,
+			time.Now().UTC(),
+			revision.TaskId,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &updatedTask, nil
+}
+
+func (s *SqliteStore) GetTaskRevisions(
+	ctx context.Context,
+	taskId int,
+) ([]models.TaskRevision, error) {
+	rows, err := s.query(ctx, `
+		select `+taskRevisionColumns+`
+		from task_revisions
+		where task_id = ?
+		order by revision
+	`, taskId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var revisions []models.TaskRevision
+	for rows.Next() {
+		revision, err := scanTaskRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, *revision)
+	}
+	return revisions, rows.Err()
+}
 
-	return updatedTask, nil
+func (s *SqliteStore) GetTaskRevision(
+	ctx context.Context,
+	taskId, revision int,
+) (*models.TaskRevision, error) {
+	taskRevision, err := scanTaskRevision(s.queryRow(ctx, `
+		select `+taskRevisionColumns+`
+		from task_revisions
+		where task_id = ? and revision = ?
+	`, taskId, revision))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf(
+			"revision %d of task with ID %d: %w",
+			revision,
+			taskId,
+			ErrResourceNotFound,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return taskRevision, nil
 }
 
-func (s *SqliteStore) ValidateUserCredentials(email, password string) bool {
-	user, err := s.GetUserByEmail(email)
+func (s *SqliteStore) ValidateUserCredentials(
+	ctx context.Context,
+	email, password string,
+) bool {
+	user, err := s.GetUserByEmail(ctx, email)
 	if err != nil {
-		log.Printf("error retrieving user for validation: %v\n", err)
+		if !errors.Is(err, ErrResourceNotFound) {
+			log.Printf("error retrieving user for validation: %v\n", err)
+		}
+		simulatePasswordCheck(password)
 		return false
 	}
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	return checkPassword([]byte(user.Password), password)
+}
+
+func (s *SqliteStore) DeleteUserMfa(ctx context.Context, userId int) error {
+	_, err := s.exec(ctx, `delete from user_mfa where user_id = ?`, userId)
+	return err
+}
+
+func (s *SqliteStore) GetUserMfa(
+	ctx context.Context,
+	userId int,
+) (*models.UserMfa, error) {
+	var mfa models.UserMfa
+	err := s.queryRow(ctx, `
+		select user_id, totp_secret, enabled, last_used_step
+		from user_mfa
+		where user_id = ?
+	`, userId).Scan(
+		&mfa.UserId,
+		&mfa.TotpSecret,
+		&mfa.Enabled,
+		&mfa.LastUsedStep,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf(
+			"MFA settings of user with ID %d: %w",
+			userId,
+			ErrResourceNotFound,
+		)
+	}
 	if err != nil {
-		return false
+		return nil, err
+	}
+	return &mfa, nil
+}
+
+func (s *SqliteStore) SaveUserMfa(ctx context.Context, mfa *models.UserMfa) error {
+	_, err := s.exec(ctx, `
+		insert into user_mfa (user_id, totp_secret, enabled, last_used_step)
+		values
+			(?, ?, ?, ?)
+		on conflict (user_id) do update set
+			totp_secret = excluded.totp_secret,
+			enabled = excluded.enabled,
+			last_used_step = excluded.last_used_step
+	`, mfa.UserId, mfa.TotpSecret, mfa.Enabled, mfa.LastUsedStep)
+	return err
+}
+
+func (s *SqliteStore) UseUserMfaStep(
+	ctx context.Context,
+	userId int,
+	step int64,
+) (bool, error) {
+	result, err := s.exec(ctx, `
+		update user_mfa
+		set last_used_step = ?
+		where user_id = ? and last_used_step < ?
+	`, step, userId, step)
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+func (s *SqliteStore) DeleteLoginAttempt(ctx context.Context, key string) error {
+	_, err := s.exec(ctx, `delete from login_attempts where key = ?`, key)
+	return err
+}
+
+func (s *SqliteStore) GetLoginAttempt(
+	ctx context.Context,
+	key string,
+) (*models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := s.queryRow(ctx, `
+		select key, failures, last_failure_at, locked_until
+		from login_attempts
+		where key = ?
+	`, key).Scan(
+		&attempt.Key,
+		&attempt.Failures,
+		&attempt.LastFailureAt,
+		&attempt.LockedUntil,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf(
+			"login attempt with key %s: %w",
+			key,
+			ErrResourceNotFound,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (s *SqliteStore) SaveLoginAttempt(
+	ctx context.Context,
+	attempt *models.LoginAttempt,
+) error {
+	_, err := s.exec(ctx, `
+		insert into login_attempts (key, failures, last_failure_at, locked_until)
+		values
+			(?, ?, ?, ?)
+		on conflict (key) do update set
+			failures = excluded.failures,
+			last_failure_at = excluded.last_failure_at,
+			locked_until = excluded.locked_until
+	`, attempt.Key, attempt.Failures, attempt.LastFailureAt, attempt.LockedUntil)
+	return err
+}
+
+func (s *SqliteStore) IncrementLoginFailures(
+	ctx context.Context,
+	key string,
+	failedAt time.Time,
+	expireAfter time.Duration,
+) (*models.LoginAttempt, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Writing before reading takes the write lock, so that concurrent
+	// failures wait for each other instead of reading the same count.
+	_, err = s.execTx(ctx, tx, `
+		insert into login_attempts (key, failures, last_failure_at, locked_until)
+		values
+			(?, 0, ?, ?)
+		on conflict (key) do nothing
+	`, key, failedAt, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	var attempt models.LoginAttempt
+	err = s.queryRowTx(ctx, tx, `
+		select key, failures, last_failure_at, locked_until
+		from login_attempts
+		where key = ?
+	`, key).Scan(
+		&attempt.Key,
+		&attempt.Failures,
+		&attempt.LastFailureAt,
+		&attempt.LockedUntil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	countLoginFailure(&attempt, failedAt, expireAfter)
+	_, err = s.execTx(ctx, tx, `
+		update login_attempts
+		set failures = ?, last_failure_at = ?
+		where key = ?
+	`, attempt.Failures, attempt.LastFailureAt, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (s *SqliteStore) LockLoginAttempt(
+	ctx context.Context,
+	key string,
+	failures int,
+	lockedUntil time.Time,
+) error {
+	_, err := s.exec(ctx, `
+		update login_attempts
+		set locked_until = ?
+		where key = ? and failures = ?
+	`, lockedUntil, key, failures)
+	return err
+}
+
+func (s *SqliteStore) CreateAuditEvent(
+	ctx context.Context,
+	event *models.AuditEvent,
+) (*models.AuditEvent, error) {
+	createdEvent := *event
+	if createdEvent.CreatedAt.IsZero() {
+		createdEvent.CreatedAt = time.Now().UTC()
+	}
+	result, err := s.exec(ctx, `
+		insert into audit_events (
+			action,
+			actor_id,
+			impersonator_id,
+			subject,
+			details,
+			created_at
+		)
+		values
+			(?, ?, ?, ?, ?, ?)
+	`,
+		createdEvent.Action,
+		createdEvent.ActorId,
+		createdEvent.ImpersonatorId,
+		createdEvent.Subject,
+		createdEvent.Details,
+		createdEvent.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	eventId, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	createdEvent.Id = int(eventId)
+
+	return &createdEvent, nil
+}
+
+func (s *SqliteStore) GetAuditEvents(ctx context.Context) ([]models.AuditEvent, error) {
+	rows, err := s.query(ctx, `
+		select id, action, actor_id, impersonator_id, subject, details, created_at
+		from audit_events
+		order by id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []models.AuditEvent
+	for rows.Next() {
+		var event models.AuditEvent
+		err := rows.Scan(
+			&event.Id,
+			&event.Action,
+			&event.ActorId,
+			&event.ImpersonatorId,
+			&event.Subject,
+			&event.Details,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (s *SqliteStore) CreateUserToken(
+	ctx context.Context,
+	token *models.UserToken,
+) error {
+	_, err := s.exec(ctx, `
+		insert into user_tokens (hash, user_id, purpose, expires_at, email)
+		values
+			(?, ?, ?, ?, ?)
+	`, token.Hash, token.UserId, token.Purpose, token.ExpiresAt, token.Email)
+	return err
+}
+
+func (s *SqliteStore) ConsumeUserToken(
+	ctx context.Context,
+	hash, purpose string,
+) (*models.UserToken, error) {
+	var token models.UserToken
+	err := s.queryRow(ctx, `
+		delete from user_tokens
+		where hash = ? and purpose = ?
+		returning hash, user_id, purpose, expires_at, email
+	`, hash, purpose).Scan(
+		&token.Hash,
+		&token.UserId,
+		&token.Purpose,
+		&token.ExpiresAt,
+		&token.Email,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s token: %w", purpose, ErrResourceNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (s *SqliteStore) DeleteUserTokens(
+	ctx context.Context,
+	userId int,
+	purpose string,
+) error {
+	_, err := s.exec(ctx, `
+		delete from user_tokens
+		where user_id = ? and purpose = ?
+	`, userId, purpose)
+	return err
+}
+
+func (s *SqliteStore) CreateApiToken(
+	ctx context.Context,
+	token *models.ApiToken,
+) (*models.ApiToken, error) {
+	createdToken := *token
+	if createdToken.CreatedAt.IsZero() {
+		createdToken.CreatedAt = time.Now().UTC()
+	}
+	result, err := s.exec(ctx, `
+		insert into api_tokens (user_id, name, hash, scopes, expires_at, created_at)
+		values
+			(?, ?, ?, ?, ?, ?)
+	`,
+		createdToken.UserId,
+		createdToken.Name,
+		createdToken.Hash,
+		strings.Join(createdToken.Scopes, " "),
+		createdToken.ExpiresAt,
+		createdToken.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenId, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	createdToken.Id = int(tokenId)
+
+	return &createdToken, nil
+}
+
+func (s *SqliteStore) DeleteApiToken(ctx context.Context, id, userId int) error {
+	result, err := s.exec(ctx, `
+		delete from api_tokens where id = ? and user_id = ?
+	`, id, userId)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result, "API token", id)
+}
+
+func (s *SqliteStore) GetApiTokenByHash(
+	ctx context.Context,
+	hash string,
+) (*models.ApiToken, error) {
+	token, err := scanApiToken(s.queryRow(
+		ctx,
+		`select `+apiTokenColumns+` from api_tokens where hash = ?`,
+		hash,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("API token: %w", ErrResourceNotFound)
+	}
+	if err != nil {
+		return nil, err
 	}
-	return true
+	return token, nil
+}
+
+func (s *SqliteStore) GetApiTokensByUserId(
+	ctx context.Context,
+	userId int,
+) ([]models.ApiToken, error) {
+	rows, err := s.query(
+		ctx,
+		`select `+apiTokenColumns+` from api_tokens where user_id = ? order by id`,
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tokens []models.ApiToken
+	for rows.Next() {
+		token, err := scanApiToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *token)
+	}
+	return tokens, nil
+}
+
+func (s *SqliteStore) UpdateApiTokenLastUsedAt(
+	ctx context.Context,
+	id int,
+	lastUsedAt time.Time,
+) error {
+	result, err := s.exec(ctx, `
+		update api_tokens
+		set last_used_at = ?
+		where id = ?
+	`, lastUsedAt, id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result, "API token", id)
+}
+
+func (s *SqliteStore) CreateProject(
+	ctx context.Context,
+	dto *models.CreateProjectDTO,
+	ownerId int,
+) (*models.Project, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := s.execTx(ctx, tx, `insert into projects (name) values (?)`, dto.Name)
+	if err != nil {
+		return nil, err
+	}
+	projectId, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.execTx(ctx, tx, `
+		insert into project_members (project_id, user_id, role)
+		values
+			(?, ?, ?)
+	`, projectId, ownerId, models.ProjectRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &models.Project{Id: int(projectId), Name: dto.Name}, nil
+}
+
+func (s *SqliteStore) GetProjectById(
+	ctx context.Context,
+	id int,
+) (*models.Project, error) {
+	var project models.Project
+	err := s.queryRow(ctx, `
+		select id, name from projects where id = ?
+	`, id).Scan(&project.Id, &project.Name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("project with ID %d: %w", id, ErrResourceNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+// DeleteProjectMember also unassigns the tasks of the project that were
+// assigned to the member.
+func (s *SqliteStore) DeleteProjectMember(
+	ctx context.Context,
+	projectId, userId int,
+) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := s.execTx(ctx, tx, `
+		delete from project_members
+		where project_id = ? and user_id = ?
+	`, projectId, userId)
+	if err != nil {
+		return err
+	}
+	if err := checkRowsAffected(result, "member of project", projectId); err != nil {
+		return err
+	}
+	_, err = s.execTx(ctx, tx, `
+		update tasks
+		set assignee_id = 0
+		where project_id = ? and assignee_id = ?
+	`, projectId, userId)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SqliteStore) GetProjectMember(
+	ctx context.Context,
+	projectId, userId int,
+) (*models.ProjectMember, error) {
+	member := models.ProjectMember{ProjectId: projectId, UserId: userId}
+	err := s.queryRow(ctx, `
+		select role
+		from project_members
+		where project_id = ? and user_id = ?
+	`, projectId, userId).Scan(&member.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf(
+			"user with ID %d in project with ID %d: %w",
+			userId,
+			projectId,
+			ErrResourceNotFound,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (s *SqliteStore) GetProjectMembers(
+	ctx context.Context,
+	projectId int,
+) ([]models.ProjectMember, error) {
+	return s.queryProjectMembers(ctx, `
+		select project_id, user_id, role
+		from project_members
+		where project_id = ?
+		order by user_id
+	`, projectId)
+}
+
+func (s *SqliteStore) GetProjectMembersByUserId(
+	ctx context.Context,
+	userId int,
+) ([]models.ProjectMember, error) {
+	return s.queryProjectMembers(ctx, `
+		select project_id, user_id, role
+		from project_members
+		where user_id = ?
+		order by project_id
+	`, userId)
+}
+
+func (s *SqliteStore) queryProjectMembers(
+	ctx context.Context,
+	query string,
+	args ...any,
+) ([]models.ProjectMember, error) {
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var members []models.ProjectMember
+	for rows.Next() {
+		var member models.ProjectMember
+		err := rows.Scan(&member.ProjectId, &member.UserId, &member.Role)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+func (s *SqliteStore) SaveProjectMember(
+	ctx context.Context,
+	member *models.ProjectMember,
+) error {
+	_, err := s.exec(ctx, `
+		insert into project_members (project_id, user_id, role)
+		values
+			(?, ?, ?)
+		on conflict (project_id, user_id) do update set
+			role = excluded.role
+	`, member.ProjectId, member.UserId, member.Role)
+	return err
+}
+
+// AcceptProjectInvitation turns the invitation into a membership, keeping the
+// role of users who already are members.
+func (s *SqliteStore) AcceptProjectInvitation(
+	ctx context.Context,
+	id, userId int,
+) (*models.ProjectMember, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	member := models.ProjectMember{UserId: userId}
+	err = s.queryRowTx(ctx, tx, `
+		delete from project_invitations
+		where id = ?
+		returning project_id, role
+	`, id).Scan(&member.ProjectId, &member.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf(
+			"project invitation with ID %d: %w",
+			id,
+			ErrResourceNotFound,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.execTx(ctx, tx, `
+		insert into project_members (project_id, user_id, role)
+		values
+			(?, ?, ?)
+		on conflict (project_id, user_id) do nothing
+	`, member.ProjectId, member.UserId, member.Role)
+	if err != nil {
+		return nil, err
+	}
+	err = s.queryRowTx(ctx, tx, `
+		select role
+		from project_members
+		where project_id = ? and user_id = ?
+	`, member.ProjectId, member.UserId).Scan(&member.Role)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (s *SqliteStore) CreateProjectInvitation(
+	ctx context.Context,
+	invitation *models.ProjectInvitation,
+) (*models.ProjectInvitation, error) {
+	createdInvitation := *invitation
+	createdInvitation.Email = NormalizeEmail(invitation.Email)
+	if createdInvitation.CreatedAt.IsZero() {
+		createdInvitation.CreatedAt = time.Now().UTC()
+	}
+	result, err := s.exec(ctx, `
+		insert into project_invitations
+			(project_id, email, role, invited_by, created_at)
+		values
+			(?, ?, ?, ?, ?)
+	`,
+		createdInvitation.ProjectId,
+		createdInvitation.Email,
+		createdInvitation.Role,
+		createdInvitation.InvitedBy,
+		createdInvitation.CreatedAt,
+	)
+	if isUniqueConstraintError(err) {
+		return nil, fmt.Errorf(
+			"invitation of %s to project with ID %d: %w",
+			createdInvitation.Email,
+			createdInvitation.ProjectId,
+			ErrConflict,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	invitationId, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	createdInvitation.Id = int(invitationId)
+
+	return &createdInvitation, nil
+}
+
+func (s *SqliteStore) DeleteProjectInvitation(ctx context.Context, id int) error {
+	result, err := s.exec(ctx, `delete from project_invitations where id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result, "project invitation", id)
+}
+
+func (s *SqliteStore) GetProjectInvitationById(
+	ctx context.Context,
+	id int,
+) (*models.ProjectInvitation, error) {
+	invitation, err := scanProjectInvitation(s.queryRow(
+		ctx,
+		`select `+projectInvitationColumns+` from project_invitations where id = ?`,
+		id,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf(
+			"project invitation with ID %d: %w",
+			id,
+			ErrResourceNotFound,
+		)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+func (s *SqliteStore) GetProjectInvitationsByEmail(
+	ctx context.Context,
+	email string,
+) ([]models.ProjectInvitation, error) {
+	rows, err := s.query(ctx, `
+		select `+projectInvitationColumns+`
+		from project_invitations
+		where email = ?
+		order by id
+	`, NormalizeEmail(email))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var invitations []models.ProjectInvitation
+	for rows.Next() {
+		invitation, err := scanProjectInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, *invitation)
+	}
+	return invitations, rows.Err()
+}
+
+// deleteProjectMemberships removes the user from their projects, unassigning
+// their tasks, and deletes the projects nobody is left in, along with their
+// tasks and invitations.
+func (s *SqliteStore) deleteProjectMemberships(
+	ctx context.Context,
+	tx *sql.Tx,
+	userId int,
+) error {
+	_, err := s.execTx(ctx, tx, `delete from project_members where user_id = ?`, userId)
+	if err != nil {
+		return err
+	}
+	_, err = s.execTx(ctx, tx, `update tasks set assignee_id = 0 where assignee_id = ?`, userId)
+	if err != nil {
+		return err
+	}
+	const abandonedProjects = `
+		select id from projects
+		where id not in (select project_id from project_members)
+	`
+	_, err = s.execTx(ctx, tx, `
+		delete from task_revisions where task_id in (
+			select id from tasks where project_id in (`+abandonedProjects+`)
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = s.execTx(ctx, tx, `delete from tasks where project_id in (`+abandonedProjects+`)`)
+	if err != nil {
+		return err
+	}
+	_, err = s.execTx(ctx, tx, `
+		delete from project_invitations where project_id in (`+abandonedProjects+`)
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = s.execTx(ctx, tx, `delete from projects where id in (`+abandonedProjects+`)`)
+	return err
+}
+
+func (s *SqliteStore) ImportProject(
+	ctx context.Context,
+	project *models.Project,
+) error {
+	_, err := s.exec(ctx, `
+		insert into projects (id, name)
+		values (?, ?)
+		on conflict (id) do update set name = excluded.name
+	`, project.Id, project.Name)
+	return err
+}
+
+func (s *SqliteStore) ImportTask(ctx context.Context, task *models.Task) error {
+	_, err := s.exec(ctx, `
+		insert into tasks (`+taskColumns+`)
+		values (?, ?, ?, ?)
+		on conflict (id) do update set
+			title = excluded.title,
+			project_id = excluded.project_id,
+			assignee_id = excluded.assignee_id
+	`, task.Id, task.Title, task.ProjectId, task.AssigneeId)
+	return err
+}
+
+func (s *SqliteStore) ImportUser(ctx context.Context, user *models.User) error {
+	email := NormalizeEmail(user.Email)
+	_, err := s.exec(ctx, `
+		insert into users (`+userColumns+`)
+		values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		on conflict (id) do update set
+			name = excluded.name,
+			email = excluded.email,
+			password = excluded.password,
+			verified = excluded.verified,
+			timezone = excluded.timezone,
+			locale = excluded.locale,
+			start_of_week = excluded.start_of_week,
+			role = excluded.role,
+			disabled = excluded.disabled,
+			password_changed_at = excluded.password_changed_at
+	`,
+		user.Id,
+		user.Name,
+		email,
+		user.Password,
+		user.Verified,
+		user.Timezone,
+		user.Locale,
+		user.StartOfWeek,
+		user.Role,
+		user.Disabled,
+		user.PasswordChangedAt,
+	)
+	if isUniqueConstraintError(err) {
+		return fmt.Errorf("user with email %s: %w", email, ErrConflict)
+	}
+	return err
+}
+
+func (s *SqliteStore) ImportApiToken(ctx context.Context, token *models.ApiToken) error {
+	_, err := s.exec(ctx, `
+		insert into api_tokens (`+apiTokenColumns+`)
+		values (?, ?, ?, ?, ?, ?, ?, ?)
+		on conflict (id) do update set
+			user_id = excluded.user_id,
+			name = excluded.name,
+			hash = excluded.hash,
+			scopes = excluded.scopes,
+			expires_at = excluded.expires_at,
+			last_used_at = excluded.last_used_at,
+			created_at = excluded.created_at
+	`,
+		token.Id,
+		token.UserId,
+		token.Name,
+		token.Hash,
+		strings.Join(token.Scopes, " "),
+		token.ExpiresAt,
+		token.LastUsedAt,
+		token.CreatedAt,
+	)
+	if isUniqueConstraintError(err) {
+		return fmt.Errorf("API token with ID %d: %w", token.Id, ErrConflict)
+	}
+	return err
+}
+
+func (s *SqliteStore) ImportAuditEvent(
+	ctx context.Context,
+	event *models.AuditEvent,
+) error {
+	_, err := s.exec(ctx, `
+		insert into audit_events (
+			id,
+			action,
+			actor_id,
+			impersonator_id,
+			subject,
+			details,
+			created_at
+		)
+		values (?, ?, ?, ?, ?, ?, ?)
+		on conflict (id) do update set
+			action = excluded.action,
+			actor_id = excluded.actor_id,
+			impersonator_id = excluded.impersonator_id,
+			subject = excluded.subject,
+			details = excluded.details,
+			created_at = excluded.created_at
+	`,
+		event.Id,
+		event.Action,
+		event.ActorId,
+		event.ImpersonatorId,
+		event.Subject,
+		event.Details,
+		event.CreatedAt,
+	)
+	return err
+}
+
+func (s *SqliteStore) ImportProjectInvitation(
+	ctx context.Context,
+	invitation *models.ProjectInvitation,
+) error {
+	email := NormalizeEmail(invitation.Email)
+	_, err := s.exec(ctx, `
+		insert into project_invitations (`+projectInvitationColumns+`)
+		values (?, ?, ?, ?, ?, ?)
+		on conflict (id) do update set
+			project_id = excluded.project_id,
+			email = excluded.email,
+			role = excluded.role,
+			invited_by = excluded.invited_by,
+			created_at = excluded.created_at
+	`,
+		invitation.Id,
+		invitation.ProjectId,
+		email,
+		invitation.Role,
+		invitation.InvitedBy,
+		invitation.CreatedAt,
+	)
+	if isUniqueConstraintError(err) {
+		return fmt.Errorf(
+			"invitation of %s to project with ID %d: %w",
+			email,
+			invitation.ProjectId,
+			ErrConflict,
+		)
+	}
+	return err
+}
+
+func (s *SqliteStore) ImportTaskRevision(
+	ctx context.Context,
+	revision *models.TaskRevision,
+) error {
+	_, err := s.exec(ctx, `
+		insert into task_revisions (`+taskRevisionColumns+`)
+		values (?, ?, ?, ?, ?, ?, ?)
+		on conflict (task_id, revision) do update set
+			title = excluded.title,
+			assignee_id = excluded.assignee_id,
+			changed_fields = excluded.changed_fields,
+			editor_id = excluded.editor_id,
+			created_at = excluded.created_at
+	`,
+		revision.TaskId,
+		revision.Revision,
+		revision.Title,
+		revision.AssigneeId,
+		strings.Join(revision.ChangedFields, " "),
+		revision.EditorId,
+		revision.CreatedAt,
+	)
+	return err
+}
+
+func (s *SqliteStore) GetProjectInvitations(
+	ctx context.Context,
+) ([]models.ProjectInvitation, error) {
+	rows, err := s.query(ctx, `
+		select `+projectInvitationColumns+`
+		from project_invitations
+		order by id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var invitations []models.ProjectInvitation
+	for rows.Next() {
+		invitation, err := scanProjectInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, *invitation)
+	}
+	return invitations, rows.Err()
+}
+
+func (s *SqliteStore) GetUserTokens(ctx context.Context) ([]models.UserToken, error) {
+	rows, err := s.query(ctx, `
+		select hash, user_id, purpose, expires_at, email
+		from user_tokens
+		order by hash
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tokens []models.UserToken
+	for rows.Next() {
+		var token models.UserToken
+		err := rows.Scan(
+			&token.Hash,
+			&token.UserId,
+			&token.Purpose,
+			&token.ExpiresAt,
+			&token.Email,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+const taskColumns = `id, title, project_id, assignee_id`
+
+func scanTask(row rowScanner) (*models.Task, error) {
+	var task models.Task
+	err := row.Scan(&task.Id, &task.Title, &task.ProjectId, &task.AssigneeId)
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+const taskRevisionColumns = `
+	task_id, revision, title, assignee_id, changed_fields, editor_id, created_at
+`
+
+func scanTaskRevision(row rowScanner) (*models.TaskRevision, error) {
+	var revision models.TaskRevision
+	var changedFields string
+	err := row.Scan(
+		&revision.TaskId,
+		&revision.Revision,
+		&revision.Title,
+		&revision.AssigneeId,
+		&changedFields,
+		&revision.EditorId,
+		&revision.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	revision.ChangedFields = strings.Fields(changedFields)
+	return &revision, nil
+}
+
+const projectInvitationColumns = `
+	id, project_id, email, role, invited_by, created_at
+`
+
+func scanProjectInvitation(row rowScanner) (*models.ProjectInvitation, error) {
+	var invitation models.ProjectInvitation
+	err := row.Scan(
+		&invitation.Id,
+		&invitation.ProjectId,
+		&invitation.Email,
+		&invitation.Role,
+		&invitation.InvitedBy,
+		&invitation.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+const apiTokenColumns = `
+	id, user_id, name, hash, scopes, expires_at, last_used_at, created_at
+`
+
+func scanApiToken(row rowScanner) (*models.ApiToken, error) {
+	var token models.ApiToken
+	var scopes string
+	err := row.Scan(
+		&token.Id,
+		&token.UserId,
+		&token.Name,
+		&token.Hash,
+		&scopes,
+		&token.ExpiresAt,
+		&token.LastUsedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	token.Scopes = strings.Fields(scopes)
+	return &token, nil
+}
+
+const userColumns = `
+	id, name, email, password, verified, timezone, locale, start_of_week, role,
+	disabled, password_changed_at
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	err := row.Scan(
+		&user.Id,
+		&user.Name,
+		&user.Email,
+		&user.Password,
+		&user.Verified,
+		&user.Timezone,
+		&user.Locale,
+		&user.StartOfWeek,
+		&user.Role,
+		&user.Disabled,
+		&user.PasswordChangedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if user.PasswordChangedAt != nil {
+		passwordChangedAt := user.PasswordChangedAt.UTC()
+		user.PasswordChangedAt = &passwordChangedAt
+	}
+	return &user, nil
+}
+
+func checkRowsAffected(result sql.Result, resource string, id int) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s with ID %d: %w", resource, id, ErrResourceNotFound)
+	}
+	return nil
+}
+
+func isUniqueConstraintError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
 }
@@ -0,0 +1,158 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+const (
+	benchmarkProjects = 100
+	benchmarkTasks    = 10_000
+)
+
+// BenchmarkSqliteStatements compares running a query from its text with
+// running the statement the store prepared for it.
+func BenchmarkSqliteStatements(b *testing.B) {
+	ctx := context.Background()
+	store := newBenchmarkSqliteStore(b, true)
+	query := `select ` + taskColumns + ` from tasks where id = ?`
+
+	b.Run("parsed every time", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			id := 0
+			for pb.Next() {
+				id = id%benchmarkTasks + 1
+				_, err := scanTask(store.db.QueryRowContext(ctx, query, id))
+				if err != nil {
+					b.Error(err)
+				}
+			}
+		})
+	})
+
+	b.Run("prepared", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			id := 0
+			for pb.Next() {
+				id = id%benchmarkTasks + 1
+				if _, err := store.GetTaskById(ctx, id); err != nil {
+					b.Error(err)
+				}
+			}
+		})
+	})
+}
+
+// BenchmarkSqliteIndexes lists the tasks of a project with and without the
+// index on tasks.project_id.
+func BenchmarkSqliteIndexes(b *testing.B) {
+	ctx := context.Background()
+
+	for _, indexed := range []bool{false, true} {
+		name := "without index"
+		if indexed {
+			name = "with index"
+		}
+		b.Run(name, func(b *testing.B) {
+			store := newBenchmarkSqliteStore(b, true)
+			if !indexed {
+				_, err := store.db.Exec(`drop index tasks_project_id_idx`)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ResetTimer()
+			for i := range b.N {
+				_, err := store.GetTasksByProjectId(ctx, i%benchmarkProjects+1)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSqliteConcurrentLoad runs a mix of nine reads to one write from
+// parallel goroutines against a database opened with the driver's defaults
+// and one opened with OpenSqlite. Writes that fail, which the defaults let
+// happen when the database is busy, are reported as failures/op.
+func BenchmarkSqliteConcurrentLoad(b *testing.B) {
+	ctx := context.Background()
+
+	for _, tuned := range []bool{false, true} {
+		name := "defaults"
+		if tuned {
+			name = "tuned"
+		}
+		b.Run(name, func(b *testing.B) {
+			store := newBenchmarkSqliteStore(b, tuned)
+			var failures atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					i++
+					var err error
+					if i%10 == 0 {
+						_, err = store.UpdateTask(
+							ctx,
+							models.NewTask(i%benchmarkTasks+1, "Updated", 1),
+						)
+					} else {
+						_, err = store.GetTasksByProjectId(ctx, i%benchmarkProjects+1)
+					}
+					if err != nil {
+						failures.Add(1)
+					}
+				}
+			})
+			b.ReportMetric(float64(failures.Load())/float64(b.N), "failures/op")
+		})
+	}
+}
+
+// newBenchmarkSqliteStore spreads benchmarkTasks tasks over benchmarkProjects
+// projects in a new database, which is opened with OpenSqlite when tuned.
+func newBenchmarkSqliteStore(b *testing.B, tuned bool) *SqliteStore {
+	b.Helper()
+	ctx := context.Background()
+	path := filepath.Join(b.TempDir(), "data.db")
+	var db *sql.DB
+	var err error
+	if tuned {
+		db, err = OpenSqlite(ctx, path)
+	} else {
+		db, err = sql.Open(SqliteDriver, path)
+	}
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+	InitEmptyDb(db)
+
+	tx, err := db.Begin()
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := range benchmarkTasks {
+		_, err := tx.Exec(
+			`insert into tasks (title, project_id) values (?, ?)`,
+			"Task",
+			i%benchmarkProjects+1,
+		)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		b.Fatal(err)
+	}
+	store := NewSqliteStore(db)
+	b.Cleanup(func() { store.Close() })
+	return store
+}
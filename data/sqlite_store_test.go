@@ -1,18 +1,56 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
-	_ "github.com/mattn/go-sqlite3"
-
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
 	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
 )
 
+func TestCreateUser(t *testing.T) {
+	ctx := context.Background()
+	dbFile := "../tmp/sqlite_store_create_user_test.db"
+	db, err := sql.Open(SqliteDriver, dbFile)
+	assert.HasNoError(t, err)
+	defer db.Close()
+	InitDb(db)
+	defer cleanSqliteDatabase(dbFile)
+	store := NewSqliteStore(db)
+
+	t.Run("normalizes the email", func(t *testing.T) {
+		dto := models.NewCreateUserDTO("John Doe", " John.Doe@Email.com ", "password")
+		user, err := store.CreateUser(ctx, dto)
+		assert.HasNoError(t, err)
+		assert.Equals(t, user.Email, "john.doe@email.com")
+
+		got, err := store.GetUserByEmail(ctx, "JOHN.DOE@email.com")
+		assert.HasNoError(t, err)
+		assert.Equals(t, got.Id, user.Id)
+	})
+
+	t.Run("returns an `ErrConflict` error if the email is taken", func(t *testing.T) {
+		usersBefore, err := store.GetUsers(ctx)
+		assert.HasNoError(t, err)
+
+		dto := models.NewCreateUserDTO("Someone Else", "CVAldric@gmail.com ", "password")
+		_, err = store.CreateUser(ctx, dto)
+		assert.ErrorContains(t, err, ErrConflict)
+
+		usersAfter, err := store.GetUsers(ctx)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, usersAfter, len(usersBefore))
+	})
+}
+
 func TestValidateUserCredentials(t *testing.T) {
+	ctx := context.Background()
 	dbFile := "../tmp/sqlite_store_test.db"
-	db, err := sql.Open("sqlite3", dbFile)
+	db, err := sql.Open(SqliteDriver, dbFile)
 	assert.HasNoError(t, err)
 	defer db.Close()
 	InitDb(db)
@@ -49,7 +87,7 @@ func TestValidateUserCredentials(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			assert.Equals(
 				t,
-				store.ValidateUserCredentials(test.email, test.password),
+				store.ValidateUserCredentials(ctx, test.email, test.password),
 				test.want,
 			)
 		})
@@ -57,6 +95,607 @@ func TestValidateUserCredentials(t *testing.T) {
 
 }
 
+func TestSqliteStoreUsers(t *testing.T) {
+	ctx := context.Background()
+	dbFile := "../tmp/sqlite_store_users_test.db"
+	db, err := sql.Open(SqliteDriver, dbFile)
+	assert.HasNoError(t, err)
+	defer db.Close()
+	InitDb(db)
+	defer cleanSqliteDatabase(dbFile)
+	store := NewSqliteStore(db)
+
+	t.Run("the seeded user is a verified admin", func(t *testing.T) {
+		user, err := store.GetUserByEmail(ctx, "cvaldric@gmail.com")
+		assert.HasNoError(t, err)
+		assert.Equals(t, user.Verified, true)
+		assert.Equals(t, user.Role, models.RoleAdmin)
+	})
+
+	t.Run("GetUserById returns an `ErrResourceNotFound` error if user does not exist", func(t *testing.T) {
+		_, err := store.GetUserById(ctx, -1)
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+	})
+
+	t.Run("UpdateUser updates and returns the user", func(t *testing.T) {
+		user, err := store.CreateUser(
+			ctx,
+			models.NewCreateUserDTO("John Doe", "john.doe@email.com", "password"),
+		)
+		assert.HasNoError(t, err)
+		assert.Equals(t, user.Verified, false)
+
+		assert.Equals(t, user.Timezone, models.DefaultTimezone)
+		assert.Equals(t, user.Role, models.RoleUser)
+
+		user.Name = "John"
+		user.Email = "John@Email.com"
+		user.Verified = true
+		user.Timezone = "Asia/Manila"
+		user.Locale = "en-PH"
+		user.StartOfWeek = "sunday"
+		user.Role = models.RoleAdmin
+		user.Disabled = true
+		updatedUser, err := store.UpdateUser(ctx, user)
+		assert.HasNoError(t, err)
+		wantedUser := *user
+		wantedUser.Email = "john@email.com"
+		wantedUser.Password = updatedUser.Password
+		assert.Equals(t, *updatedUser, wantedUser)
+
+		user.Email = "cvaldric@gmail.com"
+		_, err = store.UpdateUser(ctx, user)
+		assert.ErrorContains(t, err, ErrConflict)
+
+		_, err = store.UpdateUser(ctx, &models.User{Id: -1, Email: "x@email.com"})
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+	})
+
+	t.Run("UpdateUserPassword hashes and stores the new password", func(t *testing.T) {
+		user, err := store.GetUserByEmail(ctx, "cvaldric@gmail.com")
+		assert.HasNoError(t, err)
+
+		assert.HasNoError(t, store.UpdateUserPassword(ctx, user.Id, "new password"))
+		assert.Equals(t, store.ValidateUserCredentials(ctx, user.Email, "new password"), true)
+
+		err = store.UpdateUserPassword(ctx, -1, "new password")
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+	})
+}
+
+func TestSqliteStoreDeleteUserById(t *testing.T) {
+	ctx := context.Background()
+	dbFile := "../tmp/sqlite_store_delete_user_test.db"
+	db, err := sql.Open(SqliteDriver, dbFile)
+	assert.HasNoError(t, err)
+	defer db.Close()
+	InitDb(db)
+	defer cleanSqliteDatabase(dbFile)
+	store := NewSqliteStore(db)
+
+	t.Run("deletes the user and their tokens", func(t *testing.T) {
+		user, err := store.CreateUser(
+			ctx,
+			models.NewCreateUserDTO("John Doe", "john.doe@email.com", "password"),
+		)
+		assert.HasNoError(t, err)
+		purpose := models.UserTokenPurposePasswordReset
+		expiresAt := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		assert.HasNoError(t, store.CreateUserToken(
+			ctx,
+			models.NewUserToken("hash", user.Id, purpose, expiresAt),
+		))
+		_, err = store.CreateApiToken(ctx, &models.ApiToken{
+			UserId: user.Id,
+			Name:   "CI",
+			Hash:   "api-hash",
+			Scopes: []string{models.ApiTokenScopeReadTasks},
+		})
+		assert.HasNoError(t, err)
+		assert.HasNoError(t, store.SaveUserMfa(ctx, &models.UserMfa{
+			UserId:     user.Id,
+			TotpSecret: "secret",
+			Enabled:    true,
+		}))
+
+		assert.HasNoError(t, store.DeleteUserById(ctx, user.Id))
+
+		_, err = store.GetUserById(ctx, user.Id)
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+		_, err = store.ConsumeUserToken(ctx, "hash", purpose)
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+		_, err = store.GetApiTokenByHash(ctx, "api-hash")
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+		_, err = store.GetUserMfa(ctx, user.Id)
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+	})
+
+	t.Run("deletes the projects the user was the last member of", func(t *testing.T) {
+		user, err := store.CreateUser(
+			ctx,
+			models.NewCreateUserDTO("Jane Doe", "jane.doe@email.com", "password"),
+		)
+		assert.HasNoError(t, err)
+		project, err := store.CreateProject(ctx, &models.CreateProjectDTO{Name: "Home"}, user.Id)
+		assert.HasNoError(t, err)
+		task, err := store.CreateTask(ctx, models.NewCreateTaskDTO("Buy milk", project.Id))
+		assert.HasNoError(t, err)
+		assert.HasNoError(t, store.SaveProjectMember(ctx, &models.ProjectMember{
+			ProjectId: 1,
+			UserId:    user.Id,
+			Role:      models.ProjectRoleEditor,
+		}))
+		assignedDto := models.NewCreateTaskDTO("Walk the dog", 1)
+		assignedDto.AssigneeId = user.Id
+		assignedTask, err := store.CreateTask(ctx, assignedDto)
+		assert.HasNoError(t, err)
+
+		assert.HasNoError(t, store.DeleteUserById(ctx, user.Id))
+
+		assignedTask, err = store.GetTaskById(ctx, assignedTask.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, assignedTask.AssigneeId, 0)
+
+		_, err = store.GetProjectById(ctx, project.Id)
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+		tasks, err := store.GetTasksByProjectId(ctx, project.Id)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, tasks, 0)
+		_, err = store.GetTaskById(ctx, task.Id)
+		assert.HasError(t, err)
+		_, err = store.GetProjectById(ctx, 1)
+		assert.HasNoError(t, err)
+		_, err = store.GetProjectMember(ctx, 1, user.Id)
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+	})
+
+	t.Run("returns an `ErrResourceNotFound` error if user does not exist", func(t *testing.T) {
+		err := store.DeleteUserById(ctx, -1)
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+	})
+}
+
+func TestSqliteStoreUserTokens(t *testing.T) {
+	ctx := context.Background()
+	dbFile := "../tmp/sqlite_store_user_tokens_test.db"
+	db, err := sql.Open(SqliteDriver, dbFile)
+	assert.HasNoError(t, err)
+	defer db.Close()
+	InitDb(db)
+	defer cleanSqliteDatabase(dbFile)
+	store := NewSqliteStore(db)
+
+	expiresAt := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	purpose := models.UserTokenPurposePasswordReset
+	otherPurpose := models.UserTokenPurposeEmailVerification
+
+	t.Run("ConsumeUserToken returns the token only once", func(t *testing.T) {
+		token := models.NewUserToken("hash", 1, purpose, expiresAt)
+		assert.HasNoError(t, store.CreateUserToken(ctx, token))
+
+		_, err := store.ConsumeUserToken(ctx, token.Hash, otherPurpose)
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+
+		got, err := store.ConsumeUserToken(ctx, token.Hash, token.Purpose)
+		assert.HasNoError(t, err)
+		assert.Equals(t, got.UserId, token.UserId)
+		assert.Equals(t, got.ExpiresAt.Equal(token.ExpiresAt), true)
+
+		_, err = store.ConsumeUserToken(ctx, token.Hash, token.Purpose)
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+	})
+
+	t.Run("DeleteUserTokens only deletes the tokens of the user for the purpose", func(t *testing.T) {
+		assert.HasNoError(t, store.CreateUserToken(
+			ctx,
+			models.NewUserToken("a", 1, purpose, expiresAt),
+		))
+		assert.HasNoError(t, store.CreateUserToken(
+			ctx,
+			models.NewUserToken("b", 1, otherPurpose, expiresAt),
+		))
+		assert.HasNoError(t, store.CreateUserToken(
+			ctx,
+			models.NewUserToken("c", 2, purpose, expiresAt),
+		))
+
+		assert.HasNoError(t, store.DeleteUserTokens(ctx, 1, purpose))
+
+		_, err = store.ConsumeUserToken(ctx, "a", purpose)
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+		_, err = store.ConsumeUserToken(ctx, "b", otherPurpose)
+		assert.HasNoError(t, err)
+		_, err = store.ConsumeUserToken(ctx, "c", purpose)
+		assert.HasNoError(t, err)
+	})
+}
+
+func TestSqliteStoreApiTokens(t *testing.T) {
+	ctx := context.Background()
+	dbFile := "../tmp/sqlite_store_api_tokens_test.db"
+	db, err := sql.Open(SqliteDriver, dbFile)
+	assert.HasNoError(t, err)
+	defer db.Close()
+	InitDb(db)
+	defer cleanSqliteDatabase(dbFile)
+	store := NewSqliteStore(db)
+
+	expiresAt := time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)
+	token := &models.ApiToken{
+		UserId:    1,
+		Name:      "CI",
+		Hash:      "hash",
+		Scopes:    []string{models.ApiTokenScopeReadTasks, models.ApiTokenScopeWriteTasks},
+		ExpiresAt: &expiresAt,
+		CreatedAt: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("CreateApiToken stores the token and GetApiTokenByHash finds it", func(t *testing.T) {
+		createdToken, err := store.CreateApiToken(ctx, token)
+		assert.HasNoError(t, err)
+		assert.DoesNotEqual(t, createdToken.Id, 0)
+		token.Id = createdToken.Id
+
+		got, err := store.GetApiTokenByHash(ctx, "hash")
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, *token)
+
+		_, err = store.GetApiTokenByHash(ctx, "unknown")
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+	})
+
+	t.Run("UpdateApiTokenLastUsedAt records the last use", func(t *testing.T) {
+		lastUsedAt := time.Date(2024, time.January, 2, 0, 0, 0, 0, time.UTC)
+		assert.HasNoError(t, store.UpdateApiTokenLastUsedAt(ctx, token.Id, lastUsedAt))
+
+		got, err := store.GetApiTokenByHash(ctx, "hash")
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got.LastUsedAt, lastUsedAt)
+
+		err = store.UpdateApiTokenLastUsedAt(ctx, -1, lastUsedAt)
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+	})
+
+	t.Run("GetApiTokensByUserId only returns the user's tokens", func(t *testing.T) {
+		_, err := store.CreateApiToken(ctx, &models.ApiToken{
+			UserId: 2,
+			Name:   "Other",
+			Hash:   "other-hash",
+			Scopes: []string{models.ApiTokenScopeReadTasks},
+		})
+		assert.HasNoError(t, err)
+
+		tokens, err := store.GetApiTokensByUserId(ctx, 1)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, tokens, 1)
+		assert.Equals(t, tokens[0].Hash, "hash")
+		assert.Equals(t, tokens[0].ExpiresAt.Equal(expiresAt), true)
+	})
+
+	t.Run("DeleteApiToken only deletes the user's own token", func(t *testing.T) {
+		err := store.DeleteApiToken(ctx, token.Id, 2)
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+
+		assert.HasNoError(t, store.DeleteApiToken(ctx, token.Id, 1))
+		_, err = store.GetApiTokenByHash(ctx, "hash")
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+	})
+}
+
+func TestSqliteStoreLoginAttempts(t *testing.T) {
+	ctx := context.Background()
+	dbFile := "../tmp/sqlite_store_login_attempts_test.db"
+	db, err := sql.Open(SqliteDriver, dbFile)
+	assert.HasNoError(t, err)
+	defer db.Close()
+	InitDb(db)
+	defer cleanSqliteDatabase(dbFile)
+	store := NewSqliteStore(db)
+
+	key := "email:john.doe@email.com"
+
+	t.Run("GetLoginAttempt returns an `ErrResourceNotFound` error if there is none", func(t *testing.T) {
+		_, err := store.GetLoginAttempt(ctx, key)
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+	})
+
+	t.Run("SaveLoginAttempt creates, updates and DeleteLoginAttempt removes", func(t *testing.T) {
+		now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		attempt := models.LoginAttempt{Key: key, Failures: 1, LastFailureAt: now}
+		assert.HasNoError(t, store.SaveLoginAttempt(ctx, &attempt))
+		attempt.Failures = 2
+		attempt.LockedUntil = now.Add(time.Minute)
+		assert.HasNoError(t, store.SaveLoginAttempt(ctx, &attempt))
+
+		got, err := store.GetLoginAttempt(ctx, key)
+		assert.HasNoError(t, err)
+		assert.Equals(t, got.Failures, attempt.Failures)
+		assert.Equals(t, got.LastFailureAt.Equal(attempt.LastFailureAt), true)
+		assert.Equals(t, got.LockedUntil.Equal(attempt.LockedUntil), true)
+
+		assert.HasNoError(t, store.DeleteLoginAttempt(ctx, key))
+		_, err = store.GetLoginAttempt(ctx, key)
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+	})
+}
+
+func TestSqliteStoreUserMfa(t *testing.T) {
+	ctx := context.Background()
+	dbFile := "../tmp/sqlite_store_user_mfa_test.db"
+	db, err := sql.Open(SqliteDriver, dbFile)
+	assert.HasNoError(t, err)
+	defer db.Close()
+	InitDb(db)
+	defer cleanSqliteDatabase(dbFile)
+	store := NewSqliteStore(db)
+
+	t.Run("GetUserMfa returns an `ErrResourceNotFound` error if there is none", func(t *testing.T) {
+		_, err := store.GetUserMfa(ctx, 1)
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+	})
+
+	t.Run("SaveUserMfa creates, updates and DeleteUserMfa removes", func(t *testing.T) {
+		mfa := models.UserMfa{UserId: 1, TotpSecret: "secret"}
+		assert.HasNoError(t, store.SaveUserMfa(ctx, &mfa))
+		mfa.Enabled = true
+		mfa.LastUsedStep = 42
+		assert.HasNoError(t, store.SaveUserMfa(ctx, &mfa))
+
+		got, err := store.GetUserMfa(ctx, mfa.UserId)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, mfa)
+
+		assert.HasNoError(t, store.DeleteUserMfa(ctx, mfa.UserId))
+		assert.HasNoError(t, store.DeleteUserMfa(ctx, mfa.UserId))
+		_, err = store.GetUserMfa(ctx, mfa.UserId)
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+	})
+}
+
+func TestSqliteStoreProjects(t *testing.T) {
+	ctx := context.Background()
+	dbFile := "../tmp/sqlite_store_projects_test.db"
+	db, err := sql.Open(SqliteDriver, dbFile)
+	assert.HasNoError(t, err)
+	defer db.Close()
+	InitDb(db)
+	defer cleanSqliteDatabase(dbFile)
+	store := NewSqliteStore(db)
+
+	t.Run("moves the existing tasks to a project owned by the first user", func(t *testing.T) {
+		member, err := store.GetProjectMember(ctx, 1, 1)
+		assert.HasNoError(t, err)
+		assert.Equals(t, member.Role, models.ProjectRoleOwner)
+		tasks, err := store.GetTasksByProjectId(ctx, 1)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, tasks, 1)
+	})
+
+	t.Run("CreateProject adds the owner as a member", func(t *testing.T) {
+		project, err := store.CreateProject(ctx, &models.CreateProjectDTO{Name: "Home"}, 1)
+		assert.HasNoError(t, err)
+		got, err := store.GetProjectById(ctx, project.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, *project)
+
+		members, err := store.GetProjectMembers(ctx, project.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, members, []models.ProjectMember{
+			{ProjectId: project.Id, UserId: 1, Role: models.ProjectRoleOwner},
+		})
+	})
+
+	t.Run("GetProjectById returns an `ErrResourceNotFound` error if project does not exist", func(t *testing.T) {
+		_, err := store.GetProjectById(ctx, -1)
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+	})
+
+	t.Run("invitations can be accepted once", func(t *testing.T) {
+		user, err := store.CreateUser(
+			ctx,
+			models.NewCreateUserDTO("Jane Doe", "jane.doe@email.com", "password"),
+		)
+		assert.HasNoError(t, err)
+		invitation, err := store.CreateProjectInvitation(ctx, &models.ProjectInvitation{
+			ProjectId: 1,
+			Email:     " Jane.Doe@email.com",
+			Role:      models.ProjectRoleEditor,
+			InvitedBy: 1,
+		})
+		assert.HasNoError(t, err)
+		assert.Equals(t, invitation.Email, user.Email)
+
+		_, err = store.CreateProjectInvitation(ctx, &models.ProjectInvitation{
+			ProjectId: 1,
+			Email:     user.Email,
+			Role:      models.ProjectRoleViewer,
+			InvitedBy: 1,
+		})
+		assert.ErrorContains(t, err, ErrConflict)
+
+		invitations, err := store.GetProjectInvitationsByEmail(ctx, user.Email)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, invitations, 1)
+
+		member, err := store.AcceptProjectInvitation(ctx, invitation.Id, user.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *member, models.ProjectMember{
+			ProjectId: 1,
+			UserId:    user.Id,
+			Role:      models.ProjectRoleEditor,
+		})
+		_, err = store.AcceptProjectInvitation(ctx, invitation.Id, user.Id)
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+	})
+
+	t.Run("accepting an invitation keeps the role of an existing member", func(t *testing.T) {
+		invitation, err := store.CreateProjectInvitation(ctx, &models.ProjectInvitation{
+			ProjectId: 1,
+			Email:     "cvaldric@gmail.com",
+			Role:      models.ProjectRoleViewer,
+			InvitedBy: 1,
+		})
+		assert.HasNoError(t, err)
+
+		member, err := store.AcceptProjectInvitation(ctx, invitation.Id, 1)
+		assert.HasNoError(t, err)
+		assert.Equals(t, member.Role, models.ProjectRoleOwner)
+	})
+
+	t.Run("SaveProjectMember updates and DeleteProjectMember removes", func(t *testing.T) {
+		member := models.ProjectMember{ProjectId: 1, UserId: 2, Role: models.ProjectRoleViewer}
+		assert.HasNoError(t, store.SaveProjectMember(ctx, &member))
+		got, err := store.GetProjectMember(ctx, 1, 2)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, member)
+
+		memberships, err := store.GetProjectMembersByUserId(ctx, 2)
+		assert.HasNoError(t, err)
+		assert.Equals(t, memberships, []models.ProjectMember{member})
+
+		assert.HasNoError(t, store.DeleteProjectMember(ctx, 1, 2))
+		err = store.DeleteProjectMember(ctx, 1, 2)
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+	})
+
+	t.Run("DeleteProjectMember unassigns the tasks of the member", func(t *testing.T) {
+		user, err := store.CreateUser(
+			ctx,
+			models.NewCreateUserDTO("Bob Doe", "bob.doe@email.com", "password"),
+		)
+		assert.HasNoError(t, err)
+		assert.HasNoError(t, store.SaveProjectMember(ctx, &models.ProjectMember{
+			ProjectId: 1,
+			UserId:    user.Id,
+			Role:      models.ProjectRoleEditor,
+		}))
+		dto := models.NewCreateTaskDTO("Buy milk", 1)
+		dto.AssigneeId = user.Id
+		task, err := store.CreateTask(ctx, dto)
+		assert.HasNoError(t, err)
+		got, err := store.GetTaskById(ctx, task.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, *task)
+
+		assert.HasNoError(t, store.DeleteProjectMember(ctx, 1, user.Id))
+
+		got, err = store.GetTaskById(ctx, task.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, got.AssigneeId, 0)
+	})
+
+	t.Run("DeleteProjectInvitation returns an `ErrResourceNotFound` error if invitation does not exist", func(t *testing.T) {
+		err := store.DeleteProjectInvitation(ctx, -1)
+		assert.ErrorContains(t, err, ErrResourceNotFound)
+	})
+}
+
+func TestSqliteStoreCancelledContext(t *testing.T) {
+	dbFile := "../tmp/sqlite_store_cancelled_context_test.db"
+	db, err := sql.Open(SqliteDriver, dbFile)
+	assert.HasNoError(t, err)
+	defer db.Close()
+	InitDb(db)
+	defer cleanSqliteDatabase(dbFile)
+	store := NewSqliteStore(db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t.Run("queries return the context error", func(t *testing.T) {
+		_, err := store.GetTasks(ctx)
+		assert.ErrorContains(t, err, context.Canceled)
+	})
+
+	t.Run("transactions are not started", func(t *testing.T) {
+		_, err := store.CreateProject(ctx, &models.CreateProjectDTO{Name: "Home"}, 1)
+		assert.ErrorContains(t, err, context.Canceled)
+
+		members, err := store.GetProjectMembersByUserId(context.Background(), 1)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, members, 1)
+	})
+}
+
+func TestSqliteStoreAuditEvents(t *testing.T) {
+	ctx := context.Background()
+	dbFile := "../tmp/sqlite_store_audit_events_test.db"
+	db, err := sql.Open(SqliteDriver, dbFile)
+	assert.HasNoError(t, err)
+	defer db.Close()
+	InitDb(db)
+	defer cleanSqliteDatabase(dbFile)
+	store := NewSqliteStore(db)
+
+	t.Run("CreateAuditEvent stores the event with an ID and a timestamp", func(t *testing.T) {
+		event, err := store.CreateAuditEvent(ctx, models.NewAuditEvent(
+			"login.locked",
+			0,
+			"email:john.doe@email.com",
+			"locked",
+		))
+		assert.HasNoError(t, err)
+		assert.Equals(t, event.Id, 1)
+
+		events, err := store.GetAuditEvents(ctx)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, events, 1)
+		assert.Equals(t, events[0].Action, event.Action)
+		assert.Equals(t, events[0].Subject, event.Subject)
+		assert.Equals(t, events[0].CreatedAt.Equal(event.CreatedAt), true)
+	})
+}
+
 func cleanSqliteDatabase(path string) {
 	os.Remove(path)
 }
+
+func TestInitDbNormalizesEmails(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open(SqliteDriver, filepath.Join(t.TempDir(), "data.db"))
+	assert.HasNoError(t, err)
+	defer db.Close()
+	createUsersTable(db)
+	_, err = db.Exec(`
+		insert into users (name, email, password)
+		values
+			('Alice', ' Alice@Email.com', ''),
+			('Bob', 'Bob@Email.com', ''),
+			('Robert', 'bob@email.com ', ''),
+			('Carol', 'Carol@Email.com', ''),
+			('Caroline', 'carol@email.com', '')
+	`)
+	assert.HasNoError(t, err)
+
+	InitDb(db)
+
+	rows, err := db.Query(`select email from users order by id`)
+	assert.HasNoError(t, err)
+	defer rows.Close()
+	var emails []string
+	for rows.Next() {
+		var email string
+		assert.HasNoError(t, rows.Scan(&email))
+		emails = append(emails, email)
+	}
+	assert.HasNoError(t, rows.Err())
+	// Of the accounts sharing an email, the one already holding it or else
+	// the oldest gets it, and the others are left to be merged by hand.
+	assert.Equals(t, emails, []string{
+		"alice@email.com",
+		"bob@email.com",
+		"bob@email.com ",
+		"Carol@Email.com",
+		"carol@email.com",
+	})
+
+	store := NewSqliteStore(db)
+	user, err := store.GetUserByEmail(ctx, "Bob@Email.com")
+	assert.HasNoError(t, err)
+	assert.Equals(t, user.Name, "Bob")
+	_, err = store.CreateUser(
+		ctx,
+		models.NewCreateUserDTO("Bobby", "bob@email.com", "password"),
+	)
+	assert.ErrorContains(t, err, ErrConflict)
+}
@@ -1,22 +1,200 @@
 package data
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"github.com/claudealdric/go-todolist-restful-api-server/models"
 )
 
+var ErrConflict = errors.New("resource conflict")
 var ErrResourceNotFound = errors.New("resource not found")
+var ErrTaskHistoryNotRecorded = errors.New("task history is not recorded")
+var ErrTaskRevisionsNotRecorded = errors.New("task revisions are not recorded")
 
 type Store interface {
-	CreateTask(dto *models.CreateTaskDTO) (*models.Task, error)
-	DeleteTaskById(id int) error
-	GetTaskById(id int) (*models.Task, error)
-	GetTasks() ([]models.Task, error)
-	UpdateTask(task *models.Task) (*models.Task, error)
-
-	CreateUser(dto *models.CreateUserDTO) (*models.User, error)
-	GetUserByEmail(email string) (*models.User, error)
-	GetUsers() ([]models.User, error)
-	ValidateUserCredentials(email, password string) bool
+	CreateTask(ctx context.Context, dto *models.CreateTaskDTO) (*models.Task, error)
+	DeleteTaskById(ctx context.Context, id int) error
+	GetTaskById(ctx context.Context, id int) (*models.Task, error)
+	GetTasks(ctx context.Context) ([]models.Task, error)
+	GetTasksByProjectId(ctx context.Context, projectId int) ([]models.Task, error)
+	UpdateTask(ctx context.Context, task *models.Task) (*models.Task, error)
+
+	CreateProject(
+		ctx context.Context,
+		dto *models.CreateProjectDTO,
+		ownerId int,
+	) (*models.Project, error)
+	GetProjectById(ctx context.Context, id int) (*models.Project, error)
+
+	DeleteProjectMember(ctx context.Context, projectId, userId int) error
+	GetProjectMember(
+		ctx context.Context,
+		projectId, userId int,
+	) (*models.ProjectMember, error)
+	GetProjectMembers(
+		ctx context.Context,
+		projectId int,
+	) ([]models.ProjectMember, error)
+	GetProjectMembersByUserId(
+		ctx context.Context,
+		userId int,
+	) ([]models.ProjectMember, error)
+	SaveProjectMember(ctx context.Context, member *models.ProjectMember) error
+
+	AcceptProjectInvitation(
+		ctx context.Context,
+		id, userId int,
+	) (*models.ProjectMember, error)
+	CreateProjectInvitation(
+		ctx context.Context,
+		invitation *models.ProjectInvitation,
+	) (*models.ProjectInvitation, error)
+	DeleteProjectInvitation(ctx context.Context, id int) error
+	GetProjectInvitationById(
+		ctx context.Context,
+		id int,
+	) (*models.ProjectInvitation, error)
+	GetProjectInvitationsByEmail(
+		ctx context.Context,
+		email string,
+	) ([]models.ProjectInvitation, error)
+
+	CreateUser(ctx context.Context, dto *models.CreateUserDTO) (*models.User, error)
+	DeleteUserById(ctx context.Context, id int) error
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetUserById(ctx context.Context, id int) (*models.User, error)
+	GetUsers(ctx context.Context) ([]models.User, error)
+	UpdateUser(ctx context.Context, user *models.User) (*models.User, error)
+	// UpdateUserPassword also records when the password changed, so that
+	// older access tokens can be turned down, and deletes the user's API
+	// tokens.
+	UpdateUserPassword(ctx context.Context, id int, password string) error
+	ValidateUserCredentials(ctx context.Context, email, password string) bool
+
+	CreateApiToken(
+		ctx context.Context,
+		token *models.ApiToken,
+	) (*models.ApiToken, error)
+	DeleteApiToken(ctx context.Context, id, userId int) error
+	GetApiTokenByHash(ctx context.Context, hash string) (*models.ApiToken, error)
+	GetApiTokensByUserId(ctx context.Context, userId int) ([]models.ApiToken, error)
+	UpdateApiTokenLastUsedAt(ctx context.Context, id int, lastUsedAt time.Time) error
+
+	ConsumeUserToken(
+		ctx context.Context,
+		hash, purpose string,
+	) (*models.UserToken, error)
+	CreateUserToken(ctx context.Context, token *models.UserToken) error
+	DeleteUserTokens(ctx context.Context, userId int, purpose string) error
+
+	DeleteUserMfa(ctx context.Context, userId int) error
+	GetUserMfa(ctx context.Context, userId int) (*models.UserMfa, error)
+	SaveUserMfa(ctx context.Context, mfa *models.UserMfa) error
+	// UseUserMfaStep records step as the user's last used TOTP time step in
+	// one write and returns false, changing nothing, if the recorded step is
+	// already at or after it, so that a code cannot be accepted twice.
+	UseUserMfaStep(ctx context.Context, userId int, step int64) (bool, error)
+
+	DeleteLoginAttempt(ctx context.Context, key string) error
+	GetLoginAttempt(ctx context.Context, key string) (*models.LoginAttempt, error)
+	SaveLoginAttempt(ctx context.Context, attempt *models.LoginAttempt) error
+	// IncrementLoginFailures adds a failure to the login attempt, creating
+	// it if needed, in one step so that concurrent failures are all counted.
+	// The count starts over when the last failure is older than expireAfter.
+	IncrementLoginFailures(
+		ctx context.Context,
+		key string,
+		failedAt time.Time,
+		expireAfter time.Duration,
+	) (*models.LoginAttempt, error)
+	// LockLoginAttempt sets when the login attempt stops being locked, unless
+	// it has failed again since it had the given failures, so that the
+	// lockout of the latest failure wins.
+	LockLoginAttempt(
+		ctx context.Context,
+		key string,
+		failures int,
+		lockedUntil time.Time,
+	) error
+
+	CreateAuditEvent(
+		ctx context.Context,
+		event *models.AuditEvent,
+	) (*models.AuditEvent, error)
+	GetAuditEvents(ctx context.Context) ([]models.AuditEvent, error)
+}
+
+// Importer writes records with the IDs and password hashes they already have,
+// replacing the records with the same IDs, so that data can be copied from
+// one store to another. IDs created afterwards follow the imported ones.
+type Importer interface {
+	ImportApiToken(ctx context.Context, token *models.ApiToken) error
+	ImportAuditEvent(ctx context.Context, event *models.AuditEvent) error
+	ImportProject(ctx context.Context, project *models.Project) error
+	ImportProjectInvitation(
+		ctx context.Context,
+		invitation *models.ProjectInvitation,
+	) error
+	ImportTask(ctx context.Context, task *models.Task) error
+	// ImportTaskRevision returns ErrTaskRevisionsNotRecorded from stores
+	// that do not keep the revisions.
+	ImportTaskRevision(ctx context.Context, revision *models.TaskRevision) error
+	ImportUser(ctx context.Context, user *models.User) error
+}
+
+// Exporter lists the records that the Store methods only look up by their
+// owner or their secret, so that all of them can be copied to another store.
+type Exporter interface {
+	GetProjectInvitations(ctx context.Context) ([]models.ProjectInvitation, error)
+	GetUserTokens(ctx context.Context) ([]models.UserToken, error)
+}
+
+// TaskHistorian is a store that records every change made to the tasks.
+type TaskHistorian interface {
+	// GetTaskHistory returns the changes made to the task, deleted or not,
+	// from the oldest.
+	GetTaskHistory(ctx context.Context, id int) ([]models.TaskEvent, error)
+}
+
+// TaskReviser is a store that keeps the versions of the tasks that UpdateTask
+// replaced, crediting them to the editor of the context.
+type TaskReviser interface {
+	// GetTaskRevisions returns the revisions of the task from the oldest.
+	GetTaskRevisions(ctx context.Context, taskId int) ([]models.TaskRevision, error)
+	GetTaskRevision(
+		ctx context.Context,
+		taskId, revision int,
+	) (*models.TaskRevision, error)
+}
+
+type editorContextKey struct{}
+
+// WithEditor credits the task updates made with the returned context to the
+// user.
+func WithEditor(ctx context.Context, userId int) context.Context {
+	return context.WithValue(ctx, editorContextKey{}, userId)
+}
+
+// editorFromContext returns the user set by WithEditor, or 0 when the update
+// was not made by a user.
+func editorFromContext(ctx context.Context) int {
+	userId, _ := ctx.Value(editorContextKey{}).(int)
+	return userId
+}
+
+type readerContextKey struct{}
+
+// WithReader tells stores that cache reads whose reads are made with the
+// returned context, so that each user gets their own entries.
+func WithReader(ctx context.Context, userId int) context.Context {
+	return context.WithValue(ctx, readerContextKey{}, userId)
+}
+
+// readerFromContext returns the user set by WithReader, or 0 when the read
+// was not made for a user.
+func readerFromContext(ctx context.Context) int {
+	userId, _ := ctx.Value(readerContextKey{}).(int)
+	return userId
 }
@@ -0,0 +1,1708 @@
+// Package storetest checks that a data.Store implementation behaves exactly
+// like the others, so that the API can run on any of them.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// NewStore returns an empty store for a single test. IDs the store hands out
+// may start anywhere, as long as they keep increasing.
+type NewStore func(t *testing.T) data.Store
+
+// Run runs every conformance test against the stores newStore returns.
+func Run(t *testing.T, newStore NewStore) {
+	t.Run("tasks", func(t *testing.T) { testTasks(t, newStore) })
+	t.Run("users", func(t *testing.T) { testUsers(t, newStore) })
+	t.Run("projects", func(t *testing.T) { testProjects(t, newStore) })
+	t.Run("project members", func(t *testing.T) { testProjectMembers(t, newStore) })
+	t.Run("project invitations", func(t *testing.T) {
+		testProjectInvitations(t, newStore)
+	})
+	t.Run("API tokens", func(t *testing.T) { testApiTokens(t, newStore) })
+	t.Run("user tokens", func(t *testing.T) { testUserTokens(t, newStore) })
+	t.Run("user MFA", func(t *testing.T) { testUserMfa(t, newStore) })
+	t.Run("login attempts", func(t *testing.T) { testLoginAttempts(t, newStore) })
+	t.Run("audit events", func(t *testing.T) { testAuditEvents(t, newStore) })
+	t.Run("imports", func(t *testing.T) { testImports(t, newStore) })
+	t.Run("exports", func(t *testing.T) { testExports(t, newStore) })
+	t.Run("task revisions", func(t *testing.T) { testTaskRevisions(t, newStore) })
+	t.Run("cancelled context", func(t *testing.T) {
+		testCancelledContext(t, newStore)
+	})
+}
+
+func testTasks(t *testing.T, newStore NewStore) {
+	ctx := context.Background()
+
+	t.Run("CreateTask stores and returns the task", func(t *testing.T) {
+		store := newStore(t)
+
+		dto := models.NewCreateTaskDTO("Buy milk", 1)
+		dto.AssigneeId = 2
+		task, err := store.CreateTask(ctx, dto)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *task, models.Task{
+			Id:         task.Id,
+			Title:      "Buy milk",
+			ProjectId:  1,
+			AssigneeId: 2,
+		})
+
+		got, err := store.GetTaskById(ctx, task.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, *task)
+	})
+
+	t.Run("GetTaskById returns an `ErrResourceNotFound` error if the task does not exist", func(t *testing.T) {
+		store := newStore(t)
+
+		_, err := store.GetTaskById(ctx, 1)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("GetTasks returns every task ordered by ID", func(t *testing.T) {
+		store := newStore(t)
+
+		tasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, tasks, 0)
+
+		first := createTask(t, store, "Buy milk", 1)
+		second := createTask(t, store, "Walk the dog", 2)
+		third := createTask(t, store, "Cook dinner", 1)
+
+		tasks, err = store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, tasks, []models.Task{first, second, third})
+	})
+
+	t.Run("GetTasksByProjectId only returns the tasks of the project ordered by ID", func(t *testing.T) {
+		store := newStore(t)
+		first := createTask(t, store, "Buy milk", 1)
+		createTask(t, store, "Walk the dog", 2)
+		third := createTask(t, store, "Cook dinner", 1)
+
+		tasks, err := store.GetTasksByProjectId(ctx, 1)
+		assert.HasNoError(t, err)
+		assert.Equals(t, tasks, []models.Task{first, third})
+
+		tasks, err = store.GetTasksByProjectId(ctx, 3)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, tasks, 0)
+	})
+
+	t.Run("UpdateTask updates the title and assignee but not the project", func(t *testing.T) {
+		store := newStore(t)
+		task := createTask(t, store, "Buy milk", 1)
+
+		updated, err := store.UpdateTask(ctx, &models.Task{
+			Id:         task.Id,
+			Title:      "Buy oat milk",
+			ProjectId:  2,
+			AssigneeId: 3,
+		})
+		assert.HasNoError(t, err)
+		want := models.Task{
+			Id:         task.Id,
+			Title:      "Buy oat milk",
+			ProjectId:  1,
+			AssigneeId: 3,
+		}
+		assert.Equals(t, *updated, want)
+
+		got, err := store.GetTaskById(ctx, task.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, want)
+	})
+
+	t.Run("UpdateTask returns an `ErrResourceNotFound` error if the task does not exist", func(t *testing.T) {
+		store := newStore(t)
+
+		_, err := store.UpdateTask(ctx, models.NewTask(1, "Buy milk", 1))
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+
+		tasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, tasks, 0)
+	})
+
+	t.Run("DeleteTaskById deletes the task", func(t *testing.T) {
+		store := newStore(t)
+		task := createTask(t, store, "Buy milk", 1)
+		other := createTask(t, store, "Walk the dog", 1)
+
+		assert.HasNoError(t, store.DeleteTaskById(ctx, task.Id))
+
+		_, err := store.GetTaskById(ctx, task.Id)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+		tasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, tasks, []models.Task{other})
+	})
+
+	t.Run("DeleteTaskById returns an `ErrResourceNotFound` error if the task does not exist", func(t *testing.T) {
+		store := newStore(t)
+		task := createTask(t, store, "Buy milk", 1)
+		assert.HasNoError(t, store.DeleteTaskById(ctx, task.Id))
+
+		err := store.DeleteTaskById(ctx, task.Id)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("does not reuse the ID of a deleted task", func(t *testing.T) {
+		store := newStore(t)
+		createTask(t, store, "Buy milk", 1)
+		deleted := createTask(t, store, "Walk the dog", 1)
+		assert.HasNoError(t, store.DeleteTaskById(ctx, deleted.Id))
+
+		task := createTask(t, store, "Cook dinner", 1)
+		if task.Id <= deleted.Id {
+			t.Errorf("got ID %d, want an ID above %d", task.Id, deleted.Id)
+		}
+	})
+}
+
+func testUsers(t *testing.T, newStore NewStore) {
+	ctx := context.Background()
+
+	t.Run("CreateUser stores the user with a hashed password", func(t *testing.T) {
+		store := newStore(t)
+
+		user, err := store.CreateUser(
+			ctx,
+			models.NewCreateUserDTO("Claude Aldric", "claude@email.com", "password"),
+		)
+		assert.HasNoError(t, err)
+		assert.Equals(t, user.Name, "Claude Aldric")
+		assert.Equals(t, user.Email, "claude@email.com")
+		assert.Equals(t, user.Verified, false)
+		assert.Equals(t, user.Role, models.RoleUser)
+		assert.Equals(t, user.Timezone, models.DefaultTimezone)
+		assert.Equals(t, user.Locale, models.DefaultLocale)
+		assert.Equals(t, user.StartOfWeek, models.DefaultStartOfWeek)
+		err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("password"))
+		assert.HasNoError(t, err)
+
+		got, err := store.GetUserById(ctx, user.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, *user)
+	})
+
+	t.Run("CreateUser normalizes the email", func(t *testing.T) {
+		store := newStore(t)
+
+		user := createUser(t, store, " Claude@Email.com ")
+		assert.Equals(t, user.Email, "claude@email.com")
+	})
+
+	t.Run("CreateUser returns an `ErrConflict` error if the email is taken", func(t *testing.T) {
+		store := newStore(t)
+		createUser(t, store, "claude@email.com")
+
+		_, err := store.CreateUser(
+			ctx,
+			models.NewCreateUserDTO("Someone Else", "CLAUDE@email.com", "password"),
+		)
+		assert.ErrorContains(t, err, data.ErrConflict)
+
+		users, err := store.GetUsers(ctx)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, users, 1)
+	})
+
+	t.Run("GetUserByEmail ignores case and surrounding whitespace", func(t *testing.T) {
+		store := newStore(t)
+		user := createUser(t, store, "claude@email.com")
+
+		got, err := store.GetUserByEmail(ctx, " CLAUDE@Email.com ")
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, user)
+	})
+
+	t.Run("GetUserByEmail and GetUserById return an `ErrResourceNotFound` error if the user does not exist", func(t *testing.T) {
+		store := newStore(t)
+
+		_, err := store.GetUserByEmail(ctx, "claude@email.com")
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+		_, err = store.GetUserById(ctx, 1)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("GetUsers returns every user ordered by ID", func(t *testing.T) {
+		store := newStore(t)
+
+		users, err := store.GetUsers(ctx)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, users, 0)
+
+		first := createUser(t, store, "claude@email.com")
+		second := createUser(t, store, "john@email.com")
+
+		users, err = store.GetUsers(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, users, []models.User{first, second})
+	})
+
+	t.Run("UpdateUser updates everything but the password", func(t *testing.T) {
+		store := newStore(t)
+		user := createUser(t, store, "claude@email.com")
+
+		changes := user
+		changes.Name = "Claude"
+		changes.Email = " Claude.Aldric@Email.com "
+		changes.Password = "new password"
+		changes.Verified = true
+		changes.Timezone = "Europe/Paris"
+		changes.Locale = "fr-FR"
+		changes.StartOfWeek = "sunday"
+		changes.Role = models.RoleAdmin
+		changes.Disabled = true
+		updated, err := store.UpdateUser(ctx, &changes)
+		assert.HasNoError(t, err)
+
+		want := changes
+		want.Email = "claude.aldric@email.com"
+		want.Password = user.Password
+		assert.Equals(t, *updated, want)
+		got, err := store.GetUserById(ctx, user.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, want)
+	})
+
+	t.Run("UpdateUser returns an `ErrConflict` error if the email is taken", func(t *testing.T) {
+		store := newStore(t)
+		user := createUser(t, store, "claude@email.com")
+		createUser(t, store, "john@email.com")
+
+		changes := user
+		changes.Email = "John@email.com"
+		_, err := store.UpdateUser(ctx, &changes)
+		assert.ErrorContains(t, err, data.ErrConflict)
+
+		got, err := store.GetUserById(ctx, user.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, user)
+	})
+
+	t.Run("UpdateUser returns an `ErrResourceNotFound` error if the user does not exist", func(t *testing.T) {
+		store := newStore(t)
+
+		_, err := store.UpdateUser(
+			ctx,
+			models.NewUser(1, "Claude Aldric", "claude@email.com", "password"),
+		)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("UpdateUserPassword replaces the password", func(t *testing.T) {
+		store := newStore(t)
+		user := createUser(t, store, "claude@email.com")
+
+		err := store.UpdateUserPassword(ctx, user.Id, "new password")
+		assert.HasNoError(t, err)
+
+		assert.Equals(
+			t,
+			store.ValidateUserCredentials(ctx, user.Email, "password"),
+			false,
+		)
+		assert.Equals(
+			t,
+			store.ValidateUserCredentials(ctx, user.Email, "new password"),
+			true,
+		)
+	})
+
+	t.Run("UpdateUserPassword records when and deletes the API tokens of the user", func(t *testing.T) {
+		store := newStore(t)
+		user := createUser(t, store, "claude@email.com")
+		otherUser := createUser(t, store, "harry@hogwarts.edu")
+		for i, userId := range []int{user.Id, otherUser.Id} {
+			_, err := store.CreateApiToken(ctx, &models.ApiToken{
+				UserId:    userId,
+				Name:      "CLI",
+				Hash:      fmt.Sprintf("hash-%d", i),
+				CreatedAt: time.Now().UTC(),
+			})
+			assert.HasNoError(t, err)
+		}
+		before := time.Now().Truncate(time.Second)
+
+		err := store.UpdateUserPassword(ctx, user.Id, "new password")
+		assert.HasNoError(t, err)
+
+		got, err := store.GetUserById(ctx, user.Id)
+		assert.HasNoError(t, err)
+		if got.PasswordChangedAt == nil || got.PasswordChangedAt.Before(before) {
+			t.Errorf("got the password changed at %v, want %v or later", got.PasswordChangedAt, before)
+		}
+		tokens, err := store.GetApiTokensByUserId(ctx, user.Id)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, tokens, 0)
+		tokens, err = store.GetApiTokensByUserId(ctx, otherUser.Id)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, tokens, 1)
+	})
+
+	t.Run("UpdateUser keeps when the password changed", func(t *testing.T) {
+		store := newStore(t)
+		user := createUser(t, store, "claude@email.com")
+		assert.HasNoError(t, store.UpdateUserPassword(ctx, user.Id, "new password"))
+		changed, err := store.GetUserById(ctx, user.Id)
+		assert.HasNoError(t, err)
+
+		user.Name = "Claude"
+		updated, err := store.UpdateUser(ctx, &user)
+		assert.HasNoError(t, err)
+
+		assert.Equals(t, updated.PasswordChangedAt, changed.PasswordChangedAt)
+	})
+
+	t.Run("UpdateUserPassword returns an `ErrResourceNotFound` error if the user does not exist", func(t *testing.T) {
+		store := newStore(t)
+
+		err := store.UpdateUserPassword(ctx, 1, "new password")
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("ValidateUserCredentials", func(t *testing.T) {
+		store := newStore(t)
+		createUser(t, store, "claude@email.com")
+
+		tests := []struct {
+			name     string
+			email    string
+			password string
+			want     bool
+		}{
+			{"valid credentials", "claude@email.com", "password", true},
+			{"email in another case", " Claude@Email.com", "password", true},
+			{"wrong password", "claude@email.com", "Password", false},
+			{"unknown email", "john@email.com", "password", false},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				got := store.ValidateUserCredentials(ctx, test.email, test.password)
+				assert.Equals(t, got, test.want)
+			})
+		}
+	})
+
+	t.Run("DeleteUserById deletes the user and everything that belongs to them", func(t *testing.T) {
+		store := newStore(t)
+		user := createUser(t, store, "claude@email.com")
+		other := createUser(t, store, "john@email.com")
+
+		err := store.CreateUserToken(ctx, models.NewUserToken(
+			"hash",
+			user.Id,
+			models.UserTokenPurposePasswordReset,
+			time.Now().Add(time.Hour),
+		))
+		assert.HasNoError(t, err)
+		_, err = store.CreateApiToken(ctx, &models.ApiToken{
+			UserId: user.Id,
+			Name:   "CLI",
+			Hash:   "api-hash",
+			Scopes: []string{models.ApiTokenScopeReadTasks},
+		})
+		assert.HasNoError(t, err)
+		err = store.SaveUserMfa(ctx, &models.UserMfa{UserId: user.Id, TotpSecret: "secret"})
+		assert.HasNoError(t, err)
+
+		assert.HasNoError(t, store.DeleteUserById(ctx, user.Id))
+
+		_, err = store.GetUserById(ctx, user.Id)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+		_, err = store.ConsumeUserToken(ctx, "hash", models.UserTokenPurposePasswordReset)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+		_, err = store.GetApiTokenByHash(ctx, "api-hash")
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+		_, err = store.GetUserMfa(ctx, user.Id)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+		users, err := store.GetUsers(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, users, []models.User{other})
+	})
+
+	t.Run("DeleteUserById deletes the projects the user was the last member of", func(t *testing.T) {
+		store := newStore(t)
+		user := createUser(t, store, "claude@email.com")
+		other := createUser(t, store, "john@email.com")
+		ownProject := createProject(t, store, "Home", user.Id)
+		sharedProject := createProject(t, store, "Work", other.Id)
+		saveProjectMember(t, store, sharedProject.Id, user.Id, models.ProjectRoleEditor)
+		ownTask := createTask(t, store, "Buy milk", ownProject.Id)
+		sharedTask := createAssignedTask(t, store, "Write report", sharedProject.Id, user.Id)
+		_, err := store.CreateProjectInvitation(ctx, &models.ProjectInvitation{
+			ProjectId: ownProject.Id,
+			Email:     "jane@email.com",
+			Role:      models.ProjectRoleViewer,
+			InvitedBy: user.Id,
+		})
+		assert.HasNoError(t, err)
+
+		assert.HasNoError(t, store.DeleteUserById(ctx, user.Id))
+
+		_, err = store.GetProjectById(ctx, ownProject.Id)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+		_, err = store.GetTaskById(ctx, ownTask.Id)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+		invitations, err := store.GetProjectInvitationsByEmail(ctx, "jane@email.com")
+		assert.HasNoError(t, err)
+		assert.HasLength(t, invitations, 0)
+
+		_, err = store.GetProjectById(ctx, sharedProject.Id)
+		assert.HasNoError(t, err)
+		members, err := store.GetProjectMembers(ctx, sharedProject.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, members, []models.ProjectMember{
+			{ProjectId: sharedProject.Id, UserId: other.Id, Role: models.ProjectRoleOwner},
+		})
+		got, err := store.GetTaskById(ctx, sharedTask.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, got.AssigneeId, 0)
+	})
+
+	t.Run("DeleteUserById returns an `ErrResourceNotFound` error if the user does not exist", func(t *testing.T) {
+		store := newStore(t)
+
+		err := store.DeleteUserById(ctx, 1)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+}
+
+func testProjects(t *testing.T, newStore NewStore) {
+	ctx := context.Background()
+
+	t.Run("CreateProject stores the project with its owner", func(t *testing.T) {
+		store := newStore(t)
+		user := createUser(t, store, "claude@email.com")
+
+		project, err := store.CreateProject(
+			ctx,
+			&models.CreateProjectDTO{Name: "Home"},
+			user.Id,
+		)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *project, models.Project{Id: project.Id, Name: "Home"})
+
+		got, err := store.GetProjectById(ctx, project.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, *project)
+		member, err := store.GetProjectMember(ctx, project.Id, user.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, member.Role, models.ProjectRoleOwner)
+	})
+
+	t.Run("GetProjectById returns an `ErrResourceNotFound` error if the project does not exist", func(t *testing.T) {
+		store := newStore(t)
+
+		_, err := store.GetProjectById(ctx, 1)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("does not reuse project IDs", func(t *testing.T) {
+		store := newStore(t)
+		user := createUser(t, store, "claude@email.com")
+
+		first := createProject(t, store, "Home", user.Id)
+		second := createProject(t, store, "Work", user.Id)
+		if second.Id <= first.Id {
+			t.Errorf("got ID %d, want an ID above %d", second.Id, first.Id)
+		}
+	})
+}
+
+func testProjectMembers(t *testing.T, newStore NewStore) {
+	ctx := context.Background()
+
+	t.Run("SaveProjectMember adds the member and then updates their role", func(t *testing.T) {
+		store := newStore(t)
+		project := createProject(t, store, "Home", 1)
+
+		saveProjectMember(t, store, project.Id, 2, models.ProjectRoleViewer)
+		saveProjectMember(t, store, project.Id, 2, models.ProjectRoleEditor)
+
+		member, err := store.GetProjectMember(ctx, project.Id, 2)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *member, models.ProjectMember{
+			ProjectId: project.Id,
+			UserId:    2,
+			Role:      models.ProjectRoleEditor,
+		})
+		members, err := store.GetProjectMembers(ctx, project.Id)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, members, 2)
+	})
+
+	t.Run("GetProjectMember returns an `ErrResourceNotFound` error if the user is not a member", func(t *testing.T) {
+		store := newStore(t)
+		project := createProject(t, store, "Home", 1)
+
+		_, err := store.GetProjectMember(ctx, project.Id, 2)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("GetProjectMembers returns the members ordered by user ID", func(t *testing.T) {
+		store := newStore(t)
+		project := createProject(t, store, "Home", 2)
+		otherProject := createProject(t, store, "Work", 1)
+		saveProjectMember(t, store, project.Id, 3, models.ProjectRoleViewer)
+		saveProjectMember(t, store, project.Id, 1, models.ProjectRoleEditor)
+
+		members, err := store.GetProjectMembers(ctx, project.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, members, []models.ProjectMember{
+			{ProjectId: project.Id, UserId: 1, Role: models.ProjectRoleEditor},
+			{ProjectId: project.Id, UserId: 2, Role: models.ProjectRoleOwner},
+			{ProjectId: project.Id, UserId: 3, Role: models.ProjectRoleViewer},
+		})
+
+		members, err = store.GetProjectMembers(ctx, otherProject.Id+1)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, members, 0)
+	})
+
+	t.Run("GetProjectMembersByUserId returns the memberships ordered by project ID", func(t *testing.T) {
+		store := newStore(t)
+		first := createProject(t, store, "Home", 2)
+		second := createProject(t, store, "Work", 1)
+		third := createProject(t, store, "Garden", 3)
+		saveProjectMember(t, store, third.Id, 1, models.ProjectRoleViewer)
+		saveProjectMember(t, store, first.Id, 1, models.ProjectRoleEditor)
+
+		members, err := store.GetProjectMembersByUserId(ctx, 1)
+		assert.HasNoError(t, err)
+		assert.Equals(t, members, []models.ProjectMember{
+			{ProjectId: first.Id, UserId: 1, Role: models.ProjectRoleEditor},
+			{ProjectId: second.Id, UserId: 1, Role: models.ProjectRoleOwner},
+			{ProjectId: third.Id, UserId: 1, Role: models.ProjectRoleViewer},
+		})
+
+		members, err = store.GetProjectMembersByUserId(ctx, 4)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, members, 0)
+	})
+
+	t.Run("DeleteProjectMember removes the member and unassigns their tasks in the project", func(t *testing.T) {
+		store := newStore(t)
+		project := createProject(t, store, "Home", 1)
+		otherProject := createProject(t, store, "Work", 1)
+		saveProjectMember(t, store, project.Id, 2, models.ProjectRoleEditor)
+		saveProjectMember(t, store, otherProject.Id, 2, models.ProjectRoleEditor)
+		task := createAssignedTask(t, store, "Buy milk", project.Id, 2)
+		otherTask := createAssignedTask(t, store, "Write report", otherProject.Id, 2)
+
+		assert.HasNoError(t, store.DeleteProjectMember(ctx, project.Id, 2))
+
+		_, err := store.GetProjectMember(ctx, project.Id, 2)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+		got, err := store.GetTaskById(ctx, task.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, got.AssigneeId, 0)
+		got, err = store.GetTaskById(ctx, otherTask.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, got.AssigneeId, 2)
+	})
+
+	t.Run("DeleteProjectMember returns an `ErrResourceNotFound` error if the user is not a member", func(t *testing.T) {
+		store := newStore(t)
+		project := createProject(t, store, "Home", 1)
+
+		err := store.DeleteProjectMember(ctx, project.Id, 2)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+}
+
+func testProjectInvitations(t *testing.T, newStore NewStore) {
+	ctx := context.Background()
+	createdAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("CreateProjectInvitation stores the invitation with a normalized email", func(t *testing.T) {
+		store := newStore(t)
+		project := createProject(t, store, "Home", 1)
+
+		invitation, err := store.CreateProjectInvitation(ctx, &models.ProjectInvitation{
+			ProjectId: project.Id,
+			Email:     " John@Email.com ",
+			Role:      models.ProjectRoleEditor,
+			InvitedBy: 1,
+			CreatedAt: createdAt,
+		})
+		assert.HasNoError(t, err)
+		assert.Equals(t, *invitation, models.ProjectInvitation{
+			Id:        invitation.Id,
+			ProjectId: project.Id,
+			Email:     "john@email.com",
+			Role:      models.ProjectRoleEditor,
+			InvitedBy: 1,
+			CreatedAt: createdAt,
+		})
+
+		got, err := store.GetProjectInvitationById(ctx, invitation.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, *invitation)
+	})
+
+	t.Run("CreateProjectInvitation sets the creation time when it is missing", func(t *testing.T) {
+		store := newStore(t)
+		project := createProject(t, store, "Home", 1)
+
+		before := time.Now()
+		invitation := createProjectInvitation(t, store, project.Id, "john@email.com")
+		assertRecent(t, invitation.CreatedAt, before)
+	})
+
+	t.Run("CreateProjectInvitation returns an `ErrConflict` error if the email is already invited", func(t *testing.T) {
+		store := newStore(t)
+		project := createProject(t, store, "Home", 1)
+		otherProject := createProject(t, store, "Work", 1)
+		createProjectInvitation(t, store, project.Id, "john@email.com")
+
+		_, err := store.CreateProjectInvitation(ctx, &models.ProjectInvitation{
+			ProjectId: project.Id,
+			Email:     "JOHN@email.com",
+			Role:      models.ProjectRoleViewer,
+			InvitedBy: 1,
+		})
+		assert.ErrorContains(t, err, data.ErrConflict)
+
+		createProjectInvitation(t, store, otherProject.Id, "john@email.com")
+	})
+
+	t.Run("GetProjectInvitationsByEmail returns the invitations ordered by ID", func(t *testing.T) {
+		store := newStore(t)
+		first := createProject(t, store, "Home", 1)
+		second := createProject(t, store, "Work", 1)
+		firstInvitation := createProjectInvitation(t, store, second.Id, "john@email.com")
+		createProjectInvitation(t, store, second.Id, "jane@email.com")
+		secondInvitation := createProjectInvitation(t, store, first.Id, "john@email.com")
+
+		invitations, err := store.GetProjectInvitationsByEmail(ctx, " John@Email.com")
+		assert.HasNoError(t, err)
+		assert.Equals(
+			t,
+			invitations,
+			[]models.ProjectInvitation{firstInvitation, secondInvitation},
+		)
+
+		invitations, err = store.GetProjectInvitationsByEmail(ctx, "bob@email.com")
+		assert.HasNoError(t, err)
+		assert.HasLength(t, invitations, 0)
+	})
+
+	t.Run("GetProjectInvitationById and DeleteProjectInvitation return an `ErrResourceNotFound` error if the invitation does not exist", func(t *testing.T) {
+		store := newStore(t)
+
+		_, err := store.GetProjectInvitationById(ctx, 1)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+		err = store.DeleteProjectInvitation(ctx, 1)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("DeleteProjectInvitation deletes the invitation", func(t *testing.T) {
+		store := newStore(t)
+		project := createProject(t, store, "Home", 1)
+		invitation := createProjectInvitation(t, store, project.Id, "john@email.com")
+
+		assert.HasNoError(t, store.DeleteProjectInvitation(ctx, invitation.Id))
+
+		_, err := store.GetProjectInvitationById(ctx, invitation.Id)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("AcceptProjectInvitation turns the invitation into a membership", func(t *testing.T) {
+		store := newStore(t)
+		project := createProject(t, store, "Home", 1)
+		invitation := createProjectInvitation(t, store, project.Id, "john@email.com")
+
+		member, err := store.AcceptProjectInvitation(ctx, invitation.Id, 2)
+		assert.HasNoError(t, err)
+		want := models.ProjectMember{
+			ProjectId: project.Id,
+			UserId:    2,
+			Role:      models.ProjectRoleViewer,
+		}
+		assert.Equals(t, *member, want)
+
+		got, err := store.GetProjectMember(ctx, project.Id, 2)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, want)
+		_, err = store.GetProjectInvitationById(ctx, invitation.Id)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("AcceptProjectInvitation keeps the role of existing members", func(t *testing.T) {
+		store := newStore(t)
+		project := createProject(t, store, "Home", 1)
+		invitation := createProjectInvitation(t, store, project.Id, "claude@email.com")
+
+		member, err := store.AcceptProjectInvitation(ctx, invitation.Id, 1)
+		assert.HasNoError(t, err)
+		assert.Equals(t, member.Role, models.ProjectRoleOwner)
+
+		_, err = store.GetProjectInvitationById(ctx, invitation.Id)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("AcceptProjectInvitation returns an `ErrResourceNotFound` error if the invitation does not exist", func(t *testing.T) {
+		store := newStore(t)
+
+		_, err := store.AcceptProjectInvitation(ctx, 1, 2)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+}
+
+func testApiTokens(t *testing.T, newStore NewStore) {
+	ctx := context.Background()
+	createdAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := createdAt.AddDate(0, 1, 0)
+
+	t.Run("CreateApiToken stores the token", func(t *testing.T) {
+		store := newStore(t)
+
+		token, err := store.CreateApiToken(ctx, &models.ApiToken{
+			UserId:    1,
+			Name:      "CLI",
+			Hash:      "hash",
+			Scopes:    []string{models.ApiTokenScopeReadTasks, models.ApiTokenScopeWriteTasks},
+			ExpiresAt: &expiresAt,
+			CreatedAt: createdAt,
+		})
+		assert.HasNoError(t, err)
+		assert.Equals(t, *token, models.ApiToken{
+			Id:        token.Id,
+			UserId:    1,
+			Name:      "CLI",
+			Hash:      "hash",
+			Scopes:    []string{models.ApiTokenScopeReadTasks, models.ApiTokenScopeWriteTasks},
+			ExpiresAt: &expiresAt,
+			CreatedAt: createdAt,
+		})
+
+		got, err := store.GetApiTokenByHash(ctx, "hash")
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, *token)
+	})
+
+	t.Run("CreateApiToken sets the creation time when it is missing", func(t *testing.T) {
+		store := newStore(t)
+
+		before := time.Now()
+		token := createApiToken(t, store, 1, "hash")
+		assertRecent(t, token.CreatedAt, before)
+		assert.Equals(t, token.ExpiresAt, nil)
+		assert.Equals(t, token.LastUsedAt, nil)
+	})
+
+	t.Run("GetApiTokenByHash returns an `ErrResourceNotFound` error if the token does not exist", func(t *testing.T) {
+		store := newStore(t)
+
+		_, err := store.GetApiTokenByHash(ctx, "hash")
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("GetApiTokensByUserId returns the tokens of the user ordered by ID", func(t *testing.T) {
+		store := newStore(t)
+		first := createApiToken(t, store, 1, "first")
+		createApiToken(t, store, 2, "other")
+		second := createApiToken(t, store, 1, "second")
+
+		tokens, err := store.GetApiTokensByUserId(ctx, 1)
+		assert.HasNoError(t, err)
+		assert.Equals(t, tokens, []models.ApiToken{first, second})
+
+		tokens, err = store.GetApiTokensByUserId(ctx, 3)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, tokens, 0)
+	})
+
+	t.Run("UpdateApiTokenLastUsedAt records when the token was used", func(t *testing.T) {
+		store := newStore(t)
+		token := createApiToken(t, store, 1, "hash")
+
+		lastUsedAt := createdAt.Add(time.Hour)
+		err := store.UpdateApiTokenLastUsedAt(ctx, token.Id, lastUsedAt)
+		assert.HasNoError(t, err)
+
+		got, err := store.GetApiTokenByHash(ctx, "hash")
+		assert.HasNoError(t, err)
+		assert.Equals(t, got.LastUsedAt, &lastUsedAt)
+	})
+
+	t.Run("UpdateApiTokenLastUsedAt returns an `ErrResourceNotFound` error if the token does not exist", func(t *testing.T) {
+		store := newStore(t)
+
+		err := store.UpdateApiTokenLastUsedAt(ctx, 1, createdAt)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("DeleteApiToken only deletes the tokens of the user", func(t *testing.T) {
+		store := newStore(t)
+		token := createApiToken(t, store, 1, "hash")
+
+		err := store.DeleteApiToken(ctx, token.Id, 2)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+		_, err = store.GetApiTokenByHash(ctx, "hash")
+		assert.HasNoError(t, err)
+
+		assert.HasNoError(t, store.DeleteApiToken(ctx, token.Id, 1))
+		_, err = store.GetApiTokenByHash(ctx, "hash")
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+		err = store.DeleteApiToken(ctx, token.Id, 1)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+}
+
+func testUserTokens(t *testing.T, newStore NewStore) {
+	ctx := context.Background()
+	expiresAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("ConsumeUserToken returns the token only once", func(t *testing.T) {
+		store := newStore(t)
+		token := models.NewUserToken(
+			"hash",
+			1,
+			models.UserTokenPurposeEmailChange,
+			expiresAt,
+		)
+		token.Email = "claude@email.com"
+		assert.HasNoError(t, store.CreateUserToken(ctx, token))
+
+		got, err := store.ConsumeUserToken(ctx, "hash", models.UserTokenPurposeEmailChange)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, *token)
+
+		_, err = store.ConsumeUserToken(ctx, "hash", models.UserTokenPurposeEmailChange)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("ConsumeUserToken does not return tokens for another purpose", func(t *testing.T) {
+		store := newStore(t)
+		token := models.NewUserToken(
+			"hash",
+			1,
+			models.UserTokenPurposePasswordReset,
+			expiresAt,
+		)
+		assert.HasNoError(t, store.CreateUserToken(ctx, token))
+
+		_, err := store.ConsumeUserToken(
+			ctx,
+			"hash",
+			models.UserTokenPurposeEmailVerification,
+		)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+
+		_, err = store.ConsumeUserToken(ctx, "hash", models.UserTokenPurposePasswordReset)
+		assert.HasNoError(t, err)
+	})
+
+	t.Run("DeleteUserTokens only deletes the tokens of the user for the purpose", func(t *testing.T) {
+		store := newStore(t)
+		tokens := []*models.UserToken{
+			models.NewUserToken("deleted", 1, models.UserTokenPurposeMfaRecovery, expiresAt),
+			models.NewUserToken("other-purpose", 1, models.UserTokenPurposePasswordReset, expiresAt),
+			models.NewUserToken("other-user", 2, models.UserTokenPurposeMfaRecovery, expiresAt),
+		}
+		for _, token := range tokens {
+			assert.HasNoError(t, store.CreateUserToken(ctx, token))
+		}
+
+		err := store.DeleteUserTokens(ctx, 1, models.UserTokenPurposeMfaRecovery)
+		assert.HasNoError(t, err)
+
+		_, err = store.ConsumeUserToken(ctx, "deleted", models.UserTokenPurposeMfaRecovery)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+		for _, token := range tokens[1:] {
+			_, err = store.ConsumeUserToken(ctx, token.Hash, token.Purpose)
+			assert.HasNoError(t, err)
+		}
+	})
+
+	t.Run("DeleteUserTokens does nothing without tokens", func(t *testing.T) {
+		store := newStore(t)
+
+		err := store.DeleteUserTokens(ctx, 1, models.UserTokenPurposeMfaRecovery)
+		assert.HasNoError(t, err)
+	})
+}
+
+func testUserMfa(t *testing.T, newStore NewStore) {
+	ctx := context.Background()
+
+	t.Run("SaveUserMfa creates, updates and DeleteUserMfa removes", func(t *testing.T) {
+		store := newStore(t)
+
+		_, err := store.GetUserMfa(ctx, 1)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+
+		mfa := models.UserMfa{UserId: 1, TotpSecret: "secret"}
+		assert.HasNoError(t, store.SaveUserMfa(ctx, &mfa))
+		got, err := store.GetUserMfa(ctx, 1)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, mfa)
+
+		mfa.Enabled = true
+		mfa.LastUsedStep = 57_000_000
+		assert.HasNoError(t, store.SaveUserMfa(ctx, &mfa))
+		got, err = store.GetUserMfa(ctx, 1)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, mfa)
+
+		assert.HasNoError(t, store.DeleteUserMfa(ctx, 1))
+		_, err = store.GetUserMfa(ctx, 1)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("UseUserMfaStep only records later steps", func(t *testing.T) {
+		store := newStore(t)
+
+		used, err := store.UseUserMfaStep(ctx, 1, 57_000_000)
+		assert.HasNoError(t, err)
+		assert.Equals(t, used, false)
+
+		mfa := models.UserMfa{UserId: 1, TotpSecret: "secret", Enabled: true}
+		assert.HasNoError(t, store.SaveUserMfa(ctx, &mfa))
+		used, err = store.UseUserMfaStep(ctx, 1, 57_000_000)
+		assert.HasNoError(t, err)
+		assert.Equals(t, used, true)
+		used, err = store.UseUserMfaStep(ctx, 1, 57_000_000)
+		assert.HasNoError(t, err)
+		assert.Equals(t, used, false)
+		used, err = store.UseUserMfaStep(ctx, 1, 56_999_999)
+		assert.HasNoError(t, err)
+		assert.Equals(t, used, false)
+
+		got, err := store.GetUserMfa(ctx, 1)
+		assert.HasNoError(t, err)
+		mfa.LastUsedStep = 57_000_000
+		assert.Equals(t, *got, mfa)
+	})
+
+	t.Run("DeleteUserMfa does nothing without MFA settings", func(t *testing.T) {
+		store := newStore(t)
+
+		assert.HasNoError(t, store.DeleteUserMfa(ctx, 1))
+	})
+}
+
+func testLoginAttempts(t *testing.T, newStore NewStore) {
+	ctx := context.Background()
+	failedAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("SaveLoginAttempt creates, updates and DeleteLoginAttempt removes", func(t *testing.T) {
+		store := newStore(t)
+
+		_, err := store.GetLoginAttempt(ctx, "claude@email.com")
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+
+		attempt := models.LoginAttempt{
+			Key:           "claude@email.com",
+			Failures:      1,
+			LastFailureAt: failedAt,
+		}
+		assert.HasNoError(t, store.SaveLoginAttempt(ctx, &attempt))
+		got, err := store.GetLoginAttempt(ctx, "claude@email.com")
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, attempt)
+
+		attempt.Failures = 5
+		attempt.LockedUntil = failedAt.Add(15 * time.Minute)
+		assert.HasNoError(t, store.SaveLoginAttempt(ctx, &attempt))
+		got, err = store.GetLoginAttempt(ctx, "claude@email.com")
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, attempt)
+
+		assert.HasNoError(t, store.DeleteLoginAttempt(ctx, "claude@email.com"))
+		_, err = store.GetLoginAttempt(ctx, "claude@email.com")
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("DeleteLoginAttempt does nothing without a login attempt", func(t *testing.T) {
+		store := newStore(t)
+
+		assert.HasNoError(t, store.DeleteLoginAttempt(ctx, "claude@email.com"))
+	})
+
+	t.Run("IncrementLoginFailures counts the failures until they expire", func(t *testing.T) {
+		store := newStore(t)
+
+		attempt, err := store.IncrementLoginFailures(ctx, "claude@email.com", failedAt, time.Hour)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *attempt, models.LoginAttempt{
+			Key:           "claude@email.com",
+			Failures:      1,
+			LastFailureAt: failedAt,
+		})
+		attempt, err = store.IncrementLoginFailures(
+			ctx,
+			"claude@email.com",
+			failedAt.Add(time.Minute),
+			time.Hour,
+		)
+		assert.HasNoError(t, err)
+		assert.Equals(t, attempt.Failures, 2)
+		got, err := store.GetLoginAttempt(ctx, "claude@email.com")
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, *attempt)
+
+		attempt, err = store.IncrementLoginFailures(
+			ctx,
+			"claude@email.com",
+			failedAt.Add(2*time.Hour),
+			time.Hour,
+		)
+		assert.HasNoError(t, err)
+		assert.Equals(t, attempt.Failures, 1)
+	})
+
+	t.Run("IncrementLoginFailures counts concurrent failures", func(t *testing.T) {
+		store := newStore(t)
+
+		const failures = 10
+		var wg sync.WaitGroup
+		errs := make(chan error, failures)
+		for range failures {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := store.IncrementLoginFailures(
+					ctx,
+					"claude@email.com",
+					failedAt,
+					time.Hour,
+				)
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			assert.HasNoError(t, err)
+		}
+
+		got, err := store.GetLoginAttempt(ctx, "claude@email.com")
+		assert.HasNoError(t, err)
+		assert.Equals(t, got.Failures, failures)
+	})
+
+	t.Run("LockLoginAttempt does nothing after another failure", func(t *testing.T) {
+		store := newStore(t)
+
+		lockedUntil := failedAt.Add(time.Minute)
+		attempt, err := store.IncrementLoginFailures(ctx, "claude@email.com", failedAt, time.Hour)
+		assert.HasNoError(t, err)
+		assert.HasNoError(t, store.LockLoginAttempt(ctx, "claude@email.com", attempt.Failures, lockedUntil))
+		got, err := store.GetLoginAttempt(ctx, "claude@email.com")
+		assert.HasNoError(t, err)
+		assert.Equals(t, got.LockedUntil, lockedUntil)
+
+		_, err = store.IncrementLoginFailures(ctx, "claude@email.com", failedAt, time.Hour)
+		assert.HasNoError(t, err)
+		err = store.LockLoginAttempt(
+			ctx,
+			"claude@email.com",
+			attempt.Failures,
+			lockedUntil.Add(time.Hour),
+		)
+		assert.HasNoError(t, err)
+		got, err = store.GetLoginAttempt(ctx, "claude@email.com")
+		assert.HasNoError(t, err)
+		assert.Equals(t, got.LockedUntil, lockedUntil)
+
+		assert.HasNoError(t, store.LockLoginAttempt(ctx, "nobody@email.com", 1, lockedUntil))
+		_, err = store.GetLoginAttempt(ctx, "nobody@email.com")
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+}
+
+func testAuditEvents(t *testing.T, newStore NewStore) {
+	ctx := context.Background()
+
+	t.Run("CreateAuditEvent stores the event with an ID and a timestamp", func(t *testing.T) {
+		store := newStore(t)
+
+		before := time.Now()
+		event := models.NewAuditEvent("user.login", 1, "claude@email.com", "from the CLI")
+		event.ImpersonatorId = 2
+		event, err := store.CreateAuditEvent(ctx, event)
+		assert.HasNoError(t, err)
+		assertRecent(t, event.CreatedAt, before)
+		assert.Equals(t, *event, models.AuditEvent{
+			Id:             event.Id,
+			Action:         "user.login",
+			ActorId:        1,
+			ImpersonatorId: 2,
+			Subject:        "claude@email.com",
+			Details:        "from the CLI",
+			CreatedAt:      event.CreatedAt,
+		})
+
+		events, err := store.GetAuditEvents(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, events, []models.AuditEvent{*event})
+	})
+
+	t.Run("GetAuditEvents returns the events ordered by ID", func(t *testing.T) {
+		store := newStore(t)
+
+		events, err := store.GetAuditEvents(ctx)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, events, 0)
+
+		createdAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+		var want []models.AuditEvent
+		for _, action := range []string{"user.login", "user.logout"} {
+			event := models.NewAuditEvent(action, 1, "claude@email.com", "")
+			event.CreatedAt = createdAt
+			created, err := store.CreateAuditEvent(ctx, event)
+			assert.HasNoError(t, err)
+			want = append(want, *created)
+		}
+
+		events, err = store.GetAuditEvents(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, events, want)
+	})
+}
+
+// testImports only runs against stores that are data.Importers.
+func testImports(t *testing.T, newStore NewStore) {
+	ctx := context.Background()
+	newImporter := func(t *testing.T) interface {
+		data.Store
+		data.Importer
+	} {
+		store := newStore(t)
+		importer, ok := store.(interface {
+			data.Store
+			data.Importer
+		})
+		if !ok {
+			t.Skipf("%T is not a data.Importer", store)
+		}
+		return importer
+	}
+
+	t.Run("ImportUser keeps the ID and the password hash", func(t *testing.T) {
+		store := newImporter(t)
+		existing := createUser(t, store, "claude@email.com")
+
+		user := *models.NewUser(
+			existing.Id+10,
+			"Harry Potter",
+			" Harry@Hogwarts.edu ",
+			"$2a$10$8I2.G30FbNko74lzUp58KuaL9rXqnjHETjQhUtDzVkvUXba1.C64a",
+		)
+		user.Verified = true
+		user.Role = models.RoleAdmin
+		passwordChangedAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+		user.PasswordChangedAt = &passwordChangedAt
+		assert.HasNoError(t, store.ImportUser(ctx, &user))
+		user.Email = "harry@hogwarts.edu"
+
+		got, err := store.GetUserByEmail(ctx, user.Email)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, user)
+
+		created := createUser(t, store, "ron@hogwarts.edu")
+		if created.Id <= user.Id {
+			t.Errorf("got ID %d after importing ID %d, want a higher one", created.Id, user.Id)
+		}
+		users, err := store.GetUsers(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, users, []models.User{existing, user, created})
+	})
+
+	t.Run("ImportUser replaces the user with the same ID", func(t *testing.T) {
+		store := newImporter(t)
+		user := createUser(t, store, "claude@email.com")
+
+		user.Name = "Claude"
+		user.Email = "claude.aldric@email.com"
+		assert.HasNoError(t, store.ImportUser(ctx, &user))
+
+		got, err := store.GetUserByEmail(ctx, user.Email)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, user)
+		_, err = store.GetUserByEmail(ctx, "claude@email.com")
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("ImportUser returns an `ErrConflict` error if another user has the email", func(t *testing.T) {
+		store := newImporter(t)
+		existing := createUser(t, store, "claude@email.com")
+
+		user := *models.NewUser(existing.Id+1, "Claude", existing.Email, "hash")
+		err := store.ImportUser(ctx, &user)
+		assert.ErrorContains(t, err, data.ErrConflict)
+	})
+
+	t.Run("ImportTask keeps the ID and the order of the tasks", func(t *testing.T) {
+		store := newImporter(t)
+		first := createTask(t, store, "Buy milk", 1)
+		second := createTask(t, store, "Walk the dog", 1)
+		third := createTask(t, store, "Cook dinner", 1)
+		assert.HasNoError(t, store.DeleteTaskById(ctx, second.Id))
+
+		second.ProjectId = 2
+		second.AssigneeId = 3
+		assert.HasNoError(t, store.ImportTask(ctx, &second))
+
+		tasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, tasks, []models.Task{first, second, third})
+		tasks, err = store.GetTasksByProjectId(ctx, 2)
+		assert.HasNoError(t, err)
+		assert.Equals(t, tasks, []models.Task{second})
+	})
+
+	t.Run("ImportTask replaces the task with the same ID", func(t *testing.T) {
+		store := newImporter(t)
+		task := createTask(t, store, "Buy milk", 1)
+
+		task.Title = "Buy oat milk"
+		task.ProjectId = 2
+		assert.HasNoError(t, store.ImportTask(ctx, &task))
+
+		tasks, err := store.GetTasksByProjectId(ctx, 1)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, tasks, 0)
+		tasks, err = store.GetTasksByProjectId(ctx, 2)
+		assert.HasNoError(t, err)
+		assert.Equals(t, tasks, []models.Task{task})
+	})
+
+	t.Run("ImportTask moves the ID sequence past the task", func(t *testing.T) {
+		store := newImporter(t)
+		task := createTask(t, store, "Buy milk", 1)
+
+		task.Id += 10
+		assert.HasNoError(t, store.ImportTask(ctx, &task))
+
+		created := createTask(t, store, "Walk the dog", 1)
+		if created.Id <= task.Id {
+			t.Errorf("got ID %d after importing ID %d, want a higher one", created.Id, task.Id)
+		}
+	})
+
+	t.Run("ImportProject keeps the ID and replaces the project with the same ID", func(t *testing.T) {
+		store := newImporter(t)
+		existing := createProject(t, store, "Groceries", 1)
+
+		project := models.Project{Id: existing.Id + 10, Name: "Chores"}
+		assert.HasNoError(t, store.ImportProject(ctx, &project))
+		project.Name = "Housework"
+		assert.HasNoError(t, store.ImportProject(ctx, &project))
+
+		got, err := store.GetProjectById(ctx, project.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, project)
+		created := createProject(t, store, "Garden", 1)
+		if created.Id <= project.Id {
+			t.Errorf("got ID %d after importing ID %d, want a higher one", created.Id, project.Id)
+		}
+	})
+
+	t.Run("ImportApiToken keeps the ID and moves the ID sequence past it", func(t *testing.T) {
+		store := newImporter(t)
+		existing := createApiToken(t, store, 1, "first")
+
+		expiresAt := time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC)
+		token := models.ApiToken{
+			Id:        existing.Id + 10,
+			UserId:    1,
+			Name:      "Deploys",
+			Hash:      "second",
+			Scopes:    []string{models.ApiTokenScopeReadTasks},
+			ExpiresAt: &expiresAt,
+			CreatedAt: time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC),
+		}
+		assert.HasNoError(t, store.ImportApiToken(ctx, &token))
+		token.Name = "Releases"
+		assert.HasNoError(t, store.ImportApiToken(ctx, &token))
+
+		got, err := store.GetApiTokenByHash(ctx, token.Hash)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, token)
+		created := createApiToken(t, store, 1, "third")
+		if created.Id <= token.Id {
+			t.Errorf("got ID %d after importing ID %d, want a higher one", created.Id, token.Id)
+		}
+	})
+
+	t.Run("ImportApiToken returns an `ErrConflict` error if another token has the hash", func(t *testing.T) {
+		store := newImporter(t)
+		existing := createApiToken(t, store, 1, "first")
+
+		token := existing
+		token.Id++
+		err := store.ImportApiToken(ctx, &token)
+		assert.ErrorContains(t, err, data.ErrConflict)
+	})
+
+	t.Run("ImportProjectInvitation keeps the ID and moves the ID sequence past it", func(t *testing.T) {
+		store := newImporter(t)
+		existing := createProjectInvitation(t, store, 1, "harry@hogwarts.edu")
+
+		invitation := models.ProjectInvitation{
+			Id:        existing.Id + 10,
+			ProjectId: 1,
+			Email:     "ron@hogwarts.edu",
+			Role:      models.ProjectRoleEditor,
+			InvitedBy: 1,
+			CreatedAt: time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC),
+		}
+		assert.HasNoError(t, store.ImportProjectInvitation(ctx, &invitation))
+
+		got, err := store.GetProjectInvitationById(ctx, invitation.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, *got, invitation)
+		created := createProjectInvitation(t, store, 1, "ginny@hogwarts.edu")
+		if created.Id <= invitation.Id {
+			t.Errorf("got ID %d after importing ID %d, want a higher one", created.Id, invitation.Id)
+		}
+	})
+
+	t.Run("ImportAuditEvent keeps the ID and moves the ID sequence past it", func(t *testing.T) {
+		store := newImporter(t)
+
+		event := models.NewAuditEvent("user.login", 1, "claude@email.com", "")
+		event.Id = 10
+		event.CreatedAt = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+		assert.HasNoError(t, store.ImportAuditEvent(ctx, event))
+		created, err := store.CreateAuditEvent(
+			ctx,
+			models.NewAuditEvent("user.logout", 1, "claude@email.com", ""),
+		)
+		assert.HasNoError(t, err)
+
+		events, err := store.GetAuditEvents(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, events, []models.AuditEvent{*event, *created})
+	})
+
+	t.Run("ImportTaskRevision keeps the revision", func(t *testing.T) {
+		store := newImporter(t)
+		task := createTask(t, store, "Buy milk", 1)
+
+		revision := models.TaskRevision{
+			TaskId:        task.Id,
+			Revision:      1,
+			EditorId:      2,
+			Title:         "Buy oat milk",
+			ChangedFields: []string{"title"},
+			CreatedAt:     time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC),
+		}
+		err := store.ImportTaskRevision(ctx, &revision)
+		if errors.Is(err, data.ErrTaskRevisionsNotRecorded) {
+			t.Skipf("%T does not record task revisions", store)
+		}
+		assert.HasNoError(t, err)
+		assert.HasNoError(t, store.ImportTaskRevision(ctx, &revision))
+
+		reviser, ok := store.(data.TaskReviser)
+		if !ok {
+			t.Skipf("%T is not a data.TaskReviser", store)
+		}
+		revisions, err := reviser.GetTaskRevisions(ctx, task.Id)
+		assert.HasNoError(t, err)
+		assert.Equals(t, revisions, []models.TaskRevision{revision})
+	})
+}
+
+func testExports(t *testing.T, newStore NewStore) {
+	ctx := context.Background()
+	newExporter := func(t *testing.T) interface {
+		data.Store
+		data.Exporter
+	} {
+		store := newStore(t)
+		exporter, ok := store.(interface {
+			data.Store
+			data.Exporter
+		})
+		if !ok {
+			t.Skipf("%T is not a data.Exporter", store)
+		}
+		return exporter
+	}
+
+	t.Run("GetProjectInvitations returns every invitation ordered by ID", func(t *testing.T) {
+		store := newExporter(t)
+		var want []models.ProjectInvitation
+		for i, email := range []string{"harry@hogwarts.edu", "ron@hogwarts.edu"} {
+			created := createProjectInvitation(t, store, i+1, email)
+			invitation, err := store.GetProjectInvitationById(ctx, created.Id)
+			assert.HasNoError(t, err)
+			want = append(want, *invitation)
+		}
+
+		invitations, err := store.GetProjectInvitations(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, invitations, want)
+	})
+
+	t.Run("GetUserTokens returns every token ordered by hash", func(t *testing.T) {
+		store := newExporter(t)
+		tokens, err := store.GetUserTokens(ctx)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, tokens, 0)
+
+		expiresAt := time.Date(2030, time.March, 1, 12, 0, 0, 0, time.UTC)
+		reset := models.NewUserToken("b", 1, models.UserTokenPurposePasswordReset, expiresAt)
+		verification := models.NewUserToken(
+			"a",
+			2,
+			models.UserTokenPurposeEmailVerification,
+			expiresAt,
+		)
+		assert.HasNoError(t, store.CreateUserToken(ctx, reset))
+		assert.HasNoError(t, store.CreateUserToken(ctx, verification))
+
+		tokens, err = store.GetUserTokens(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, tokens, []models.UserToken{*verification, *reset})
+	})
+}
+
+func testTaskRevisions(t *testing.T, newStore NewStore) {
+	ctx := context.Background()
+	newReviser := func(t *testing.T) interface {
+		data.Store
+		data.TaskReviser
+	} {
+		store := newStore(t)
+		reviser, ok := store.(interface {
+			data.Store
+			data.TaskReviser
+		})
+		if !ok {
+			t.Skipf("%T is not a data.TaskReviser", store)
+		}
+		_, err := reviser.GetTaskRevisions(ctx, 1)
+		if errors.Is(err, data.ErrTaskRevisionsNotRecorded) {
+			t.Skipf("%T does not record task revisions", store)
+		}
+		return reviser
+	}
+
+	t.Run("UpdateTask keeps the versions it replaces with their editor", func(t *testing.T) {
+		store := newReviser(t)
+		task := createTask(t, store, "Buy milk", 1)
+		other := createTask(t, store, "Walk the dog", 1)
+		before := time.Now()
+
+		renamed := task
+		renamed.Title = "Buy oat milk"
+		_, err := store.UpdateTask(data.WithEditor(ctx, 7), &renamed)
+		assert.HasNoError(t, err)
+		assigned := renamed
+		assigned.AssigneeId = 3
+		_, err = store.UpdateTask(data.WithEditor(ctx, 8), &assigned)
+		assert.HasNoError(t, err)
+		_, err = store.UpdateTask(data.WithEditor(ctx, 8), &assigned)
+		assert.HasNoError(t, err)
+		otherRenamed := other
+		otherRenamed.Title = "Walk the cat"
+		_, err = store.UpdateTask(ctx, &otherRenamed)
+		assert.HasNoError(t, err)
+
+		revisions, err := store.GetTaskRevisions(ctx, task.Id)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, revisions, 2)
+		for i := range revisions {
+			assertRecent(t, revisions[i].CreatedAt, before)
+			revisions[i].CreatedAt = time.Time{}
+		}
+		assert.Equals(t, revisions, []models.TaskRevision{
+			{
+				TaskId:        task.Id,
+				Revision:      1,
+				Title:         "Buy milk",
+				ChangedFields: []string{models.TaskFieldTitle},
+				EditorId:      7,
+			},
+			{
+				TaskId:        task.Id,
+				Revision:      2,
+				Title:         "Buy oat milk",
+				ChangedFields: []string{models.TaskFieldAssigneeId},
+				EditorId:      8,
+			},
+		})
+
+		revision, err := store.GetTaskRevision(ctx, other.Id, 1)
+		assert.HasNoError(t, err)
+		assert.Equals(t, revision.Title, "Walk the dog")
+		assert.Equals(t, revision.EditorId, 0)
+	})
+
+	t.Run("GetTaskRevision returns an `ErrResourceNotFound` error if the revision does not exist", func(t *testing.T) {
+		store := newReviser(t)
+		task := createTask(t, store, "Buy milk", 1)
+
+		_, err := store.GetTaskRevision(ctx, task.Id, 1)
+		assert.ErrorContains(t, err, data.ErrResourceNotFound)
+	})
+
+	t.Run("DeleteTaskById deletes the revisions of the task", func(t *testing.T) {
+		store := newReviser(t)
+		task := createTask(t, store, "Buy milk", 1)
+		task.Title = "Buy oat milk"
+		_, err := store.UpdateTask(ctx, &task)
+		assert.HasNoError(t, err)
+
+		assert.HasNoError(t, store.DeleteTaskById(ctx, task.Id))
+
+		revisions, err := store.GetTaskRevisions(ctx, task.Id)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, revisions, 0)
+	})
+}
+
+func testCancelledContext(t *testing.T, newStore NewStore) {
+	store := newStore(t)
+	task := createTask(t, store, "Buy milk", 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := store.CreateTask(ctx, models.NewCreateTaskDTO("Walk the dog", 1))
+	assert.ErrorContains(t, err, context.Canceled)
+	_, err = store.GetTasks(ctx)
+	assert.ErrorContains(t, err, context.Canceled)
+	err = store.DeleteTaskById(ctx, task.Id)
+	assert.ErrorContains(t, err, context.Canceled)
+
+	tasks, err := store.GetTasks(context.Background())
+	assert.HasNoError(t, err)
+	assert.Equals(t, tasks, []models.Task{task})
+}
+
+func createTask(t *testing.T, store data.Store, title string, projectId int) models.Task {
+	t.Helper()
+	task, err := store.CreateTask(
+		context.Background(),
+		models.NewCreateTaskDTO(title, projectId),
+	)
+	assert.HasNoError(t, err)
+	return *task
+}
+
+func createAssignedTask(
+	t *testing.T,
+	store data.Store,
+	title string,
+	projectId, assigneeId int,
+) models.Task {
+	t.Helper()
+	dto := models.NewCreateTaskDTO(title, projectId)
+	dto.AssigneeId = assigneeId
+	task, err := store.CreateTask(context.Background(), dto)
+	assert.HasNoError(t, err)
+	return *task
+}
+
+func createUser(t *testing.T, store data.Store, email string) models.User {
+	t.Helper()
+	user, err := store.CreateUser(
+		context.Background(),
+		models.NewCreateUserDTO("Claude Aldric", email, "password"),
+	)
+	assert.HasNoError(t, err)
+	return *user
+}
+
+func createProject(
+	t *testing.T,
+	store data.Store,
+	name string,
+	ownerId int,
+) models.Project {
+	t.Helper()
+	project, err := store.CreateProject(
+		context.Background(),
+		&models.CreateProjectDTO{Name: name},
+		ownerId,
+	)
+	assert.HasNoError(t, err)
+	return *project
+}
+
+func saveProjectMember(
+	t *testing.T,
+	store data.Store,
+	projectId, userId int,
+	role string,
+) {
+	t.Helper()
+	err := store.SaveProjectMember(context.Background(), &models.ProjectMember{
+		ProjectId: projectId,
+		UserId:    userId,
+		Role:      role,
+	})
+	assert.HasNoError(t, err)
+}
+
+func createProjectInvitation(
+	t *testing.T,
+	store data.Store,
+	projectId int,
+	email string,
+) models.ProjectInvitation {
+	t.Helper()
+	invitation, err := store.CreateProjectInvitation(
+		context.Background(),
+		&models.ProjectInvitation{
+			ProjectId: projectId,
+			Email:     email,
+			Role:      models.ProjectRoleViewer,
+			InvitedBy: 1,
+		},
+	)
+	assert.HasNoError(t, err)
+	return *invitation
+}
+
+func createApiToken(
+	t *testing.T,
+	store data.Store,
+	userId int,
+	hash string,
+) models.ApiToken {
+	t.Helper()
+	token, err := store.CreateApiToken(context.Background(), &models.ApiToken{
+		UserId: userId,
+		Name:   "CLI",
+		Hash:   hash,
+		Scopes: []string{models.ApiTokenScopeReadTasks},
+	})
+	assert.HasNoError(t, err)
+	return *token
+}
+
+// assertRecent checks that a timestamp the store set itself is in UTC and
+// no older than before.
+func assertRecent(t *testing.T, got, before time.Time) {
+	t.Helper()
+	if got.Location() != time.UTC {
+		t.Errorf("got a time in %v, want UTC", got.Location())
+	}
+	if got.Before(before.Add(-time.Second)) || got.After(time.Now()) {
+		t.Errorf("got %v, want a time between %v and now", got, before)
+	}
+}
@@ -0,0 +1,116 @@
+package data_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/data/storetest"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestStoreConformance(t *testing.T) {
+	t.Run("SqliteStore", func(t *testing.T) {
+		storetest.Run(t, newEmptySqliteStore)
+	})
+
+	t.Run("FileSystemStore", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) data.Store {
+			database, cleanDatabase := testutils.CreateTempFile(t, "")
+			t.Cleanup(cleanDatabase)
+			store, err := data.NewFileSystemStore(database)
+			assert.HasNoError(t, err)
+			t.Cleanup(func() { store.Close() })
+			return store
+		})
+	})
+
+	t.Run("FileSystemStore with a journal", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) data.Store {
+			database, cleanDatabase := testutils.CreateTempFile(t, "")
+			t.Cleanup(cleanDatabase)
+			store, err := data.NewFileSystemStore(database, data.WithJournal(3))
+			assert.HasNoError(t, err)
+			t.Cleanup(func() { store.Close() })
+			return store
+		})
+	})
+
+	t.Run("BoltStore", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) data.Store {
+			store, err := data.NewBoltStore(filepath.Join(t.TempDir(), "data.db"))
+			assert.HasNoError(t, err)
+			t.Cleanup(func() { store.Close() })
+			return store
+		})
+	})
+
+	t.Run("MemoryStore", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) data.Store {
+			store, err := data.NewMemoryStore()
+			assert.HasNoError(t, err)
+			return store
+		})
+	})
+
+	t.Run("EventSourcedStore", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) data.Store {
+			store, err := data.NewEventSourcedStore(
+				context.Background(),
+				newEmptySqliteDb(t),
+			)
+			assert.HasNoError(t, err)
+			return store
+		})
+	})
+
+	t.Run("EventSourcedStore with frequent snapshots", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) data.Store {
+			store, err := data.NewEventSourcedStore(
+				context.Background(),
+				newEmptySqliteDb(t),
+				data.WithTaskSnapshotEvery(2),
+			)
+			assert.HasNoError(t, err)
+			return store
+		})
+	})
+
+	t.Run("CachingStore", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) data.Store {
+			return data.NewCachingStore(newEmptySqliteStore(t), 100, time.Minute)
+		})
+	})
+
+	t.Run("CachingStore with a small cache", func(t *testing.T) {
+		storetest.Run(t, func(t *testing.T) data.Store {
+			store, err := data.NewMemoryStore()
+			assert.HasNoError(t, err)
+			return data.NewCachingStore(store, 1, time.Minute)
+		})
+	})
+}
+
+func newEmptySqliteStore(t *testing.T) data.Store {
+	return data.NewSqliteStore(newEmptySqliteDb(t))
+}
+
+// newEmptySqliteDb removes what InitDb seeds the database with.
+func newEmptySqliteDb(t *testing.T) *sql.DB {
+	db, err := data.OpenSqlite(
+		context.Background(),
+		filepath.Join(t.TempDir(), "data.db"),
+	)
+	assert.HasNoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	data.InitDb(db)
+	for _, table := range []string{"project_members", "projects", "tasks", "users"} {
+		_, err := db.Exec(`delete from ` + table)
+		assert.HasNoError(t, err)
+	}
+	return db
+}
@@ -1,21 +0,0 @@
-package data
-
-import (
-	"io"
-	"os"
-)
-
-type tape struct {
-	file *os.File
-}
-
-func (t *tape) Read(p []byte) (n int, err error) {
-	t.file.Seek(0, io.SeekStart)
-	return t.file.Read(p)
-}
-
-func (t *tape) Write(p []byte) (n int, err error) {
-	t.file.Truncate(0)
-	t.file.Seek(0, io.SeekStart)
-	return t.file.Write(p)
-}
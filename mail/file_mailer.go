@@ -0,0 +1,35 @@
+package mail
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("problem creating mail directory %s, %v", dir, err)
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(message Message) error {
+	file, err := os.CreateTemp(
+		m.dir,
+		fmt.Sprintf("%s-*.eml", time.Now().UTC().Format("20060102T150405")),
+	)
+	if err != nil {
+		return fmt.Errorf("error creating mail file: %w", err)
+	}
+	defer file.Close()
+	_, err = file.Write(formatMessage(m.from, message))
+	if err != nil {
+		return fmt.Errorf("error writing mail file %s: %w", file.Name(), err)
+	}
+	return nil
+}
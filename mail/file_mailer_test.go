@@ -0,0 +1,37 @@
+package mail
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestFileMailer(t *testing.T) {
+	t.Run("writes every message to its own file", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "mail")
+		mailer, err := NewFileMailer(dir, "no-reply@email.com")
+		assert.HasNoError(t, err)
+
+		message := Message{
+			To:      "claude.aldric@email.com",
+			Subject: "Verify your email",
+			Body:    "Hi Claude,\nHere is your token.",
+		}
+		assert.HasNoError(t, mailer.Send(message))
+		assert.HasNoError(t, mailer.Send(message))
+
+		files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+		assert.HasNoError(t, err)
+		assert.HasLength(t, files, 2)
+
+		content, err := os.ReadFile(files[0])
+		assert.HasNoError(t, err)
+		assert.Equals(t, strings.Contains(string(content), "From: no-reply@email.com\r\n"), true)
+		assert.Equals(t, strings.Contains(string(content), "To: claude.aldric@email.com\r\n"), true)
+		assert.Equals(t, strings.Contains(string(content), "Subject: Verify your email\r\n"), true)
+		assert.Equals(t, strings.HasSuffix(string(content), "Hi Claude,\r\nHere is your token."), true)
+	})
+}
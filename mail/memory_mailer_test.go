@@ -0,0 +1,20 @@
+package mail
+
+import (
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestMemoryMailer(t *testing.T) {
+	t.Run("keeps the sent messages in order", func(t *testing.T) {
+		mailer := NewMemoryMailer()
+		first := Message{To: "a@email.com", Subject: "First", Body: "1"}
+		second := Message{To: "b@email.com", Subject: "Second", Body: "2"}
+
+		assert.HasNoError(t, mailer.Send(first))
+		assert.HasNoError(t, mailer.Send(second))
+
+		assert.Equals(t, mailer.Messages(), []Message{first, second})
+	})
+}
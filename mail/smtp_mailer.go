@@ -0,0 +1,43 @@
+package mail
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+)
+
+type SmtpMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSmtpMailer(addr string, auth smtp.Auth, from string) *SmtpMailer {
+	return &SmtpMailer{addr: addr, auth: auth, from: from}
+}
+
+func (m *SmtpMailer) Send(message Message) error {
+	err := smtp.SendMail(
+		m.addr,
+		m.auth,
+		m.from,
+		[]string{message.To},
+		formatMessage(m.from, message),
+	)
+	if err != nil {
+		return fmt.Errorf("error sending mail to %s: %w", message.To, err)
+	}
+	return nil
+}
+
+func formatMessage(from string, message Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", message.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", message.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
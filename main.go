@@ -1,35 +1,268 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
-
-	_ "github.com/mattn/go-sqlite3"
+	"net/smtp"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/claudealdric/go-todolist-restful-api-server/api"
+	"github.com/claudealdric/go-todolist-restful-api-server/backup"
+	"github.com/claudealdric/go-todolist-restful-api-server/config"
 	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/mail"
+	"github.com/claudealdric/go-todolist-restful-api-server/oidc"
 )
 
-const dbFilePath = "./data/data.db"
-const port = 8080
+const mailDir = "./data/mail"
 
 func main() {
-	db, err := sql.Open("sqlite3", dbFilePath)
+	if len(os.Args) > 1 && os.Args[1] == "migrate-data" {
+		if err := migrateData(context.Background(), os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "restore" {
+		err := restore(context.Background(), os.Args[2:], os.Stdout, time.Now())
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
+		log.Fatalf("problem loading the configuration: %v", err)
+	}
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatalf("problem printing the configuration: %v", err)
+		}
+		return
+	}
+	if err := run(cfg); err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
-	data.InitDb(db)
+}
+
+func run(cfg *config.Config) error {
+	slog.SetLogLoggerLevel(cfg.SlogLevel())
+
+	// Restoring a snapshot checks this lock so that it cannot replace the
+	// SQLite database under the running server.
+	if cfg.Backend == config.BackendSqlite || cfg.Backend == config.BackendEvents {
+		unlock, err := data.LockSqlite(cfg.Dsn)
+		if err != nil {
+			return fmt.Errorf("problem locking the database: %v", err)
+		}
+		defer unlock()
+	}
+
+	store, closeStore, err := openStore(cfg.Backend, cfg.Dsn, data.InitDb)
+	if err != nil {
+		return fmt.Errorf("problem creating the data store: %v", err)
+	}
+	defer closeStore()
+
+	ctx, stop := signal.NotifyContext(
+		context.Background(),
+		os.Interrupt,
+		syscall.SIGTERM,
+	)
+	defer stop()
+
+	mailer, err := newMailer()
+	if err != nil {
+		return fmt.Errorf("problem creating the mailer: %v", err)
+	}
 
-	store := data.NewSqliteStore(db)
+	jwtKey, err := newJwtKey(cfg)
 	if err != nil {
-		log.Fatalf("problem creating file system data store: %v", err)
+		return fmt.Errorf("problem creating the JWT key: %v", err)
+	}
+	options := []api.ServerOption{
+		api.WithMailer(mailer),
+		api.WithJwtKey(jwtKey),
+		api.WithAccessTokenTtl(cfg.Jwt.AccessTokenTtl),
+	}
+	if provider := newOidcProvider(); provider != nil {
+		options = append(options, api.WithOidcProvider(provider))
+	}
+	if source, ok := store.(backup.Source); ok {
+		backups := backup.NewManager(source, cfg.Backup.Dir, cfg.Backup.Keep)
+		options = append(options, api.WithBackups(backups))
+		if cfg.Backup.Interval > 0 {
+			go backups.Run(ctx, cfg.Backup.Interval)
+		}
 	}
 
-	server := api.NewServer(store)
-	log.Printf("Starting server on port %d", port)
-	err = http.ListenAndServe(fmt.Sprintf(":%d", port), server)
-	log.Fatal(err)
+	if cfg.Cache.Size > 0 {
+		cache := data.NewCachingStore(store, cfg.Cache.Size, cfg.Cache.Ttl)
+		defer func() { slog.Info("task cache", "stats", cache.Stats()) }()
+		store = cache
+	}
+
+	server := &http.Server{
+		Addr:         cfg.ListenAddr,
+		Handler:      api.NewServer(store, options...),
+		ReadTimeout:  cfg.Timeouts.Read,
+		WriteTimeout: cfg.Timeouts.Write,
+		IdleTimeout:  cfg.Timeouts.Idle,
+	}
+	errs := make(chan error, 1)
+	go func() {
+		slog.Info("starting server", "addr", cfg.ListenAddr, "backend", cfg.Backend)
+		errs <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	slog.Info("shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeouts.Shutdown)
+	defer cancel()
+	return server.Shutdown(ctx)
+}
+
+// openStore opens the store of the backend. initDb prepares SQLite databases,
+// so that migrate-data can create them without the seed data.
+func openStore(
+	backend, dsn string,
+	initDb func(*sql.DB),
+) (data.Store, func() error, error) {
+	switch backend {
+	case config.BackendPostgres:
+		db, err := data.OpenPostgres(context.Background(), dsn)
+		if err != nil {
+			return nil, nil, err
+		}
+		return data.NewPostgresStore(db), db.Close, nil
+
+	case config.BackendBolt:
+		store, err := data.NewBoltStore(dsn)
+		if err != nil {
+			return nil, nil, err
+		}
+		return store, store.Close, nil
+
+	case config.BackendFile:
+		file, err := os.OpenFile(dsn, os.O_RDWR|os.O_CREATE, 0o600)
+		if err != nil {
+			return nil, nil, err
+		}
+		store, err := data.NewFileSystemStore(file)
+		if err != nil {
+			file.Close()
+			return nil, nil, err
+		}
+		return store, func() error {
+			return errors.Join(store.Close(), file.Close())
+		}, nil
+
+	case config.BackendMemory:
+		var options []data.MemoryStoreOption
+		if dsn != "" {
+			options = append(options, data.WithSnapshot(dsn))
+		}
+		store, err := data.NewMemoryStore(options...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return store, store.Close, nil
+
+	case config.BackendEvents:
+		db, err := data.OpenSqlite(context.Background(), dsn)
+		if err != nil {
+			return nil, nil, err
+		}
+		initDb(db)
+		store, err := data.NewEventSourcedStore(context.Background(), db)
+		if err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		return store, func() error {
+			return errors.Join(store.Close(), db.Close())
+		}, nil
+
+	default:
+		db, err := data.OpenSqlite(context.Background(), dsn)
+		if err != nil {
+			return nil, nil, err
+		}
+		initDb(db)
+		store := data.NewSqliteStore(db)
+		return store, func() error {
+			return errors.Join(store.Close(), db.Close())
+		}, nil
+	}
+}
+
+// newJwtKey makes up a key when none is configured, which logs everyone out
+// whenever the server restarts.
+func newJwtKey(cfg *config.Config) ([]byte, error) {
+	if cfg.Jwt.Secret != "" {
+		return []byte(cfg.Jwt.Secret), nil
+	}
+	slog.Warn("no JWT secret is configured, so sessions end when the server stops")
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	return key, err
+}
+
+// newMailer sends mail through SMTP_ADDR when it is set and otherwise drops
+// each message as a file in mailDir for local development.
+func newMailer() (mail.Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+	addr := os.Getenv("SMTP_ADDR")
+	if addr == "" {
+		return mail.NewFileMailer(mailDir, from)
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	var auth smtp.Auth
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+	return mail.NewSmtpMailer(addr, auth, from), nil
+}
+
+// newOidcProvider enables signing in through an OpenID Connect identity
+// provider when OIDC_ISSUER is set.
+func newOidcProvider() *oidc.Provider {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return nil
+	}
+	return oidc.NewProvider(oidc.Config{
+		Issuer:       issuer,
+		ClientId:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectUrl:  os.Getenv("OIDC_REDIRECT_URL"),
+	})
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -15,11 +16,13 @@ import (
 	"github.com/claudealdric/go-todolist-restful-api-server/models"
 	"github.com/claudealdric/go-todolist-restful-api-server/testutils"
 	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestServerWithSqliteStore(t *testing.T) {
+	ctx := context.Background()
 	dbFile := "./tmp/data.db"
-	db, err := sql.Open("sqlite3", dbFile)
+	db, err := sql.Open(data.SqliteDriver, dbFile)
 	assert.HasNoError(t, err)
 	defer db.Close()
 	data.InitDb(db)
@@ -28,42 +31,107 @@ func TestServerWithSqliteStore(t *testing.T) {
 	server := api.NewServer(store)
 
 	t.Run("tasks", func(t *testing.T) {
-		createTaskDTO := models.NewCreateTaskDTO("Write integration tests")
-		createTaskResponse, err := sendPostTask(server, createTaskDTO)
+		// The seeded user owns the project the existing tasks were moved to.
+		token := login(t, server, "cvaldric@gmail.com", "Caput Draconis")
+		projectId := 1
+
+		createTaskDTO := models.NewCreateTaskDTO("Write integration tests", projectId)
+		createTaskResponse, err := sendPostTask(server, token, createTaskDTO)
 		assert.HasNoError(t, err)
 		createdTask := testutils.GetTaskFromResponse(t, createTaskResponse.Body)
-		wantedTask := models.NewTask(createdTask.Id, createTaskDTO.Title)
+		wantedTask := models.NewTask(createdTask.Id, createTaskDTO.Title, projectId)
 		assert.Equals(t, createdTask, wantedTask)
 
-		getTaskByIdResponse := sendGetTaskById(server, createdTask.Id)
+		getTaskByIdResponse := sendGetTaskById(server, token, createdTask.Id)
 		task := testutils.GetTaskFromResponse(t, getTaskByIdResponse.Body)
 		assert.Equals(t, task, wantedTask)
 
-		getTasksResponse := sendGetTasks(server)
+		getTasksResponse := sendGetTasks(server, token)
 		tasks := testutils.GetTasksFromResponse(t, getTasksResponse.Body)
 		assert.Contains(t, tasks, *wantedTask)
 
 		updatedTitle := "Profit"
 		updateTaskDTO := models.UpdateTaskDTO{Title: &updatedTitle}
-		patchTaskResponse, err := sendPatchTask(server, updateTaskDTO, createdTask.Id)
+		patchTaskResponse, err := sendPatchTask(server, token, updateTaskDTO, createdTask.Id)
 		assert.HasNoError(t, err)
 		task = testutils.GetTaskFromResponse(t, patchTaskResponse.Body)
-		wantedTask = models.NewTask(createdTask.Id, updatedTitle)
+		wantedTask = models.NewTask(createdTask.Id, updatedTitle, projectId)
 		assert.Equals(t, task, wantedTask)
 
-		sendDeleteTask(server, createdTask.Id)
+		sendDeleteTask(server, token, createdTask.Id)
 		unwantedTask := wantedTask
 
-		getTaskByIdResponse = sendGetTaskById(server, createdTask.Id)
+		getTaskByIdResponse = sendGetTaskById(server, token, createdTask.Id)
 		task = testutils.GetTaskFromResponse(t, getTaskByIdResponse.Body)
 		assert.Equals(t, task, nil)
 
-		getTasksResponse = sendGetTasks(server)
+		getTasksResponse = sendGetTasks(server, token)
 		tasks = testutils.GetTasksFromResponse(t, getTasksResponse.Body)
 		fmt.Println("tasks", tasks)
 		assert.DoesNotContain(t, tasks, *unwantedTask)
 	})
 
+	t.Run("projects", func(t *testing.T) {
+		ownerToken := login(t, server, "cvaldric@gmail.com", "Caput Draconis")
+		_, err := sendPostUser(server, models.NewCreateUserDTO(
+			"Watson",
+			"watson@email.com",
+			"elementary",
+		))
+		assert.HasNoError(t, err)
+		watsonToken := login(t, server, "watson@email.com", "elementary")
+
+		createTaskResponse, err := sendPostTask(
+			server,
+			ownerToken,
+			models.NewCreateTaskDTO("Buy milk", 1),
+		)
+		assert.HasNoError(t, err)
+		task := testutils.GetTaskFromResponse(t, createTaskResponse.Body)
+		response := sendGetTaskById(server, watsonToken, task.Id)
+		assert.Status(t, response.Code, http.StatusNotFound)
+
+		response = sendJson(
+			server,
+			ownerToken,
+			http.MethodPost,
+			"/projects/1/invitations",
+			models.CreateProjectInvitationDTO{
+				Email: "watson@email.com",
+				Role:  models.ProjectRoleViewer,
+			},
+		)
+		assert.Status(t, response.Code, http.StatusCreated)
+		invitation := models.ProjectInvitation{}
+		assert.HasNoError(t, json.NewDecoder(response.Body).Decode(&invitation))
+
+		watson, err := store.GetUserByEmail(ctx, "watson@email.com")
+		assert.HasNoError(t, err)
+		watson.Verified = true
+		_, err = store.UpdateUser(ctx, watson)
+		assert.HasNoError(t, err)
+		response = sendJson(
+			server,
+			watsonToken,
+			http.MethodPost,
+			fmt.Sprintf("/me/invitations/%d/accept", invitation.Id),
+			nil,
+		)
+		assert.Status(t, response.Code, http.StatusOK)
+
+		response = sendGetTaskById(server, watsonToken, task.Id)
+		assert.Status(t, response.Code, http.StatusOK)
+		title := "Buy oat milk"
+		response, err = sendPatchTask(
+			server,
+			watsonToken,
+			models.UpdateTaskDTO{Title: &title},
+			task.Id,
+		)
+		assert.HasNoError(t, err)
+		assert.Status(t, response.Code, http.StatusForbidden)
+	})
+
 	t.Run("users", func(t *testing.T) {
 		createUserDTO := models.NewCreateUserDTO(
 			"Sherlock",
@@ -77,9 +145,14 @@ func TestServerWithSqliteStore(t *testing.T) {
 			createdUser.Id,
 			createUserDTO.Name,
 			createdUser.Email,
-			createUserDTO.Password,
+			createdUser.Password,
 		)
 		assert.Equals(t, createdUser, *wantedUser)
+		err = bcrypt.CompareHashAndPassword(
+			[]byte(createdUser.Password),
+			[]byte(createUserDTO.Password),
+		)
+		assert.HasNoError(t, err)
 	})
 }
 
@@ -88,16 +161,34 @@ func TestServerWithFileSystemStore(t *testing.T) {
 	defer cleanDatabase()
 	store, err := data.NewFileSystemStore(dbFile)
 	assert.HasNoError(t, err)
+	defer store.Close()
 	server := api.NewServer(store)
 
+	_, err = sendPostUser(server, models.NewCreateUserDTO(
+		"Sherlock",
+		"sherlock@email.com",
+		"sherlocked",
+	))
+	assert.HasNoError(t, err)
+	token := login(t, server, "sherlock@email.com", "sherlocked")
+	response := sendJson(
+		server,
+		token,
+		http.MethodPost,
+		"/projects",
+		models.CreateProjectDTO{Name: "Home"},
+	)
+	assert.Status(t, response.Code, http.StatusCreated)
+	projectId := 1
+
 	initialTasks := []models.Task{
-		*models.NewTask(1, "Buy groceries"),
-		*models.NewTask(2, "Pack clothes"),
+		*models.NewTask(1, "Buy groceries", projectId),
+		*models.NewTask(2, "Pack clothes", projectId),
 	}
 
 	for _, task := range initialTasks {
-		dto := models.NewCreateTaskDTO(task.Title)
-		_, err := sendPostTask(server, dto)
+		dto := models.NewCreateTaskDTO(task.Title, projectId)
+		_, err := sendPostTask(server, token, dto)
 		assert.HasNoError(t, err)
 	}
 
@@ -116,7 +207,7 @@ func TestServerWithFileSystemStore(t *testing.T) {
 	})
 
 	t.Run("returns a slice of tasks with GET `/tasks`", func(t *testing.T) {
-		response := sendGetTasks(server)
+		response := sendGetTasks(server, token)
 		assert.Status(t, response.Code, http.StatusOK)
 		tasks := testutils.GetTasksFromResponse(t, response.Body)
 		assert.Equals(t, tasks, initialTasks)
@@ -124,95 +215,79 @@ func TestServerWithFileSystemStore(t *testing.T) {
 
 	t.Run("returns the correct task with GET `/tasks/{id}`", func(t *testing.T) {
 		wantedTask := initialTasks[1]
-		request := httptest.NewRequest(
-			http.MethodGet,
-			fmt.Sprintf("/tasks/%d", wantedTask.Id),
-			nil,
-		)
-		response := httptest.NewRecorder()
-		server.ServeHTTP(response, request)
+		response := sendGetTaskById(server, token, wantedTask.Id)
 		assert.Status(t, response.Code, http.StatusOK)
 		got := testutils.GetTaskFromResponse(t, response.Body)
 		assert.Equals(t, *got, wantedTask)
 	})
 
 	t.Run("deletes the task with DELETE `/tasks/{id}`", func(t *testing.T) {
-		newTaskDto := models.NewCreateTaskDTO("Cook food")
-		postResponse, err := sendPostTask(server, newTaskDto)
+		newTaskDto := models.NewCreateTaskDTO("Cook food", projectId)
+		postResponse, err := sendPostTask(server, token, newTaskDto)
 		assert.HasNoError(t, err)
 		newTask := testutils.GetTaskFromResponse(t, postResponse.Body)
 
-		deleteRequest := httptest.NewRequest(
-			http.MethodDelete,
-			fmt.Sprintf("/tasks/%d", newTask.Id),
-			nil,
-		)
-		deleteResponse := httptest.NewRecorder()
-		server.ServeHTTP(deleteResponse, deleteRequest)
+		deleteResponse := sendDeleteTask(server, token, newTask.Id)
 		assert.Status(t, deleteResponse.Code, http.StatusNoContent)
 
-		getResponse := sendGetTasks(server)
+		getResponse := sendGetTasks(server, token)
 		tasks := testutils.GetTasksFromResponse(t, getResponse.Body)
 		assert.DoesNotContain(t, tasks, *newTask)
 	})
 
 	t.Run("updates the task with PATCH `/tasks/{id}`", func(t *testing.T) {
-		newTaskDto := models.NewCreateTaskDTO("Walk the dog")
-		postResponse, err := sendPostTask(server, newTaskDto)
+		newTaskDto := models.NewCreateTaskDTO("Walk the dog", projectId)
+		postResponse, err := sendPostTask(server, token, newTaskDto)
 		assert.HasNoError(t, err)
 
 		taskId := testutils.GetTaskFromResponse(t, postResponse.Body).Id
 
 		newTitle := "Walk the cat"
 		updateTaskDTO := models.UpdateTaskDTO{Title: &newTitle}
-		patchResponse, err := sendPatchTask(server, updateTaskDTO, taskId)
+		patchResponse, err := sendPatchTask(server, token, updateTaskDTO, taskId)
 		assert.HasNoError(t, err)
 
-		wantedTask := models.NewTask(taskId, *updateTaskDTO.Title)
+		wantedTask := models.NewTask(taskId, *updateTaskDTO.Title, projectId)
 
 		updatedTask := testutils.GetTaskFromResponse(t, patchResponse.Body)
 		assert.Status(t, patchResponse.Code, http.StatusOK)
 		assert.Equals(t, updatedTask, wantedTask)
 
-		getResponse := sendGetTaskById(server, taskId)
+		getResponse := sendGetTaskById(server, token, taskId)
 		task := testutils.GetTaskFromResponse(t, getResponse.Body)
 		assert.Equals(t, task, wantedTask)
 	})
 }
 
-func sendGetTaskById(server *api.Server, id int) *httptest.ResponseRecorder {
-	request := httptest.NewRequest(
-		http.MethodGet,
-		fmt.Sprintf("/tasks/%d", id),
-		nil,
-	)
-	response := httptest.NewRecorder()
-	server.ServeHTTP(response, request)
-	return response
+func sendGetTaskById(
+	server *api.Server,
+	token string,
+	id int,
+) *httptest.ResponseRecorder {
+	return sendJson(server, token, http.MethodGet, fmt.Sprintf("/tasks/%d", id), nil)
 }
 
 func sendDeleteTask(
 	server *api.Server,
+	token string,
 	taskId int,
-) {
-	request := httptest.NewRequest(
+) *httptest.ResponseRecorder {
+	return sendJson(
+		server,
+		token,
 		http.MethodDelete,
 		fmt.Sprintf("/tasks/%d", taskId),
 		nil,
 	)
-	response := httptest.NewRecorder()
-	server.ServeHTTP(response, request)
 }
 
-func sendGetTasks(server *api.Server) *httptest.ResponseRecorder {
-	request := httptest.NewRequest(http.MethodGet, "/tasks", nil)
-	response := httptest.NewRecorder()
-	server.ServeHTTP(response, request)
-	return response
+func sendGetTasks(server *api.Server, token string) *httptest.ResponseRecorder {
+	return sendJson(server, token, http.MethodGet, "/tasks", nil)
 }
 
 func sendPatchTask(
 	server *api.Server,
+	token string,
 	DTO models.UpdateTaskDTO,
 	taskId int,
 ) (
@@ -228,12 +303,17 @@ func sendPatchTask(
 		fmt.Sprintf("/tasks/%d", taskId),
 		bytes.NewBuffer(jsonBody),
 	)
+	request.Header.Set("Authorization", "Bearer "+token)
 	response := httptest.NewRecorder()
 	server.ServeHTTP(response, request)
 	return response, nil
 }
 
-func sendPostTask(server *api.Server, dto *models.CreateTaskDTO) (
+func sendPostTask(
+	server *api.Server,
+	token string,
+	dto *models.CreateTaskDTO,
+) (
 	*httptest.ResponseRecorder,
 	error,
 ) {
@@ -246,11 +326,43 @@ func sendPostTask(server *api.Server, dto *models.CreateTaskDTO) (
 		"/tasks",
 		bytes.NewBuffer(jsonBody),
 	)
+	request.Header.Set("Authorization", "Bearer "+token)
 	response := httptest.NewRecorder()
 	server.ServeHTTP(response, request)
 	return response, nil
 }
 
+func sendJson(
+	server *api.Server,
+	token, method, path string,
+	body any,
+) *httptest.ResponseRecorder {
+	var buffer bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buffer).Encode(body)
+	}
+	request := httptest.NewRequest(method, path, &buffer)
+	request.Header.Set("Authorization", "Bearer "+token)
+	response := httptest.NewRecorder()
+	server.ServeHTTP(response, request)
+	return response
+}
+
+func login(t *testing.T, server *api.Server, email, password string) string {
+	t.Helper()
+	response := sendJson(
+		server,
+		"",
+		http.MethodPost,
+		"/login",
+		api.LoginCredentials{Email: email, Password: password},
+	)
+	assert.Status(t, response.Code, http.StatusOK)
+	var body api.LoginResponse
+	assert.HasNoError(t, json.NewDecoder(response.Body).Decode(&body))
+	return body.AccessToken
+}
+
 func sendPostUser(server *api.Server, dto *models.CreateUserDTO) (
 	*httptest.ResponseRecorder,
 	error,
@@ -0,0 +1,618 @@
+// Package migrate copies the data of one store into another, keeping the IDs,
+// password hashes and secrets, so that a server can move to another backend.
+// Only the failed login counters are left behind, which lets clients that
+// were locked out try again. The task history of the events backend is not
+// copied either; the target starts out with the tasks as they are.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+)
+
+// Source is a store that data can be copied from.
+type Source interface {
+	data.Store
+	data.Exporter
+}
+
+// Target is a store that data can be copied into.
+type Target interface {
+	Source
+	data.Importer
+}
+
+const (
+	KindUsers              = "users"
+	KindUserMfa            = "MFA settings"
+	KindUserTokens         = "user tokens"
+	KindApiTokens          = "API tokens"
+	KindProjects           = "projects"
+	KindProjectMembers     = "project members"
+	KindProjectInvitations = "project invitations"
+	KindTasks              = "tasks"
+	KindTaskRevisions      = "task revisions"
+	KindAuditEvents        = "audit events"
+)
+
+// Stats counts the records of one kind that Copy wrote, those it found
+// already copied by an earlier run and those it deleted from the target
+// because they are gone from the source.
+type Stats struct {
+	Kind      string
+	Copied    int
+	Unchanged int
+	Deleted   int
+}
+
+// Checksum sums up the records of one kind in a store.
+type Checksum struct {
+	Kind  string
+	Count int
+	Sum   string
+}
+
+type snapshot struct {
+	users       []models.User
+	userMfa     []models.UserMfa
+	userTokens  []models.UserToken
+	apiTokens   []models.ApiToken
+	projects    []models.Project
+	members     []models.ProjectMember
+	invitations []models.ProjectInvitation
+	tasks       []models.Task
+	revisions   []models.TaskRevision
+	auditEvents []models.AuditEvent
+
+	// recordsRevisions is false for stores that do not keep task revisions.
+	recordsRevisions bool
+}
+
+type collection interface {
+	kind() string
+	missing(source, target *snapshot) int
+	deleteMissing(ctx context.Context, source, target *snapshot, to Target) (int, error)
+	copy(ctx context.Context, source, target *snapshot, to Target) (Stats, error)
+	checksum(s *snapshot) (Checksum, error)
+}
+
+type keyedCollection[T any] struct {
+	name    string
+	records func(*snapshot) []T
+	key     func(T) string
+	put     func(ctx context.Context, to Target, record *T) error
+	// delete is nil for the records that only go away along with the records
+	// they belong to, or never.
+	delete func(ctx context.Context, to Target, record *T) error
+	// normalize evens out how stores differ in keeping the same record, such
+	// as the precision of their timestamps.
+	normalize func(T) T
+}
+
+// collections are in the order they are copied in, so that every record is
+// copied after the records it refers to. Deleting goes the other way.
+var collections = []collection{
+	keyedCollection[models.User]{
+		name:    KindUsers,
+		records: func(s *snapshot) []models.User { return s.users },
+		key:     func(u models.User) string { return strconv.Itoa(u.Id) },
+		put: func(ctx context.Context, to Target, u *models.User) error {
+			return to.ImportUser(ctx, u)
+		},
+		delete: func(ctx context.Context, to Target, u *models.User) error {
+			return to.DeleteUserById(ctx, u.Id)
+		},
+		normalize: func(u models.User) models.User {
+			u.PasswordChangedAt = normalizeTimePointer(u.PasswordChangedAt)
+			return u
+		},
+	},
+	keyedCollection[models.UserMfa]{
+		name:    KindUserMfa,
+		records: func(s *snapshot) []models.UserMfa { return s.userMfa },
+		key:     func(m models.UserMfa) string { return strconv.Itoa(m.UserId) },
+		put: func(ctx context.Context, to Target, m *models.UserMfa) error {
+			return to.SaveUserMfa(ctx, m)
+		},
+		delete: func(ctx context.Context, to Target, m *models.UserMfa) error {
+			return to.DeleteUserMfa(ctx, m.UserId)
+		},
+	},
+	keyedCollection[models.UserToken]{
+		name:    KindUserTokens,
+		records: func(s *snapshot) []models.UserToken { return s.userTokens },
+		key:     func(t models.UserToken) string { return t.Hash },
+		put: func(ctx context.Context, to Target, t *models.UserToken) error {
+			return to.CreateUserToken(ctx, t)
+		},
+		// The other tokens of the user for the purpose go as well, and are
+		// copied again right after.
+		delete: func(ctx context.Context, to Target, t *models.UserToken) error {
+			return to.DeleteUserTokens(ctx, t.UserId, t.Purpose)
+		},
+		normalize: func(t models.UserToken) models.UserToken {
+			t.ExpiresAt = normalizeTime(t.ExpiresAt)
+			return t
+		},
+	},
+	keyedCollection[models.ApiToken]{
+		name:    KindApiTokens,
+		records: func(s *snapshot) []models.ApiToken { return s.apiTokens },
+		key:     func(t models.ApiToken) string { return strconv.Itoa(t.Id) },
+		put: func(ctx context.Context, to Target, t *models.ApiToken) error {
+			return to.ImportApiToken(ctx, t)
+		},
+		delete: func(ctx context.Context, to Target, t *models.ApiToken) error {
+			return to.DeleteApiToken(ctx, t.Id, t.UserId)
+		},
+		normalize: func(t models.ApiToken) models.ApiToken {
+			if len(t.Scopes) == 0 {
+				t.Scopes = nil
+			}
+			t.ExpiresAt = normalizeTimePointer(t.ExpiresAt)
+			t.LastUsedAt = normalizeTimePointer(t.LastUsedAt)
+			t.CreatedAt = normalizeTime(t.CreatedAt)
+			return t
+		},
+	},
+	// Projects are deleted along with the last user in them.
+	keyedCollection[models.Project]{
+		name:    KindProjects,
+		records: func(s *snapshot) []models.Project { return s.projects },
+		key:     func(p models.Project) string { return strconv.Itoa(p.Id) },
+		put: func(ctx context.Context, to Target, p *models.Project) error {
+			return to.ImportProject(ctx, p)
+		},
+	},
+	keyedCollection[models.ProjectMember]{
+		name:    KindProjectMembers,
+		records: func(s *snapshot) []models.ProjectMember { return s.members },
+		key: func(m models.ProjectMember) string {
+			return fmt.Sprintf("%d:%d", m.ProjectId, m.UserId)
+		},
+		put: func(ctx context.Context, to Target, m *models.ProjectMember) error {
+			return to.SaveProjectMember(ctx, m)
+		},
+		delete: func(ctx context.Context, to Target, m *models.ProjectMember) error {
+			return to.DeleteProjectMember(ctx, m.ProjectId, m.UserId)
+		},
+	},
+	keyedCollection[models.ProjectInvitation]{
+		name:    KindProjectInvitations,
+		records: func(s *snapshot) []models.ProjectInvitation { return s.invitations },
+		key:     func(i models.ProjectInvitation) string { return strconv.Itoa(i.Id) },
+		put: func(ctx context.Context, to Target, i *models.ProjectInvitation) error {
+			return to.ImportProjectInvitation(ctx, i)
+		},
+		delete: func(ctx context.Context, to Target, i *models.ProjectInvitation) error {
+			return to.DeleteProjectInvitation(ctx, i.Id)
+		},
+		normalize: func(i models.ProjectInvitation) models.ProjectInvitation {
+			i.CreatedAt = normalizeTime(i.CreatedAt)
+			return i
+		},
+	},
+	keyedCollection[models.Task]{
+		name:    KindTasks,
+		records: func(s *snapshot) []models.Task { return s.tasks },
+		key:     func(t models.Task) string { return strconv.Itoa(t.Id) },
+		put: func(ctx context.Context, to Target, t *models.Task) error {
+			return to.ImportTask(ctx, t)
+		},
+		delete: func(ctx context.Context, to Target, t *models.Task) error {
+			return to.DeleteTaskById(ctx, t.Id)
+		},
+	},
+	// Revisions are deleted along with their task.
+	keyedCollection[models.TaskRevision]{
+		name:    KindTaskRevisions,
+		records: func(s *snapshot) []models.TaskRevision { return s.revisions },
+		key: func(r models.TaskRevision) string {
+			return fmt.Sprintf("%d:%d", r.TaskId, r.Revision)
+		},
+		put: func(ctx context.Context, to Target, r *models.TaskRevision) error {
+			return to.ImportTaskRevision(ctx, r)
+		},
+		normalize: func(r models.TaskRevision) models.TaskRevision {
+			if len(r.ChangedFields) == 0 {
+				r.ChangedFields = nil
+			}
+			r.CreatedAt = normalizeTime(r.CreatedAt)
+			return r
+		},
+	},
+	// The audit log is only ever added to.
+	keyedCollection[models.AuditEvent]{
+		name:    KindAuditEvents,
+		records: func(s *snapshot) []models.AuditEvent { return s.auditEvents },
+		key:     func(e models.AuditEvent) string { return strconv.Itoa(e.Id) },
+		put: func(ctx context.Context, to Target, e *models.AuditEvent) error {
+			return to.ImportAuditEvent(ctx, e)
+		},
+		normalize: func(e models.AuditEvent) models.AuditEvent {
+			e.CreatedAt = normalizeTime(e.CreatedAt)
+			return e
+		},
+	},
+}
+
+// ErrPruneNeeded is returned by Copy when the target holds records that are
+// gone from the source and pruning them was not asked for.
+var ErrPruneNeeded = errors.New("the target holds records the source does not")
+
+// CopyOption changes how Copy treats the target.
+type CopyOption func(*copyOptions)
+
+type copyOptions struct {
+	prune bool
+}
+
+// WithPrune lets Copy delete the records of the target that are gone from the
+// source. Deleting a user deletes everything that belongs to them, so Copy
+// refuses to delete anything without it.
+func WithPrune() CopyOption {
+	return func(o *copyOptions) {
+		o.prune = true
+	}
+}
+
+// Copy writes the records of from into to, skipping those that are already
+// there as they are. A Copy that was interrupted can so simply be run again,
+// and running it again right before switching backends catches up with what
+// changed in from in the meantime; WithPrune lets it delete what is gone from
+// from as well. Copy refuses to start when to cannot keep everything from
+// holds, or holds records from does not and pruning was not asked for.
+func Copy(
+	ctx context.Context,
+	from Source,
+	to Target,
+	options ...CopyOption,
+) ([]Stats, error) {
+	var opts copyOptions
+	for _, option := range options {
+		option(&opts)
+	}
+	source, err := read(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("problem reading the source, %v", err)
+	}
+	target, err := read(ctx, to)
+	if err != nil {
+		return nil, fmt.Errorf("problem reading the target, %v", err)
+	}
+	if len(source.revisions) > 0 && !target.recordsRevisions {
+		return nil, fmt.Errorf(
+			"the target cannot keep the %d %s of the source",
+			len(source.revisions),
+			KindTaskRevisions,
+		)
+	}
+	if !opts.prune {
+		var missing []string
+		for _, c := range collections {
+			if n := c.missing(source, target); n > 0 {
+				missing = append(missing, fmt.Sprintf("%d %s", n, c.kind()))
+			}
+		}
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("%w: %s", ErrPruneNeeded, strings.Join(missing, ", "))
+		}
+	}
+
+	deleted := make([]int, len(collections))
+	for i := len(collections) - 1; i >= 0; i-- {
+		deleted[i], err = collections[i].deleteMissing(ctx, source, target, to)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// Deleting a record also deletes the records that belong to it.
+	if slices.ContainsFunc(deleted, func(n int) bool { return n > 0 }) {
+		target, err = read(ctx, to)
+		if err != nil {
+			return nil, fmt.Errorf("problem reading the target, %v", err)
+		}
+	}
+
+	var stats []Stats
+	for i, c := range collections {
+		s, err := c.copy(ctx, source, target, to)
+		s.Deleted = deleted[i]
+		stats = append(stats, s)
+		if err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+func (c keyedCollection[T]) kind() string {
+	return c.name
+}
+
+// missing counts the records of the target that deleteMissing deletes.
+func (c keyedCollection[T]) missing(source, target *snapshot) int {
+	if c.delete == nil {
+		return 0
+	}
+	kept := c.keys(source)
+	n := 0
+	for _, record := range c.records(target) {
+		if !kept[c.key(record)] {
+			n++
+		}
+	}
+	return n
+}
+
+func (c keyedCollection[T]) deleteMissing(
+	ctx context.Context,
+	source, target *snapshot,
+	to Target,
+) (int, error) {
+	if c.delete == nil {
+		return 0, nil
+	}
+	kept := c.keys(source)
+	deleted := 0
+	for _, record := range c.records(target) {
+		if kept[c.key(record)] {
+			continue
+		}
+		err := c.delete(ctx, to, &record)
+		if err != nil && !errors.Is(err, data.ErrResourceNotFound) {
+			return deleted, fmt.Errorf("problem deleting %s, %v", c.name, err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+func (c keyedCollection[T]) keys(s *snapshot) map[string]bool {
+	keys := map[string]bool{}
+	for _, record := range c.records(s) {
+		keys[c.key(record)] = true
+	}
+	return keys
+}
+
+func (c keyedCollection[T]) copy(
+	ctx context.Context,
+	source, target *snapshot,
+	to Target,
+) (Stats, error) {
+	stats := Stats{Kind: c.name}
+	existing := map[string]string{}
+	for _, record := range c.records(target) {
+		encoded, err := c.encode(record)
+		if err != nil {
+			return stats, err
+		}
+		existing[c.key(record)] = encoded
+	}
+	for _, record := range c.records(source) {
+		encoded, err := c.encode(record)
+		if err != nil {
+			return stats, err
+		}
+		if existing[c.key(record)] == encoded {
+			stats.Unchanged++
+			continue
+		}
+		if err := c.put(ctx, to, &record); err != nil {
+			return stats, fmt.Errorf("problem copying %s, %v", c.name, err)
+		}
+		stats.Copied++
+	}
+	return stats, nil
+}
+
+func (c keyedCollection[T]) encode(record T) (string, error) {
+	if c.normalize != nil {
+		record = c.normalize(record)
+	}
+	encoded, err := json.Marshal(record)
+	return string(encoded), err
+}
+
+func (c keyedCollection[T]) checksum(s *snapshot) (Checksum, error) {
+	hash := sha256.New()
+	records := c.records(s)
+	for _, record := range records {
+		encoded, err := c.encode(record)
+		if err != nil {
+			return Checksum{}, err
+		}
+		hash.Write([]byte(encoded + "\n"))
+	}
+	return Checksum{
+		Kind:  c.name,
+		Count: len(records),
+		Sum:   hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+// Postgres keeps timestamps to the microsecond.
+func normalizeTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+func normalizeTimePointer(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	normalized := normalizeTime(*t)
+	return &normalized
+}
+
+// Checksums sums up the records Copy copies, in the same way for every
+// store.
+func Checksums(ctx context.Context, store Source) ([]Checksum, error) {
+	s, err := read(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	var checksums []Checksum
+	for _, c := range collections {
+		checksum, err := c.checksum(s)
+		if err != nil {
+			return nil, err
+		}
+		checksums = append(checksums, checksum)
+	}
+	return checksums, nil
+}
+
+// Verify reports the kinds of records that differ between the stores.
+func Verify(ctx context.Context, from, to Source) error {
+	want, err := Checksums(ctx, from)
+	if err != nil {
+		return fmt.Errorf("problem reading the source, %v", err)
+	}
+	got, err := Checksums(ctx, to)
+	if err != nil {
+		return fmt.Errorf("problem reading the target, %v", err)
+	}
+	var errs []error
+	for i := range want {
+		if got[i] != want[i] {
+			errs = append(errs, fmt.Errorf(
+				"the source has %d %s with checksum %s but the target %d with %s",
+				want[i].Count,
+				want[i].Kind,
+				want[i].Sum,
+				got[i].Count,
+				got[i].Sum,
+			))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// read gathers the records in the same order from every store. Stores cannot
+// list their projects, but every project has members, so the projects are
+// found through the users.
+func read(ctx context.Context, store Source) (*snapshot, error) {
+	var s snapshot
+	var err error
+	s.users, err = store.GetUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range s.users {
+		mfa, err := store.GetUserMfa(ctx, user.Id)
+		if err == nil {
+			s.userMfa = append(s.userMfa, *mfa)
+		} else if !errors.Is(err, data.ErrResourceNotFound) {
+			return nil, err
+		}
+
+		tokens, err := store.GetApiTokensByUserId(ctx, user.Id)
+		if err != nil {
+			return nil, err
+		}
+		s.apiTokens = append(s.apiTokens, tokens...)
+
+		members, err := store.GetProjectMembersByUserId(ctx, user.Id)
+		if err != nil {
+			return nil, err
+		}
+		s.members = append(s.members, members...)
+	}
+	slices.SortFunc(s.apiTokens, func(a, b models.ApiToken) int {
+		return a.Id - b.Id
+	})
+	slices.SortFunc(s.members, func(a, b models.ProjectMember) int {
+		if a.ProjectId != b.ProjectId {
+			return a.ProjectId - b.ProjectId
+		}
+		return a.UserId - b.UserId
+	})
+
+	s.userTokens, err = store.GetUserTokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(s.userTokens, func(a, b models.UserToken) int {
+		return strings.Compare(a.Hash, b.Hash)
+	})
+
+	for i, member := range s.members {
+		if i > 0 && s.members[i-1].ProjectId == member.ProjectId {
+			continue
+		}
+		project, err := store.GetProjectById(ctx, member.ProjectId)
+		if errors.Is(err, data.ErrResourceNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.projects = append(s.projects, *project)
+	}
+
+	s.invitations, err = store.GetProjectInvitations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(s.invitations, func(a, b models.ProjectInvitation) int {
+		return a.Id - b.Id
+	})
+
+	s.tasks, err = store.GetTasks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.revisions, s.recordsRevisions, err = readTaskRevisions(ctx, store, s.tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditEvents, err = store.GetAuditEvents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// readTaskRevisions also reports whether the store keeps the revisions at
+// all.
+func readTaskRevisions(
+	ctx context.Context,
+	store Source,
+	tasks []models.Task,
+) ([]models.TaskRevision, bool, error) {
+	reviser, ok := store.(data.TaskReviser)
+	if !ok {
+		return nil, false, nil
+	}
+	// Asking for the revisions of a task that does not exist tells the
+	// stores that keep them apart from those that do not, even without tasks.
+	_, err := reviser.GetTaskRevisions(ctx, 0)
+	if errors.Is(err, data.ErrTaskRevisionsNotRecorded) {
+		return nil, false, nil
+	}
+	if err != nil && !errors.Is(err, data.ErrResourceNotFound) {
+		return nil, false, err
+	}
+	var revisions []models.TaskRevision
+	for _, task := range tasks {
+		taskRevisions, err := reviser.GetTaskRevisions(ctx, task.Id)
+		if err != nil {
+			return nil, false, err
+		}
+		revisions = append(revisions, taskRevisions...)
+	}
+	return revisions, true, nil
+}
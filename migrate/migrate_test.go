@@ -0,0 +1,237 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("copies the records with their IDs, password hashes and secrets", func(t *testing.T) {
+		source := newSource(t)
+		target := newTarget(t)
+
+		stats, err := Copy(ctx, source, target)
+		assert.HasNoError(t, err)
+		assert.Equals(t, stats, []Stats{
+			{Kind: KindUsers, Copied: 2},
+			{Kind: KindUserMfa, Copied: 1},
+			{Kind: KindUserTokens, Copied: 1},
+			{Kind: KindApiTokens, Copied: 1},
+			{Kind: KindProjects, Copied: 1},
+			{Kind: KindProjectMembers, Copied: 2},
+			{Kind: KindProjectInvitations, Copied: 1},
+			{Kind: KindTasks, Copied: 2},
+			{Kind: KindTaskRevisions},
+			{Kind: KindAuditEvents, Copied: 1},
+		})
+		assert.HasNoError(t, Verify(ctx, source, target))
+
+		users, err := source.GetUsers(ctx)
+		assert.HasNoError(t, err)
+		copiedUsers, err := target.GetUsers(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, copiedUsers, users)
+		assert.Equals(t, target.ValidateUserCredentials(ctx, "ron@hogwarts.edu", "password"), true)
+		mfa, err := target.GetUserMfa(ctx, 1)
+		assert.HasNoError(t, err)
+		assert.Equals(t, mfa.TotpSecret, "secret")
+		token, err := target.GetApiTokenByHash(ctx, "api-token-hash")
+		assert.HasNoError(t, err)
+		assert.Equals(t, token.Scopes, []string{models.ApiTokenScopeReadTasks})
+	})
+
+	t.Run("only copies what changed since the last run", func(t *testing.T) {
+		source := newSource(t)
+		target := newTarget(t)
+		_, err := Copy(ctx, source, target)
+		assert.HasNoError(t, err)
+
+		_, err = source.UpdateTask(ctx, models.NewTask(2, "Catch the snitch", 1))
+		assert.HasNoError(t, err)
+		_, err = source.CreateTask(ctx, models.NewCreateTaskDTO("Win the cup", 1))
+		assert.HasNoError(t, err)
+
+		stats, err := Copy(ctx, source, target)
+		assert.HasNoError(t, err)
+		assert.Equals(t, stats[7], Stats{Kind: KindTasks, Copied: 2, Unchanged: 1})
+		assert.Equals(t, stats[8], Stats{Kind: KindTaskRevisions, Copied: 1})
+		assert.HasNoError(t, Verify(ctx, source, target))
+	})
+
+	t.Run("prunes what is gone from the source only when asked to", func(t *testing.T) {
+		source := newSource(t)
+		target := newTarget(t)
+		_, err := Copy(ctx, source, target)
+		assert.HasNoError(t, err)
+
+		assert.HasNoError(t, source.DeleteUserMfa(ctx, 1))
+		assert.HasNoError(t, source.DeleteUserTokens(ctx, 1, models.UserTokenPurposePasswordReset))
+		assert.HasNoError(t, source.DeleteApiToken(ctx, 1, 1))
+		assert.HasNoError(t, source.DeleteProjectMember(ctx, 1, 2))
+		assert.HasNoError(t, source.DeleteProjectInvitation(ctx, 1))
+		assert.HasNoError(t, source.DeleteTaskById(ctx, 1))
+		before, err := Checksums(ctx, target)
+		assert.HasNoError(t, err)
+
+		_, err = Copy(ctx, source, target)
+		assert.ErrorContains(t, err, ErrPruneNeeded)
+		assert.Equals(
+			t,
+			err.Error(),
+			"the target holds records the source does not: 1 MFA settings, 1 user tokens, 1 API tokens, 1 project members, 1 project invitations, 1 tasks",
+		)
+		after, err := Checksums(ctx, target)
+		assert.HasNoError(t, err)
+		assert.Equals(t, after, before)
+
+		stats, err := Copy(ctx, source, target, WithPrune())
+		assert.HasNoError(t, err)
+		assert.Equals(t, stats, []Stats{
+			{Kind: KindUsers, Unchanged: 2},
+			{Kind: KindUserMfa, Deleted: 1},
+			{Kind: KindUserTokens, Deleted: 1},
+			{Kind: KindApiTokens, Deleted: 1},
+			{Kind: KindProjects, Unchanged: 1},
+			{Kind: KindProjectMembers, Unchanged: 1, Deleted: 1},
+			{Kind: KindProjectInvitations, Deleted: 1},
+			{Kind: KindTasks, Unchanged: 1, Deleted: 1},
+			{Kind: KindTaskRevisions},
+			{Kind: KindAuditEvents, Unchanged: 1},
+		})
+		assert.HasNoError(t, Verify(ctx, source, target))
+	})
+
+	t.Run("refuses targets that cannot keep the task revisions", func(t *testing.T) {
+		source := newSource(t)
+		_, err := source.UpdateTask(ctx, models.NewTask(2, "Catch the snitch", 1))
+		assert.HasNoError(t, err)
+		target, err := data.NewBoltStore(filepath.Join(t.TempDir(), "data.bolt"))
+		assert.HasNoError(t, err)
+		t.Cleanup(func() { target.Close() })
+
+		_, err = Copy(ctx, source, target)
+		assert.HasError(t, err)
+		assert.Equals(t, err.Error(), "the target cannot keep the 1 task revisions of the source")
+		users, err := target.GetUsers(ctx)
+		assert.HasNoError(t, err)
+		assert.HasLength(t, users, 0)
+	})
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("reports the kinds of records that differ", func(t *testing.T) {
+		source := newSource(t)
+		target := newTarget(t)
+		_, err := Copy(ctx, source, target)
+		assert.HasNoError(t, err)
+
+		_, err = target.CreateTask(ctx, models.NewCreateTaskDTO("Lose the cup", 1))
+		assert.HasNoError(t, err)
+
+		err = Verify(ctx, source, target)
+		assert.HasError(t, err)
+		assert.Equals(
+			t,
+			err.Error()[:len("the source has 2 tasks")],
+			"the source has 2 tasks",
+		)
+	})
+
+	t.Run("passes for empty stores", func(t *testing.T) {
+		source, err := data.NewMemoryStore()
+		assert.HasNoError(t, err)
+		assert.HasNoError(t, Verify(ctx, source, newTarget(t)))
+	})
+}
+
+// newSource returns a store with two users sharing a project with two tasks.
+func newSource(t *testing.T) *data.MemoryStore {
+	t.Helper()
+	ctx := context.Background()
+	store, err := data.NewMemoryStore()
+	assert.HasNoError(t, err)
+
+	harry, err := store.CreateUser(
+		ctx,
+		models.NewCreateUserDTO("Harry Potter", "harry@hogwarts.edu", "password"),
+	)
+	assert.HasNoError(t, err)
+	ron, err := store.CreateUser(
+		ctx,
+		models.NewCreateUserDTO("Ron Weasley", "ron@hogwarts.edu", "password"),
+	)
+	assert.HasNoError(t, err)
+	project, err := store.CreateProject(
+		ctx,
+		&models.CreateProjectDTO{Name: "Quidditch"},
+		harry.Id,
+	)
+	assert.HasNoError(t, err)
+	err = store.SaveProjectMember(ctx, &models.ProjectMember{
+		ProjectId: project.Id,
+		UserId:    ron.Id,
+		Role:      models.ProjectRoleEditor,
+	})
+	assert.HasNoError(t, err)
+	for _, title := range []string{"Practice", "Find the snitch"} {
+		_, err := store.CreateTask(ctx, models.NewCreateTaskDTO(title, project.Id))
+		assert.HasNoError(t, err)
+	}
+
+	err = store.SaveUserMfa(ctx, &models.UserMfa{
+		UserId:     harry.Id,
+		TotpSecret: "secret",
+		Enabled:    true,
+	})
+	assert.HasNoError(t, err)
+	err = store.CreateUserToken(ctx, models.NewUserToken(
+		"reset-token-hash",
+		harry.Id,
+		models.UserTokenPurposePasswordReset,
+		time.Now().Add(time.Hour),
+	))
+	assert.HasNoError(t, err)
+	_, err = store.CreateApiToken(ctx, &models.ApiToken{
+		UserId:    harry.Id,
+		Name:      "Scoreboard",
+		Hash:      "api-token-hash",
+		Scopes:    []string{models.ApiTokenScopeReadTasks},
+		CreatedAt: time.Now(),
+	})
+	assert.HasNoError(t, err)
+	_, err = store.CreateProjectInvitation(ctx, &models.ProjectInvitation{
+		ProjectId: project.Id,
+		Email:     "ginny@hogwarts.edu",
+		Role:      models.ProjectRoleViewer,
+		InvitedBy: harry.Id,
+		CreatedAt: time.Now(),
+	})
+	assert.HasNoError(t, err)
+	_, err = store.CreateAuditEvent(
+		ctx,
+		models.NewAuditEvent("project.invite", harry.Id, "project:1", "ginny@hogwarts.edu"),
+	)
+	assert.HasNoError(t, err)
+	return store
+}
+
+// newTarget returns an empty SQLite store, which keeps task revisions.
+func newTarget(t *testing.T) *data.SqliteStore {
+	t.Helper()
+	db, err := sql.Open(data.SqliteDriver, filepath.Join(t.TempDir(), "data.db"))
+	assert.HasNoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	data.InitEmptyDb(db)
+	return data.NewSqliteStore(db)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/config"
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/migrate"
+)
+
+// migrateData copies the data of one store into another, as in
+//
+//	migrate-data --from file:./tasks.json --to sqlite:./data.db
+//
+// and then checks that both stores hold the same records. Records of the
+// target that are gone from the source are only deleted with --prune.
+func migrateData(ctx context.Context, args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("migrate-data", flag.ContinueOnError)
+	from := flags.String("from", "", "store to copy from, as backend:dsn")
+	to := flags.String("to", "", "store to copy into, as backend:dsn")
+	prune := flags.Bool(
+		"prune",
+		false,
+		"delete the records of the target that are gone from the source",
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" || *to == "" {
+		return errors.New("migrate-data needs both --from and --to")
+	}
+
+	fromBackend, fromDsn, err := parseStore(*from)
+	if err != nil {
+		return err
+	}
+	toBackend, toDsn, err := parseStore(*to)
+	if err != nil {
+		return err
+	}
+	source, closeSource, err := openSource(fromBackend, fromDsn)
+	if err != nil {
+		return fmt.Errorf("problem opening %s: %v", *from, err)
+	}
+	defer closeSource()
+	// The lock keeps a server from starting on the target, and the copy
+	// from starting while one runs on it, as the server takes it too.
+	if toBackend == config.BackendSqlite || toBackend == config.BackendEvents {
+		unlock, err := data.LockSqlite(toDsn)
+		if err != nil {
+			return fmt.Errorf("problem locking %s: %w", *to, err)
+		}
+		defer unlock()
+	}
+	store, closeTarget, err := openStore(toBackend, toDsn, data.InitEmptyDb)
+	if err != nil {
+		return fmt.Errorf("problem opening %s: %v", *to, err)
+	}
+	defer closeTarget()
+	target, ok := store.(migrate.Target)
+	if !ok {
+		return fmt.Errorf("the %s backend cannot be copied into", toBackend)
+	}
+
+	var options []migrate.CopyOption
+	if *prune {
+		options = append(options, migrate.WithPrune())
+	}
+	stats, err := migrate.Copy(ctx, source, target, options...)
+	for _, s := range stats {
+		fmt.Fprintf(
+			w,
+			"%s: %d copied, %d unchanged, %d deleted\n",
+			s.Kind,
+			s.Copied,
+			s.Unchanged,
+			s.Deleted,
+		)
+	}
+	if errors.Is(err, migrate.ErrPruneNeeded) {
+		return fmt.Errorf("%w; pass --prune to delete them", err)
+	}
+	if err != nil {
+		return err
+	}
+	if err := migrate.Verify(ctx, source, target); err != nil {
+		return fmt.Errorf("the stores differ after copying: %v", err)
+	}
+	fmt.Fprintln(w, "verified")
+	return nil
+}
+
+// openSource reads file stores without taking their lock, so that they can be
+// copied while the server keeps running on them.
+func openSource(backend, dsn string) (migrate.Source, func() error, error) {
+	if backend == config.BackendFile {
+		store, err := data.ReadFileSystemStore(dsn)
+		if err != nil {
+			return nil, nil, err
+		}
+		return store, func() error { return nil }, nil
+	}
+	store, closeStore, err := openStore(backend, dsn, data.InitEmptyDb)
+	if err != nil {
+		return nil, nil, err
+	}
+	source, ok := store.(migrate.Source)
+	if !ok {
+		closeStore()
+		return nil, nil, fmt.Errorf("the %s backend cannot be copied from", backend)
+	}
+	return source, closeStore, nil
+}
+
+// parseStore splits backend:dsn. Postgres URLs can be given as they are.
+func parseStore(value string) (backend, dsn string, err error) {
+	if strings.HasPrefix(value, "postgres://") ||
+		strings.HasPrefix(value, "postgresql://") {
+		return config.BackendPostgres, value, nil
+	}
+	backend, dsn, _ = strings.Cut(value, ":")
+	if !slices.Contains(config.Backends, backend) {
+		return "", "", fmt.Errorf(
+			"store %q does not start with one of %s",
+			value,
+			strings.Join(config.Backends, ", "),
+		)
+	}
+	if dsn == "" && backend != config.BackendMemory {
+		return "", "", fmt.Errorf("store %q has no DSN", value)
+	}
+	return backend, dsn, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/migrate"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestMigrateData(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("copies a store into another and can run again", func(t *testing.T) {
+		dir := t.TempDir()
+		source := filepath.Join(dir, "data.db")
+		db, err := sql.Open(data.SqliteDriver, source)
+		assert.HasNoError(t, err)
+		data.InitDb(db)
+		assert.HasNoError(t, db.Close())
+		args := []string{
+			"--from", "sqlite:" + source,
+			"--to", "file:" + filepath.Join(dir, "data.json"),
+		}
+
+		var output strings.Builder
+		assert.HasNoError(t, migrateData(ctx, args, &output))
+		assert.Equals(t, strings.HasSuffix(output.String(), "verified\n"), true)
+		for _, line := range []string{"users: 1 copied", "tasks: 1 copied"} {
+			if !strings.Contains(output.String(), line) {
+				t.Errorf("got %q, want %q", output.String(), line)
+			}
+		}
+
+		output.Reset()
+		assert.HasNoError(t, migrateData(ctx, args, &output))
+		lines := strings.Split(strings.TrimSuffix(output.String(), "verified\n"), "\n")
+		for _, line := range lines[:len(lines)-1] {
+			if !strings.Contains(line, " 0 copied") {
+				t.Errorf("got %q, want nothing copied again", line)
+			}
+		}
+	})
+
+	t.Run("copies a file store the server has open", func(t *testing.T) {
+		dir := t.TempDir()
+		source := filepath.Join(dir, "data.json")
+		file, err := os.OpenFile(source, os.O_RDWR|os.O_CREATE, 0o600)
+		assert.HasNoError(t, err)
+		defer file.Close()
+		store, err := data.NewFileSystemStore(file)
+		assert.HasNoError(t, err)
+		defer store.Close()
+		_, err = store.CreateUser(
+			ctx,
+			models.NewCreateUserDTO("Harry Potter", "harry@hogwarts.edu", "password"),
+		)
+		assert.HasNoError(t, err)
+		args := []string{
+			"--from", "file:" + source,
+			"--to", "sqlite:" + filepath.Join(dir, "data.db"),
+		}
+
+		var output strings.Builder
+		assert.HasNoError(t, migrateData(ctx, args, &output))
+		assert.Equals(t, strings.HasPrefix(output.String(), "users: 1 copied"), true)
+	})
+
+	t.Run("deletes what is gone from the source only with --prune", func(t *testing.T) {
+		dir := t.TempDir()
+		target := filepath.Join(dir, "data.db")
+		db, err := sql.Open(data.SqliteDriver, target)
+		assert.HasNoError(t, err)
+		data.InitDb(db)
+		assert.HasNoError(t, db.Close())
+		args := []string{"--from", "memory:", "--to", "sqlite:" + target}
+
+		var output strings.Builder
+		err = migrateData(ctx, args, &output)
+		assert.ErrorContains(t, err, migrate.ErrPruneNeeded)
+
+		output.Reset()
+		assert.HasNoError(t, migrateData(ctx, append(args, "--prune"), &output))
+		assert.Equals(t, strings.HasPrefix(output.String(), "users: 0 copied, 0 unchanged, 1 deleted"), true)
+	})
+
+	t.Run("refuses a SQLite target a server runs on", func(t *testing.T) {
+		dir := t.TempDir()
+		target := filepath.Join(dir, "data.db")
+		unlock, err := data.LockSqlite(target)
+		assert.HasNoError(t, err)
+		defer unlock()
+		args := []string{
+			"--from", "memory:",
+			"--to", "sqlite:" + target,
+		}
+
+		var output strings.Builder
+		err = migrateData(ctx, args, &output)
+		assert.ErrorContains(t, err, data.ErrStoreLocked)
+		assert.Equals(t, output.String(), "")
+	})
+
+	t.Run("needs both stores", func(t *testing.T) {
+		var output strings.Builder
+		err := migrateData(ctx, []string{"--from", "memory:"}, &output)
+		assert.HasError(t, err)
+	})
+}
+
+func TestParseStore(t *testing.T) {
+	cases := map[string][2]string{
+		"file:./tasks.json":         {"file", "./tasks.json"},
+		"sqlite:./data.db":          {"sqlite", "./data.db"},
+		"postgres://localhost/todo": {"postgres", "postgres://localhost/todo"},
+		"memory:":                   {"memory", ""},
+	}
+	for value, want := range cases {
+		backend, dsn, err := parseStore(value)
+		assert.HasNoError(t, err)
+		assert.Equals(t, [2]string{backend, dsn}, want)
+	}
+
+	for _, value := range []string{"./data.db", "mongodb:todo", "sqlite:"} {
+		_, _, err := parseStore(value)
+		assert.HasError(t, err)
+	}
+}
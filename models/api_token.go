@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+const (
+	ApiTokenScopeReadProfile = "read:profile"
+	ApiTokenScopeReadTasks   = "read:tasks"
+	ApiTokenScopeWriteTasks  = "write:tasks"
+)
+
+var ApiTokenScopes = []string{
+	ApiTokenScopeReadProfile,
+	ApiTokenScopeReadTasks,
+	ApiTokenScopeWriteTasks,
+}
+
+type ApiToken struct {
+	Id         int        `json:"id"`
+	UserId     int        `json:"userId"`
+	Name       string     `json:"name"`
+	Hash       string     `json:"hash"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+type CreateApiTokenDTO struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
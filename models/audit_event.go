@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// AuditEvent is credited to ActorId, and also to ImpersonatorId when an admin
+// acted as that user.
+type AuditEvent struct {
+	Id             int       `json:"id"`
+	Action         string    `json:"action"`
+	ActorId        int       `json:"actorId"`
+	ImpersonatorId int       `json:"impersonatorId,omitempty"`
+	Subject        string    `json:"subject"`
+	Details        string    `json:"details"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+func NewAuditEvent(action string, actorId int, subject, details string) *AuditEvent {
+	return &AuditEvent{
+		Action:  action,
+		ActorId: actorId,
+		Subject: subject,
+		Details: details,
+	}
+}
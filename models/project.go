@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+const (
+	ProjectRoleViewer = "viewer"
+	ProjectRoleEditor = "editor"
+	ProjectRoleOwner  = "owner"
+)
+
+type Project struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type CreateProjectDTO struct {
+	Name string `json:"name"`
+}
+
+type ProjectMember struct {
+	ProjectId int    `json:"projectId"`
+	UserId    int    `json:"userId"`
+	Role      string `json:"role"`
+}
+
+// ProjectInvitation is addressed to an email rather than a user so that
+// people can be invited before they sign up.
+type ProjectInvitation struct {
+	Id        int       `json:"id"`
+	ProjectId int       `json:"projectId"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	InvitedBy int       `json:"invitedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type CreateProjectInvitationDTO struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
@@ -1,22 +1,29 @@
 package models
 
 type Task struct {
-	Id    int    `json:"id"`
-	Title string `json:"title"`
+	Id         int    `json:"id"`
+	Title      string `json:"title"`
+	ProjectId  int    `json:"projectId"`
+	AssigneeId int    `json:"assigneeId"`
 }
 
-func NewTask(id int, title string) *Task {
-	return &Task{id, title}
+func NewTask(id int, title string, projectId int) *Task {
+	return &Task{Id: id, Title: title, ProjectId: projectId}
 }
 
 type CreateTaskDTO struct {
-	Title string `json:"title"`
+	Title      string `json:"title"`
+	ProjectId  int    `json:"projectId"`
+	AssigneeId int    `json:"assigneeId"`
 }
 
-func NewCreateTaskDTO(title string) *CreateTaskDTO {
-	return &CreateTaskDTO{Title: title}
+func NewCreateTaskDTO(title string, projectId int) *CreateTaskDTO {
+	return &CreateTaskDTO{Title: title, ProjectId: projectId}
 }
 
+// UpdateTaskDTO leaves the fields it omits unchanged; an AssigneeId of 0
+// unassigns the task.
 type UpdateTaskDTO struct {
-	Title *string `json:"title,omitempty"`
+	Title      *string `json:"title,omitempty"`
+	AssigneeId *int    `json:"assigneeId,omitempty"`
 }
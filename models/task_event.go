@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+const (
+	TaskCreated  = "TaskCreated"
+	TaskRenamed  = "TaskRenamed"
+	TaskAssigned = "TaskAssigned"
+	TaskDeleted  = "TaskDeleted"
+)
+
+// TaskEvent is a change made to a task. Task is the task as the change left
+// it, or as it was before it was deleted.
+type TaskEvent struct {
+	Id        int       `json:"id"`
+	Type      string    `json:"type"`
+	Task      Task      `json:"task"`
+	CreatedAt time.Time `json:"createdAt"`
+}
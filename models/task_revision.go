@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+const (
+	TaskFieldTitle      = "title"
+	TaskFieldAssigneeId = "assigneeId"
+)
+
+// TaskRevision is a version of a task that an update replaced. Revisions are
+// numbered from 1 for each task, and ChangedFields lists the fields the
+// update changed.
+type TaskRevision struct {
+	TaskId        int       `json:"taskId"`
+	Revision      int       `json:"revision"`
+	Title         string    `json:"title"`
+	AssigneeId    int       `json:"assigneeId"`
+	ChangedFields []string  `json:"changedFields"`
+	EditorId      int       `json:"editorId"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// NewTaskRevision keeps old as it was before it was updated to updated, or
+// returns nil when the update changed nothing.
+func NewTaskRevision(old, updated *Task, editorId int) *TaskRevision {
+	var changedFields []string
+	if old.Title != updated.Title {
+		changedFields = append(changedFields, TaskFieldTitle)
+	}
+	if old.AssigneeId != updated.AssigneeId {
+		changedFields = append(changedFields, TaskFieldAssigneeId)
+	}
+	if len(changedFields) == 0 {
+		return nil
+	}
+	return &TaskRevision{
+		TaskId:        old.Id,
+		Title:         old.Title,
+		AssigneeId:    old.AssigneeId,
+		ChangedFields: changedFields,
+		EditorId:      editorId,
+	}
+}
@@ -1,14 +1,45 @@
 package models
 
+import "time"
+
+const (
+	DefaultLocale      = "en"
+	DefaultStartOfWeek = "monday"
+	DefaultTimezone    = "UTC"
+)
+
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
 type User struct {
-	Id       int    `json:"id"`
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Id          int    `json:"id"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	Password    string `json:"password"`
+	Verified    bool   `json:"verified"`
+	Timezone    string `json:"timezone"`
+	Locale      string `json:"locale"`
+	StartOfWeek string `json:"startOfWeek"`
+	Role        string `json:"role"`
+	Disabled    bool   `json:"disabled"`
+	// PasswordChangedAt is when the password last changed; access tokens
+	// issued before then no longer work.
+	PasswordChangedAt *time.Time `json:"passwordChangedAt"`
 }
 
 func NewUser(id int, name string, email string, password string) *User {
-	user := User{Id: id, Name: name, Email: email, Password: password}
+	user := User{
+		Id:          id,
+		Name:        name,
+		Email:       email,
+		Password:    password,
+		Timezone:    DefaultTimezone,
+		Locale:      DefaultLocale,
+		StartOfWeek: DefaultStartOfWeek,
+		Role:        RoleUser,
+	}
 	return &user
 }
 
@@ -26,3 +57,18 @@ func NewCreateUserDTO(
 	dto := CreateUserDTO{Name: name, Email: email, Password: password}
 	return &dto
 }
+
+type UpdateUserDTO struct {
+	Name        *string `json:"name,omitempty"`
+	Email       *string `json:"email,omitempty"`
+	Timezone    *string `json:"timezone,omitempty"`
+	Locale      *string `json:"locale,omitempty"`
+	StartOfWeek *string `json:"startOfWeek,omitempty"`
+	// CurrentPassword is required to change the email.
+	CurrentPassword string `json:"currentPassword,omitempty"`
+}
+
+type ChangePasswordDTO struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
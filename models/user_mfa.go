@@ -0,0 +1,10 @@
+package models
+
+type UserMfa struct {
+	UserId     int    `json:"userId"`
+	TotpSecret string `json:"totpSecret"`
+	Enabled    bool   `json:"enabled"`
+	// LastUsedStep is the TOTP time step of the last accepted code, so that
+	// a code cannot be used twice.
+	LastUsedStep int64 `json:"lastUsedStep"`
+}
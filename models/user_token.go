@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+const (
+	// An email change token carries the new address in Email, which only
+	// replaces the user's once the token mailed to it is confirmed.
+	UserTokenPurposeEmailChange       = "email_change"
+	UserTokenPurposeEmailVerification = "email_verification"
+	// MFA recovery codes stay valid until they are used or replaced, so
+	// their ExpiresAt is left zero.
+	UserTokenPurposeMfaRecovery   = "mfa_recovery"
+	UserTokenPurposePasswordReset = "password_reset"
+)
+
+type UserToken struct {
+	Hash      string    `json:"hash"`
+	UserId    int       `json:"userId"`
+	Purpose   string    `json:"purpose"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Email     string    `json:"email,omitempty"`
+}
+
+func NewUserToken(
+	hash string,
+	userId int,
+	purpose string,
+	expiresAt time.Time,
+) *UserToken {
+	return &UserToken{
+		Hash:      hash,
+		UserId:    userId,
+		Purpose:   purpose,
+		ExpiresAt: expiresAt,
+	}
+}
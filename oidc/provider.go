@@ -0,0 +1,335 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrInvalidIdToken = errors.New("invalid ID token")
+
+type Config struct {
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectUrl  string
+	Scopes       []string
+	HttpClient   *http.Client
+}
+
+type IdTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string `json:"nonce"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// keyRefreshInterval is how long the signing keys are kept before an ID
+// token signed with a key they lack can have them fetched again, so that
+// made-up key IDs cannot make the server hammer the identity provider.
+const keyRefreshInterval = time.Minute
+
+// Provider talks to an OpenID Connect identity provider. Its discovery
+// document is fetched on first use and its signing keys are refetched, at
+// most once every keyRefreshInterval, when an ID token is signed with a key
+// it does not know yet.
+type Provider struct {
+	config   Config
+	client   *http.Client
+	now      func() time.Time
+	mu       sync.Mutex
+	metadata *providerMetadata
+	keys     map[string]*rsa.PublicKey
+	// refreshMu lets a single request fetch the keys at a time.
+	refreshMu       sync.Mutex
+	keysRefreshedAt time.Time
+}
+
+func NewProvider(config Config) *Provider {
+	client := config.HttpClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &Provider{
+		config: config,
+		client: client,
+		now:    time.Now,
+	}
+}
+
+func (p *Provider) AuthCodeUrl(
+	ctx context.Context,
+	state, nonce, codeChallenge string,
+) (string, error) {
+	metadata, err := p.getMetadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	authUrl, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+	query := authUrl.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientId)
+	query.Set("redirect_uri", p.config.RedirectUrl)
+	query.Set("scope", strings.Join(p.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+	authUrl.RawQuery = query.Encode()
+	return authUrl.String(), nil
+}
+
+// Exchange trades the authorization code for tokens and returns the raw ID
+// token, which still has to go through VerifyIdToken.
+func (p *Provider) Exchange(
+	ctx context.Context,
+	code, codeVerifier string,
+) (string, error) {
+	metadata, err := p.getMetadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectUrl},
+		"client_id":     {p.config.ClientId},
+		"client_secret": {p.config.ClientSecret},
+		"code_verifier": {codeVerifier},
+	}
+	request, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		metadata.TokenEndpoint,
+		strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	var body struct {
+		IdToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	statusCode, err := p.doJson(request, &body)
+	if err != nil {
+		return "", fmt.Errorf("error exchanging the code: %w", err)
+	}
+	if statusCode != http.StatusOK {
+		return "", fmt.Errorf(
+			"error exchanging the code: %d %s %s",
+			statusCode,
+			body.Error,
+			body.ErrorDescription,
+		)
+	}
+	if body.IdToken == "" {
+		return "", errors.New("error exchanging the code: no ID token returned")
+	}
+	return body.IdToken, nil
+}
+
+func (p *Provider) VerifyIdToken(
+	ctx context.Context,
+	rawIdToken, nonce string,
+) (*IdTokenClaims, error) {
+	metadata, err := p.getMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var claims IdTokenClaims
+	_, err = jwt.ParseWithClaims(
+		rawIdToken,
+		&claims,
+		func(token *jwt.Token) (any, error) {
+			kid, _ := token.Header["kid"].(string)
+			return p.getKey(ctx, kid)
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(p.config.ClientId),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+		jwt.WithTimeFunc(p.now),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIdToken, err)
+	}
+	if claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIdToken)
+	}
+	return &claims, nil
+}
+
+func (p *Provider) getMetadata(ctx context.Context) (*providerMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	discoveryUrl := strings.TrimSuffix(p.config.Issuer, "/") +
+		"/.well-known/openid-configuration"
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	var metadata providerMetadata
+	statusCode, err := p.doJson(request, &metadata)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching the discovery document: %w", err)
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf(
+			"error fetching the discovery document: status %d",
+			statusCode,
+		)
+	}
+	if metadata.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf(
+			"discovery document issuer %q does not match %q",
+			metadata.Issuer,
+			p.config.Issuer,
+		)
+	}
+	p.metadata = &metadata
+	return p.metadata, nil
+}
+
+func (p *Provider) getKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	p.refreshMu.Lock()
+	defer p.refreshMu.Unlock()
+	// The keys may have been fetched while this request waited.
+	p.mu.Lock()
+	key, ok = p.keys[kid]
+	refreshedAt := p.keysRefreshedAt
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if refreshedAt.IsZero() || p.now().Sub(refreshedAt) >= keyRefreshInterval {
+		if err := p.refreshKeys(ctx); err != nil {
+			return nil, err
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key, ok = p.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// refreshKeys fetches the signing keys. Failed fetches count towards the
+// interval too, so that an unreachable provider is not retried on every
+// sign-in either.
+func (p *Provider) refreshKeys(ctx context.Context) error {
+	p.mu.Lock()
+	p.keysRefreshedAt = p.now()
+	p.mu.Unlock()
+	metadata, err := p.getMetadata(ctx)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, metadata.JwksUri, nil)
+	if err != nil {
+		return err
+	}
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	statusCode, err := p.doJson(request, &jwks)
+	if err != nil {
+		return fmt.Errorf("error fetching the signing keys: %w", err)
+	}
+	if statusCode != http.StatusOK {
+		return fmt.Errorf("error fetching the signing keys: status %d", statusCode)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+		key, err := parseRsaPublicKey(jwk)
+		if err != nil {
+			return fmt.Errorf("error parsing signing key %q: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *Provider) doJson(request *http.Request, v any) (int, error) {
+	response, err := p.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	err = json.NewDecoder(response.Body).Decode(v)
+	if err != nil && response.StatusCode == http.StatusOK {
+		return response.StatusCode, err
+	}
+	return response.StatusCode, nil
+}
+
+func parseRsaPublicKey(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, err
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
@@ -0,0 +1,174 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/oidc"
+	"github.com/claudealdric/go-todolist-restful-api-server/oidc/oidctest"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestProvider(t *testing.T) {
+	idp := oidctest.NewServer("client-id", "client-secret")
+	defer idp.Close()
+	idp.SetUser(oidctest.User{
+		Subject:       "subject",
+		Email:         "claude.aldric@email.com",
+		EmailVerified: true,
+		Name:          "Claude Aldric",
+	})
+	provider := oidc.NewProvider(oidc.Config{
+		Issuer:       idp.URL,
+		ClientId:     "client-id",
+		ClientSecret: "client-secret",
+		RedirectUrl:  "http://localhost/auth/oidc/callback",
+	})
+	ctx := context.Background()
+
+	authorize := func(t *testing.T, codeVerifier string) string {
+		t.Helper()
+		authUrl, err := provider.AuthCodeUrl(
+			ctx,
+			"state",
+			"nonce",
+			oidc.CodeChallenge(codeVerifier),
+		)
+		assert.HasNoError(t, err)
+		callbackUrl, err := idp.Authorize(authUrl)
+		assert.HasNoError(t, err)
+		assert.Equals(t, callbackUrl.Query().Get("state"), "state")
+		return callbackUrl.Query().Get("code")
+	}
+
+	t.Run("completes the authorization code flow with PKCE", func(t *testing.T) {
+		codeVerifier, err := oidc.NewCodeVerifier()
+		assert.HasNoError(t, err)
+		code := authorize(t, codeVerifier)
+
+		idToken, err := provider.Exchange(ctx, code, codeVerifier)
+		assert.HasNoError(t, err)
+		claims, err := provider.VerifyIdToken(ctx, idToken, "nonce")
+		assert.HasNoError(t, err)
+
+		assert.Equals(t, claims.Subject, "subject")
+		assert.Equals(t, claims.Email, "claude.aldric@email.com")
+		assert.Equals(t, claims.EmailVerified, true)
+		assert.Equals(t, claims.Name, "Claude Aldric")
+	})
+
+	t.Run("Exchange fails given the wrong code verifier", func(t *testing.T) {
+		code := authorize(t, "verifier")
+
+		_, err := provider.Exchange(ctx, code, "other-verifier")
+		assert.HasError(t, err)
+	})
+
+	t.Run("Exchange fails when the code is reused", func(t *testing.T) {
+		code := authorize(t, "verifier")
+
+		_, err := provider.Exchange(ctx, code, "verifier")
+		assert.HasNoError(t, err)
+		_, err = provider.Exchange(ctx, code, "verifier")
+		assert.HasError(t, err)
+	})
+
+	t.Run("VerifyIdToken rejects a nonce mismatch", func(t *testing.T) {
+		code := authorize(t, "verifier")
+		idToken, err := provider.Exchange(ctx, code, "verifier")
+		assert.HasNoError(t, err)
+
+		_, err = provider.VerifyIdToken(ctx, idToken, "other-nonce")
+		assert.ErrorContains(t, err, oidc.ErrInvalidIdToken)
+	})
+
+	t.Run("VerifyIdToken rejects tokens with the wrong claims", func(t *testing.T) {
+		valid := func() jwt.MapClaims {
+			return jwt.MapClaims{
+				"iss":   idp.URL,
+				"sub":   "subject",
+				"aud":   "client-id",
+				"exp":   time.Now().Add(time.Hour).Unix(),
+				"nonce": "nonce",
+			}
+		}
+		tests := []struct {
+			name   string
+			modify func(jwt.MapClaims)
+		}{
+			{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+			{"wrong audience", func(c jwt.MapClaims) { c["aud"] = "other-client" }},
+			{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+			{"missing expiry", func(c jwt.MapClaims) { delete(c, "exp") }},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				claims := valid()
+				test.modify(claims)
+				idToken, err := idp.SignIdToken(claims)
+				assert.HasNoError(t, err)
+
+				_, err = provider.VerifyIdToken(ctx, idToken, "nonce")
+				assert.ErrorContains(t, err, oidc.ErrInvalidIdToken)
+			})
+		}
+
+		idToken, err := idp.SignIdToken(valid())
+		assert.HasNoError(t, err)
+		_, err = provider.VerifyIdToken(ctx, idToken, "nonce")
+		assert.HasNoError(t, err)
+	})
+
+	t.Run("VerifyIdToken rejects tokens signed with another algorithm", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"iss":   idp.URL,
+			"aud":   "client-id",
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"nonce": "nonce",
+		})
+		idToken, err := token.SignedString([]byte("secret"))
+		assert.HasNoError(t, err)
+
+		_, err = provider.VerifyIdToken(ctx, idToken, "nonce")
+		assert.ErrorContains(t, err, oidc.ErrInvalidIdToken)
+	})
+
+	t.Run("VerifyIdToken fetches the keys at most once a minute for unknown key IDs", func(t *testing.T) {
+		provider := oidc.NewProvider(oidc.Config{
+			Issuer:       idp.URL,
+			ClientId:     "client-id",
+			ClientSecret: "client-secret",
+			RedirectUrl:  "http://localhost/auth/oidc/callback",
+		})
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.HasNoError(t, err)
+		jwksRequests := idp.JwksRequests()
+
+		for i := range 3 {
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+				"iss":   idp.URL,
+				"aud":   "client-id",
+				"exp":   time.Now().Add(time.Hour).Unix(),
+				"nonce": "nonce",
+			})
+			token.Header["kid"] = fmt.Sprintf("unknown-key-%d", i)
+			idToken, err := token.SignedString(key)
+			assert.HasNoError(t, err)
+
+			_, err = provider.VerifyIdToken(ctx, idToken, "nonce")
+			assert.ErrorContains(t, err, oidc.ErrInvalidIdToken)
+		}
+		assert.Equals(t, idp.JwksRequests()-jwksRequests, 1)
+	})
+}
+
+func TestCodeChallenge(t *testing.T) {
+	// Example from RFC 7636, appendix B.
+	got := oidc.CodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	assert.Equals(t, got, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM")
+}
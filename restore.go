@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/backup"
+	"github.com/claudealdric/go-todolist-restful-api-server/config"
+)
+
+// restore swaps a snapshot in for the SQLite database, as in
+//
+//	restore --at 2026-10-19T12:00:00Z
+//
+// which picks the last snapshot taken by then. The server must be stopped;
+// restore refuses to replace the database while the server holds its lock.
+func restore(ctx context.Context, args []string, w io.Writer, now time.Time) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	dsn := flags.String(
+		"dsn",
+		config.DefaultDsn(config.BackendSqlite),
+		"path of the database to replace",
+	)
+	dir := flags.String("dir", config.Default().Backup.Dir, "directory of the snapshots")
+	path := flags.String("snapshot", "", "path of the snapshot to restore")
+	at := flags.String(
+		"at",
+		"",
+		"restore the last snapshot in the directory taken at or before this RFC 3339 time",
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *path != "" && *at != "" {
+		return errors.New("restore takes either --snapshot or --at")
+	}
+
+	if *path == "" {
+		until := now
+		if *at != "" {
+			var err error
+			until, err = time.Parse(time.RFC3339, *at)
+			if err != nil {
+				return fmt.Errorf("--at: %v", err)
+			}
+		}
+		snapshot, err := backup.Find(*dir, until)
+		if err != nil {
+			return err
+		}
+		*path = snapshot.Path
+	}
+
+	if err := backup.Restore(ctx, *path, *dsn); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "restored %s to %s\n", *path, *dsn)
+	return nil
+}
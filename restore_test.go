@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/backup"
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
+	"github.com/claudealdric/go-todolist-restful-api-server/models"
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("restores the last snapshot taken by the given time", func(t *testing.T) {
+		dir := t.TempDir()
+		dsn := filepath.Join(dir, "data.db")
+		backups := filepath.Join(dir, "backups")
+		db, err := sql.Open(data.SqliteDriver, dsn)
+		assert.HasNoError(t, err)
+		data.InitDb(db)
+		store := data.NewSqliteStore(db)
+		manager := backup.NewManager(store, backups, 5)
+		snapshot, err := manager.Backup(ctx)
+		assert.HasNoError(t, err)
+		tasks, err := store.GetTasks(ctx)
+		assert.HasNoError(t, err)
+		_, err = store.CreateTask(ctx, models.NewCreateTaskDTO("Not backed up", 1))
+		assert.HasNoError(t, err)
+		assert.HasNoError(t, db.Close())
+
+		var output strings.Builder
+		err = restore(ctx, []string{
+			"--dsn", dsn,
+			"--dir", backups,
+			"--at", snapshot.CreatedAt.Add(time.Second).Format(time.RFC3339),
+		}, &output, time.Now())
+		assert.HasNoError(t, err)
+		assert.Equals(t, output.String(), "restored "+snapshot.Path+" to "+dsn+"\n")
+
+		db, err = sql.Open(data.SqliteDriver, dsn)
+		assert.HasNoError(t, err)
+		defer db.Close()
+		restoredTasks, err := data.NewSqliteStore(db).GetTasks(ctx)
+		assert.HasNoError(t, err)
+		assert.Equals(t, restoredTasks, tasks)
+	})
+
+	t.Run("fails without a snapshot to restore", func(t *testing.T) {
+		dir := t.TempDir()
+		var output strings.Builder
+		err := restore(ctx, []string{
+			"--dsn", filepath.Join(dir, "data.db"),
+			"--dir", filepath.Join(dir, "backups"),
+		}, &output, time.Now())
+		assert.HasError(t, err)
+	})
+
+	t.Run("takes either a snapshot or a time", func(t *testing.T) {
+		var output strings.Builder
+		err := restore(ctx, []string{
+			"--snapshot", "snapshot.db",
+			"--at", "2026-10-19T12:00:00Z",
+		}, &output, time.Now())
+		assert.HasError(t, err)
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/claudealdric/go-todolist-restful-api-server/models"
@@ -23,9 +24,14 @@ func CreateTempFile(t testing.TB, initialData string) (*os.File, func()) {
 
 	tempFile.Write([]byte(initialData))
 
+	// Stores keep their lock and temporary files next to the file.
 	removeFile := func() {
 		tempFile.Close()
 		os.Remove(tempFile.Name())
+		siblings, _ := filepath.Glob(tempFile.Name() + ".*")
+		for _, sibling := range siblings {
+			os.Remove(sibling)
+		}
 	}
 
 	return tempFile, removeFile
@@ -1,8 +1,10 @@
 package testutils
 
 import (
+	"context"
 	"errors"
 	"slices"
+	"time"
 
 	"github.com/claudealdric/go-todolist-restful-api-server/data"
 	"github.com/claudealdric/go-todolist-restful-api-server/models"
@@ -10,20 +12,38 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-var initialMockStoreTasks = []models.Task{*models.NewTask(1, "Pack clothes")}
+var initialMockStoreProjects = []models.Project{{Id: 1, Name: "Home"}}
+var initialMockStoreTasks = []models.Task{*models.NewTask(1, "Pack clothes", 1)}
 var forcedError = errors.New("forced error")
 
 type mockStore struct {
+	ApiTokens                    []models.ApiToken
+	AuditEvents                  []models.AuditEvent
 	CreateTaskCalls              int
 	CreateUserCalls              int
+	DeleteUserByIdCalls          int
 	GetTaskByIdCalls             int
+	GetTasksByProjectIdCalls     int
 	GetTasksCalls                int
 	GetUserByEmailCalls          int
+	GetUserByIdCalls             int
 	GetUsersCalls                int
+	LoginAttempts                map[string]models.LoginAttempt
+	ProjectInvitations           []models.ProjectInvitation
+	ProjectMembers               []models.ProjectMember
+	Projects                     []models.Project
+	TaskEvents                   []models.TaskEvent
+	TaskRevisions                []models.TaskRevision
 	Tasks                        []models.Task
 	UpdateTaskCalls              int
+	UpdateUserCalls              int
+	UserMfa                      map[int]models.UserMfa
+	UserTokens                   []models.UserToken
 	Users                        []models.User
 	ValidateUserCredentialsCalls int
+	lastApiTokenId               int
+	lastProjectId                int
+	lastProjectInvitationId      int
 	lastTaskId                   int
 	lastUserId                   int
 	shouldForceError             bool
@@ -31,24 +51,36 @@ type mockStore struct {
 
 func NewMockStore(shouldError bool) *mockStore {
 	m := &mockStore{
-		Tasks:            initialMockStoreTasks,
+		LoginAttempts:    map[string]models.LoginAttempt{},
+		UserMfa:          map[int]models.UserMfa{},
+		Projects:         slices.Clone(initialMockStoreProjects),
+		Tasks:            slices.Clone(initialMockStoreTasks),
 		shouldForceError: shouldError,
+		lastProjectId:    1,
 		lastTaskId:       1,
 	}
 	return m
 }
 
-func (m *mockStore) CreateTask(dto *models.CreateTaskDTO) (*models.Task, error) {
+func (m *mockStore) CreateTask(
+	ctx context.Context,
+	dto *models.CreateTaskDTO,
+) (*models.Task, error) {
 	m.CreateTaskCalls++
 	if m.shouldForceError {
 		return nil, forcedError
 	}
-	task := models.Task{Id: m.getNewTaskId(), Title: dto.Title}
+	task := models.Task{
+		Id:         m.getNewTaskId(),
+		Title:      dto.Title,
+		ProjectId:  dto.ProjectId,
+		AssigneeId: dto.AssigneeId,
+	}
 	m.Tasks = append(m.Tasks, task)
 	return &task, nil
 }
 
-func (m *mockStore) GetTaskById(id int) (*models.Task, error) {
+func (m *mockStore) GetTaskById(ctx context.Context, id int) (*models.Task, error) {
 	m.GetTaskByIdCalls++
 	if m.shouldForceError {
 		if id == -1 {
@@ -57,14 +89,14 @@ func (m *mockStore) GetTaskById(id int) (*models.Task, error) {
 			return nil, forcedError
 		}
 	}
-	tasks, _ := m.GetTasks()
+	tasks, _ := m.GetTasks(ctx)
 	task, _ := utils.SliceFind(tasks, func(t models.Task) bool {
 		return t.Id == id
 	})
 	return &task, nil
 }
 
-func (m *mockStore) GetTasks() ([]models.Task, error) {
+func (m *mockStore) GetTasks(ctx context.Context) ([]models.Task, error) {
 	m.GetTasksCalls++
 	if m.shouldForceError {
 		return nil, forcedError
@@ -72,7 +104,24 @@ func (m *mockStore) GetTasks() ([]models.Task, error) {
 	return m.Tasks, nil
 }
 
-func (m *mockStore) DeleteTaskById(id int) error {
+func (m *mockStore) GetTasksByProjectId(
+	ctx context.Context,
+	projectId int,
+) ([]models.Task, error) {
+	m.GetTasksByProjectIdCalls++
+	if m.shouldForceError {
+		return nil, forcedError
+	}
+	var tasks []models.Task
+	for _, task := range m.Tasks {
+		if task.ProjectId == projectId {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+func (m *mockStore) DeleteTaskById(ctx context.Context, id int) error {
 	if m.shouldForceError {
 		return forcedError
 	}
@@ -88,7 +137,10 @@ func (m *mockStore) DeleteTaskById(id int) error {
 	return nil
 }
 
-func (m *mockStore) UpdateTask(task *models.Task) (*models.Task, error) {
+func (m *mockStore) UpdateTask(
+	ctx context.Context,
+	task *models.Task,
+) (*models.Task, error) {
 	m.UpdateTaskCalls++
 	if m.shouldForceError {
 		return nil, forcedError
@@ -102,40 +154,108 @@ func (m *mockStore) UpdateTask(task *models.Task) (*models.Task, error) {
 	return nil, data.ErrResourceNotFound
 }
 
-func (m *mockStore) CreateUser(dto *models.CreateUserDTO) (*models.User, error) {
+func (m *mockStore) GetTaskHistory(
+	ctx context.Context,
+	id int,
+) ([]models.TaskEvent, error) {
+	if m.shouldForceError {
+		return nil, forcedError
+	}
+	var events []models.TaskEvent
+	for _, event := range m.TaskEvents {
+		if event.Task.Id == id {
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		return nil, data.ErrResourceNotFound
+	}
+	return events, nil
+}
+
+func (m *mockStore) GetTaskRevisions(
+	ctx context.Context,
+	taskId int,
+) ([]models.TaskRevision, error) {
+	if m.shouldForceError {
+		return nil, forcedError
+	}
+	var revisions []models.TaskRevision
+	for _, revision := range m.TaskRevisions {
+		if revision.TaskId == taskId {
+			revisions = append(revisions, revision)
+		}
+	}
+	return revisions, nil
+}
+
+func (m *mockStore) GetTaskRevision(
+	ctx context.Context,
+	taskId, revision int,
+) (*models.TaskRevision, error) {
+	revisions, err := m.GetTaskRevisions(ctx, taskId)
+	if err != nil {
+		return nil, err
+	}
+	taskRevision, ok := utils.SliceFind(revisions, func(r models.TaskRevision) bool {
+		return r.Revision == revision
+	})
+	if !ok {
+		return nil, data.ErrResourceNotFound
+	}
+	return &taskRevision, nil
+}
+
+func (m *mockStore) CreateUser(
+	ctx context.Context,
+	dto *models.CreateUserDTO,
+) (*models.User, error) {
 	m.CreateUserCalls++
 	if m.shouldForceError {
 		return nil, forcedError
 	}
+	email := data.NormalizeEmail(dto.Email)
+	if slices.ContainsFunc(m.Users, func(u models.User) bool {
+		return data.NormalizeEmail(u.Email) == email
+	}) {
+		return nil, data.ErrConflict
+	}
 	hashedPassword, err := bcrypt.GenerateFromPassword(
 		[]byte(dto.Password),
-		bcrypt.DefaultCost,
+		bcrypt.MinCost,
 	)
 	if err != nil {
 		return nil, err
 	}
-	user := models.User{
-		Id:       m.getNewUserId(),
-		Name:     dto.Name,
-		Email:    dto.Email,
-		Password: string(hashedPassword),
-	}
+	user := *models.NewUser(
+		m.getNewUserId(),
+		dto.Name,
+		email,
+		string(hashedPassword),
+	)
 	m.Users = append(m.Users, user)
 	return &user, nil
 }
 
-func (m *mockStore) GetUserByEmail(email string) (*models.User, error) {
+func (m *mockStore) GetUserByEmail(
+	ctx context.Context,
+	email string,
+) (*models.User, error) {
 	m.GetUserByEmailCalls++
 	if m.shouldForceError {
 		return nil, forcedError
 	}
-	user, _ := utils.SliceFind(m.Users, func(u models.User) bool {
-		return u.Email == email
+	email = data.NormalizeEmail(email)
+	user, ok := utils.SliceFind(m.Users, func(u models.User) bool {
+		return data.NormalizeEmail(u.Email) == email
 	})
+	if !ok {
+		return nil, data.ErrResourceNotFound
+	}
 	return &user, nil
 }
 
-func (m *mockStore) GetUsers() ([]models.User, error) {
+func (m *mockStore) GetUsers(ctx context.Context) ([]models.User, error) {
 	m.GetUsersCalls++
 	if m.shouldForceError {
 		return nil, forcedError
@@ -143,12 +263,128 @@ func (m *mockStore) GetUsers() ([]models.User, error) {
 	return m.Users, nil
 }
 
-func (m *mockStore) ValidateUserCredentials(username, password string) bool {
+func (m *mockStore) GetUserById(ctx context.Context, id int) (*models.User, error) {
+	m.GetUserByIdCalls++
+	if m.shouldForceError {
+		return nil, forcedError
+	}
+	user, ok := utils.SliceFind(m.Users, func(u models.User) bool {
+		return u.Id == id
+	})
+	if !ok {
+		return nil, data.ErrResourceNotFound
+	}
+	return &user, nil
+}
+
+func (m *mockStore) UpdateUser(
+	ctx context.Context,
+	user *models.User,
+) (*models.User, error) {
+	m.UpdateUserCalls++
+	if m.shouldForceError {
+		return nil, forcedError
+	}
+	email := data.NormalizeEmail(user.Email)
+	if slices.ContainsFunc(m.Users, func(u models.User) bool {
+		return u.Id != user.Id && data.NormalizeEmail(u.Email) == email
+	}) {
+		return nil, data.ErrConflict
+	}
+	for i, u := range m.Users {
+		if u.Id == user.Id {
+			updatedUser := *user
+			updatedUser.Email = email
+			updatedUser.Password = u.Password
+			updatedUser.PasswordChangedAt = u.PasswordChangedAt
+			m.Users[i] = updatedUser
+			return &updatedUser, nil
+		}
+	}
+	return nil, data.ErrResourceNotFound
+}
+
+func (m *mockStore) DeleteUserById(ctx context.Context, id int) error {
+	m.DeleteUserByIdCalls++
+	if m.shouldForceError {
+		return forcedError
+	}
+	i := slices.IndexFunc(m.Users, func(u models.User) bool {
+		return u.Id == id
+	})
+	if i == -1 {
+		return data.ErrResourceNotFound
+	}
+	m.Users = slices.Delete(m.Users, i, i+1)
+	m.UserTokens = slices.DeleteFunc(m.UserTokens, func(t models.UserToken) bool {
+		return t.UserId == id
+	})
+	m.ApiTokens = slices.DeleteFunc(m.ApiTokens, func(t models.ApiToken) bool {
+		return t.UserId == id
+	})
+	delete(m.UserMfa, id)
+	m.ProjectMembers = slices.DeleteFunc(
+		m.ProjectMembers,
+		func(member models.ProjectMember) bool {
+			return member.UserId == id
+		},
+	)
+	for i, task := range m.Tasks {
+		if task.AssigneeId == id {
+			m.Tasks[i].AssigneeId = 0
+		}
+	}
+	return nil
+}
+
+func (m *mockStore) UpdateUserPassword(
+	ctx context.Context,
+	id int,
+	password string,
+) error {
+	if m.shouldForceError {
+		return forcedError
+	}
+	for i, u := range m.Users {
+		if u.Id == id {
+			hashedPassword, err := bcrypt.GenerateFromPassword(
+				[]byte(password),
+				bcrypt.MinCost,
+			)
+			if err != nil {
+				return err
+			}
+			passwordChangedAt := time.Now().UTC()
+			m.Users[i].Password = string(hashedPassword)
+			m.Users[i].PasswordChangedAt = &passwordChangedAt
+			m.ApiTokens = slices.DeleteFunc(m.ApiTokens, func(t models.ApiToken) bool {
+				return t.UserId == id
+			})
+			return nil
+		}
+	}
+	return data.ErrResourceNotFound
+}
+
+// ValidateUserCredentials accepts both hashed passwords, as stored by
+// CreateUser, and plain ones, as set directly on Users by tests.
+func (m *mockStore) ValidateUserCredentials(
+	ctx context.Context,
+	email, password string,
+) bool {
 	m.ValidateUserCredentialsCalls++
 	if m.shouldForceError {
 		return false
 	}
-	return true
+	email = data.NormalizeEmail(email)
+	user, ok := utils.SliceFind(m.Users, func(u models.User) bool {
+		return data.NormalizeEmail(u.Email) == email
+	})
+	if !ok {
+		return false
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	return err == nil || user.Password == password
 }
 
 func (m *mockStore) getNewTaskId() int {
@@ -162,3 +398,389 @@ func (m *mockStore) getNewUserId() int {
 	m.lastUserId++
 	return newUserId
 }
+
+func (m *mockStore) CreateProject(
+	ctx context.Context,
+	dto *models.CreateProjectDTO,
+	ownerId int,
+) (*models.Project, error) {
+	m.lastProjectId++
+	project := models.Project{Id: m.lastProjectId, Name: dto.Name}
+	m.Projects = append(m.Projects, project)
+	m.ProjectMembers = append(m.ProjectMembers, models.ProjectMember{
+		ProjectId: project.Id,
+		UserId:    ownerId,
+		Role:      models.ProjectRoleOwner,
+	})
+	return &project, nil
+}
+
+func (m *mockStore) GetProjectById(ctx context.Context, id int) (*models.Project, error) {
+	project, ok := utils.SliceFind(m.Projects, func(p models.Project) bool {
+		return p.Id == id
+	})
+	if !ok {
+		return nil, data.ErrResourceNotFound
+	}
+	return &project, nil
+}
+
+func (m *mockStore) DeleteProjectMember(
+	ctx context.Context,
+	projectId, userId int,
+) error {
+	i := slices.IndexFunc(m.ProjectMembers, func(member models.ProjectMember) bool {
+		return member.ProjectId == projectId && member.UserId == userId
+	})
+	if i == -1 {
+		return data.ErrResourceNotFound
+	}
+	m.ProjectMembers = slices.Delete(m.ProjectMembers, i, i+1)
+	for i, task := range m.Tasks {
+		if task.ProjectId == projectId && task.AssigneeId == userId {
+			m.Tasks[i].AssigneeId = 0
+		}
+	}
+	return nil
+}
+
+func (m *mockStore) GetProjectMember(
+	ctx context.Context,
+	projectId, userId int,
+) (*models.ProjectMember, error) {
+	member, ok := utils.SliceFind(
+		m.ProjectMembers,
+		func(member models.ProjectMember) bool {
+			return member.ProjectId == projectId && member.UserId == userId
+		},
+	)
+	if !ok {
+		return nil, data.ErrResourceNotFound
+	}
+	return &member, nil
+}
+
+func (m *mockStore) GetProjectMembers(
+	ctx context.Context,
+	projectId int,
+) ([]models.ProjectMember, error) {
+	var members []models.ProjectMember
+	for _, member := range m.ProjectMembers {
+		if member.ProjectId == projectId {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+func (m *mockStore) GetProjectMembersByUserId(
+	ctx context.Context,
+	userId int,
+) ([]models.ProjectMember, error) {
+	var members []models.ProjectMember
+	for _, member := range m.ProjectMembers {
+		if member.UserId == userId {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+func (m *mockStore) SaveProjectMember(
+	ctx context.Context,
+	member *models.ProjectMember,
+) error {
+	for i, existing := range m.ProjectMembers {
+		if existing.ProjectId == member.ProjectId && existing.UserId == member.UserId {
+			m.ProjectMembers[i] = *member
+			return nil
+		}
+	}
+	m.ProjectMembers = append(m.ProjectMembers, *member)
+	return nil
+}
+
+func (m *mockStore) AcceptProjectInvitation(
+	ctx context.Context,
+	id, userId int,
+) (*models.ProjectMember, error) {
+	invitation, err := m.GetProjectInvitationById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.DeleteProjectInvitation(ctx, id); err != nil {
+		return nil, err
+	}
+	if member, err := m.GetProjectMember(ctx, invitation.ProjectId, userId); err == nil {
+		return member, nil
+	}
+	member := models.ProjectMember{
+		ProjectId: invitation.ProjectId,
+		UserId:    userId,
+		Role:      invitation.Role,
+	}
+	m.ProjectMembers = append(m.ProjectMembers, member)
+	return &member, nil
+}
+
+func (m *mockStore) CreateProjectInvitation(
+	ctx context.Context,
+	invitation *models.ProjectInvitation,
+) (*models.ProjectInvitation, error) {
+	createdInvitation := *invitation
+	createdInvitation.Email = data.NormalizeEmail(invitation.Email)
+	if slices.ContainsFunc(
+		m.ProjectInvitations,
+		func(inv models.ProjectInvitation) bool {
+			return inv.ProjectId == createdInvitation.ProjectId &&
+				inv.Email == createdInvitation.Email
+		},
+	) {
+		return nil, data.ErrConflict
+	}
+	m.lastProjectInvitationId++
+	createdInvitation.Id = m.lastProjectInvitationId
+	m.ProjectInvitations = append(m.ProjectInvitations, createdInvitation)
+	return &createdInvitation, nil
+}
+
+func (m *mockStore) DeleteProjectInvitation(ctx context.Context, id int) error {
+	i := slices.IndexFunc(
+		m.ProjectInvitations,
+		func(inv models.ProjectInvitation) bool {
+			return inv.Id == id
+		},
+	)
+	if i == -1 {
+		return data.ErrResourceNotFound
+	}
+	m.ProjectInvitations = slices.Delete(m.ProjectInvitations, i, i+1)
+	return nil
+}
+
+func (m *mockStore) GetProjectInvitationById(
+	ctx context.Context,
+	id int,
+) (*models.ProjectInvitation, error) {
+	invitation, ok := utils.SliceFind(
+		m.ProjectInvitations,
+		func(inv models.ProjectInvitation) bool {
+			return inv.Id == id
+		},
+	)
+	if !ok {
+		return nil, data.ErrResourceNotFound
+	}
+	return &invitation, nil
+}
+
+func (m *mockStore) GetProjectInvitationsByEmail(
+	ctx context.Context,
+	email string,
+) ([]models.ProjectInvitation, error) {
+	email = data.NormalizeEmail(email)
+	var invitations []models.ProjectInvitation
+	for _, invitation := range m.ProjectInvitations {
+		if invitation.Email == email {
+			invitations = append(invitations, invitation)
+		}
+	}
+	return invitations, nil
+}
+
+func (m *mockStore) DeleteUserMfa(ctx context.Context, userId int) error {
+	delete(m.UserMfa, userId)
+	return nil
+}
+
+func (m *mockStore) GetUserMfa(ctx context.Context, userId int) (*models.UserMfa, error) {
+	mfa, ok := m.UserMfa[userId]
+	if !ok {
+		return nil, data.ErrResourceNotFound
+	}
+	return &mfa, nil
+}
+
+func (m *mockStore) SaveUserMfa(ctx context.Context, mfa *models.UserMfa) error {
+	m.UserMfa[mfa.UserId] = *mfa
+	return nil
+}
+
+func (m *mockStore) UseUserMfaStep(
+	ctx context.Context,
+	userId int,
+	step int64,
+) (bool, error) {
+	mfa, ok := m.UserMfa[userId]
+	if !ok || mfa.LastUsedStep >= step {
+		return false, nil
+	}
+	mfa.LastUsedStep = step
+	m.UserMfa[userId] = mfa
+	return true, nil
+}
+
+func (m *mockStore) DeleteLoginAttempt(ctx context.Context, key string) error {
+	delete(m.LoginAttempts, key)
+	return nil
+}
+
+func (m *mockStore) GetLoginAttempt(
+	ctx context.Context,
+	key string,
+) (*models.LoginAttempt, error) {
+	attempt, ok := m.LoginAttempts[key]
+	if !ok {
+		return nil, data.ErrResourceNotFound
+	}
+	return &attempt, nil
+}
+
+func (m *mockStore) SaveLoginAttempt(
+	ctx context.Context,
+	attempt *models.LoginAttempt,
+) error {
+	m.LoginAttempts[attempt.Key] = *attempt
+	return nil
+}
+
+func (m *mockStore) IncrementLoginFailures(
+	ctx context.Context,
+	key string,
+	failedAt time.Time,
+	expireAfter time.Duration,
+) (*models.LoginAttempt, error) {
+	attempt, ok := m.LoginAttempts[key]
+	if !ok {
+		attempt = models.LoginAttempt{Key: key}
+	}
+	if failedAt.Sub(attempt.LastFailureAt) > expireAfter {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = failedAt
+	m.LoginAttempts[key] = attempt
+	return &attempt, nil
+}
+
+func (m *mockStore) LockLoginAttempt(
+	ctx context.Context,
+	key string,
+	failures int,
+	lockedUntil time.Time,
+) error {
+	attempt, ok := m.LoginAttempts[key]
+	if !ok || attempt.Failures != failures {
+		return nil
+	}
+	attempt.LockedUntil = lockedUntil
+	m.LoginAttempts[key] = attempt
+	return nil
+}
+
+func (m *mockStore) CreateAuditEvent(
+	ctx context.Context,
+	event *models.AuditEvent,
+) (*models.AuditEvent, error) {
+	createdEvent := *event
+	createdEvent.Id = len(m.AuditEvents) + 1
+	m.AuditEvents = append(m.AuditEvents, createdEvent)
+	return &createdEvent, nil
+}
+
+func (m *mockStore) GetAuditEvents(ctx context.Context) ([]models.AuditEvent, error) {
+	return m.AuditEvents, nil
+}
+
+func (m *mockStore) ConsumeUserToken(
+	ctx context.Context,
+	hash, purpose string,
+) (*models.UserToken, error) {
+	i := slices.IndexFunc(m.UserTokens, func(t models.UserToken) bool {
+		return t.Hash == hash && t.Purpose == purpose
+	})
+	if i == -1 {
+		return nil, data.ErrResourceNotFound
+	}
+	token := m.UserTokens[i]
+	m.UserTokens = slices.Delete(m.UserTokens, i, i+1)
+	return &token, nil
+}
+
+func (m *mockStore) CreateUserToken(ctx context.Context, token *models.UserToken) error {
+	m.UserTokens = append(m.UserTokens, *token)
+	return nil
+}
+
+func (m *mockStore) DeleteUserTokens(
+	ctx context.Context,
+	userId int,
+	purpose string,
+) error {
+	m.UserTokens = slices.DeleteFunc(m.UserTokens, func(t models.UserToken) bool {
+		return t.UserId == userId && t.Purpose == purpose
+	})
+	return nil
+}
+
+func (m *mockStore) CreateApiToken(
+	ctx context.Context,
+	token *models.ApiToken,
+) (*models.ApiToken, error) {
+	m.lastApiTokenId++
+	createdToken := *token
+	createdToken.Id = m.lastApiTokenId
+	m.ApiTokens = append(m.ApiTokens, createdToken)
+	return &createdToken, nil
+}
+
+func (m *mockStore) DeleteApiToken(ctx context.Context, id, userId int) error {
+	i := slices.IndexFunc(m.ApiTokens, func(t models.ApiToken) bool {
+		return t.Id == id && t.UserId == userId
+	})
+	if i == -1 {
+		return data.ErrResourceNotFound
+	}
+	m.ApiTokens = slices.Delete(m.ApiTokens, i, i+1)
+	return nil
+}
+
+func (m *mockStore) GetApiTokenByHash(
+	ctx context.Context,
+	hash string,
+) (*models.ApiToken, error) {
+	token, ok := utils.SliceFind(m.ApiTokens, func(t models.ApiToken) bool {
+		return t.Hash == hash
+	})
+	if !ok {
+		return nil, data.ErrResourceNotFound
+	}
+	return &token, nil
+}
+
+func (m *mockStore) GetApiTokensByUserId(
+	ctx context.Context,
+	userId int,
+) ([]models.ApiToken, error) {
+	var tokens []models.ApiToken
+	for _, token := range m.ApiTokens {
+		if token.UserId == userId {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (m *mockStore) UpdateApiTokenLastUsedAt(
+	ctx context.Context,
+	id int,
+	lastUsedAt time.Time,
+) error {
+	for i, token := range m.ApiTokens {
+		if token.Id == id {
+			m.ApiTokens[i].LastUsedAt = &lastUsedAt
+			return nil
+		}
+	}
+	return data.ErrResourceNotFound
+}
@@ -1,13 +1,16 @@
 package testutils
 
 import (
+	"context"
 	"testing"
 
+	"github.com/claudealdric/go-todolist-restful-api-server/data"
 	"github.com/claudealdric/go-todolist-restful-api-server/models"
 	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
 )
 
 func TestGetUserByEmail(t *testing.T) {
+	ctx := context.Background()
 	t.Run("CreateUser increments the internal counter and returns the new user", func(t *testing.T) {
 		mockStore := NewMockStore(false)
 		dto := models.CreateUserDTO{
@@ -15,7 +18,7 @@ func TestGetUserByEmail(t *testing.T) {
 			Email:    "claude.aldric@email.com",
 			Password: "password",
 		}
-		createdUser, err := mockStore.CreateUser(&dto)
+		createdUser, err := mockStore.CreateUser(ctx, &dto)
 		gotUser := models.User{
 			Id:    createdUser.Id,
 			Name:  createdUser.Name,
@@ -32,6 +35,23 @@ func TestGetUserByEmail(t *testing.T) {
 		assert.Equals(t, gotUser, wantedUser)
 	})
 
+	t.Run("CreateUser returns an `ErrConflict` error if the email is taken", func(t *testing.T) {
+		mockStore := NewMockStore(false)
+		mockStore.Users = []models.User{
+			*models.NewUser(1, "Claude Aldric", "claude.aldric@email.com", "password"),
+		}
+		dto := models.NewCreateUserDTO(
+			"Someone Else",
+			"Claude.Aldric@email.com",
+			"password",
+		)
+		gotUser, err := mockStore.CreateUser(ctx, dto)
+
+		assert.ErrorContains(t, err, data.ErrConflict)
+		assert.Equals(t, gotUser, nil)
+		assert.HasLength(t, mockStore.Users, 1)
+	})
+
 	t.Run("forcing CreateUser to fail returns the forced error", func(t *testing.T) {
 		mockStore := NewMockStore(true)
 		dto := models.CreateUserDTO{
@@ -39,7 +59,7 @@ func TestGetUserByEmail(t *testing.T) {
 			Email:    "claude.aldric@email.com",
 			Password: "password",
 		}
-		gotUser, err := mockStore.CreateUser(&dto)
+		gotUser, err := mockStore.CreateUser(ctx, &dto)
 
 		assert.ErrorContains(t, err, forcedError)
 		assert.Equals(t, mockStore.CreateUserCalls, 1)
@@ -55,7 +75,7 @@ func TestGetUserByEmail(t *testing.T) {
 			Password: "password",
 		}
 		mockStore.Users = []models.User{wantedUser}
-		gotUser, err := mockStore.GetUserByEmail(wantedUser.Email)
+		gotUser, err := mockStore.GetUserByEmail(ctx, wantedUser.Email)
 
 		assert.HasNoError(t, err)
 		assert.Equals(t, mockStore.GetUserByEmailCalls, 1)
@@ -71,7 +91,7 @@ func TestGetUserByEmail(t *testing.T) {
 			Password: "password",
 		}
 		mockStore.Users = []models.User{wantedUser}
-		gotUser, err := mockStore.GetUserByEmail(wantedUser.Email)
+		gotUser, err := mockStore.GetUserByEmail(ctx, wantedUser.Email)
 
 		assert.ErrorContains(t, err, forcedError)
 		assert.Equals(t, gotUser, nil)
@@ -89,7 +109,7 @@ func TestGetUserByEmail(t *testing.T) {
 			},
 		}
 		mockStore.Users = initialUsers
-		users, err := mockStore.GetUsers()
+		users, err := mockStore.GetUsers(ctx)
 
 		assert.HasNoError(t, err)
 		assert.Equals(t, mockStore.GetUsersCalls, 1)
@@ -107,7 +127,7 @@ func TestGetUserByEmail(t *testing.T) {
 			},
 		}
 		mockStore.Users = initialUsers
-		users, err := mockStore.GetUsers()
+		users, err := mockStore.GetUsers(ctx)
 
 		assert.ErrorContains(t, err, forcedError)
 		assert.Equals(t, users, nil)
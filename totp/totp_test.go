@@ -0,0 +1,97 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/claudealdric/go-todolist-restful-api-server/testutils/assert"
+)
+
+// The SHA-1 test vectors from RFC 6238, appendix B, truncated to six digits.
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, test := range tests {
+		got, err := Code(rfcSecret, time.Unix(test.unix, 0))
+		assert.HasNoError(t, err)
+		assert.Equals(t, got, test.code)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	t.Run("accepts the current code and returns its step", func(t *testing.T) {
+		step, ok := Validate(rfcSecret, "050471", now)
+		assert.Equals(t, ok, true)
+		assert.Equals(t, step, Step(now))
+	})
+
+	t.Run("accepts codes one period off", func(t *testing.T) {
+		previous, err := Code(rfcSecret, now.Add(-Period))
+		assert.HasNoError(t, err)
+		next, err := Code(rfcSecret, now.Add(Period))
+		assert.HasNoError(t, err)
+
+		step, ok := Validate(rfcSecret, previous, now)
+		assert.Equals(t, ok, true)
+		assert.Equals(t, step, Step(now)-1)
+		step, ok = Validate(rfcSecret, next, now)
+		assert.Equals(t, ok, true)
+		assert.Equals(t, step, Step(now)+1)
+	})
+
+	t.Run("rejects codes outside the skew", func(t *testing.T) {
+		old, err := Code(rfcSecret, now.Add(-2*Period))
+		assert.HasNoError(t, err)
+
+		_, ok := Validate(rfcSecret, old, now)
+		assert.Equals(t, ok, false)
+	})
+
+	t.Run("rejects malformed codes and secrets", func(t *testing.T) {
+		_, ok := Validate(rfcSecret, "50471", now)
+		assert.Equals(t, ok, false)
+		_, ok = Validate("not base32!", "050471", now)
+		assert.Equals(t, ok, false)
+	})
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.HasNoError(t, err)
+	assert.HasLength(t, []byte(secret), 32)
+
+	otherSecret, err := GenerateSecret()
+	assert.HasNoError(t, err)
+	assert.DoesNotEqual(t, secret, otherSecret)
+
+	_, err = Code(secret, time.Now())
+	assert.HasNoError(t, err)
+}
+
+func TestKeyUri(t *testing.T) {
+	uri, err := url.Parse(KeyUri("To-do list", "claude.aldric@email.com", "SECRET"))
+	assert.HasNoError(t, err)
+
+	assert.Equals(t, uri.Scheme, "otpauth")
+	assert.Equals(t, uri.Host, "totp")
+	assert.Equals(t, uri.Path, "/To-do list:claude.aldric@email.com")
+	assert.Equals(t, uri.Query().Get("secret"), "SECRET")
+	assert.Equals(t, uri.Query().Get("issuer"), "To-do list")
+	assert.Equals(t, uri.Query().Get("digits"), "6")
+	assert.Equals(t, uri.Query().Get("period"), "30")
+}